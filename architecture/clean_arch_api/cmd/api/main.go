@@ -1,14 +1,33 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/handler"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/idempotency"
 	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/jobs"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/outbox"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
 	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/webhook"
+	"github.com/josediaz/go-mastery-lab/finance/payments"
+	"github.com/josediaz/go-mastery-lab/persistence/wal"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 // ============================================================================
@@ -17,30 +36,375 @@ import (
 // Aquí se ensamblan todas las capas usando Dependency Injection
 // ============================================================================
 
+// config se lee de variables de entorno (12-factor, ver docker-compose.yml)
+type config struct {
+	Port string
+
+	// UserStore elige el backend: "memory" o "sqlite"
+	UserStore   string
+	DatabaseDSN string
+
+	// MemoryDataDir activa el modo durable de USER_STORE=memory (vacío = volátil)
+	MemoryDataDir          string
+	MemoryFsync            wal.SyncPolicy
+	MemoryFsyncInterval    time.Duration
+	MemorySnapshotInterval time.Duration
+
+	UserCacheEnabled     bool
+	UserCacheTTL         time.Duration
+	UserCacheNegativeTTL time.Duration
+	UserCacheMaxEntries  int
+
+	// OutboxPublishers: lista separada por comas de log, bus y webhook
+	// (vacío = no se emiten eventos)
+	OutboxPublishers   string
+	OutboxWebhookURL   string
+	OutboxPollInterval time.Duration
+
+	// Entrega a las suscripciones de /webhooks (requiere outbox activo)
+	WebhookWorkers     int
+	WebhookMaxAttempts int
+	WebhookMaxFailures int
+
+	// Inventario (siempre en memoria): TTL por defecto de las reservas, cada
+	// cuánto se liberan las vencidas y cuánto se guardan las ya cerradas
+	ReservationTTL       time.Duration
+	ReservationSweep     time.Duration
+	ReservationRetention time.Duration
+
+	// Los pedidos cobran con el proveedor de prueba de finance/payments, que
+	// rechaza los montos mayores que este límite en unidades menores (0 = nunca)
+	PaymentDeclineOver int64
+
+	// Jobs en segundo plano (/jobs): workers, tamaño de la cola, cuánto se
+	// guardan los terminados y dónde van los archivos temporales
+	JobWorkers   int
+	JobQueueSize int
+	JobRetention time.Duration
+	JobDir       string
+
+	// ImportMaxBytes limita el body de /users:import y /jobs/users:import
+	ImportMaxBytes int64
+
+	// IdempotencyTTL es cuánto se recuerda un Idempotency-Key
+	IdempotencyTTL time.Duration
+
+	// OpenAPIValidate rechaza con 400 los requests que no cumplen /openapi.json
+	OpenAPIValidate bool
+}
+
+func loadConfig() config {
+	return config{
+		Port:                   getEnv("PORT", "8080"),
+		UserStore:              getEnv("USER_STORE", "memory"),
+		DatabaseDSN:            getEnv("DATABASE_DSN", "users.db"),
+		MemoryDataDir:          getEnv("MEMORY_DATA_DIR", ""),
+		MemoryFsync:            getEnvSyncPolicy("MEMORY_FSYNC", wal.SyncAlways),
+		MemoryFsyncInterval:    getEnvDuration("MEMORY_FSYNC_INTERVAL", time.Second),
+		MemorySnapshotInterval: getEnvDuration("MEMORY_SNAPSHOT_INTERVAL", 5*time.Minute),
+		UserCacheEnabled:       getEnvBool("USER_CACHE_ENABLED", false),
+		UserCacheTTL:           getEnvDuration("USER_CACHE_TTL", 5*time.Minute),
+		UserCacheNegativeTTL:   getEnvDuration("USER_CACHE_NEGATIVE_TTL", 30*time.Second),
+		UserCacheMaxEntries:    getEnvInt("USER_CACHE_MAX_ENTRIES", 10000),
+		OutboxPublishers:       getEnv("OUTBOX_PUBLISHERS", "log"),
+		OutboxWebhookURL:       getEnv("OUTBOX_WEBHOOK_URL", ""),
+		OutboxPollInterval:     getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		WebhookWorkers:         getEnvInt("WEBHOOK_WORKERS", 4),
+		WebhookMaxAttempts:     getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookMaxFailures:     getEnvInt("WEBHOOK_MAX_FAILURES", 5),
+		ReservationTTL:         getEnvDuration("RESERVATION_TTL", 15*time.Minute),
+		ReservationSweep:       getEnvDuration("RESERVATION_SWEEP_INTERVAL", 10*time.Second),
+		ReservationRetention:   getEnvDuration("RESERVATION_RETENTION", 24*time.Hour),
+		PaymentDeclineOver:     int64(getEnvInt("PAYMENT_DECLINE_OVER", 0)),
+		JobWorkers:             getEnvInt("JOB_WORKERS", 2),
+		JobQueueSize:           getEnvInt("JOB_QUEUE_SIZE", 100),
+		JobRetention:           getEnvDuration("JOB_RETENTION", time.Hour),
+		JobDir:                 getEnv("JOB_DIR", os.TempDir()),
+		ImportMaxBytes:         int64(getEnvInt("IMPORT_MAX_BYTES", handler.DefaultImportMaxBytes)),
+		IdempotencyTTL:         getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		OpenAPIValidate:        getEnvBool("OPENAPI_VALIDATE", false),
+	}
+}
+
 func main() {
-	// 1. Crear repositorio (infrastructure)
-	userRepo := infrastructure.NewMemoryUserRepository()
+	cfg := loadConfig()
+
+	// Con SIGINT/SIGTERM se cancela ctx y el servidor se apaga ordenadamente
+	// para que corran los defer (relay, snapshot final del store en memoria, etc.)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 1. Crear repositorio y unit of work (infrastructure)
+	var userRepo repository.UserRepository
+	var txManager repository.TxManager
+	var outboxRepo repository.OutboxRepository
+	var webhookRepo repository.WebhookRepository
+	// El inventario está siempre en memoria: con sqlite usa su propia
+	// unit of work en memoria y emite sus eventos al outbox SQL después
+	// del commit
+	var inventoryTxManager repository.TxManager
+
+	switch cfg.UserStore {
+	case "memory":
+		if cfg.MemoryDataDir == "" {
+			userRepo = infrastructure.NewMemoryUserRepository()
+		} else {
+			durable, err := infrastructure.OpenDurableMemoryUserRepository(infrastructure.DurabilityConfig{
+				Dir:              cfg.MemoryDataDir,
+				Sync:             cfg.MemoryFsync,
+				SyncInterval:     cfg.MemoryFsyncInterval,
+				SnapshotInterval: cfg.MemorySnapshotInterval,
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer func() {
+				if err := durable.Close(); err != nil {
+					log.Printf("closing user store: %v", err)
+				}
+			}()
+			userRepo = durable
+		}
+		txManager = infrastructure.NewMemoryTxManager()
+		inventoryTxManager = txManager
+		outboxRepo = infrastructure.NewMemoryOutboxRepository()
+		webhookRepo = infrastructure.NewMemoryWebhookRepository()
+	case "sqlite":
+		db, err := sql.Open("sqlite3", cfg.DatabaseDSN)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		if err := infrastructure.MigrateUserStore(context.Background(), db); err != nil {
+			log.Fatal(err)
+		}
+		userRepo = infrastructure.NewSQLUserRepository(db)
+		txManager = infrastructure.NewSQLTxManager(db)
+		inventoryTxManager = infrastructure.NewMemoryTxManager()
+		outboxRepo = infrastructure.NewSQLOutboxRepository(db)
+		webhookRepo = infrastructure.NewSQLWebhookRepository(db)
+	default:
+		log.Fatalf("invalid USER_STORE %q (want memory or sqlite)", cfg.UserStore)
+	}
+
+	// 1b. Decorar con cache si está habilitado (los usecases no cambian)
+	if cfg.UserCacheEnabled {
+		cached := infrastructure.NewCachedUserRepository(userRepo, infrastructure.CacheConfig{
+			TTL:         cfg.UserCacheTTL,
+			NegativeTTL: cfg.UserCacheNegativeTTL,
+			MaxEntries:  cfg.UserCacheMaxEntries,
+		})
+		defer cached.Close()
+		expvar.Publish("user_cache", expvar.Func(func() any {
+			return cached.Stats()
+		}))
+		userRepo = cached
+	}
+
+	// 2. Crear casos de uso (usecase). Con publishers configurados los
+	// cambios se registran en el outbox y un relay los publica.
+	var usecaseOpts []usecase.Option
+	inventoryOpts := []usecase.InventoryOption{usecase.WithReservationTTL(cfg.ReservationTTL)}
+	if cfg.OutboxPublishers != "" {
+		publisher, err := newOutboxPublisher(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Las suscripciones de /webhooks reciben los eventos junto a los demás
+		dispatcher := webhook.NewDispatcher(webhookRepo,
+			webhook.WithWorkers(cfg.WebhookWorkers),
+			webhook.WithMaxAttempts(cfg.WebhookMaxAttempts),
+			webhook.WithMaxConsecutiveFailures(cfg.WebhookMaxFailures),
+		)
+		defer dispatcher.Close()
+		publisher = outbox.Multi(publisher, dispatcher)
+		usecaseOpts = append(usecaseOpts, usecase.WithOutbox(outboxRepo))
+		inventoryOpts = append(inventoryOpts, usecase.WithInventoryOutbox(outboxRepo))
+
+		relay := outbox.NewRelay(outboxRepo, publisher, outbox.WithPollInterval(cfg.OutboxPollInterval))
+		relayDone := make(chan struct{})
+		go func() {
+			defer close(relayDone)
+			relay.Run(ctx)
+		}()
+		// Esperar al relay antes de cerrar el store (los defer corren al revés)
+		defer func() { <-relayDone }()
+	}
+	userUsecase := usecase.NewUserUsecase(userRepo, txManager, usecaseOpts...)
+	webhookUsecase := usecase.NewWebhookUsecase(webhookRepo, webhook.GenerateSecret, nil)
+	inventoryUsecase := usecase.NewInventoryUsecase(infrastructure.NewMemoryInventoryRepository(), inventoryTxManager, inventoryOpts...)
+	go inventoryUsecase.RunExpirer(ctx, cfg.ReservationSweep, cfg.ReservationRetention)
+	paymentProvider := payments.NewFakeProvider("fake")
+	paymentProvider.Decline(cfg.PaymentDeclineOver)
+	paymentService := payments.NewService(payments.NewMemoryRepository(), paymentProvider)
+	orderUsecase := usecase.NewOrderUsecase(infrastructure.NewMemoryOrderRepository(),
+		userUsecase, inventoryUsecase, infrastructure.NewPaymentGateway(paymentService))
 
-	// 2. Crear casos de uso (usecase)
-	userUsecase := usecase.NewUserUsecase(userRepo)
+	// Los jobs que siguen corriendo al apagar se cancelan
+	jobManager := jobs.NewManager(
+		jobs.WithWorkers(cfg.JobWorkers),
+		jobs.WithQueueSize(cfg.JobQueueSize),
+		jobs.WithRetention(cfg.JobRetention),
+	)
+	defer jobManager.Close()
 
 	// 3. Crear handlers (handler)
-	userHandler := handler.NewUserHandler(userUsecase)
+	importLimit := handler.WithImportMaxBytes(cfg.ImportMaxBytes)
+	userHandler := handler.NewUserHandler(userUsecase, importLimit)
+	webhookHandler := handler.NewWebhookHandler(webhookUsecase)
+	inventoryHandler := handler.NewInventoryHandler(inventoryUsecase)
+	orderHandler := handler.NewOrderHandler(orderUsecase)
+	jobHandler := handler.NewJobHandler(jobManager, userUsecase, cfg.JobDir, importLimit)
 
 	// 4. Configurar router
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
+	// El contrato se genera de las rutas de abajo y los tipos de los handlers
+	spec := handler.NewAPISpec()
+	if cfg.OpenAPIValidate {
+		r.Use(spec.Validator(r))
+	}
+
+	// Los POST que crean o cambian algo aceptan Idempotency-Key (los imports
+	// no: su body puede ser demasiado grande para guardarlo)
+	idempotencyStore := idempotency.NewMemoryStore(nil)
+	go idempotencyStore.RunCleanup(ctx, time.Minute)
+	idem := idempotency.Middleware(idempotencyStore, idempotency.WithTTL(cfg.IdempotencyTTL))
+
 	// 5. Definir rutas
-	r.Post("/users", userHandler.CreateUser)
+	r.With(idem).Post("/users", userHandler.CreateUser)
+	r.Get("/users", userHandler.ListUsers)
+	r.Post("/users:import", userHandler.ImportUsers)
+	r.Get("/users:export", userHandler.ExportUsers)
 	r.Get("/users/{id}", userHandler.GetUser)
+	r.Patch("/users/{id}", userHandler.UpdateUser)
+	r.Delete("/users/{id}", userHandler.DeleteUser)
+	r.With(idem).Post("/webhooks", webhookHandler.CreateWebhook)
+	r.Get("/webhooks", webhookHandler.ListWebhooks)
+	r.Get("/webhooks/{id}", webhookHandler.GetWebhook)
+	r.Delete("/webhooks/{id}", webhookHandler.DeleteWebhook)
+	r.With(idem).Post("/webhooks/{id}/enable", webhookHandler.EnableWebhook)
+	r.Get("/webhooks/{id}/deliveries", webhookHandler.ListDeliveries)
+	r.With(idem).Post("/products", inventoryHandler.CreateProduct)
+	r.Get("/products", inventoryHandler.ListProducts)
+	r.Get("/products/{sku}", inventoryHandler.GetProduct)
+	r.With(idem).Post("/products/{sku}/stock", inventoryHandler.AdjustStock)
+	r.With(idem).Post("/reservations", inventoryHandler.CreateReservation)
+	r.Get("/reservations/{id}", inventoryHandler.GetReservation)
+	r.With(idem).Post("/reservations/{id}/commit", inventoryHandler.CommitReservation)
+	r.With(idem).Post("/reservations/{id}/release", inventoryHandler.ReleaseReservation)
+	r.With(idem).Post("/orders", orderHandler.PlaceOrder)
+	r.Get("/orders", orderHandler.ListOrders)
+	r.Get("/orders/{id}", orderHandler.GetOrder)
+	r.Post("/jobs/users:import", jobHandler.ImportUsers)
+	r.Post("/jobs/users:export", jobHandler.ExportUsers)
+	r.Get("/jobs/{id}", jobHandler.GetJob)
+	r.Delete("/jobs/{id}", jobHandler.CancelJob)
+	r.Get("/jobs/{id}/result", jobHandler.GetJobResult)
+	r.Handle("/debug/vars", expvar.Handler())
+	r.Get("/openapi.json", spec.Handler(r))
+
+	// Verificar el contrato al arrancar: un Describe sin ruta es un error
+	doc, err := spec.Build(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, route := range doc.Undocumented() {
+		log.Printf("openapi: %s has no description in handler.NewAPISpec", route)
+	}
 
 	// 6. Iniciar servidor
-	port := ":8080"
-	fmt.Printf("Server starting on port %s\n", port)
-	if err := http.ListenAndServe(port, r); err != nil {
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Server starting on port %s\n", srv.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
 	}
+	// ListenAndServe vuelve apenas empieza Shutdown: esperar a los requests en curso
+	<-shutdownDone
+}
+
+// newOutboxPublisher arma el Publisher según OUTBOX_PUBLISHERS. El bus en
+// proceso lleva un suscriptor de ejemplo que cuenta eventos por tipo en
+// /debug/vars (deduplicado, porque la entrega es at-least-once).
+func newOutboxPublisher(cfg config) (outbox.Publisher, error) {
+	var publishers []outbox.Publisher
+	for _, name := range strings.Split(cfg.OutboxPublishers, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "log":
+			publishers = append(publishers, outbox.LogPublisher{})
+		case "bus":
+			counts := expvar.NewMap("user_events")
+			bus := outbox.NewBus()
+			err := bus.Subscribe("*", outbox.Deduplicate(func(ctx context.Context, env outbox.Envelope) error {
+				counts.Add(env.Type, 1)
+				return nil
+			}, 10000, time.Hour))
+			if err != nil {
+				return nil, err
+			}
+			publishers = append(publishers, bus)
+		case "webhook":
+			if cfg.OutboxWebhookURL == "" {
+				return nil, errors.New("OUTBOX_PUBLISHERS includes webhook but OUTBOX_WEBHOOK_URL is empty")
+			}
+			publishers = append(publishers, outbox.WebhookPublisher{URL: cfg.OutboxWebhookURL})
+		default:
+			return nil, fmt.Errorf("invalid OUTBOX_PUBLISHERS entry %q (want log, bus or webhook)", name)
+		}
+	}
+	if len(publishers) == 1 {
+		return publishers[0], nil
+	}
+	return outbox.Multi(publishers...), nil
+}
+
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
 }
 
+func getEnvBool(key string, fallback bool) bool {
+	v, err := strconv.ParseBool(getEnv(key, strconv.FormatBool(fallback)))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return v
+}
+
+func getEnvInt(key string, fallback int) int {
+	v, err := strconv.Atoi(getEnv(key, strconv.Itoa(fallback)))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return v
+}
+
+func getEnvSyncPolicy(key string, fallback wal.SyncPolicy) wal.SyncPolicy {
+	v, err := wal.ParseSyncPolicy(getEnv(key, fallback.String()))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return v
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(getEnv(key, fallback.String()))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+package domain
+
+import "time"
+
+// ============================================================================
+// EVENTOS DE DOMINIO
+// ============================================================================
+// Un evento es un hecho que ya ocurrió (por eso el nombre en pasado).
+// Los emite el usecase y se publican de forma asíncrona vía outbox, así que
+// los consumidores no deben asumir que el usuario sigue en ese estado.
+// Similar a los ApplicationEvent de Spring o a los eventos de Axon en Java.
+// ============================================================================
+
+// Tipos de evento; son parte del contrato con los consumidores
+const (
+	EventUserCreated = "user.created"
+	EventUserUpdated = "user.updated"
+	EventUserDeleted = "user.deleted"
+
+	EventInventoryLowStock = "inventory.low_stock"
+)
+
+// eventTypes son todos los tipos que se emiten: un tipo nuevo se agrega aquí
+// para que las suscripciones de webhooks lo puedan pedir
+var eventTypes = map[string]bool{
+	EventUserCreated:       true,
+	EventUserUpdated:       true,
+	EventUserDeleted:       true,
+	EventInventoryLowStock: true,
+}
+
+// KnownEventType indica si t es uno de los tipos de evento que se emiten
+func KnownEventType(t string) bool {
+	return eventTypes[t]
+}
+
+// Tipos de agregado: los IDs de usuarios y productos se repiten entre sí,
+// así que un agregado se identifica por (tipo, ID)
+const (
+	AggregateUser    = "user"
+	AggregateProduct = "product"
+)
+
+// Event es un evento de dominio sobre un agregado
+type Event interface {
+	EventType() string
+	AggregateType() string
+	AggregateID() int
+}
+
+type UserCreated struct {
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (e UserCreated) EventType() string     { return EventUserCreated }
+func (e UserCreated) AggregateType() string { return AggregateUser }
+func (e UserCreated) AggregateID() int      { return e.UserID }
+
+type UserUpdated struct {
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (e UserUpdated) EventType() string     { return EventUserUpdated }
+func (e UserUpdated) AggregateType() string { return AggregateUser }
+func (e UserUpdated) AggregateID() int      { return e.UserID }
+
+type UserDeleted struct {
+	UserID    int       `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+func (e UserDeleted) EventType() string     { return EventUserDeleted }
+func (e UserDeleted) AggregateType() string { return AggregateUser }
+func (e UserDeleted) AggregateID() int      { return e.UserID }
+
+// InventoryLowStock se emite cuando el disponible de un producto baja hasta
+// su LowStockThreshold (una vez por cruce, no en cada reserva)
+type InventoryLowStock struct {
+	ProductID  int       `json:"product_id"`
+	SKU        string    `json:"sku"`
+	Available  int       `json:"available"`
+	Threshold  int       `json:"threshold"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func (e InventoryLowStock) EventType() string     { return EventInventoryLowStock }
+func (e InventoryLowStock) AggregateType() string { return AggregateProduct }
+func (e InventoryLowStock) AggregateID() int      { return e.ProductID }
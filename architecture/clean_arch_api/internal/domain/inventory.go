@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// INVENTARIO Y RESERVAS
+// ============================================================================
+// El stock de un producto tiene dos partes:
+//
+//	OnHand:    unidades físicas en el almacén
+//	Reserved:  unidades apartadas por reservas activas (aún no vendidas)
+//	Available: OnHand - Reserved, lo que se puede reservar
+//
+// Una reserva aparta stock por un tiempo (TTL):
+//
+//	active ──> committed   (la venta se confirmó: sale del almacén)
+//	   │──> released       (se canceló: vuelve a estar disponible)
+//	   └──> expired        (venció el TTL sin confirmarse: igual que released)
+// ============================================================================
+
+// Product es un producto con su stock
+type Product struct {
+	ID    int         `json:"id"`
+	SKU   string      `json:"sku"`
+	Name  string      `json:"name"`
+	Price money.Money `json:"price"`
+
+	OnHand   int `json:"on_hand"`
+	Reserved int `json:"reserved"`
+	// LowStockThreshold: con Available <= este valor se emite
+	// InventoryLowStock (0 = avisar solo al agotarse)
+	LowStockThreshold int `json:"low_stock_threshold"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Available es lo que se puede reservar
+func (p *Product) Available() int {
+	return p.OnHand - p.Reserved
+}
+
+// IsLowStock indica si el disponible llegó al umbral
+func (p *Product) IsLowStock() bool {
+	return p.Available() <= p.LowStockThreshold
+}
+
+func (p *Product) Validate() error {
+	if p.SKU == "" || p.Name == "" {
+		return ErrInvalidProduct
+	}
+	if p.OnHand < 0 || p.LowStockThreshold < 0 {
+		return ErrInvalidQuantity
+	}
+	if p.Price.Currency() == "" || p.Price.IsNegative() {
+		return ErrInvalidProduct
+	}
+	return nil
+}
+
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// ReservationItem es una línea de una reserva
+type ReservationItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// Reservation aparta stock de uno o más productos. Es todo o nada: si un
+// producto no alcanza, no se reserva ninguno.
+type Reservation struct {
+	ID        int               `json:"id"`
+	Items     []ReservationItem `json:"items"`
+	Status    ReservationStatus `json:"status"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Active indica si la reserva todavía aparta stock
+func (r *Reservation) Active() bool {
+	return r.Status == ReservationActive
+}
+
+// ExpiredAt indica si la reserva está activa pero ya venció su TTL
+func (r *Reservation) ExpiredAt(now time.Time) bool {
+	return r.Active() && !now.Before(r.ExpiresAt)
+}
+
+// Finish cierra una reserva activa con status
+func (r *Reservation) Finish(status ReservationStatus, now time.Time) error {
+	if !r.Active() {
+		return ErrReservationNotActive
+	}
+	r.Status = status
+	r.UpdatedAt = now.UTC()
+	return nil
+}
+
+var (
+	ErrProductNotFound      = &DomainError{Message: "product not found"}
+	ErrProductAlreadyExists = &DomainError{Message: "product with that sku already exists"}
+	ErrInvalidProduct       = &DomainError{Message: "invalid product (sku, name and a non-negative price are required)"}
+	ErrInvalidQuantity      = &DomainError{Message: "invalid quantity"}
+	ErrInsufficientStock    = &DomainError{Message: "insufficient stock"}
+	ErrReservationNotFound  = &DomainError{Message: "reservation not found"}
+	ErrReservationNotActive = &DomainError{Message: "reservation is not active"}
+	ErrReservationExpired   = &DomainError{Message: "reservation expired"}
+)
@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// PEDIDOS
+// ============================================================================
+// Un pedido junta los otros contextos: el cliente (User), el stock
+// (Reservation) y el cobro (finance/payments). No hay una transacción que
+// abarque los tres, así que el pedido avanza con una saga: cada paso tiene
+// una acción de compensación que deshace su efecto si un paso posterior falla.
+//
+//	pending ──> stock_reserved ──> paid ──> confirmed
+//	   │               │             │
+//	   └───────────────┴─────────────┴──> failed (con compensaciones)
+//
+//	paso           acción              compensación
+//	reserve_stock  Reserve             Release
+//	charge_payment Charge              Refund
+//	confirm        Commit reserva      (último paso: no se compensa)
+// ============================================================================
+
+type OrderStatus string
+
+const (
+	OrderPending       OrderStatus = "pending"
+	OrderStockReserved OrderStatus = "stock_reserved"
+	OrderPaid          OrderStatus = "paid"
+	OrderConfirmed     OrderStatus = "confirmed"
+	OrderFailed        OrderStatus = "failed"
+)
+
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:       {OrderStockReserved, OrderFailed},
+	OrderStockReserved: {OrderPaid, OrderFailed},
+	OrderPaid:          {OrderConfirmed, OrderFailed},
+}
+
+// Pasos de la saga, en el orden en que se ejecutan
+const (
+	StepReserveStock  = "reserve_stock"
+	StepChargePayment = "charge_payment"
+	StepConfirm       = "confirm"
+)
+
+type StepStatus string
+
+const (
+	StepDone               StepStatus = "done"
+	StepFailed             StepStatus = "failed"
+	StepCompensated        StepStatus = "compensated"
+	StepCompensationFailed StepStatus = "compensation_failed"
+)
+
+// SagaStep es una entrada del historial de la saga de un pedido
+type SagaStep struct {
+	Name   string     `json:"name"`
+	Status StepStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	At     time.Time  `json:"at"`
+}
+
+// OrderLine es un producto del pedido con el precio al momento de comprar
+type OrderLine struct {
+	SKU       string      `json:"sku"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price"`
+}
+
+type Order struct {
+	ID         int         `json:"id"`
+	CustomerID int         `json:"customer_id"`
+	Lines      []OrderLine `json:"lines"`
+	Total      money.Money `json:"total"`
+	Status     OrderStatus `json:"status"`
+
+	// Referencias a los otros contextos (vacías hasta que el paso se hace)
+	ReservationID int    `json:"reservation_id,omitempty"`
+	PaymentID     string `json:"payment_id,omitempty"`
+
+	FailureReason string     `json:"failure_reason,omitempty"`
+	Steps         []SagaStep `json:"steps"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Transition cambia el estado validando la máquina de estados
+func (o *Order) Transition(to OrderStatus, now time.Time) error {
+	for _, s := range orderTransitions[o.Status] {
+		if s == to {
+			o.Status = to
+			o.UpdatedAt = now.UTC()
+			return nil
+		}
+	}
+	return ErrInvalidOrderTransition
+}
+
+// RecordStep agrega un paso al historial de la saga
+func (o *Order) RecordStep(name string, status StepStatus, err error, now time.Time) {
+	step := SagaStep{Name: name, Status: status, At: now.UTC()}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	o.Steps = append(o.Steps, step)
+	o.UpdatedAt = step.At
+}
+
+// ReservationItems convierte las líneas en items para Reserve
+func (o *Order) ReservationItems() []ReservationItem {
+	items := make([]ReservationItem, len(o.Lines))
+	for i, line := range o.Lines {
+		items[i] = ReservationItem{SKU: line.SKU, Quantity: line.Quantity}
+	}
+	return items
+}
+
+var (
+	ErrOrderNotFound          = &DomainError{Message: "order not found"}
+	ErrInvalidOrder           = &DomainError{Message: "invalid order (customer and at least one item are required)"}
+	ErrInvalidOrderTransition = &DomainError{Message: "invalid order status transition"}
+	ErrPaymentDeclined        = &DomainError{Message: "payment declined"}
+)
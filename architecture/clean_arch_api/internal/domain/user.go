@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 // ============================================================================
 // DOMAIN LAYER - Entidades puras sin dependencias externas
 // ============================================================================
@@ -13,6 +15,40 @@ type User struct {
 	Email    string `json:"email"`
 	Name     string `json:"name"`
 	Password string `json:"-"` // No serializar password
+
+	// Version cambia en cada escritura (optimistic locking, como @Version en
+	// JPA). El repositorio solo acepta un Update si la versión coincide con
+	// la almacenada; si no, alguien más modificó el usuario entretanto.
+	Version int `json:"version"`
+
+	// Campos de auditoría, siempre en UTC. Los asigna el usecase con su
+	// Clock (no time.Now()) para que sean deterministas en tests.
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // nil = activo (soft delete)
+}
+
+// IsDeleted indica si el usuario fue eliminado lógicamente
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
+}
+
+// MarkCreated inicializa los timestamps de un usuario nuevo
+func (u *User) MarkCreated(now time.Time) {
+	u.CreatedAt = now.UTC()
+	u.UpdatedAt = u.CreatedAt
+}
+
+// MarkUpdated registra una modificación
+func (u *User) MarkUpdated(now time.Time) {
+	u.UpdatedAt = now.UTC()
+}
+
+// SoftDelete marca el usuario como eliminado sin borrar el registro
+func (u *User) SoftDelete(now time.Time) {
+	deletedAt := now.UTC()
+	u.DeletedAt = &deletedAt
+	u.UpdatedAt = deletedAt
 }
 
 // Validate valida los datos del usuario
@@ -28,9 +64,11 @@ func (u *User) Validate() error {
 
 // Errores del dominio
 var (
-	ErrInvalidEmail = &DomainError{Message: "invalid email"}
-	ErrInvalidName  = &DomainError{Message: "invalid name"}
-	ErrUserNotFound = &DomainError{Message: "user not found"}
+	ErrInvalidEmail      = &DomainError{Message: "invalid email"}
+	ErrInvalidName       = &DomainError{Message: "invalid name"}
+	ErrUserNotFound      = &DomainError{Message: "user not found"}
+	ErrUserAlreadyExists = &DomainError{Message: "user already exists"}
+	ErrVersionConflict   = &DomainError{Message: "user was modified by another request"}
 )
 
 type DomainError struct {
@@ -40,4 +78,3 @@ type DomainError struct {
 func (e *DomainError) Error() string {
 	return e.Message
 }
-
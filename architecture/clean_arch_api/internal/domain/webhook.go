@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"net/url"
+	"time"
+)
+
+// ============================================================================
+// WEBHOOKS
+// ============================================================================
+// Una suscripción recibe por HTTP los eventos de dominio de los tipos que
+// eligió. Cada entrega va firmada con su Secret (HMAC-SHA256) para que el
+// receptor pueda verificar que viene de nosotros.
+// ============================================================================
+
+// WebhookSubscription es un destino de eventos
+type WebhookSubscription struct {
+	ID         int      `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"` // "*" = todos
+	Secret     string   `json:"-"`           // Solo se muestra al crear
+
+	// Fallos consecutivos (entregas que agotaron sus reintentos). Al llegar
+	// al límite la suscripción se desactiva sola.
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// Active indica si la suscripción recibe entregas
+func (s *WebhookSubscription) Active() bool {
+	return s.DisabledAt == nil
+}
+
+// Matches indica si la suscripción quiere eventos de eventType
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == "*" || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordSuccess reinicia el contador de fallos
+func (s *WebhookSubscription) RecordSuccess() {
+	s.ConsecutiveFailures = 0
+}
+
+// RecordFailure suma un fallo y desactiva la suscripción si llegó a maxFailures.
+// Devuelve true si esta llamada la desactivó.
+func (s *WebhookSubscription) RecordFailure(now time.Time, maxFailures int) bool {
+	s.ConsecutiveFailures++
+	if s.Active() && maxFailures > 0 && s.ConsecutiveFailures >= maxFailures {
+		disabledAt := now.UTC()
+		s.DisabledAt = &disabledAt
+		return true
+	}
+	return false
+}
+
+// Enable reactiva una suscripción desactivada
+func (s *WebhookSubscription) Enable() {
+	s.DisabledAt = nil
+	s.ConsecutiveFailures = 0
+}
+
+func (s *WebhookSubscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	if len(s.EventTypes) == 0 {
+		return ErrInvalidEventTypes
+	}
+	for _, t := range s.EventTypes {
+		if t != "*" && !KnownEventType(t) {
+			return ErrInvalidEventTypes
+		}
+	}
+	return nil
+}
+
+// WebhookDelivery es un intento de entrega (el log de entregas)
+type WebhookDelivery struct {
+	ID             int           `json:"id"`
+	SubscriptionID int           `json:"subscription_id"`
+	EventID        string        `json:"event_id"`
+	EventType      string        `json:"event_type"`
+	Attempt        int           `json:"attempt"`
+	StatusCode     int           `json:"status_code,omitempty"` // 0 = sin respuesta
+	Error          string        `json:"error,omitempty"`
+	Duration       time.Duration `json:"duration_ns"`
+	AttemptedAt    time.Time     `json:"attempted_at"`
+}
+
+// Succeeded indica si el receptor respondió 2xx
+func (d *WebhookDelivery) Succeeded() bool {
+	return d.Error == "" && d.StatusCode >= 200 && d.StatusCode <= 299
+}
+
+var (
+	ErrSubscriptionNotFound = &DomainError{Message: "webhook subscription not found"}
+	ErrInvalidWebhookURL    = &DomainError{Message: "invalid webhook url (want http or https)"}
+	ErrInvalidEventTypes    = &DomainError{Message: "invalid event types"}
+)
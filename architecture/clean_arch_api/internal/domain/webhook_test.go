@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWebhookSubscriptionValidateEventTypes(t *testing.T) {
+	tests := []struct {
+		eventTypes []string
+		valid      bool
+	}{
+		{[]string{"*"}, true},
+		{[]string{EventUserCreated, EventUserDeleted}, true},
+		{[]string{EventInventoryLowStock}, true},
+		{nil, false},
+		{[]string{"user.renamed"}, false},
+		{[]string{EventUserCreated, "inventory.*"}, false},
+	}
+	for _, tt := range tests {
+		s := WebhookSubscription{URL: "https://partner.example.com/hooks", EventTypes: tt.eventTypes}
+		err := s.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("Validate(%v) = %v, want nil", tt.eventTypes, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidEventTypes) {
+			t.Errorf("Validate(%v) = %v, want ErrInvalidEventTypes", tt.eventTypes, err)
+		}
+	}
+}
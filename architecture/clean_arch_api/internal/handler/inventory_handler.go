@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// INVENTARIO - productos, stock y reservas
+// ============================================================================
+
+type InventoryHandler struct {
+	inventoryUsecase *usecase.InventoryUsecase
+}
+
+func NewInventoryHandler(inventoryUsecase *usecase.InventoryUsecase) *InventoryHandler {
+	return &InventoryHandler{inventoryUsecase: inventoryUsecase}
+}
+
+type CreateProductRequest struct {
+	SKU               string      `json:"sku"`
+	Name              string      `json:"name"`
+	Price             money.Money `json:"price"` // {"amount": "10.50", "currency": "USD"}
+	Stock             int         `json:"stock"`
+	LowStockThreshold int         `json:"low_stock_threshold"`
+}
+
+type AdjustStockRequest struct {
+	Delta int `json:"delta"` // Positivo = reposición, negativo = merma
+}
+
+type ReserveRequest struct {
+	Items []domain.ReservationItem `json:"items"`
+	TTL   string                   `json:"ttl,omitempty"` // "10m"; vacío = el TTL por defecto
+}
+
+type ProductResponse struct {
+	ID                int         `json:"id"`
+	SKU               string      `json:"sku"`
+	Name              string      `json:"name"`
+	Price             money.Money `json:"price"`
+	OnHand            int         `json:"on_hand"`
+	Reserved          int         `json:"reserved"`
+	Available         int         `json:"available"`
+	LowStockThreshold int         `json:"low_stock_threshold"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+func newProductResponse(p *domain.Product) ProductResponse {
+	return ProductResponse{
+		ID:                p.ID,
+		SKU:               p.SKU,
+		Name:              p.Name,
+		Price:             p.Price,
+		OnHand:            p.OnHand,
+		Reserved:          p.Reserved,
+		Available:         p.Available(),
+		LowStockThreshold: p.LowStockThreshold,
+		CreatedAt:         p.CreatedAt.UTC(),
+		UpdatedAt:         p.UpdatedAt.UTC(),
+	}
+}
+
+// CreateProduct atiende POST /products
+func (h *InventoryHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	var req CreateProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.inventoryUsecase.CreateProduct(r.Context(), req.SKU, req.Name, req.Price, req.Stock, req.LowStockThreshold)
+	if err != nil {
+		http.Error(w, err.Error(), inventoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newProductResponse(p))
+}
+
+func (h *InventoryHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := h.inventoryUsecase.ListProducts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := make([]ProductResponse, 0, len(products))
+	for _, p := range products {
+		data = append(data, newProductResponse(p))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func (h *InventoryHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	p, err := h.inventoryUsecase.GetProduct(r.Context(), chi.URLParam(r, "sku"))
+	if err != nil {
+		http.Error(w, err.Error(), inventoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newProductResponse(p))
+}
+
+// AdjustStock atiende POST /products/{sku}/stock
+func (h *InventoryHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	var req AdjustStockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.inventoryUsecase.AdjustStock(r.Context(), chi.URLParam(r, "sku"), req.Delta)
+	if err != nil {
+		http.Error(w, err.Error(), inventoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newProductResponse(p))
+}
+
+// CreateReservation atiende POST /reservations (todo o nada)
+func (h *InventoryHandler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	var req ReserveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid ttl", http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+
+	res, err := h.inventoryUsecase.Reserve(r.Context(), req.Items, ttl)
+	if err != nil {
+		http.Error(w, err.Error(), inventoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *InventoryHandler) GetReservation(w http.ResponseWriter, r *http.Request) {
+	h.reservationAction(w, r, h.inventoryUsecase.GetReservation)
+}
+
+// CommitReservation atiende POST /reservations/{id}/commit
+func (h *InventoryHandler) CommitReservation(w http.ResponseWriter, r *http.Request) {
+	h.reservationAction(w, r, h.inventoryUsecase.Commit)
+}
+
+// ReleaseReservation atiende POST /reservations/{id}/release
+func (h *InventoryHandler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	h.reservationAction(w, r, h.inventoryUsecase.Release)
+}
+
+func (h *InventoryHandler) reservationAction(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, id int) (*domain.Reservation, error)) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	res, err := action(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), inventoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// inventoryErrorStatus traduce errores del dominio a códigos HTTP
+func inventoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrProductNotFound), errors.Is(err, domain.ErrReservationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrProductAlreadyExists),
+		errors.Is(err, domain.ErrInsufficientStock),
+		errors.Is(err, domain.ErrReservationNotActive):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrReservationExpired):
+		return http.StatusGone
+	case errors.Is(err, domain.ErrInvalidProduct), errors.Is(err, domain.ErrInvalidQuantity):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/jobs"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+)
+
+// ============================================================================
+// JOBS - operaciones largas en segundo plano
+// ============================================================================
+// POST /jobs/users:import y POST /jobs/users:export aceptan lo mismo que
+// /users:import y /users:export pero responden 202 con el job enseguida.
+// El cliente consulta GET /jobs/{id} (o sigue el header Location) y baja el
+// archivo de una exportación con GET /jobs/{id}/result.
+// ============================================================================
+
+const (
+	jobTypeUsersImport = "users.import"
+	jobTypeUsersExport = "users.export"
+)
+
+type JobHandler struct {
+	manager     *jobs.Manager
+	userUsecase *usecase.UserUsecase
+	dir         string // Archivos temporales (entradas de import y exportaciones)
+	imports     importConfig
+}
+
+func NewJobHandler(manager *jobs.Manager, userUsecase *usecase.UserUsecase, dir string, opts ...ImportOption) *JobHandler {
+	return &JobHandler{manager: manager, userUsecase: userUsecase, dir: dir, imports: newImportConfig(opts)}
+}
+
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), jobErrorStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// CancelJob atiende DELETE /jobs/{id}. Responde 202: un job que está
+// corriendo pasa a cancelled cuando su función se entera.
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.Cancel(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), jobErrorStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetJobResult atiende GET /jobs/{id}/result (el archivo de una exportación)
+func (h *JobHandler) GetJobResult(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	path, contentType, err := h.manager.Artifact(id)
+	if err != nil {
+		http.Error(w, err.Error(), jobErrorStatus(err))
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "result is no longer available", http.StatusGone)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, id))
+	io.Copy(w, f)
+}
+
+// ImportUsers atiende POST /jobs/users:import. El body se copia a un archivo
+// temporal antes de responder: después del 202 ya no se puede leer. Un body
+// más grande que el límite responde 413 sin crear el job.
+func (h *JobHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	format := requestFormat(r, r.Header.Get("Content-Type"))
+	if format != formatCSV && format != formatNDJSON {
+		http.Error(w, "format must be csv or ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+	dryRun, err := parseBoolParam(r, "dry_run")
+	if err != nil {
+		http.Error(w, "Invalid dry_run", http.StatusBadRequest)
+		return
+	}
+
+	input, err := os.CreateTemp(h.dir, "users-import-*."+format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = io.Copy(input, http.MaxBytesReader(w, r.Body, h.imports.maxBytes))
+	if closeErr := input.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(input.Name())
+		http.Error(w, "reading request body: "+err.Error(), importErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	// El archivo se borra con el job (OnRemove): también si se cancela antes
+	// de empezar y su función nunca corre
+	removeInput := func() { os.Remove(input.Name()) }
+	h.submit(w, jobTypeUsersImport, func(ctx context.Context, job *jobs.Handle) (any, error) {
+		f, err := os.Open(input.Name())
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		var rows usecase.UserRowReader
+		if format == formatCSV {
+			if rows, err = newCSVRowReader(f); err != nil {
+				return nil, err
+			}
+		} else {
+			rows = newNDJSONRowReader(f)
+		}
+		done := 0
+		return h.userUsecase.ImportUsers(ctx, rows, usecase.ImportOptions{
+			DryRun: dryRun,
+			Progress: func(rows int) {
+				job.Add(int64(rows - done))
+				done = rows
+			},
+		})
+	}, removeInput)
+}
+
+// ExportUsers atiende POST /jobs/users:export. El archivo queda en
+// GET /jobs/{id}/result hasta que el job sale de la retención.
+func (h *JobHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	format := requestFormat(r, r.Header.Get("Accept"))
+	if format == "" {
+		format = formatNDJSON
+	}
+	if format != formatCSV && format != formatNDJSON {
+		http.Error(w, "format must be csv or ndjson", http.StatusNotAcceptable)
+		return
+	}
+
+	h.submit(w, jobTypeUsersExport, func(ctx context.Context, job *jobs.Handle) (any, error) {
+		f, err := os.CreateTemp(h.dir, "users-export-*."+format)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		out, err := newExportWriter(f, format)
+		if err != nil {
+			os.Remove(f.Name())
+			return nil, err
+		}
+		job.Attach(f.Name(), out.contentType) // Se borra con el job, aunque falle
+
+		last := 0
+		n, err := exportUsers(ctx, h.userUsecase, out, func(n int) {
+			job.Add(int64(n - last))
+			last = n
+		})
+		job.Add(int64(n - last))
+		if err != nil {
+			return nil, err
+		}
+		if err := f.Sync(); err != nil {
+			return nil, err
+		}
+		return map[string]any{"rows": n, "format": format}, nil
+	}, nil)
+}
+
+// submit encola el job y responde 202. cleanup (puede ser nil) libera lo que
+// se preparó para el job: corre cuando el job se borra o si no se pudo encolar.
+func (h *JobHandler) submit(w http.ResponseWriter, typ string, fn jobs.Func, cleanup func()) {
+	var opts []jobs.SubmitOption
+	if cleanup != nil {
+		opts = append(opts, jobs.OnRemove(cleanup))
+	}
+	job, err := h.manager.Submit(typ, fn, opts...)
+	if err != nil {
+		if cleanup != nil {
+			cleanup()
+		}
+		http.Error(w, err.Error(), jobErrorStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// jobErrorStatus traduce errores de jobs a códigos HTTP
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound), errors.Is(err, jobs.ErrNoArtifact):
+		return http.StatusNotFound
+	case errors.Is(err, jobs.ErrFinished), errors.Is(err, jobs.ErrNotFinished):
+		return http.StatusConflict
+	case errors.Is(err, jobs.ErrQueueFull), errors.Is(err, jobs.ErrClosed):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
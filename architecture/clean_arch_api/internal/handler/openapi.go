@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/idempotency"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/jobs"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+	"github.com/josediaz/go-mastery-lab/http/openapi"
+)
+
+// ============================================================================
+// CONTRATO OPENAPI
+// ============================================================================
+// Las rutas las registra main; aquí se describe lo que chi no sabe: tipos
+// de body, respuestas y parámetros. Los tipos son los mismos que usan los
+// handlers, así que un campo nuevo en UserResponse aparece solo en
+// /openapi.json. Un Describe de una ruta que ya no existe hace fallar el
+// arranque (ver openapi.Spec.Build).
+// ============================================================================
+
+// NewAPISpec arma la descripción de la API
+func NewAPISpec() *openapi.Spec {
+	spec := openapi.New("Clean Architecture API", "1.0.0",
+		openapi.WithDescription("Usuarios, webhooks, inventario, pedidos y jobs en segundo plano."))
+
+	// Tipos con JSON propio o valores fijos
+	spec.DefineSchema(money.Money{}, openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"amount":   {Type: "string", Description: "Monto decimal, p. ej. \"10.50\"", Example: "10.50"},
+			"currency": {Type: "string", Description: "Código ISO 4217", Example: "USD"},
+		},
+		Required: []string{"amount", "currency"},
+	})
+	spec.DefineSchema(domain.OrderStatus(""), *openapi.Enum(string(domain.OrderPending), string(domain.OrderStockReserved),
+		string(domain.OrderPaid), string(domain.OrderConfirmed), string(domain.OrderFailed)))
+	spec.DefineSchema(domain.StepStatus(""), *openapi.Enum(string(domain.StepDone), string(domain.StepFailed),
+		string(domain.StepCompensated), string(domain.StepCompensationFailed)))
+	spec.DefineSchema(domain.ReservationStatus(""), *openapi.Enum(string(domain.ReservationActive),
+		string(domain.ReservationCommitted), string(domain.ReservationReleased), string(domain.ReservationExpired)))
+	spec.DefineSchema(jobs.Status(""), *openapi.Enum(string(jobs.StatusQueued), string(jobs.StatusRunning),
+		string(jobs.StatusSucceeded), string(jobs.StatusFailed), string(jobs.StatusCancelled)))
+
+	describeUsers(spec)
+	describeWebhooks(spec)
+	describeInventory(spec)
+	describeOrders(spec)
+	describeJobs(spec)
+
+	spec.Describe(http.MethodGet, "/openapi.json", openapi.Operation{
+		ID:        "getOpenAPI",
+		Summary:   "Este documento",
+		Tags:      []string{"meta"},
+		Responses: openapi.Responses{200: {Body: &openapi.Schema{Type: "object"}}},
+	})
+	spec.Exclude("/debug/vars")
+	return spec
+}
+
+var (
+	idParam      = openapi.PathParam("id", openapi.Integer())
+	idempotent   = openapi.HeaderParam(idempotency.HeaderKey, openapi.String(), "Reintentos seguros: repite la respuesta del primer intento")
+	ifMatch      = openapi.HeaderParam("If-Match", openapi.String(), "ETag (o lista de ETags) de un GET previo; 412 si el recurso cambió")
+	formatParam  = openapi.QueryParam("format", openapi.Enum(formatCSV, formatNDJSON), false, "Pisa el formato de Content-Type/Accept")
+	dryRunParam  = openapi.QueryParam("dry_run", openapi.Boolean(), false, "Valida sin guardar")
+	badRequest   = openapi.Response{Description: "Request inválido"}
+	notFound     = openapi.Response{Description: "No existe"}
+	usersFile    = openapi.Response{Body: openapi.String(), ContentType: "application/x-ndjson", Description: "Un usuario por línea (text/csv con Accept o ?format=csv)"}
+	importReport = openapi.Response{Body: usecase.ImportReport{}, Description: "Reporte por fila"}
+	importTooBig = openapi.Response{Description: "Body más grande que IMPORT_MAX_BYTES"}
+)
+
+type listResponse[T any] struct {
+	Data []T `json:"data"`
+}
+
+func describeUsers(spec *openapi.Spec) {
+	tags := []string{"users"}
+	spec.Describe(http.MethodPost, "/users", openapi.Operation{
+		ID: "createUser", Summary: "Crear usuario", Tags: tags,
+		Params:    []openapi.Param{idempotent},
+		Request:   CreateUserRequest{},
+		Responses: openapi.Responses{200: {Body: UserResponse{}}, 400: {Description: "Request inválido o el email ya existe"}},
+	})
+	spec.Describe(http.MethodGet, "/users", openapi.Operation{
+		ID: "listUsers", Summary: "Listar usuarios (paginado por cursor)", Tags: tags,
+		Params: []openapi.Param{
+			openapi.QueryParam("limit", openapi.Integer(), false, ""),
+			openapi.QueryParam("cursor", openapi.String(), false, "next_cursor de la página anterior"),
+			openapi.QueryParam("email_prefix", openapi.String(), false, ""),
+			openapi.QueryParam("name_contains", openapi.String(), false, ""),
+			openapi.QueryParam("created_after", &openapi.Schema{Type: "string", Format: "date-time"}, false, ""),
+			openapi.QueryParam("created_before", &openapi.Schema{Type: "string", Format: "date-time"}, false, ""),
+			openapi.QueryParam("sort", openapi.Enum("id", "email", "name", "created_at"), false, ""),
+			openapi.QueryParam("order", openapi.Enum("asc", "desc"), false, ""),
+		},
+		Responses: openapi.Responses{200: {Body: ListUsersResponse{}}, 400: badRequest},
+	})
+	spec.Describe(http.MethodPost, "/users:import", openapi.Operation{
+		ID: "importUsers", Summary: "Importar usuarios (CSV o NDJSON)", Tags: tags,
+		Params: []openapi.Param{formatParam, dryRunParam,
+			openapi.QueryParam("batch_size", openapi.Integer(), false, "")},
+		Request:            openapi.String(),
+		RequestContentType: "text/csv",
+		Responses:          openapi.Responses{200: importReport, 400: badRequest, 413: importTooBig, 415: {Description: "Formato no soportado"}},
+	})
+	spec.Describe(http.MethodGet, "/users:export", openapi.Operation{
+		ID: "exportUsers", Summary: "Exportar usuarios", Tags: tags,
+		Params:    []openapi.Param{formatParam},
+		Responses: openapi.Responses{200: usersFile, 406: {Description: "Formato no soportado"}},
+	})
+	spec.Describe(http.MethodGet, "/users/{id}", openapi.Operation{
+		ID: "getUser", Summary: "Obtener usuario (con ETag)", Tags: tags,
+		Params:    []openapi.Param{idParam},
+		Responses: openapi.Responses{200: {Body: UserResponse{}}, 404: notFound},
+	})
+	spec.Describe(http.MethodPatch, "/users/{id}", openapi.Operation{
+		ID: "updateUser", Summary: "Actualizar usuario (parcial)", Tags: tags,
+		Params:    []openapi.Param{idParam, ifMatch},
+		Request:   UpdateUserRequest{},
+		Responses: openapi.Responses{200: {Body: UserResponse{}}, 400: badRequest, 404: notFound, 412: {Description: "If-Match no coincide"}},
+	})
+	spec.Describe(http.MethodDelete, "/users/{id}", openapi.Operation{
+		ID: "deleteUser", Summary: "Borrar usuario", Tags: tags,
+		Params:    []openapi.Param{idParam, ifMatch},
+		Responses: openapi.Responses{204: {}, 404: notFound, 412: {Description: "If-Match no coincide"}},
+	})
+}
+
+func describeWebhooks(spec *openapi.Spec) {
+	tags := []string{"webhooks"}
+	spec.Describe(http.MethodPost, "/webhooks", openapi.Operation{
+		ID: "createWebhook", Summary: "Crear suscripción (el secreto solo se devuelve aquí)", Tags: tags,
+		Params:    []openapi.Param{idempotent},
+		Request:   CreateWebhookRequest{},
+		Responses: openapi.Responses{201: {Body: WebhookResponse{}}, 400: badRequest},
+	})
+	spec.Describe(http.MethodGet, "/webhooks", openapi.Operation{
+		ID: "listWebhooks", Summary: "Listar suscripciones", Tags: tags,
+		Responses: openapi.Responses{200: {Body: listResponse[WebhookResponse]{}}},
+	})
+	spec.Describe(http.MethodGet, "/webhooks/{id}", openapi.Operation{
+		ID: "getWebhook", Summary: "Obtener suscripción", Tags: tags,
+		Params:    []openapi.Param{idParam},
+		Responses: openapi.Responses{200: {Body: WebhookResponse{}}, 404: notFound},
+	})
+	spec.Describe(http.MethodDelete, "/webhooks/{id}", openapi.Operation{
+		ID: "deleteWebhook", Summary: "Borrar suscripción", Tags: tags,
+		Params:    []openapi.Param{idParam},
+		Responses: openapi.Responses{204: {}, 404: notFound},
+	})
+	spec.Describe(http.MethodPost, "/webhooks/{id}/enable", openapi.Operation{
+		ID: "enableWebhook", Summary: "Reactivar una suscripción deshabilitada", Tags: tags,
+		Params:    []openapi.Param{idParam, idempotent},
+		Responses: openapi.Responses{200: {Body: WebhookResponse{}}, 404: notFound},
+	})
+	spec.Describe(http.MethodGet, "/webhooks/{id}/deliveries", openapi.Operation{
+		ID: "listWebhookDeliveries", Summary: "Intentos de entrega", Tags: tags,
+		Params:    []openapi.Param{idParam, openapi.QueryParam("limit", openapi.Integer(), false, "")},
+		Responses: openapi.Responses{200: {Body: listResponse[domain.WebhookDelivery]{}}, 404: notFound},
+	})
+}
+
+func describeInventory(spec *openapi.Spec) {
+	tags := []string{"inventory"}
+	skuParam := openapi.PathParam("sku", openapi.String())
+	reservation := openapi.Responses{200: {Body: domain.Reservation{}}, 404: notFound,
+		409: {Description: "La reserva ya está cerrada"}, 410: {Description: "La reserva venció"}}
+
+	spec.Describe(http.MethodPost, "/products", openapi.Operation{
+		ID: "createProduct", Summary: "Crear producto", Tags: tags,
+		Params:    []openapi.Param{idempotent},
+		Request:   CreateProductRequest{},
+		Responses: openapi.Responses{201: {Body: ProductResponse{}}, 400: badRequest, 409: {Description: "El SKU ya existe"}},
+	})
+	spec.Describe(http.MethodGet, "/products", openapi.Operation{
+		ID: "listProducts", Summary: "Listar productos", Tags: tags,
+		Responses: openapi.Responses{200: {Body: listResponse[ProductResponse]{}}},
+	})
+	spec.Describe(http.MethodGet, "/products/{sku}", openapi.Operation{
+		ID: "getProduct", Summary: "Obtener producto", Tags: tags,
+		Params:    []openapi.Param{skuParam},
+		Responses: openapi.Responses{200: {Body: ProductResponse{}}, 404: notFound},
+	})
+	spec.Describe(http.MethodPost, "/products/{sku}/stock", openapi.Operation{
+		ID: "adjustStock", Summary: "Ajustar stock (reposición o merma)", Tags: tags,
+		Params:    []openapi.Param{skuParam, idempotent},
+		Request:   AdjustStockRequest{},
+		Responses: openapi.Responses{200: {Body: ProductResponse{}}, 400: badRequest, 404: notFound, 409: {Description: "Stock insuficiente"}},
+	})
+	spec.Describe(http.MethodPost, "/reservations", openapi.Operation{
+		ID: "createReservation", Summary: "Reservar stock", Tags: tags,
+		Params:    []openapi.Param{idempotent},
+		Request:   ReserveRequest{},
+		Responses: openapi.Responses{201: {Body: domain.Reservation{}}, 400: badRequest, 404: notFound, 409: {Description: "Stock insuficiente"}},
+	})
+	spec.Describe(http.MethodGet, "/reservations/{id}", openapi.Operation{
+		ID: "getReservation", Summary: "Obtener reserva", Tags: tags,
+		Params:    []openapi.Param{idParam},
+		Responses: openapi.Responses{200: {Body: domain.Reservation{}}, 404: notFound},
+	})
+	spec.Describe(http.MethodPost, "/reservations/{id}/commit", openapi.Operation{
+		ID: "commitReservation", Summary: "Confirmar reserva (descuenta el stock)", Tags: tags,
+		Params:    []openapi.Param{idParam, idempotent},
+		Responses: reservation,
+	})
+	spec.Describe(http.MethodPost, "/reservations/{id}/release", openapi.Operation{
+		ID: "releaseReservation", Summary: "Liberar reserva", Tags: tags,
+		Params:    []openapi.Param{idParam, idempotent},
+		Responses: reservation,
+	})
+}
+
+func describeOrders(spec *openapi.Spec) {
+	tags := []string{"orders"}
+	spec.Describe(http.MethodPost, "/orders", openapi.Operation{
+		ID: "placeOrder", Summary: "Crear pedido (reserva, cobro y confirmación)", Tags: tags,
+		Description: "Si un paso falla el pedido queda en failed y se devuelve con sus pasos compensados.",
+		Params:      []openapi.Param{idempotent},
+		Request:     PlaceOrderRequest{},
+		Responses: openapi.Responses{
+			201: {Body: domain.Order{}},
+			400: badRequest,
+			402: {Body: domain.Order{}, Description: "Cobro rechazado"},
+			409: {Body: domain.Order{}, Description: "Sin stock"},
+		},
+	})
+	spec.Describe(http.MethodGet, "/orders", openapi.Operation{
+		ID: "listOrders", Summary: "Pedidos de un cliente", Tags: tags,
+		Params:    []openapi.Param{openapi.QueryParam("customer_id", openapi.Integer(), true, "")},
+		Responses: openapi.Responses{200: {Body: listResponse[domain.Order]{}}, 400: badRequest},
+	})
+	spec.Describe(http.MethodGet, "/orders/{id}", openapi.Operation{
+		ID: "getOrder", Summary: "Obtener pedido", Tags: tags,
+		Params:    []openapi.Param{idParam},
+		Responses: openapi.Responses{200: {Body: domain.Order{}}, 404: notFound},
+	})
+}
+
+func describeJobs(spec *openapi.Spec) {
+	tags := []string{"jobs"}
+	jobIDParam := openapi.PathParam("id", openapi.String())
+	accepted := openapi.Response{Body: jobs.Job{}, Description: "Job encolado; consultar GET /jobs/{id}"}
+	queueFull := openapi.Response{Description: "Cola llena, reintentar más tarde"}
+
+	spec.Describe(http.MethodPost, "/jobs/users:import", openapi.Operation{
+		ID: "submitUserImport", Summary: "Importar usuarios en segundo plano", Tags: tags,
+		Params:             []openapi.Param{formatParam, dryRunParam},
+		Request:            openapi.String(),
+		RequestContentType: "text/csv",
+		Responses:          openapi.Responses{202: accepted, 400: badRequest, 413: importTooBig, 415: {Description: "Formato no soportado"}, 503: queueFull},
+	})
+	spec.Describe(http.MethodPost, "/jobs/users:export", openapi.Operation{
+		ID: "submitUserExport", Summary: "Exportar usuarios en segundo plano", Tags: tags,
+		Params:    []openapi.Param{formatParam},
+		Responses: openapi.Responses{202: accepted, 406: {Description: "Formato no soportado"}, 503: queueFull},
+	})
+	spec.Describe(http.MethodGet, "/jobs/{id}", openapi.Operation{
+		ID: "getJob", Summary: "Estado y progreso de un job", Tags: tags,
+		Params:    []openapi.Param{jobIDParam},
+		Responses: openapi.Responses{200: {Body: jobs.Job{}}, 404: notFound},
+	})
+	spec.Describe(http.MethodDelete, "/jobs/{id}", openapi.Operation{
+		ID: "cancelJob", Summary: "Cancelar job", Tags: tags,
+		Params:    []openapi.Param{jobIDParam},
+		Responses: openapi.Responses{202: {Body: jobs.Job{}}, 404: notFound, 409: {Description: "El job ya terminó"}},
+	})
+	spec.Describe(http.MethodGet, "/jobs/{id}/result", openapi.Operation{
+		ID: "getJobResult", Summary: "Archivo de una exportación", Tags: tags,
+		Params:    []openapi.Param{jobIDParam},
+		Responses: openapi.Responses{200: usersFile, 404: notFound, 409: {Description: "El job no terminó"}, 410: {Description: "El archivo ya no está"}},
+	})
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+)
+
+// ============================================================================
+// PEDIDOS
+// ============================================================================
+
+type OrderHandler struct {
+	orderUsecase *usecase.OrderUsecase
+}
+
+func NewOrderHandler(orderUsecase *usecase.OrderUsecase) *OrderHandler {
+	return &OrderHandler{orderUsecase: orderUsecase}
+}
+
+type PlaceOrderRequest struct {
+	CustomerID int                 `json:"customer_id"`
+	Items      []usecase.OrderItem `json:"items"`
+}
+
+// PlaceOrder atiende POST /orders. Si la saga falla el pedido igual queda
+// guardado en failed: se devuelve en el body con el código del paso que falló
+// (409 sin stock, 402 cobro rechazado) para que el cliente vea qué se compensó.
+func (h *OrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+	var req PlaceOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderUsecase.PlaceOrder(r.Context(), req.CustomerID, req.Items)
+	if err != nil && order == nil {
+		http.Error(w, err.Error(), orderErrorStatus(err))
+		return
+	}
+
+	status := http.StatusCreated
+	if err != nil {
+		status = orderErrorStatus(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/orders/"+strconv.Itoa(order.ID))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderUsecase.GetOrder(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), orderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// ListOrders atiende GET /orders?customer_id=N
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	customerID, err := strconv.Atoi(r.URL.Query().Get("customer_id"))
+	if err != nil {
+		http.Error(w, "customer_id is required", http.StatusBadRequest)
+		return
+	}
+
+	orders, err := h.orderUsecase.ListCustomerOrders(r.Context(), customerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if orders == nil {
+		orders = []*domain.Order{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": orders})
+}
+
+// orderErrorStatus traduce errores del dominio a códigos HTTP
+func orderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidOrder), errors.Is(err, domain.ErrInvalidQuantity):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, domain.ErrInsufficientStock),
+		errors.Is(err, domain.ErrReservationExpired),
+		errors.Is(err, domain.ErrReservationNotActive):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
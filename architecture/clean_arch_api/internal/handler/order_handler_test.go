@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+	"github.com/josediaz/go-mastery-lab/finance/payments"
+)
+
+// Tests HTTP de /orders: códigos de estado y cuerpo de la respuesta. Los
+// pasos y compensaciones de la saga se prueban en usecase/order_usecase_test.go.
+
+func newOrderServer(t *testing.T) (*httptest.Server, *payments.FakeProvider) {
+	t.Helper()
+	ctx := context.Background()
+	users := usecase.NewUserUsecase(infrastructure.NewMemoryUserRepository(), infrastructure.NewMemoryTxManager())
+	if _, err := users.CreateUser(ctx, "ana@example.com", "Ana", "secret123"); err != nil {
+		t.Fatal(err)
+	}
+	inventory := usecase.NewInventoryUsecase(infrastructure.NewMemoryInventoryRepository(), infrastructure.NewMemoryTxManager())
+	for sku, price := range map[string]string{"KB-1": "79.99", "MS-1": "20.00"} {
+		if _, err := inventory.CreateProduct(ctx, sku, sku, money.MustParse(price, "USD"), 5, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	provider := payments.NewFakeProvider("fake")
+	gateway := infrastructure.NewPaymentGateway(payments.NewService(payments.NewMemoryRepository(), provider))
+
+	orders := usecase.NewOrderUsecase(infrastructure.NewMemoryOrderRepository(), users, inventory, gateway,
+		usecase.WithChargeRetries(3, 0))
+	h := NewOrderHandler(orders)
+	r := chi.NewRouter()
+	r.Post("/orders", h.PlaceOrder)
+	r.Get("/orders", h.ListOrders)
+	r.Get("/orders/{id}", h.GetOrder)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, provider
+}
+
+func placeOrder(t *testing.T, server *httptest.Server, body string) (int, domain.Order) {
+	t.Helper()
+	resp, err := http.Post(server.URL+"/orders", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var order domain.Order
+	if resp.StatusCode < 300 || resp.Header.Get("Content-Type") == "application/json" {
+		if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, order
+}
+
+func TestPlaceOrderConfirms(t *testing.T) {
+	server, _ := newOrderServer(t)
+
+	status, order := placeOrder(t, server, `{"customer_id":1,"items":[{"sku":"KB-1","quantity":2},{"sku":"MS-1","quantity":1}]}`)
+	if status != http.StatusCreated || order.Status != domain.OrderConfirmed {
+		t.Fatalf("POST /orders = %d %s, want 201 confirmed", status, order.Status)
+	}
+	if got := order.Total.Decimal(); got != "179.98" {
+		t.Errorf("total = %s, want 179.98", got)
+	}
+
+	resp, err := http.Get(server.URL + "/orders?customer_id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list struct{ Data []domain.Order }
+	json.NewDecoder(resp.Body).Decode(&list)
+	if len(list.Data) != 1 || list.Data[0].ID != order.ID {
+		t.Errorf("GET /orders = %+v, want the confirmed order", list.Data)
+	}
+}
+
+func TestPlaceOrderFailureStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*payments.FakeProvider)
+		body  string
+		want  int
+	}{
+		{"no stock", nil, `{"customer_id":1,"items":[{"sku":"MS-1","quantity":6}]}`, http.StatusConflict},
+		{"declined", func(p *payments.FakeProvider) { p.Decline(5000) }, `{"customer_id":1,"items":[{"sku":"KB-1","quantity":1}]}`, http.StatusPaymentRequired},
+		{"provider down", func(p *payments.FakeProvider) { p.FailNext(payments.ErrProviderUnavailable) }, `{"customer_id":1,"items":[{"sku":"KB-1","quantity":1}]}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, provider := newOrderServer(t)
+			if tt.setup != nil {
+				tt.setup(provider)
+			}
+			// Un pedido fallido también se devuelve, con su historial
+			status, order := placeOrder(t, server, tt.body)
+			if status != tt.want || order.Status != domain.OrderFailed || order.FailureReason == "" {
+				t.Fatalf("POST /orders = %d %s %q, want %d failed with a reason", status, order.Status, order.FailureReason, tt.want)
+			}
+
+			resp, err := http.Get(server.URL + "/orders/1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var stored domain.Order
+			json.NewDecoder(resp.Body).Decode(&stored)
+			if resp.StatusCode != http.StatusOK || stored.Status != domain.OrderFailed {
+				t.Errorf("GET /orders/1 = %d %s, want the failed order", resp.StatusCode, stored.Status)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderValidation(t *testing.T) {
+	server, _ := newOrderServer(t)
+
+	for _, body := range []string{
+		`{"customer_id":99,"items":[{"sku":"KB-1","quantity":1}]}`,
+		`{"customer_id":1,"items":[]}`,
+		`{"customer_id":1,"items":[{"sku":"NOPE","quantity":1}]}`,
+		`{"customer_id":1,"items":[{"sku":"KB-1","quantity":0}]}`,
+	} {
+		if status, _ := placeOrder(t, server, body); status != http.StatusBadRequest {
+			t.Errorf("POST /orders %s = %d, want 400", body, status)
+		}
+	}
+}
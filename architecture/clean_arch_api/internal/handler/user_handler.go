@@ -2,9 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"github.com/go-chi/chi/v5"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
 	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
 )
 
@@ -20,24 +25,40 @@ import (
 
 type UserHandler struct {
 	userUsecase *usecase.UserUsecase
+	imports     importConfig
 }
 
-func NewUserHandler(userUsecase *usecase.UserUsecase) *UserHandler {
+func NewUserHandler(userUsecase *usecase.UserUsecase, opts ...ImportOption) *UserHandler {
 	return &UserHandler{
 		userUsecase: userUsecase,
+		imports:     newImportConfig(opts),
 	}
 }
 
 type CreateUserRequest struct {
 	Email    string `json:"email"`
 	Name     string `json:"name"`
-	Password string `json:"password"`
+	Password string `json:"password,omitempty"`
 }
 
 type UserResponse struct {
-	ID    int    `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"` // RFC 3339 en UTC
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newUserResponse(user *domain.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		Version:   user.Version,
+		CreatedAt: user.CreatedAt.UTC(),
+		UpdatedAt: user.UpdatedAt.UTC(),
+	}
 }
 
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -54,11 +75,8 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(UserResponse{
-		ID:    user.ID,
-		Email: user.Email,
-		Name:  user.Name,
-	})
+	w.Header().Set("ETag", etag(user))
+	json.NewEncoder(w).Encode(newUserResponse(user))
 }
 
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
@@ -76,10 +94,218 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(UserResponse{
-		ID:    user.ID,
-		Email: user.Email,
-		Name:  user.Name,
+	w.Header().Set("ETag", etag(user))
+	json.NewEncoder(w).Encode(newUserResponse(user))
+}
+
+type ListUsersResponse struct {
+	Data       []UserResponse `json:"data"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// ListUsers atiende GET /users con parámetros:
+//
+//	limit, cursor, email_prefix, name_contains,
+//	created_after, created_before (RFC 3339), sort (id|email|name|created_at), order (asc|desc)
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := repository.ListQuery{
+		EmailPrefix:  params.Get("email_prefix"),
+		NameContains: params.Get("name_contains"),
+		SortBy:       repository.UserSortField(params.Get("sort")),
+		Cursor:       params.Get("cursor"),
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		http.Error(w, "Invalid order (want asc or desc)", http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = limit
+	}
+	for name, dst := range map[string]*time.Time{
+		"created_after":  &q.CreatedAfter,
+		"created_before": &q.CreatedBefore,
+	} {
+		if v := params.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" (want RFC 3339)", http.StatusBadRequest)
+				return
+			}
+			*dst = t
+		}
+	}
+
+	page, err := h.userUsecase.ListUsers(r.Context(), q)
+	if err != nil {
+		if repository.IsInvalidQuery(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListUsersResponse{Data: make([]UserResponse, 0, len(page.Users)), NextCursor: page.NextCursor}
+	for _, user := range page.Users {
+		resp.Data = append(resp.Data, newUserResponse(user))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UpdateUserRequest admite cambios parciales: los campos omitidos no cambian
+type UpdateUserRequest struct {
+	Email *string `json:"email"`
+	Name  *string `json:"name"`
+}
+
+// UpdateUser atiende PATCH /users/{id}. Con If-Match (el ETag de un GET
+// previo) solo se aplica si nadie modificó el usuario desde esa lectura;
+// si no coincide responde 412 y el cliente debe releer y reintentar.
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.expectedVersion(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), userErrorStatus(err))
+		return
+	}
+
+	user, err := h.userUsecase.UpdateUser(r.Context(), id, version, usecase.UserChanges{
+		Email: req.Email,
+		Name:  req.Name,
 	})
+	if err != nil {
+		http.Error(w, err.Error(), userErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(user))
+	json.NewEncoder(w).Encode(newUserResponse(user))
+}
+
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.expectedVersion(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), userErrorStatus(err))
+		return
+	}
+
+	if err := h.userUsecase.DeleteUser(r.Context(), id, version); err != nil {
+		http.Error(w, err.Error(), userErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userErrorStatus traduce errores del dominio a códigos HTTP
+func userErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrVersionConflict), errors.Is(err, errPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, domain.ErrUserAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrInvalidEmail), errors.Is(err, domain.ErrInvalidName):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
+// ============================================================================
+// ETAG / IF-MATCH
+// ============================================================================
+// El ETag es la versión del usuario entre comillas ("3"). Es un ETag fuerte:
+// cambia con cada escritura, así que If-Match equivale a "versión esperada".
+
+func etag(user *domain.User) string {
+	return `"` + strconv.Itoa(user.Version) + `"`
+}
+
+var errPreconditionFailed = errors.New("If-Match does not match any current version")
+
+// ifMatchVersions devuelve las versiones pedidas en If-Match, o nil si el
+// header no está o es "*". Acepta la lista separada por comas de RFC 9110
+// ("1", "2"). Un ETag débil (W/"3") o mal formado nunca coincide: If-Match
+// usa comparación fuerte; si ninguno sirve, la precondición falla.
+func ifMatchVersions(r *http.Request) ([]int, error) {
+	header := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, errPreconditionFailed
+	}
+	return versions, nil
+}
+
+// expectedVersion traduce If-Match a la versión que recibe el usecase.
+// Con varias versiones se lee la actual y, si está en la lista, se escribe
+// condicionado a ella: si otro la cambia en el medio, el usecase devuelve
+// ErrVersionConflict igual que con una sola.
+func (h *UserHandler) expectedVersion(r *http.Request, id int) (int, error) {
+	versions, err := ifMatchVersions(r)
+	if err != nil {
+		return 0, err
+	}
+	switch len(versions) {
+	case 0:
+		return usecase.AnyVersion, nil
+	case 1:
+		return versions[0], nil
+	}
+	user, err := h.userUsecase.GetUser(r.Context(), id)
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version == user.Version {
+			return version, nil
+		}
+	}
+	return 0, errPreconditionFailed
+}
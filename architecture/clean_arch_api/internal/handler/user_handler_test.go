@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+)
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		fails  bool
+	}{
+		{header: "", want: "[]"},
+		{header: "*", want: "[]"},
+		{header: `"3"`, want: "[3]"},
+		{header: `"1", "2"`, want: "[1 2]"},
+		{header: `"1","2" ,  "5"`, want: "[1 2 5]"},
+		// Débiles y mal formados no coinciden, pero no anulan al resto
+		{header: `W/"2", "3"`, want: "[3]"},
+		{header: `"x", "0", "4"`, want: "[4]"},
+		{header: `W/"2"`, fails: true},
+		{header: `3`, fails: true},
+		{header: `"abc"`, fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/users/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			got, err := ifMatchVersions(r)
+			if tt.fails {
+				if err == nil {
+					t.Errorf("versions = %v, want errPreconditionFailed", got)
+				}
+				return
+			}
+			if err != nil || fmt.Sprint(got) != tt.want {
+				t.Errorf("versions = %v, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfMatchListOnWrites(t *testing.T) {
+	users := usecase.NewUserUsecase(infrastructure.NewMemoryUserRepository(), infrastructure.NewMemoryTxManager())
+	h := NewUserHandler(users)
+	r := chi.NewRouter()
+	r.Patch("/users/{id}", h.UpdateUser)
+	r.Delete("/users/{id}", h.DeleteUser)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	user, err := users.CreateUser(context.Background(), "ana@example.com", "Ana", "secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(method, ifMatch, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+"/users/"+strconv.Itoa(user.ID), strings.NewReader(body))
+		req.Header.Set("If-Match", ifMatch)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Versión 1 -> 2
+	if code := send(http.MethodPatch, `"7", "1"`, `{"name":"Ana B"}`); code != http.StatusOK {
+		t.Fatalf("PATCH with the current version in the list = %d", code)
+	}
+	if code := send(http.MethodPatch, `"1", "3"`, `{"name":"Ana C"}`); code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH without the current version in the list = %d, want 412", code)
+	}
+	if code := send(http.MethodDelete, `"1", "2"`, ``); code != http.StatusNoContent {
+		t.Errorf("DELETE with the current version in the list = %d", code)
+	}
+	if code := send(http.MethodDelete, `"1", "2"`, ``); code != http.StatusNotFound {
+		t.Errorf("DELETE of a deleted user = %d, want 404", code)
+	}
+}
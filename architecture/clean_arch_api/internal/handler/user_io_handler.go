@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+)
+
+// ============================================================================
+// IMPORTACIÓN / EXPORTACIÓN - CSV y NDJSON
+// ============================================================================
+// El formato sale de ?format=csv|ndjson o, si no está, del Content-Type (import)
+// o del Accept (export). Los dos sentidos trabajan en streaming: el body se
+// lee fila por fila y la exportación se escribe página por página.
+//
+// CSV: la primera línea es el encabezado; email y name son obligatorios,
+// password es opcional y el orden de las columnas es libre.
+// NDJSON: un objeto por línea, {"email": "...", "name": "...", "password": "..."}
+// ============================================================================
+
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+
+	// maxNDJSONLine limita el tamaño de una línea de NDJSON
+	maxNDJSONLine = 1 << 20
+	// exportFlushEvery es cada cuántas filas se envía lo escrito al cliente
+	exportFlushEvery = 100
+)
+
+// DefaultImportMaxBytes es el tamaño máximo por defecto del body de
+// /users:import y /jobs/users:import
+const DefaultImportMaxBytes = 32 << 20
+
+var errMalformedFile = errors.New("malformed file")
+
+// ImportOption configura los endpoints de importación de UserHandler y JobHandler
+type ImportOption func(*importConfig)
+
+type importConfig struct {
+	maxBytes int64
+}
+
+// WithImportMaxBytes limita el body de una importación
+// (DefaultImportMaxBytes); uno más grande responde 413
+func WithImportMaxBytes(n int64) ImportOption {
+	return func(c *importConfig) {
+		c.maxBytes = n
+	}
+}
+
+func newImportConfig(opts []ImportOption) importConfig {
+	c := importConfig{maxBytes: DefaultImportMaxBytes}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// importErrorStatus devuelve 413 si err viene de un body más grande que
+// el límite, y status si no
+func importErrorStatus(err error, status int) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return status
+}
+
+var exportColumns = []string{"id", "email", "name", "version", "created_at", "updated_at"}
+
+// ImportUsers atiende POST /users:import[?dry_run=true]. Responde el reporte
+// por fila; un archivo que no se puede leer responde 400 con el reporte
+// parcial (los lotes anteriores quedan importados), y 413 si supera el límite.
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	format := requestFormat(r, r.Header.Get("Content-Type"))
+	dryRun, err := parseBoolParam(r, "dry_run")
+	if err != nil {
+		http.Error(w, "Invalid dry_run", http.StatusBadRequest)
+		return
+	}
+	batchSize := 0
+	if v := r.URL.Query().Get("batch_size"); v != "" {
+		if batchSize, err = strconv.Atoi(v); err != nil || batchSize < 1 {
+			http.Error(w, "Invalid batch_size", http.StatusBadRequest)
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, h.imports.maxBytes)
+	var rows usecase.UserRowReader
+	switch format {
+	case formatCSV:
+		rows, err = newCSVRowReader(body)
+	case formatNDJSON:
+		rows = newNDJSONRowReader(body)
+	default:
+		http.Error(w, "format must be csv or ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), importErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	report, err := h.userUsecase.ImportUsers(r.Context(), rows, usecase.ImportOptions{DryRun: dryRun, BatchSize: batchSize})
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errMalformedFile) {
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(importErrorStatus(err, status))
+		json.NewEncoder(w).Encode(map[string]any{"error": err.Error(), "report": report})
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// ExportUsers atiende GET /users:export. Si algo falla a mitad de camino
+// el status ya se envió: se corta la respuesta y queda en el log.
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	format := requestFormat(r, r.Header.Get("Accept"))
+	if format == "" {
+		format = formatNDJSON
+	}
+	out, err := newExportWriter(w, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotAcceptable)
+		return
+	}
+	w.Header().Set("Content-Type", out.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, format))
+
+	flusher, _ := w.(http.Flusher)
+	n, err := exportUsers(r.Context(), h.userUsecase, out, func(n int) {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+	if err != nil {
+		log.Printf("export users: aborted after %d rows: %v", n, err)
+	}
+}
+
+// exportWriter escribe usuarios en un formato de exportación
+type exportWriter struct {
+	contentType string
+	write       func(*domain.User) error
+	flush       func() error
+}
+
+func newExportWriter(w io.Writer, format string) (*exportWriter, error) {
+	switch format {
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return &exportWriter{
+			contentType: "text/csv",
+			write: func(u *domain.User) error {
+				return cw.Write([]string{
+					strconv.Itoa(u.ID), u.Email, u.Name, strconv.Itoa(u.Version),
+					u.CreatedAt.UTC().Format(time.RFC3339Nano), u.UpdatedAt.UTC().Format(time.RFC3339Nano),
+				})
+			},
+			flush: func() error {
+				cw.Flush()
+				return cw.Error()
+			},
+		}, nil
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		return &exportWriter{
+			contentType: "application/x-ndjson",
+			write: func(u *domain.User) error {
+				return enc.Encode(newUserResponse(u))
+			},
+			flush: func() error { return nil },
+		}, nil
+	default:
+		return nil, errors.New("format must be csv or ndjson")
+	}
+}
+
+// exportUsers escribe todos los usuarios en out y llama a flushed cada
+// exportFlushEvery filas. Devuelve cuántas filas escribió.
+func exportUsers(ctx context.Context, uc *usecase.UserUsecase, out *exportWriter, flushed func(n int)) (int, error) {
+	n := 0
+	err := uc.ExportUsers(ctx, func(u *domain.User) error {
+		if err := out.write(u); err != nil {
+			return err
+		}
+		n++
+		if n%exportFlushEvery == 0 {
+			if err := out.flush(); err != nil {
+				return err
+			}
+			flushed(n)
+		}
+		return nil
+	})
+	if err == nil {
+		err = out.flush()
+	}
+	return n, err
+}
+
+// requestFormat devuelve ?format o el formato que indica el header
+func requestFormat(r *http.Request, header string) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return strings.ToLower(f)
+	}
+	for _, part := range strings.Split(header, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return formatCSV
+		case "application/x-ndjson", "application/ndjson", "application/jsonl":
+			return formatNDJSON
+		}
+	}
+	return ""
+}
+
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// csvRowReader lee filas de un CSV con encabezado
+type csvRowReader struct {
+	r                     *csv.Reader
+	email, name, password int // Índice de cada columna (-1 = no está)
+}
+
+func newCSVRowReader(body io.Reader) (*csvRowReader, error) {
+	r := csv.NewReader(body)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading CSV header: %w", errMalformedFile, err)
+	}
+	c := &csvRowReader{r: r, email: -1, name: -1, password: -1}
+	for i, col := range header {
+		switch strings.ToLower(strings.TrimSpace(col)) {
+		case "email":
+			c.email = i
+		case "name":
+			c.name = i
+		case "password":
+			c.password = i
+		}
+	}
+	if c.email < 0 || c.name < 0 {
+		return nil, fmt.Errorf("%w: CSV header must include email and name", errMalformedFile)
+	}
+	return c, nil
+}
+
+func (c *csvRowReader) Next() (usecase.ImportRow, error) {
+	record, err := c.r.Read()
+	var parseErr *csv.ParseError
+	switch {
+	case errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount):
+		// Fila con otra cantidad de columnas: se reporta y se sigue
+		return usecase.ImportRow{Line: parseErr.StartLine, Err: err}, nil
+	case errors.Is(err, io.EOF):
+		return usecase.ImportRow{}, io.EOF
+	case err != nil:
+		return usecase.ImportRow{}, fmt.Errorf("%w: %w", errMalformedFile, err)
+	}
+
+	line, _ := c.r.FieldPos(0)
+	row := usecase.ImportRow{Line: line, Email: record[c.email], Name: record[c.name]}
+	if c.password >= 0 {
+		row.Password = record[c.password]
+	}
+	return row, nil
+}
+
+// ndjsonRowReader lee un objeto JSON por línea (las líneas vacías se saltean)
+type ndjsonRowReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newNDJSONRowReader(body io.Reader) *ndjsonRowReader {
+	s := bufio.NewScanner(body)
+	s.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+	return &ndjsonRowReader{s: s}
+}
+
+func (n *ndjsonRowReader) Next() (usecase.ImportRow, error) {
+	for n.s.Scan() {
+		n.line++
+		data := strings.TrimSpace(n.s.Text())
+		if data == "" {
+			continue
+		}
+		var req CreateUserRequest
+		if err := json.Unmarshal([]byte(data), &req); err != nil {
+			return usecase.ImportRow{Line: n.line, Err: fmt.Errorf("invalid JSON: %v", err)}, nil
+		}
+		return usecase.ImportRow{Line: n.line, Email: req.Email, Name: req.Name, Password: req.Password}, nil
+	}
+	if err := n.s.Err(); err != nil {
+		return usecase.ImportRow{}, fmt.Errorf("%w: line %d: %w", errMalformedFile, n.line+1, err)
+	}
+	return usecase.ImportRow{}, io.EOF
+}
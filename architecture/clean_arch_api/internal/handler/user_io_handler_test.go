@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/jobs"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+)
+
+func TestImportBodyLimit(t *testing.T) {
+	users := usecase.NewUserUsecase(infrastructure.NewMemoryUserRepository(), infrastructure.NewMemoryTxManager())
+	manager := jobs.NewManager()
+	defer manager.Close()
+	dir := t.TempDir()
+
+	limit := WithImportMaxBytes(100)
+	r := chi.NewRouter()
+	r.Post("/users:import", NewUserHandler(users, limit).ImportUsers)
+	r.Post("/jobs/users:import", NewJobHandler(manager, users, dir, limit).ImportUsers)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	small := "email,name\nana@example.com,Ana\n"
+	large := small + strings.Repeat("x@example.com,X\n", 10)
+	tests := []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/users:import?dry_run=true", small, http.StatusOK},
+		{"/users:import", large, http.StatusRequestEntityTooLarge},
+		// Un header más grande que el límite también es 413, no 400
+		{"/users:import", "email,name," + strings.Repeat("x", 200), http.StatusRequestEntityTooLarge},
+		{"/jobs/users:import?dry_run=true", small, http.StatusAccepted},
+		{"/jobs/users:import", large, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		resp, err := http.Post(server.URL+tt.path, "text/csv", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("POST %s with %d bytes = %d, want %d", tt.path, len(tt.body), resp.StatusCode, tt.want)
+		}
+	}
+
+	// El import rechazado no deja su archivo temporal; el aceptado lo
+	// guarda hasta que se borre el job
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("%d files in the job dir, want only the accepted import", len(files))
+	}
+}
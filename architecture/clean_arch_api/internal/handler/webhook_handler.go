@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/usecase"
+)
+
+// ============================================================================
+// WEBHOOKS - administración de suscripciones
+// ============================================================================
+
+type WebhookHandler struct {
+	webhookUsecase *usecase.WebhookUsecase
+}
+
+func NewWebhookHandler(webhookUsecase *usecase.WebhookUsecase) *WebhookHandler {
+	return &WebhookHandler{webhookUsecase: webhookUsecase}
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"` // vacío = se genera uno
+}
+
+type WebhookResponse struct {
+	ID                  int        `json:"id"`
+	URL                 string     `json:"url"`
+	EventTypes          []string   `json:"event_types"`
+	Secret              string     `json:"secret,omitempty"` // solo en la respuesta de POST
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+func newWebhookResponse(sub *domain.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:                  sub.ID,
+		URL:                 sub.URL,
+		EventTypes:          sub.EventTypes,
+		Active:              sub.Active(),
+		ConsecutiveFailures: sub.ConsecutiveFailures,
+		DisabledAt:          sub.DisabledAt,
+		CreatedAt:           sub.CreatedAt.UTC(),
+	}
+}
+
+// CreateWebhook atiende POST /webhooks. El secreto se devuelve solo aquí.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.webhookUsecase.CreateSubscription(r.Context(), req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	resp := newWebhookResponse(sub)
+	resp.Secret = sub.Secret
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookUsecase.ListSubscriptions(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := make([]WebhookResponse, 0, len(subs))
+	for _, sub := range subs {
+		data = append(data, newWebhookResponse(sub))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookUsecase.GetSubscription(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newWebhookResponse(sub))
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookUsecase.DeleteSubscription(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EnableWebhook atiende POST /webhooks/{id}/enable (reactivar tras auto-disable)
+func (h *WebhookHandler) EnableWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookUsecase.EnableSubscription(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newWebhookResponse(sub))
+}
+
+// ListDeliveries atiende GET /webhooks/{id}/deliveries?limit=N
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.webhookUsecase.Deliveries(r.Context(), id, limit)
+	if err != nil {
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*domain.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": deliveries})
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// webhookErrorStatus traduce errores del dominio a códigos HTTP
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrSubscriptionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidWebhookURL), errors.Is(err, domain.ErrInvalidEventTypes):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
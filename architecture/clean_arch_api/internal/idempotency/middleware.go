@@ -0,0 +1,181 @@
+// Package idempotency implementa el header Idempotency-Key: un cliente que
+// reintenta un POST después de un timeout recibe la respuesta del primer
+// intento en vez de crear el recurso dos veces.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ============================================================================
+// MIDDLEWARE IDEMPOTENCY-KEY
+// ============================================================================
+// El record se guarda por key + método + ruta, junto con el hash del body:
+//
+//	primer request      -> reserva la key, ejecuta el handler, guarda la respuesta
+//	reintento igual     -> repite la respuesta guardada (Idempotent-Replayed: true)
+//	mismo key, otro body-> 422 (el cliente reusó la key para otra cosa)
+//	primero en curso    -> 409 (reintentar en un momento)
+//
+// Las respuestas 5xx no se guardan: la key se libera para que el reintento
+// vuelva a ejecutar el handler. Las 4xx sí (el mismo request va a fallar
+// igual). Requests sin header pasan sin cambios.
+//
+// Es el mismo esquema que usan las APIs de pagos (Stripe, Adyen); para la
+// idempotencia dentro del dominio ver finance/payments y finance/ledger.
+// ============================================================================
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// config del middleware
+type config struct {
+	ttl          time.Duration
+	maxBodyBytes int64
+	logger       *log.Logger
+}
+
+type Option func(*config)
+
+// WithTTL define cuánto se recuerda una key (24h)
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxBodyBytes limita el body que se lee para calcular el hash (1 MiB)
+func WithMaxBodyBytes(n int64) Option {
+	return func(c *config) {
+		c.maxBodyBytes = n
+	}
+}
+
+func WithLogger(l *log.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// Middleware aplica Idempotency-Key a los requests que traen el header
+func Middleware(store Store, opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{ttl: 24 * time.Hour, maxBodyBytes: 1 << 20, logger: log.Default()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idemKey := r.Header.Get(HeaderKey)
+			if idemKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(idemKey) > maxKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cfg.maxBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, "request body too large for Idempotency-Key", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "reading request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(body)
+			bodyHash := hex.EncodeToString(sum[:])
+			key := r.Method + " " + r.URL.Path + "\x00" + idemKey
+
+			ctx := r.Context()
+			existing, err := store.Begin(ctx, key, bodyHash, cfg.ttl)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if existing != nil {
+				switch {
+				case existing.BodyHash != bodyHash:
+					http.Error(w, "Idempotency-Key was already used with a different request body", http.StatusUnprocessableEntity)
+				case existing.Response == nil:
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, "a request with this Idempotency-Key is still in progress", http.StatusConflict)
+				default:
+					replay(w, existing.Response)
+				}
+				return
+			}
+
+			// Guardar la respuesta aunque el cliente ya haya cortado
+			storeCtx := context.WithoutCancel(ctx)
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				// Panic o respuesta 5xx: liberar la key para permitir el reintento
+				if !completed {
+					if err := store.Release(storeCtx, key); err != nil {
+						cfg.logger.Printf("idempotency: releasing key: %v", err)
+					}
+				}
+			}()
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= 500 {
+				return
+			}
+			resp := Response{Status: rec.status, Header: rec.Header().Clone(), Body: rec.body.Bytes()}
+			if err := store.Complete(storeCtx, key, resp); err != nil {
+				cfg.logger.Printf("idempotency: storing response: %v", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, resp *Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recorder escribe la respuesta al cliente y además la copia para guardarla
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,149 @@
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func post(t *testing.T, h http.Handler, path, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// counting responde 201 con el body y cuántas veces se ejecutó
+func counting(calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Call", string(rune('0'+n)))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	})
+}
+
+func TestRetryReplaysFirstResponse(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(nil))(counting(&calls))
+
+	first := post(t, h, "/users", "k1", `{"email":"a@x.com"}`)
+	retry := post(t, h, "/users", "k1", `{"email":"a@x.com"}`)
+	if calls.Load() != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls.Load())
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get("X-Call") != "1" {
+		t.Errorf("retry = %d %q %v, want the first response", retry.Code, retry.Body, retry.Header())
+	}
+	if retry.Header().Get(HeaderReplayed) != "true" || first.Header().Get(HeaderReplayed) != "" {
+		t.Errorf("replayed header: first %q, retry %q", first.Header().Get(HeaderReplayed), retry.Header().Get(HeaderReplayed))
+	}
+
+	// Otra ruta o sin header: requests independientes
+	post(t, h, "/orders", "k1", `{"email":"a@x.com"}`)
+	post(t, h, "/users", "", `{"email":"a@x.com"}`)
+	if calls.Load() != 3 {
+		t.Errorf("handler calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestDifferentBodyIsRejected(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(nil))(counting(&calls))
+
+	post(t, h, "/users", "k1", `{"email":"a@x.com"}`)
+	if w := post(t, h, "/users", "k1", `{"email":"b@x.com"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("mismatched body = %d, want 422", w.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestConcurrentDuplicateGetsConflict(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := Middleware(NewMemoryStore(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan int)
+	go func() { done <- post(t, h, "/users", "k1", `{}`).Code }()
+	<-started
+	if w := post(t, h, "/users", "k1", `{}`); w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("in-flight duplicate = %d, want 409 with Retry-After", w.Code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("first request = %d", code)
+	}
+	if w := post(t, h, "/users", "k1", `{}`); w.Code != http.StatusCreated || w.Header().Get(HeaderReplayed) != "true" {
+		t.Errorf("retry after completion = %d, want replayed 201", w.Code)
+	}
+}
+
+func TestServerErrorsAreNotStored(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "database down", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	post(t, h, "/users", "k1", `{}`)
+	if w := post(t, h, "/users", "k1", `{}`); w.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("retry after 503 = %d (calls %d), want a fresh 201", w.Code, calls.Load())
+	}
+}
+
+func TestKeysExpireAfterTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	store := NewMemoryStore(clock.Now)
+	var calls atomic.Int32
+	h := Middleware(store, WithTTL(time.Hour))(counting(&calls))
+
+	post(t, h, "/users", "k1", `{}`)
+	clock.advance(59 * time.Minute)
+	post(t, h, "/users", "k1", `{}`)
+	if calls.Load() != 1 {
+		t.Fatalf("handler calls = %d before TTL, want 1", calls.Load())
+	}
+	clock.advance(time.Minute)
+	if n := store.Cleanup(); n != 1 {
+		t.Errorf("Cleanup = %d, want 1", n)
+	}
+	post(t, h, "/users", "k1", `{"other":"body"}`)
+	if calls.Load() != 2 {
+		t.Errorf("handler calls = %d after TTL, want 2", calls.Load())
+	}
+}
@@ -0,0 +1,109 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Response es la respuesta guardada para repetirla en los reintentos
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record es lo que se guarda por cada key
+type Record struct {
+	BodyHash  string
+	Response  *Response // nil = el primer request todavía está en curso
+	ExpiresAt time.Time
+}
+
+// Store guarda los records. key ya incluye método y ruta.
+type Store interface {
+	// Begin reserva key para un request nuevo. Si ya existía un record
+	// vigente devuelve ese record y no reserva nada.
+	Begin(ctx context.Context, key, bodyHash string, ttl time.Duration) (existing *Record, err error)
+	// Complete guarda la respuesta del request que reservó key
+	Complete(ctx context.Context, key string, resp Response) error
+	// Release borra la reserva (el request falló y se puede reintentar)
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryStore es un Store en memoria. Los records vencidos se borran al
+// pasar por encima o con Cleanup.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+	now     func() time.Time
+}
+
+func NewMemoryStore(now func() time.Time) *MemoryStore {
+	if now == nil {
+		now = time.Now
+	}
+	return &MemoryStore{records: make(map[string]*Record), now: now}
+}
+
+func (s *MemoryStore) Begin(ctx context.Context, key, bodyHash string, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if rec, ok := s.records[key]; ok && now.Before(rec.ExpiresAt) {
+		copied := *rec
+		return &copied, nil
+	}
+	s.records[key] = &Record{BodyHash: bodyHash, ExpiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, resp Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok {
+		rec.Response = &resp
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// Cleanup borra los records vencidos y devuelve cuántos borró
+func (s *MemoryStore) Cleanup() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	n := 0
+	for key, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n
+}
+
+// RunCleanup llama a Cleanup cada interval hasta que ctx se cancela
+func (s *MemoryStore) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Cleanup()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+	"github.com/josediaz/go-mastery-lab/concurrency/cache"
+)
+
+// ============================================================================
+// DECORATOR: CACHE READ-THROUGH PARA UserRepository
+// ============================================================================
+// CachedUserRepository implementa repository.UserRepository envolviendo a
+// otro UserRepository (patrón Decorator). Los usecases no saben que existe:
+// se activa o no al ensamblar las capas en main.go.
+//
+// - GetByID: primero el cache, si no está se consulta el backend. Las
+//   cargas usan cache.GetOrLoad: requests concurrentes por el mismo ID
+//   comparten una sola consulta, y si un Update o Delete llega durante la
+//   consulta el valor leído (ya viejo) no se guarda
+// - GetByEmail: byEmail es solo un índice email -> ID; el usuario se lee de
+//   byID y se verifica que siga teniendo ese email. Así un índice viejo (el
+//   usuario cambió de email) se detecta y se vuelve a consultar el backend.
+//   Un miss llena las dos entradas: el GetByID siguiente ya no va al backend
+// - Negative caching: "no existe" también se cachea (TTL más corto) para que
+//   IDs inexistentes no golpeen la base de datos en cada request
+// - Create/Update/Delete: escriben en el backend e invalidan las claves
+//   afectadas (ID, email nuevo y, si está en cache, el email anterior) sin
+//   consultas extra al backend
+// - Update con ErrVersionConflict también invalida: el cache tenía una
+//   versión vieja y el cliente va a releer antes de reintentar
+//
+// Dentro de una transacción (ver repository.TxManager) el cache se ignora:
+// las lecturas van al backend para ver las escrituras propias, y nada se
+// cachea hasta el commit, porque un rollback dejaría datos que nunca existieron.
+// ============================================================================
+
+// cachedUser es lo que se guarda en cache: un usuario o un "no existe"
+type cachedUser struct {
+	user     *domain.User
+	notFound bool
+}
+
+// cachedEmail es una entrada del índice por email: un ID o un "no existe"
+type cachedEmail struct {
+	id       int
+	notFound bool
+}
+
+// CacheStats son las métricas del decorator
+type CacheStats struct {
+	Hits          int64 `json:"hits"`
+	NegativeHits  int64 `json:"negative_hits"`
+	Misses        int64 `json:"misses"`
+	Invalidations int64 `json:"invalidations"`
+	Entries       int   `json:"entries"`
+}
+
+type CacheConfig struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	MaxEntries  int
+}
+
+type CachedUserRepository struct {
+	next    repository.UserRepository
+	cfg     CacheConfig
+	byID    *cache.Cache[int, cachedUser]
+	byEmail *cache.Cache[string, cachedEmail]
+
+	hits, negativeHits, misses, invalidations atomic.Int64
+	// writes cuenta las invalidaciones; fillByID lo usa para no guardar
+	// un usuario leído antes de una escritura
+	writes atomic.Int64
+}
+
+func NewCachedUserRepository(next repository.UserRepository, cfg CacheConfig) *CachedUserRepository {
+	common := []cache.Option{
+		cache.WithMaxEntries(cfg.MaxEntries),
+		cache.WithJanitor(time.Minute),
+	}
+	return &CachedUserRepository{
+		next: next,
+		cfg:  cfg,
+		byID: cache.New[int, cachedUser](append(common, cache.WithTTLFunc(func(id int, item cachedUser) time.Duration {
+			return cfg.ttl(item.notFound)
+		}))...),
+		byEmail: cache.New[string, cachedEmail](append(common, cache.WithTTLFunc(func(email string, item cachedEmail) time.Duration {
+			return cfg.ttl(item.notFound)
+		}))...),
+	}
+}
+
+func (c CacheConfig) ttl(notFound bool) time.Duration {
+	if notFound {
+		return c.NegativeTTL
+	}
+	return c.TTL
+}
+
+func (r *CachedUserRepository) Create(ctx context.Context, user *domain.User) error {
+	if err := r.next.Create(ctx, user); err != nil {
+		return err
+	}
+	// Puede haber un "no existe" cacheado para este email o ID
+	r.invalidate(ctx, user.ID, user.Email)
+	return nil
+}
+
+func (r *CachedUserRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	if _, inTx := repository.TxFromContext(ctx); inTx {
+		return r.next.GetByID(ctx, id)
+	}
+	if item, ok := r.byID.Get(id); ok {
+		return r.hit(item)
+	}
+	r.misses.Add(1)
+
+	item, err := r.byID.GetOrLoad(ctx, id, func(ctx context.Context, id int) (cachedUser, error) {
+		user, err := r.next.GetByID(ctx, id)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return cachedUser{notFound: true}, nil
+		}
+		if err != nil {
+			return cachedUser{}, err
+		}
+		return cachedUser{user: copyUser(user)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if item.notFound {
+		return nil, domain.ErrUserNotFound
+	}
+	return copyUser(item.user), nil
+}
+
+func (r *CachedUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if _, inTx := repository.TxFromContext(ctx); inTx {
+		return r.next.GetByEmail(ctx, email)
+	}
+	if entry, ok := r.byEmail.Get(email); ok {
+		if entry.notFound {
+			r.negativeHits.Add(1)
+			return nil, domain.ErrUserNotFound
+		}
+		user, err := r.GetByID(ctx, entry.id)
+		if err == nil && user.Email == email && !user.IsDeleted() {
+			return user, nil
+		}
+		if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+			return nil, err
+		}
+		// Índice viejo: el usuario cambió de email, fue eliminado o ya no existe
+		r.byEmail.Delete(email)
+	}
+	r.misses.Add(1)
+
+	// loaded solo se asigna si este llamador hizo la consulta; si compartió
+	// la carga de otro, el usuario se lee por ID
+	var loaded *domain.User
+	entry, err := r.byEmail.GetOrLoad(ctx, email, func(ctx context.Context, email string) (cachedEmail, error) {
+		writes := r.writes.Load()
+		user, err := r.next.GetByEmail(ctx, email)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return cachedEmail{notFound: true}, nil
+		}
+		if err != nil {
+			return cachedEmail{}, err
+		}
+		loaded = user
+		r.fillByID(user, writes)
+		return cachedEmail{id: user.ID}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entry.notFound {
+		return nil, domain.ErrUserNotFound
+	}
+	if loaded != nil {
+		return loaded, nil
+	}
+	user, err := r.GetByID(ctx, entry.id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != email || user.IsDeleted() {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *CachedUserRepository) Update(ctx context.Context, user *domain.User) error {
+	// El email anterior sale del cache; si no está cacheado no hay
+	// nada que invalidar con él (un índice viejo se detecta al leerlo)
+	previousEmail := r.cachedEmail(user.ID)
+	if err := r.next.Update(ctx, user); err != nil {
+		if errors.Is(err, domain.ErrVersionConflict) {
+			r.invalidate(ctx, user.ID, user.Email, previousEmail)
+		}
+		return err
+	}
+	r.invalidate(ctx, user.ID, user.Email, previousEmail)
+	return nil
+}
+
+func (r *CachedUserRepository) Delete(ctx context.Context, id int) error {
+	previousEmail := r.cachedEmail(id)
+	if err := r.next.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id, previousEmail)
+	return nil
+}
+
+// List no se cachea: las combinaciones de filtros y cursores son casi
+// infinitas y cualquier escritura invalidaría todas las páginas
+func (r *CachedUserRepository) List(ctx context.Context, q repository.ListQuery) (*repository.UserPage, error) {
+	return r.next.List(ctx, q)
+}
+
+// Stats devuelve las métricas de hit/miss del decorator
+func (r *CachedUserRepository) Stats() CacheStats {
+	return CacheStats{
+		Hits:          r.hits.Load(),
+		NegativeHits:  r.negativeHits.Load(),
+		Misses:        r.misses.Load(),
+		Invalidations: r.invalidations.Load(),
+		Entries:       r.byID.Len() + r.byEmail.Len(),
+	}
+}
+
+// Close detiene los janitors de los caches internos
+func (r *CachedUserRepository) Close() {
+	r.byID.Close()
+	r.byEmail.Close()
+}
+
+func (r *CachedUserRepository) hit(item cachedUser) (*domain.User, error) {
+	if item.notFound {
+		r.negativeHits.Add(1)
+		return nil, domain.ErrUserNotFound
+	}
+	r.hits.Add(1)
+	return copyUser(item.user), nil
+}
+
+// fillByID guarda en byID un usuario leído por otra vía (GetByEmail).
+// writes es el valor de r.writes antes de la lectura: si hubo una escritura
+// desde entonces el usuario puede ser viejo y no se guarda. Si la escritura
+// llega después del Set, su invalidate lo borra.
+func (r *CachedUserRepository) fillByID(user *domain.User, writes int64) {
+	r.byID.Set(user.ID, cachedUser{user: copyUser(user)})
+	if r.writes.Load() != writes {
+		r.byID.Delete(user.ID)
+	}
+}
+
+// cachedEmail devuelve el email del usuario cacheado con ese ID, o ""
+func (r *CachedUserRepository) cachedEmail(id int) string {
+	if item, ok := r.byID.Get(id); ok && !item.notFound {
+		return item.user.Email
+	}
+	return ""
+}
+
+// invalidate borra las claves ahora y, si hay transacción, otra vez después
+// del commit (otra goroutine pudo cachear el estado previo mientras tanto)
+func (r *CachedUserRepository) invalidate(ctx context.Context, id int, emails ...string) {
+	evict := func() {
+		r.invalidations.Add(1)
+		r.writes.Add(1)
+		r.byID.Delete(id)
+		for _, email := range emails {
+			if email != "" {
+				r.byEmail.Delete(email)
+			}
+		}
+	}
+	evict()
+	if tx, ok := repository.TxFromContext(ctx); ok {
+		tx.OnCommit(evict)
+	}
+}
+
+func copyUser(user *domain.User) *domain.User {
+	u := *user
+	if user.DeletedAt != nil {
+		deletedAt := *user.DeletedAt
+		u.DeletedAt = &deletedAt
+	}
+	return &u
+}
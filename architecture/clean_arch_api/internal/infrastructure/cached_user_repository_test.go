@@ -0,0 +1,282 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// countingUserRepository cuenta las lecturas que llegan al backend y
+// permite bloquear un GetByID o GetByEmail (después de leer) para probar
+// invalidaciones durante una carga
+type countingUserRepository struct {
+	repository.UserRepository
+	byID, byEmail                       atomic.Int32
+	holdNextGetByID, holdNextGetByEmail atomic.Bool
+	started, release                    chan struct{}
+}
+
+func (r *countingUserRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	r.byID.Add(1)
+	user, err := r.UserRepository.GetByID(ctx, id)
+	r.hold(&r.holdNextGetByID)
+	return user, err
+}
+
+func (r *countingUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.byEmail.Add(1)
+	user, err := r.UserRepository.GetByEmail(ctx, email)
+	r.hold(&r.holdNextGetByEmail)
+	return user, err
+}
+
+func (r *countingUserRepository) hold(next *atomic.Bool) {
+	if next.CompareAndSwap(true, false) {
+		close(r.started)
+		<-r.release
+	}
+}
+
+func newTestCachedRepository(t *testing.T) (*CachedUserRepository, *countingUserRepository) {
+	t.Helper()
+	backend := &countingUserRepository{UserRepository: NewMemoryUserRepository()}
+	cached := NewCachedUserRepository(backend, CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, MaxEntries: 100})
+	t.Cleanup(cached.Close)
+	return cached, backend
+}
+
+func TestCachedUserRepositoryHitAndMiss(t *testing.T) {
+	ctx := context.Background()
+	repo, backend := newTestCachedRepository(t)
+	user := newTestUser(t, repo, "ana@example.com")
+
+	for i := 0; i < 3; i++ {
+		got, err := repo.GetByID(ctx, user.ID)
+		if err != nil || got.Email != "ana@example.com" {
+			t.Fatalf("GetByID = %+v, %v", got, err)
+		}
+		got.Name = "mutated" // No debe alterar lo cacheado
+	}
+	if n := backend.byID.Load(); n != 1 {
+		t.Errorf("backend GetByID calls = %d, want 1", n)
+	}
+	if got, _ := repo.GetByID(ctx, user.ID); got.Name != "Test" {
+		t.Errorf("cached name = %q after mutating a read", got.Name)
+	}
+
+	// GetByEmail guarda el índice y reusa el usuario cacheado por ID
+	for i := 0; i < 3; i++ {
+		if got, err := repo.GetByEmail(ctx, "ana@example.com"); err != nil || got.ID != user.ID {
+			t.Fatalf("GetByEmail = %+v, %v", got, err)
+		}
+	}
+	if n := backend.byEmail.Load(); n != 1 {
+		t.Errorf("backend GetByEmail calls = %d, want 1", n)
+	}
+
+	st := repo.Stats()
+	if st.Misses != 2 || st.Hits < 5 {
+		t.Errorf("stats = %+v, want 2 misses and the rest hits", st)
+	}
+}
+
+func TestCachedUserRepositoryNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	repo, backend := newTestCachedRepository(t)
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.GetByEmail(ctx, "nobody@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Fatalf("GetByEmail err = %v", err)
+		}
+		if _, err := repo.GetByID(ctx, 1); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Fatalf("GetByID err = %v", err)
+		}
+	}
+	if backend.byID.Load() != 1 || backend.byEmail.Load() != 1 {
+		t.Errorf("backend calls = %d by ID, %d by email; want 1 each", backend.byID.Load(), backend.byEmail.Load())
+	}
+	if st := repo.Stats(); st.NegativeHits != 4 {
+		t.Errorf("NegativeHits = %d, want 4", st.NegativeHits)
+	}
+
+	// Create invalida los "no existe" cacheados
+	user := newTestUser(t, repo, "nobody@example.com")
+	if got, err := repo.GetByID(ctx, user.ID); err != nil || got.ID != user.ID {
+		t.Errorf("GetByID after Create = %+v, %v", got, err)
+	}
+	if got, err := repo.GetByEmail(ctx, "nobody@example.com"); err != nil || got.ID != user.ID {
+		t.Errorf("GetByEmail after Create = %+v, %v", got, err)
+	}
+}
+
+func TestCachedUserRepositoryUpdateInvalidates(t *testing.T) {
+	ctx := context.Background()
+	repo, backend := newTestCachedRepository(t)
+	user := newTestUser(t, repo, "old@example.com")
+	repo.GetByID(ctx, user.ID)
+	repo.GetByEmail(ctx, "old@example.com")
+	repo.GetByEmail(ctx, "new@example.com") // "no existe" cacheado
+
+	reads := backend.byID.Load()
+	user.Email = "new@example.com"
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if backend.byID.Load() != reads {
+		t.Error("Update read the previous user from the backend")
+	}
+
+	if _, err := repo.GetByEmail(ctx, "old@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByEmail(old) err = %v, want ErrUserNotFound", err)
+	}
+	got, err := repo.GetByEmail(ctx, "new@example.com")
+	if err != nil || got.Version != user.Version {
+		t.Errorf("GetByEmail(new) = %+v, %v", got, err)
+	}
+	if got, _ := repo.GetByID(ctx, user.ID); got.Email != "new@example.com" {
+		t.Errorf("GetByID email = %q after Update", got.Email)
+	}
+
+	// Un conflicto de versión también invalida
+	stale := *user
+	stale.Version--
+	if err := repo.Update(ctx, &stale); !errors.Is(err, domain.ErrVersionConflict) {
+		t.Fatalf("Update with stale version err = %v", err)
+	}
+	if _, ok := repo.byID.Get(user.ID); ok {
+		t.Error("user still cached after a version conflict")
+	}
+}
+
+func TestCachedUserRepositoryDetectsStaleEmailIndex(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestCachedRepository(t)
+	user := newTestUser(t, repo, "old@example.com")
+
+	// Solo el índice por email queda cacheado: Update no conoce el email
+	// anterior y no puede borrarlo
+	repo.GetByEmail(ctx, "old@example.com")
+	repo.byID.Delete(user.ID)
+	user.Email = "new@example.com"
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.GetByEmail(ctx, "old@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByEmail(old) with a stale index err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestCachedUserRepositoryDelete(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestCachedRepository(t)
+	user := newTestUser(t, repo, "ana@example.com")
+	repo.GetByEmail(ctx, "ana@example.com")
+	repo.GetByID(ctx, user.ID)
+
+	if err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByID after Delete err = %v", err)
+	}
+	if _, err := repo.GetByEmail(ctx, "ana@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByEmail after Delete err = %v", err)
+	}
+}
+
+func TestCachedUserRepositoryUpdateDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	repo, backend := newTestCachedRepository(t)
+	user := newTestUser(t, repo, "ana@example.com")
+
+	// El GetByID lee del backend antes del Update pero termina después:
+	// su valor viejo no debe quedar en cache
+	backend.started, backend.release = make(chan struct{}), make(chan struct{})
+	backend.holdNextGetByID.Store(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		repo.GetByID(ctx, user.ID)
+	}()
+	<-backend.started
+
+	user.Name = "Updated"
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	close(backend.release)
+	<-done
+
+	if got, _ := repo.GetByID(ctx, user.ID); got.Name != "Updated" {
+		t.Errorf("cached name = %q, want the value written by Update", got.Name)
+	}
+}
+
+func TestCachedUserRepositoryGetByEmailFillsByID(t *testing.T) {
+	ctx := context.Background()
+	repo, backend := newTestCachedRepository(t)
+	user := newTestUser(t, repo, "ana@example.com")
+
+	if _, err := repo.GetByEmail(ctx, "ana@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetByID(ctx, user.ID); err != nil || got.Email != "ana@example.com" {
+		t.Fatalf("GetByID = %+v, %v", got, err)
+	}
+	if n := backend.byID.Load(); n != 0 {
+		t.Errorf("backend GetByID calls = %d, want 0 after a GetByEmail miss", n)
+	}
+}
+
+func TestCachedUserRepositoryUpdateDuringEmailLoad(t *testing.T) {
+	ctx := context.Background()
+	repo, backend := newTestCachedRepository(t)
+	user := newTestUser(t, repo, "ana@example.com")
+
+	// El GetByEmail lee antes del Update y termina después: el usuario
+	// viejo no debe quedar en la entrada por ID
+	backend.started, backend.release = make(chan struct{}), make(chan struct{})
+	backend.holdNextGetByEmail.Store(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		repo.GetByEmail(ctx, "ana@example.com")
+	}()
+	<-backend.started
+
+	user.Name = "Updated"
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	close(backend.release)
+	<-done
+
+	if got, _ := repo.GetByID(ctx, user.ID); got.Name != "Updated" {
+		t.Errorf("cached name = %q, want the value written by Update", got.Name)
+	}
+}
+
+func TestCachedUserRepositoryBypassesCacheInTx(t *testing.T) {
+	ctx := context.Background()
+	repo, backend := newTestCachedRepository(t)
+	user := newTestUser(t, repo, "ana@example.com")
+
+	tm := NewMemoryTxManager()
+	err := tm.WithTx(ctx, func(ctx context.Context) error {
+		repo.GetByID(ctx, user.ID)
+		repo.GetByID(ctx, user.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.byID.Load() != 2 || repo.byID.Len() != 0 {
+		t.Errorf("in tx: %d backend reads, %d cached entries; want 2 and 0", backend.byID.Load(), repo.byID.Len())
+	}
+}
@@ -0,0 +1,197 @@
+package infrastructure
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+)
+
+// MemoryInventoryRepository guarda productos y reservas en memoria,
+// copiando al leer y al escribir como MemoryUserRepository. Las escrituras
+// participan de MemoryTxManager con el mismo undo log.
+//
+// No tiene versión SQL todavía: con USER_STORE=sqlite el inventario sigue
+// en memoria.
+type MemoryInventoryRepository struct {
+	mu           sync.RWMutex
+	products     map[string]*domain.Product // por SKU
+	reservations map[int]*domain.Reservation
+	nextID       int
+	nextResID    int
+}
+
+func NewMemoryInventoryRepository() *MemoryInventoryRepository {
+	return &MemoryInventoryRepository{
+		products:     make(map[string]*domain.Product),
+		reservations: make(map[int]*domain.Reservation),
+		nextID:       1,
+		nextResID:    1,
+	}
+}
+
+func (r *MemoryInventoryRepository) CreateProduct(ctx context.Context, p *domain.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[p.SKU]; ok {
+		return domain.ErrProductAlreadyExists
+	}
+	p.ID = r.nextID
+	r.nextID++
+	stored := *p
+	r.products[p.SKU] = &stored
+
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		sku := p.SKU
+		tx.addUndo(func() { r.restoreProduct(sku, nil) })
+	}
+	return nil
+}
+
+func (r *MemoryInventoryRepository) GetProduct(ctx context.Context, sku string) (*domain.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.products[sku]
+	if !ok {
+		return nil, domain.ErrProductNotFound
+	}
+	copied := *p
+	return &copied, nil
+}
+
+func (r *MemoryInventoryRepository) ListProducts(ctx context.Context) ([]*domain.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]*domain.Product, 0, len(r.products))
+	for _, p := range r.products {
+		copied := *p
+		products = append(products, &copied)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].SKU < products[j].SKU })
+	return products, nil
+}
+
+func (r *MemoryInventoryRepository) UpdateProduct(ctx context.Context, p *domain.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, ok := r.products[p.SKU]
+	if !ok {
+		return domain.ErrProductNotFound
+	}
+	stored := *p
+	r.products[p.SKU] = &stored
+
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		sku := p.SKU
+		tx.addUndo(func() { r.restoreProduct(sku, previous) })
+	}
+	return nil
+}
+
+// restoreProduct deja el producto como estaba (nil = no existía)
+func (r *MemoryInventoryRepository) restoreProduct(sku string, previous *domain.Product) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if previous == nil {
+		delete(r.products, sku)
+		return
+	}
+	r.products[sku] = previous
+}
+
+func (r *MemoryInventoryRepository) CreateReservation(ctx context.Context, res *domain.Reservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res.ID = r.nextResID
+	r.nextResID++
+	r.reservations[res.ID] = copyReservation(res)
+
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		id := res.ID
+		tx.addUndo(func() { r.restoreReservation(id, nil) })
+	}
+	return nil
+}
+
+func (r *MemoryInventoryRepository) GetReservation(ctx context.Context, id int) (*domain.Reservation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res, ok := r.reservations[id]
+	if !ok {
+		return nil, domain.ErrReservationNotFound
+	}
+	return copyReservation(res), nil
+}
+
+func (r *MemoryInventoryRepository) UpdateReservation(ctx context.Context, res *domain.Reservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, ok := r.reservations[res.ID]
+	if !ok {
+		return domain.ErrReservationNotFound
+	}
+	r.reservations[res.ID] = copyReservation(res)
+
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		id := res.ID
+		tx.addUndo(func() { r.restoreReservation(id, previous) })
+	}
+	return nil
+}
+
+// restoreReservation deja la reserva como estaba (nil = no existía)
+func (r *MemoryInventoryRepository) restoreReservation(id int, previous *domain.Reservation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if previous == nil {
+		delete(r.reservations, id)
+		return
+	}
+	r.reservations[id] = previous
+}
+
+func (r *MemoryInventoryRepository) ExpiredReservations(ctx context.Context, now time.Time, limit int) ([]*domain.Reservation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var expired []*domain.Reservation
+	for _, res := range r.reservations {
+		if res.ExpiredAt(now) {
+			expired = append(expired, copyReservation(res))
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ExpiresAt.Before(expired[j].ExpiresAt) })
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+func (r *MemoryInventoryRepository) DeleteFinishedReservations(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for id, res := range r.reservations {
+		if !res.Active() && res.UpdatedAt.Before(before) {
+			delete(r.reservations, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func copyReservation(res *domain.Reservation) *domain.Reservation {
+	copied := *res
+	copied.Items = append([]domain.ReservationItem(nil), res.Items...)
+	return &copied
+}
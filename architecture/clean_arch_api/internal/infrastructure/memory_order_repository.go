@@ -0,0 +1,77 @@
+package infrastructure
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+)
+
+// MemoryOrderRepository guarda pedidos en memoria, copiando al leer y al
+// escribir como MemoryUserRepository
+type MemoryOrderRepository struct {
+	mu     sync.RWMutex
+	orders map[int]*domain.Order
+	nextID int
+}
+
+func NewMemoryOrderRepository() *MemoryOrderRepository {
+	return &MemoryOrderRepository{
+		orders: make(map[int]*domain.Order),
+		nextID: 1,
+	}
+}
+
+func (r *MemoryOrderRepository) Create(ctx context.Context, o *domain.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o.ID = r.nextID
+	r.nextID++
+	r.orders[o.ID] = copyOrder(o)
+	return nil
+}
+
+func (r *MemoryOrderRepository) GetByID(ctx context.Context, id int) (*domain.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, domain.ErrOrderNotFound
+	}
+	return copyOrder(o), nil
+}
+
+func (r *MemoryOrderRepository) Update(ctx context.Context, o *domain.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[o.ID]; !ok {
+		return domain.ErrOrderNotFound
+	}
+	r.orders[o.ID] = copyOrder(o)
+	return nil
+}
+
+func (r *MemoryOrderRepository) ListByCustomer(ctx context.Context, customerID int) ([]*domain.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []*domain.Order
+	for _, o := range r.orders {
+		if o.CustomerID == customerID {
+			orders = append(orders, copyOrder(o))
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID > orders[j].ID })
+	return orders, nil
+}
+
+func copyOrder(o *domain.Order) *domain.Order {
+	copied := *o
+	copied.Lines = append([]domain.OrderLine(nil), o.Lines...)
+	copied.Steps = append([]domain.SagaStep(nil), o.Steps...)
+	return &copied
+}
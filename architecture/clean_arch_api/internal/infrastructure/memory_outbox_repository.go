@@ -0,0 +1,118 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// MemoryOutboxRepository es el outbox para USER_STORE=memory. Dentro de una
+// transacción de MemoryTxManager, Add acumula los mensajes y los agrega al
+// confirmar (OnCommit): Pending nunca ve eventos de una transacción en curso
+// ni de una que hizo rollback.
+//
+// No se escribe en el WAL del modo durable: los eventos pendientes se
+// pierden al reiniciar. Si eso importa, usar USER_STORE=sqlite.
+type MemoryOutboxRepository struct {
+	mu       sync.Mutex
+	messages []*repository.OutboxMessage // en orden de creación
+	byID     map[string]*repository.OutboxMessage
+}
+
+func NewMemoryOutboxRepository() *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{byID: make(map[string]*repository.OutboxMessage)}
+}
+
+func (r *MemoryOutboxRepository) Add(ctx context.Context, msgs ...repository.OutboxMessage) error {
+	stored := make([]*repository.OutboxMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		copied := msg
+		copied.Payload = append([]byte(nil), msg.Payload...)
+		stored = append(stored, &copied)
+	}
+
+	// El rollback de un savepoint descarta también sus hooks
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		tx.OnCommit(func() { r.append(stored) })
+		return nil
+	}
+	r.append(stored)
+	return nil
+}
+
+func (r *MemoryOutboxRepository) append(msgs []*repository.OutboxMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, msg := range msgs {
+		r.messages = append(r.messages, msg)
+		r.byID[msg.ID] = msg
+	}
+}
+
+func (r *MemoryOutboxRepository) Pending(ctx context.Context, now time.Time, limit int) ([]repository.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []repository.OutboxMessage
+	waiting := make(map[repository.AggregateKey]bool) // agregados con un mensaje anterior en backoff
+	for _, msg := range r.messages {
+		if len(pending) == limit {
+			break
+		}
+		if msg.PublishedAt != nil || waiting[msg.Aggregate()] {
+			continue
+		}
+		if msg.NextAttemptAt.After(now) {
+			waiting[msg.Aggregate()] = true
+			continue
+		}
+		pending = append(pending, *msg)
+	}
+	return pending, nil
+}
+
+func (r *MemoryOutboxRepository) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg, ok := r.byID[id]
+	if !ok {
+		return nil // Ya se borró por retención
+	}
+	publishedAt := at.UTC()
+	msg.PublishedAt = &publishedAt
+	return nil
+}
+
+func (r *MemoryOutboxRepository) MarkFailed(ctx context.Context, id string, nextAttempt time.Time, lastErr string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if msg, ok := r.byID[id]; ok {
+		msg.Attempts++
+		msg.NextAttemptAt = nextAttempt.UTC()
+		msg.LastError = lastErr
+	}
+	return nil
+}
+
+func (r *MemoryOutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.messages[:0]
+	deleted := 0
+	for _, msg := range r.messages {
+		if msg.PublishedAt != nil && msg.PublishedAt.Before(before) {
+			delete(r.byID, msg.ID)
+			deleted++
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	r.messages = kept
+	return deleted, nil
+}
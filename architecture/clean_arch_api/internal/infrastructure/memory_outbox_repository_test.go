@@ -0,0 +1,115 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/outbox"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+func outboxMsg(id string) repository.OutboxMessage {
+	return repository.OutboxMessage{ID: id, Type: "user.created", AggregateType: domain.AggregateUser, AggregateID: 1, Payload: []byte(`{}`)}
+}
+
+func TestMemoryOutboxPublishesOnlyCommittedMessages(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryOutboxRepository()
+	txm := NewMemoryTxManager()
+	boom := errors.New("boom")
+
+	// Confirmada, con un savepoint anidado que hizo rollback
+	err := txm.WithTx(ctx, func(ctx context.Context) error {
+		if err := repo.Add(ctx, outboxMsg("committed")); err != nil {
+			return err
+		}
+		txm.WithTx(ctx, func(ctx context.Context) error {
+			repo.Add(ctx, outboxMsg("savepoint"))
+			return boom
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rollback completo
+	txm.WithTx(ctx, func(ctx context.Context) error {
+		repo.Add(ctx, outboxMsg("rolled-back"))
+		return boom
+	})
+
+	var published []string
+	relay := outbox.NewRelay(repo, outbox.PublisherFunc(func(ctx context.Context, msg repository.OutboxMessage) error {
+		published = append(published, msg.ID)
+		return nil
+	}))
+
+	// Mientras la transacción está abierta el relay no ve sus mensajes
+	txm.WithTx(ctx, func(ctx context.Context) error {
+		repo.Add(ctx, outboxMsg("in-flight"))
+		if _, err := relay.RunOnce(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return boom
+	})
+	if _, err := relay.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(published) != "[committed]" {
+		t.Errorf("published = %v, want only the committed message", published)
+	}
+	if pending, _ := repo.Pending(ctx, time.Now(), 10); len(pending) != 0 {
+		t.Errorf("pending after relay = %+v", pending)
+	}
+}
+
+func TestOutboxOrderIsPerAggregateTypeAndID(t *testing.T) {
+	repos := map[string]func(t *testing.T) repository.OutboxRepository{
+		"memory": func(t *testing.T) repository.OutboxRepository { return NewMemoryOutboxRepository() },
+		"sqlite": func(t *testing.T) repository.OutboxRepository {
+			db, _ := openTestSQLDB(t, 5*time.Second)
+			return NewSQLOutboxRepository(db)
+		},
+	}
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepo(t)
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			// Usuario 1 y producto 1 comparten ID pero son agregados distintos
+			product := outboxMsg("product-1")
+			product.Type, product.AggregateType = domain.EventInventoryLowStock, domain.AggregateProduct
+			for _, msg := range []repository.OutboxMessage{outboxMsg("user-1a"), product, outboxMsg("user-1b")} {
+				msg.OccurredAt, msg.NextAttemptAt = now, now
+				if err := repo.Add(ctx, msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.MarkFailed(ctx, "user-1a", now.Add(time.Minute), "boom"); err != nil {
+				t.Fatal(err)
+			}
+
+			pending, err := repo.Pending(ctx, now, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, msg := range pending {
+				ids = append(ids, msg.ID)
+			}
+			// user-1b espera a user-1a; el producto no
+			if fmt.Sprint(ids) != "[product-1]" {
+				t.Errorf("pending = %v, want only the product message", ids)
+			}
+			if len(pending) == 1 && pending[0].Aggregate() != (repository.AggregateKey{Type: domain.AggregateProduct, ID: 1}) {
+				t.Errorf("aggregate = %+v", pending[0].Aggregate())
+			}
+		})
+	}
+}
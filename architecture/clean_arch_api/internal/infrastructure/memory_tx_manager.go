@@ -0,0 +1,137 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// ============================================================================
+// UNIT OF WORK EN MEMORIA
+// ============================================================================
+// Las transacciones se serializan con un mutex (equivale a SERIALIZABLE) y
+// cada escritura de MemoryUserRepository dentro de una transacción registra
+// su operación inversa (undo log). Rollback = ejecutar el undo log al revés.
+//
+// Limitación: las lecturas fuera de una transacción pueden ver escrituras
+// aún no confirmadas. Los flujos multi-paso deben ir dentro de WithTx.
+//
+// En modo durable (ver OpenDurableMemoryUserRepository) las escrituras se
+// acumulan en un redo log y se escriben al WAL en un solo registro al
+// confirmar: después de un crash la transacción está completa o no está.
+// ============================================================================
+
+// txHooks es el estado común de las transacciones (memoria y SQL)
+type txHooks struct {
+	onCommit []func()
+}
+
+func (h *txHooks) OnCommit(fn func()) {
+	h.onCommit = append(h.onCommit, fn)
+}
+
+func (h *txHooks) runOnCommit() {
+	for _, fn := range h.onCommit {
+		fn()
+	}
+}
+
+type memoryTx struct {
+	txHooks
+	undo []func()
+
+	// redo son los cambios a escribir en el WAL al confirmar (modo durable)
+	redo    []userOp
+	redoLog *userWAL
+}
+
+func (tx *memoryTx) addUndo(fn func()) {
+	tx.undo = append(tx.undo, fn)
+}
+
+func (tx *memoryTx) addRedo(log *userWAL, op userOp) {
+	tx.redoLog = log
+	tx.redo = append(tx.redo, op)
+}
+
+// txMark es la posición de un savepoint en los logs de la transacción
+type txMark struct {
+	undo, hooks, redo int
+}
+
+func (tx *memoryTx) mark() txMark {
+	return txMark{undo: len(tx.undo), hooks: len(tx.onCommit), redo: len(tx.redo)}
+}
+
+// rollbackTo deshace todo lo registrado después de la marca
+func (tx *memoryTx) rollbackTo(m txMark) {
+	for i := len(tx.undo) - 1; i >= m.undo; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = tx.undo[:m.undo]
+	tx.onCommit = tx.onCommit[:m.hooks]
+	tx.redo = tx.redo[:m.redo]
+}
+
+// commit escribe el redo log; si falla, la transacción no se confirmó
+func (tx *memoryTx) commit() error {
+	if tx.redoLog == nil || len(tx.redo) == 0 {
+		return nil
+	}
+	return tx.redoLog.append(tx.redo)
+}
+
+type MemoryTxManager struct {
+	mu sync.Mutex
+}
+
+func NewMemoryTxManager() *MemoryTxManager {
+	return &MemoryTxManager{}
+}
+
+func (m *MemoryTxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	// Transacción anidada: savepoint = posición actual del undo log
+	if current, ok := repository.TxFromContext(ctx); ok {
+		if tx, ok := current.(*memoryTx); ok {
+			savepoint := tx.mark()
+			if err := fn(ctx); err != nil {
+				tx.rollbackTo(savepoint)
+				return err
+			}
+			return nil
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &memoryTx{}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollbackTo(txMark{})
+			panic(r)
+		}
+	}()
+
+	if err := fn(repository.ContextWithTx(ctx, tx)); err != nil {
+		tx.rollbackTo(txMark{})
+		return err
+	}
+	if err := tx.commit(); err != nil {
+		tx.rollbackTo(txMark{})
+		return err
+	}
+	tx.runOnCommit()
+	return nil
+}
+
+// memoryTxFromContext devuelve la transacción en memoria activa, si hay una
+func memoryTxFromContext(ctx context.Context) *memoryTx {
+	if current, ok := repository.TxFromContext(ctx); ok {
+		if tx, ok := current.(*memoryTx); ok {
+			return tx
+		}
+	}
+	return nil
+}
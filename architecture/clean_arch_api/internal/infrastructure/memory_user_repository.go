@@ -1,8 +1,12 @@
 package infrastructure
 
 import (
-	"fmt"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
 	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
 )
@@ -14,31 +18,59 @@ import (
 // En producción, aquí estaría la conexión a la base de datos
 // ============================================================================
 
-// MemoryUserRepository es una implementación en memoria del UserRepository
+// MemoryUserRepository es una implementación en memoria del UserRepository.
+//
+// Nunca comparte punteros con el llamador: guarda una copia al escribir y
+// devuelve una copia al leer (como devolver un DTO en vez de la entidad
+// gestionada en Java). Así un handler que modifica el usuario devuelto no
+// altera el store sin lock. Los usuarios guardados no se mutan nunca: cada
+// escritura reemplaza el puntero, lo que permite que el undo log y List
+// los usen fuera del lock.
 type MemoryUserRepository struct {
-	mu    sync.RWMutex
-	users map[int]*domain.User
-	nextID int
+	mu      sync.RWMutex
+	users   map[int]*domain.User
+	byEmail map[string]int // índice secundario: email -> ID (solo usuarios activos)
+	nextID  int
+	wal     *userWAL // nil = solo memoria (ver OpenDurableMemoryUserRepository)
 }
 
 func NewMemoryUserRepository() repository.UserRepository {
 	return &MemoryUserRepository{
-		users: make(map[int]*domain.User),
-		nextID: 1,
+		users:   make(map[int]*domain.User),
+		byEmail: make(map[string]int),
+		nextID:  1,
 	}
 }
 
-func (r *MemoryUserRepository) Create(user *domain.User) error {
+func (r *MemoryUserRepository) Create(ctx context.Context, user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	user.ID = r.nextID
+	// Mismo contrato que el índice UNIQUE parcial de SQLUserRepository:
+	// el email es único entre los usuarios no eliminados
+	if _, taken := r.byEmail[user.Email]; taken {
+		return domain.ErrUserAlreadyExists
+	}
+
+	stored := copyUser(user)
+	stored.ID = r.nextID
+	stored.Version = 1
+	r.put(stored)
+	if err := r.persist(ctx, putOp(stored)); err != nil {
+		r.remove(stored.ID)
+		return err
+	}
 	r.nextID++
-	r.users[user.ID] = user
+	user.ID, user.Version = stored.ID, stored.Version
+
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		id := user.ID
+		tx.addUndo(func() { r.restore(id, nil) })
+	}
 	return nil
 }
 
-func (r *MemoryUserRepository) GetByID(id int) (*domain.User, error) {
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -46,40 +78,212 @@ func (r *MemoryUserRepository) GetByID(id int) (*domain.User, error) {
 	if !exists {
 		return nil, domain.ErrUserNotFound
 	}
-	return user, nil
+	return copyUser(user), nil
 }
 
-func (r *MemoryUserRepository) GetByEmail(email string) (*domain.User, error) {
+// GetByEmail es O(1) gracias al índice byEmail
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	for _, user := range r.users {
-		if user.Email == email {
-			return user, nil
-		}
+	id, exists := r.byEmail[email]
+	if !exists {
+		return nil, domain.ErrUserNotFound
 	}
-	return nil, domain.ErrUserNotFound
+	return copyUser(r.users[id]), nil
 }
 
-func (r *MemoryUserRepository) Update(user *domain.User) error {
+func (r *MemoryUserRepository) Update(ctx context.Context, user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.users[user.ID]; !exists {
+	previous, exists := r.users[user.ID]
+	if !exists {
 		return domain.ErrUserNotFound
 	}
-	r.users[user.ID] = user
+	// Compare-and-swap bajo el lock: la versión del llamador debe ser la actual
+	if previous.Version != user.Version {
+		return domain.ErrVersionConflict
+	}
+	if owner, taken := r.byEmail[user.Email]; taken && owner != user.ID && !user.IsDeleted() {
+		return domain.ErrUserAlreadyExists
+	}
+
+	updated := copyUser(user)
+	updated.Version++
+	r.put(updated)
+	if err := r.persist(ctx, putOp(updated)); err != nil {
+		r.put(previous)
+		return err
+	}
+	user.Version = updated.Version
+
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		tx.addUndo(func() { r.restore(user.ID, previous) })
+	}
 	return nil
 }
 
-func (r *MemoryUserRepository) Delete(id int) error {
+func (r *MemoryUserRepository) Delete(ctx context.Context, id int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.users[id]; !exists {
+	previous, exists := r.users[id]
+	if !exists {
 		return domain.ErrUserNotFound
 	}
-	delete(r.users, id)
+	r.remove(id)
+	if err := r.persist(ctx, deleteOp(id)); err != nil {
+		r.put(previous)
+		return err
+	}
+
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		tx.addUndo(func() { r.restore(id, previous) })
+	}
 	return nil
 }
 
+// restore vuelve un ID a su estado anterior (nil = no existía); lo usa el rollback
+func (r *MemoryUserRepository) restore(id int, previous *domain.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if previous == nil {
+		r.remove(id)
+		return
+	}
+	r.put(previous)
+}
+
+// put guarda user (que ya debe ser una copia propia) y mantiene el índice.
+// Los eliminados lógicamente no se indexan: su email queda libre para un
+// usuario nuevo. Requiere r.mu tomado para escritura.
+func (r *MemoryUserRepository) put(user *domain.User) {
+	if current, exists := r.users[user.ID]; exists {
+		r.unindex(current)
+	}
+	r.users[user.ID] = user
+	if !user.IsDeleted() {
+		r.byEmail[user.Email] = user.ID
+	}
+}
+
+// remove borra un usuario y su entrada en el índice. Requiere r.mu tomado.
+func (r *MemoryUserRepository) remove(id int) {
+	if current, exists := r.users[id]; exists {
+		r.unindex(current)
+		delete(r.users, id)
+	}
+}
+
+// unindex borra la entrada del email solo si apunta a este usuario: con el
+// email reutilizado puede pertenecer a otro. Requiere r.mu tomado.
+func (r *MemoryUserRepository) unindex(user *domain.User) {
+	if id, ok := r.byEmail[user.Email]; ok && id == user.ID {
+		delete(r.byEmail, user.Email)
+	}
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, q repository.ListQuery) (*repository.UserPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	cursor, err := repository.DecodeCursor(q)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var matches []*domain.User
+	for _, user := range r.users {
+		if matchesListQuery(user, q) {
+			matches = append(matches, user)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		c := compareUsers(matches[i], repository.SortKey(matches[j], q.SortBy), matches[j].ID, q.SortBy)
+		if q.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	// Saltar hasta después del cursor
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(matches), func(i int) bool {
+			c := compareUsers(matches[i], cursor.Key, cursor.ID, q.SortBy)
+			if q.Descending {
+				return c < 0
+			}
+			return c > 0
+		})
+	}
+
+	page := &repository.UserPage{}
+	end := start + q.Limit
+	if end < len(matches) {
+		page.NextCursor = repository.EncodeCursor(q, matches[end-1])
+	} else {
+		end = len(matches)
+	}
+	// Solo se copia la página, no todos los usuarios que coinciden
+	page.Users = make([]*domain.User, 0, end-start)
+	for _, user := range matches[start:end] {
+		page.Users = append(page.Users, copyUser(user))
+	}
+	return page, nil
+}
+
+func matchesListQuery(user *domain.User, q repository.ListQuery) bool {
+	if !q.IncludeDeleted && user.IsDeleted() {
+		return false
+	}
+	if q.EmailPrefix != "" && !strings.HasPrefix(repository.FoldCase(user.Email), repository.FoldCase(q.EmailPrefix)) {
+		return false
+	}
+	if q.NameContains != "" && !strings.Contains(repository.FoldCase(user.Name), repository.FoldCase(q.NameContains)) {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && user.CreatedAt.Before(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !user.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// compareUsers compara (campo, id) de user contra una clave de orden,
+// con la misma semántica que ORDER BY campo, id en SQL
+func compareUsers(user *domain.User, key string, id int, field repository.UserSortField) int {
+	var c int
+	switch field {
+	case repository.SortByID:
+		other, _ := strconv.Atoi(key)
+		c = compareInts(user.ID, other)
+	case repository.SortByCreatedAt:
+		other, _ := time.Parse(time.RFC3339Nano, key)
+		c = user.CreatedAt.Compare(other)
+	default:
+		c = strings.Compare(repository.SortKey(user, field), key)
+	}
+	if c != 0 {
+		return c
+	}
+	return compareInts(user.ID, id)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,245 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// ============================================================================
+// TESTS DE MemoryUserRepository
+// ============================================================================
+// Los tests concurrentes solo detectan data races con el race detector:
+// go test -race ./internal/infrastructure/
+// ============================================================================
+
+func newTestUser(t *testing.T, repo repository.UserRepository, email string) *domain.User {
+	t.Helper()
+	user := &domain.User{Email: email, Name: "Test"}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create(%q): %v", email, err)
+	}
+	return user
+}
+
+func TestMemoryUserRepositoryDefensiveCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	user := newTestUser(t, repo, "ana@example.com")
+
+	// Modificar el usuario pasado a Create no altera el store
+	user.Name = "changed after create"
+
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Test" {
+		t.Errorf("stored name = %q; want %q", got.Name, "Test")
+	}
+
+	// Modificar lo devuelto por cualquier lectura tampoco
+	got.Name = "changed after GetByID"
+	byEmail, _ := repo.GetByEmail(ctx, "ana@example.com")
+	byEmail.Name = "changed after GetByEmail"
+	page, _ := repo.List(ctx, repository.ListQuery{})
+	page.Users[0].Name = "changed after List"
+
+	again, _ := repo.GetByID(ctx, user.ID)
+	if again.Name != "Test" {
+		t.Errorf("stored name = %q after mutating reads; want %q", again.Name, "Test")
+	}
+	if again == got || again == byEmail || again == page.Users[0] {
+		t.Error("reads returned the same pointer twice")
+	}
+}
+
+func TestMemoryUserRepositoryEmailIndex(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	ana := newTestUser(t, repo, "ana@example.com")
+	newTestUser(t, repo, "bob@example.com")
+
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{"duplicate create", func() error {
+			return repo.Create(ctx, &domain.User{Email: "bob@example.com", Name: "Bob"})
+		}, domain.ErrUserAlreadyExists},
+		{"update to taken email", func() error {
+			u, _ := repo.GetByID(ctx, ana.ID)
+			u.Email = "bob@example.com"
+			return repo.Update(ctx, u)
+		}, domain.ErrUserAlreadyExists},
+		{"update email", func() error {
+			u, _ := repo.GetByID(ctx, ana.ID)
+			u.Email = "ana@new.example.com"
+			return repo.Update(ctx, u)
+		}, nil},
+		{"old email released", func() error {
+			_, err := repo.GetByEmail(ctx, "ana@example.com")
+			return err
+		}, domain.ErrUserNotFound},
+		{"new email indexed", func() error {
+			u, err := repo.GetByEmail(ctx, "ana@new.example.com")
+			if err == nil && u.ID != ana.ID {
+				return fmt.Errorf("got ID %d; want %d", u.ID, ana.ID)
+			}
+			return err
+		}, nil},
+		{"delete removes index entry", func() error {
+			if err := repo.Delete(ctx, ana.ID); err != nil {
+				return err
+			}
+			_, err := repo.GetByEmail(ctx, "ana@new.example.com")
+			return err
+		}, domain.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryUserRepositoryRollbackRestoresIndex(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	txManager := NewMemoryTxManager()
+	user := newTestUser(t, repo, "ana@example.com")
+
+	errAbort := errors.New("abort")
+	err := txManager.WithTx(ctx, func(ctx context.Context) error {
+		u, _ := repo.GetByID(ctx, user.ID)
+		u.Email = "renamed@example.com"
+		if err := repo.Update(ctx, u); err != nil {
+			return err
+		}
+		if err := repo.Create(ctx, &domain.User{Email: "new@example.com", Name: "New"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithTx err = %v; want %v", err, errAbort)
+	}
+
+	if got, err := repo.GetByEmail(ctx, "ana@example.com"); err != nil || got.Version != 1 {
+		t.Errorf("original email after rollback: user=%v err=%v", got, err)
+	}
+	for _, email := range []string{"renamed@example.com", "new@example.com"} {
+		if _, err := repo.GetByEmail(ctx, email); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("GetByEmail(%q) after rollback: err = %v; want ErrUserNotFound", email, err)
+		}
+	}
+}
+
+// Lectores que modifican sus copias mientras escritores actualizan el mismo
+// usuario: con punteros compartidos esto es un data race
+func TestMemoryUserRepositoryConcurrentReadWrite(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	user := newTestUser(t, repo, "ana@example.com")
+
+	const workers, iterations = 8, 200
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				u, err := repo.GetByID(ctx, user.ID)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				u.Name = "mutated by reader"
+				if byEmail, err := repo.GetByEmail(ctx, u.Email); err == nil {
+					byEmail.Name = "mutated by reader"
+				}
+				if page, err := repo.List(ctx, repository.ListQuery{}); err == nil {
+					for _, p := range page.Users {
+						p.Name = "mutated by reader"
+					}
+				}
+			}
+		}()
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				u, err := repo.GetByID(ctx, user.ID)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				u.Name = fmt.Sprintf("writer-%d-%d", w, i)
+				if err := repo.Update(ctx, u); err != nil && !errors.Is(err, domain.ErrVersionConflict) {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	got, _ := repo.GetByID(ctx, user.ID)
+	if got.Name == "mutated by reader" {
+		t.Error("a reader mutation reached the store")
+	}
+}
+
+// Read-modify-write con reintentos: ningún incremento se pierde
+func TestMemoryUserRepositoryConcurrentCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	user := newTestUser(t, repo, "counter@example.com")
+
+	const workers, increments = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < increments; i++ {
+				for {
+					u, err := repo.GetByID(ctx, user.ID)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					n, _ := strconv.Atoi(u.Name)
+					u.Name = strconv.Itoa(n + 1)
+					err = repo.Update(ctx, u)
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, domain.ErrVersionConflict) {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _ := repo.GetByID(ctx, user.ID)
+	// El nombre inicial era "Test" (Atoi -> 0)
+	if want := strconv.Itoa(workers * increments); got.Name != want {
+		t.Errorf("counter = %s; want %s (lost updates)", got.Name, want)
+	}
+	if want := 1 + workers*increments; got.Version != want {
+		t.Errorf("Version = %d; want %d", got.Version, want)
+	}
+}
@@ -0,0 +1,251 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/persistence/wal"
+)
+
+// ============================================================================
+// DURABILIDAD PARA MemoryUserRepository (WAL + SNAPSHOTS)
+// ============================================================================
+// Para despliegues chicos sin base de datos: el estado vive en memoria pero
+// cada escritura confirmada se agrega a un write-ahead log (persistence/wal).
+//
+// - Cada registro del WAL es una lista de operaciones put/delete con el
+//   usuario completo, así que reproducirlo es idempotente
+// - Dentro de WithTx las operaciones se escriben juntas al confirmar
+//   (un registro por transacción); fuera de una transacción, una por escritura
+// - Cada SnapshotInterval el WAL se compacta en un snapshot con el estado
+//   completo, para que el arranque no tenga que reproducir toda la historia
+// - Al arrancar: último snapshot + registros posteriores. Un checksum
+//   inválido aborta el arranque (wal.ErrCorrupt)
+//
+// Las escrituras fuera de una transacción pueden quedar en el WAL antes que
+// las de una transacción concurrente sobre el mismo usuario; los usecases
+// siempre escriben dentro de WithTx.
+// ============================================================================
+
+// DurabilityConfig configura el modo durable
+type DurabilityConfig struct {
+	Dir              string
+	Sync             wal.SyncPolicy
+	SyncInterval     time.Duration // Solo con wal.SyncInterval
+	SnapshotInterval time.Duration // 0 = solo al cerrar
+}
+
+// userRecord es el formato persistido: a diferencia de domain.User incluye
+// el password (que tiene json:"-")
+type userRecord struct {
+	ID        int        `json:"id"`
+	Email     string     `json:"email"`
+	Name      string     `json:"name"`
+	Password  string     `json:"password"`
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func newUserRecord(u *domain.User) *userRecord {
+	return &userRecord{
+		ID: u.ID, Email: u.Email, Name: u.Name, Password: u.Password, Version: u.Version,
+		CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt, DeletedAt: u.DeletedAt,
+	}
+}
+
+func (rec *userRecord) user() *domain.User {
+	return &domain.User{
+		ID: rec.ID, Email: rec.Email, Name: rec.Name, Password: rec.Password, Version: rec.Version,
+		CreatedAt: rec.CreatedAt, UpdatedAt: rec.UpdatedAt, DeletedAt: rec.DeletedAt,
+	}
+}
+
+// userOp es una operación del WAL: put guarda el usuario completo, delete borra por ID
+type userOp struct {
+	Op   string      `json:"op"`
+	User *userRecord `json:"user,omitempty"`
+	ID   int         `json:"id,omitempty"`
+}
+
+func putOp(u *domain.User) userOp { return userOp{Op: "put", User: newUserRecord(u)} }
+func deleteOp(id int) userOp      { return userOp{Op: "delete", ID: id} }
+
+type walEntry struct {
+	Ops []userOp `json:"ops"`
+}
+
+type userSnapshot struct {
+	NextID int           `json:"next_id"`
+	Users  []*userRecord `json:"users"`
+}
+
+// userState es el estado reconstruido desde snapshot + WAL. Lo usan tanto
+// el arranque como la compactación, así ambos aplican las mismas reglas.
+type userState struct {
+	nextID int
+	users  map[int]*domain.User
+}
+
+func newUserState() *userState {
+	return &userState{nextID: 1, users: make(map[int]*domain.User)}
+}
+
+func (s *userState) restore(data []byte) error {
+	var snap userSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	s.nextID = snap.NextID
+	for _, rec := range snap.Users {
+		s.users[rec.ID] = rec.user()
+	}
+	return nil
+}
+
+func (s *userState) apply(data []byte) error {
+	var entry walEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	for _, op := range entry.Ops {
+		switch op.Op {
+		case "put":
+			s.users[op.User.ID] = op.User.user()
+			if op.User.ID >= s.nextID {
+				s.nextID = op.User.ID + 1
+			}
+		case "delete":
+			delete(s.users, op.ID)
+		}
+	}
+	return nil
+}
+
+func (s *userState) snapshot() ([]byte, error) {
+	snap := userSnapshot{NextID: s.nextID, Users: make([]*userRecord, 0, len(s.users))}
+	for _, u := range s.users {
+		snap.Users = append(snap.Users, newUserRecord(u))
+	}
+	return json.Marshal(snap)
+}
+
+// foldUserSnapshot es la función de compactación para wal.Log.Compact
+func foldUserSnapshot(base []byte, records [][]byte) ([]byte, error) {
+	state := newUserState()
+	if base != nil {
+		if err := state.restore(base); err != nil {
+			return nil, err
+		}
+	}
+	for _, record := range records {
+		if err := state.apply(record); err != nil {
+			return nil, err
+		}
+	}
+	return state.snapshot()
+}
+
+// userWAL envuelve el wal.Log con la compactación periódica
+type userWAL struct {
+	log  *wal.Log
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (w *userWAL) append(ops []userOp) error {
+	data, err := json.Marshal(walEntry{Ops: ops})
+	if err != nil {
+		return err
+	}
+	return w.log.Append(data)
+}
+
+func (w *userWAL) compactEvery(interval time.Duration) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := w.log.Compact(foldUserSnapshot); err != nil {
+					log.Printf("user store: snapshot failed: %v", err)
+				}
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (w *userWAL) close() error {
+	close(w.stop)
+	w.wg.Wait()
+	// Snapshot final: el próximo arranque no tiene que reproducir el WAL
+	err := w.log.Compact(foldUserSnapshot)
+	if cerr := w.log.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// OpenDurableMemoryUserRepository crea un MemoryUserRepository que recupera su
+// estado de cfg.Dir y registra cada escritura. Hay que llamar a Close al salir.
+func OpenDurableMemoryUserRepository(cfg DurabilityConfig) (*MemoryUserRepository, error) {
+	opts := []wal.Option{wal.WithSyncPolicy(cfg.Sync)}
+	if cfg.SyncInterval > 0 {
+		opts = append(opts, wal.WithSyncInterval(cfg.SyncInterval))
+	}
+	l, err := wal.Open(cfg.Dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	state := newUserState()
+	if err := l.Recover(state.restore, state.apply); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	r := NewMemoryUserRepository().(*MemoryUserRepository)
+	r.nextID = state.nextID
+	for _, u := range state.users {
+		r.put(u)
+	}
+
+	r.wal = &userWAL{log: l, stop: make(chan struct{})}
+	if cfg.SnapshotInterval > 0 {
+		r.wal.compactEvery(cfg.SnapshotInterval)
+	}
+	return r, nil
+}
+
+// Close detiene la compactación, escribe un último snapshot y cierra el WAL.
+// Sin modo durable no hace nada.
+func (r *MemoryUserRepository) Close() error {
+	if r.wal == nil {
+		return nil
+	}
+	return r.wal.close()
+}
+
+// persist registra op en el WAL: al confirmar si hay transacción, ya mismo si
+// no. Sin modo durable no hace nada. Se llama con r.mu tomado, así el orden en
+// el WAL es el mismo que en memoria.
+func (r *MemoryUserRepository) persist(ctx context.Context, op userOp) error {
+	if r.wal == nil {
+		return nil
+	}
+	if tx := memoryTxFromContext(ctx); tx != nil {
+		tx.addRedo(r.wal, op)
+		return nil
+	}
+	return r.wal.append([]userOp{op})
+}
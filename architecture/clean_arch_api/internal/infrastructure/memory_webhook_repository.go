@@ -0,0 +1,137 @@
+package infrastructure
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+)
+
+// maxMemoryDeliveries es cuántas entregas se guardan por suscripción en memoria
+const maxMemoryDeliveries = 100
+
+// MemoryWebhookRepository guarda suscripciones y entregas en memoria,
+// copiando al leer y al escribir como MemoryUserRepository
+type MemoryWebhookRepository struct {
+	mu             sync.RWMutex
+	subs           map[int]*domain.WebhookSubscription
+	deliveries     map[int][]*domain.WebhookDelivery // por suscripción, la más vieja primero
+	nextID         int
+	nextDeliveryID int
+}
+
+func NewMemoryWebhookRepository() *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		subs:           make(map[int]*domain.WebhookSubscription),
+		deliveries:     make(map[int][]*domain.WebhookDelivery),
+		nextID:         1,
+		nextDeliveryID: 1,
+	}
+}
+
+func (r *MemoryWebhookRepository) Create(ctx context.Context, sub *domain.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub.ID = r.nextID
+	r.nextID++
+	r.subs[sub.ID] = copySubscription(sub)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetByID(ctx context.Context, id int) (*domain.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.subs[id]
+	if !ok {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	return copySubscription(sub), nil
+}
+
+func (r *MemoryWebhookRepository) List(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	return r.list(func(*domain.WebhookSubscription) bool { return true }), nil
+}
+
+func (r *MemoryWebhookRepository) ListActive(ctx context.Context, eventType string) ([]*domain.WebhookSubscription, error) {
+	return r.list(func(sub *domain.WebhookSubscription) bool {
+		return sub.Active() && sub.Matches(eventType)
+	}), nil
+}
+
+func (r *MemoryWebhookRepository) list(keep func(*domain.WebhookSubscription) bool) []*domain.WebhookSubscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var subs []*domain.WebhookSubscription
+	for _, sub := range r.subs {
+		if keep(sub) {
+			subs = append(subs, copySubscription(sub))
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs
+}
+
+func (r *MemoryWebhookRepository) Update(ctx context.Context, sub *domain.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[sub.ID]; !ok {
+		return domain.ErrSubscriptionNotFound
+	}
+	r.subs[sub.ID] = copySubscription(sub)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[id]; !ok {
+		return domain.ErrSubscriptionNotFound
+	}
+	delete(r.subs, id)
+	delete(r.deliveries, id)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) RecordDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d.ID = r.nextDeliveryID
+	r.nextDeliveryID++
+	stored := *d
+	history := append(r.deliveries[d.SubscriptionID], &stored)
+	if len(history) > maxMemoryDeliveries {
+		history = history[len(history)-maxMemoryDeliveries:]
+	}
+	r.deliveries[d.SubscriptionID] = history
+	return nil
+}
+
+func (r *MemoryWebhookRepository) Deliveries(ctx context.Context, subscriptionID, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := r.deliveries[subscriptionID]
+	result := make([]*domain.WebhookDelivery, 0, min(limit, len(history)))
+	for i := len(history) - 1; i >= 0 && len(result) < limit; i-- {
+		d := *history[i]
+		result = append(result, &d)
+	}
+	return result, nil
+}
+
+func copySubscription(sub *domain.WebhookSubscription) *domain.WebhookSubscription {
+	s := *sub
+	s.EventTypes = append([]string(nil), sub.EventTypes...)
+	if sub.DisabledAt != nil {
+		disabledAt := *sub.DisabledAt
+		s.DisabledAt = &disabledAt
+	}
+	return &s
+}
@@ -0,0 +1,6 @@
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	password TEXT NOT NULL
+);
@@ -0,0 +1,5 @@
+-- SQLite no permite defaults no constantes en ADD COLUMN: las filas
+-- existentes quedan en epoch y la aplicación siempre envía valores (UTC)
+ALTER TABLE users ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
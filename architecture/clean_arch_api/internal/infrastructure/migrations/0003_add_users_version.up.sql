@@ -0,0 +1,2 @@
+-- Las filas existentes empiezan en la versión 1, igual que los usuarios nuevos
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
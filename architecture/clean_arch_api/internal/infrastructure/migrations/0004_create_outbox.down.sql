@@ -0,0 +1,2 @@
+DROP INDEX idx_outbox_pending;
+DROP TABLE outbox;
@@ -0,0 +1,14 @@
+-- seq da el orden de creación; id es el dedup ID que ven los consumidores
+CREATE TABLE outbox (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL UNIQUE,
+	type TEXT NOT NULL,
+	aggregate_id INTEGER NOT NULL,
+	payload TEXT NOT NULL,
+	occurred_at TIMESTAMP NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	published_at TIMESTAMP
+);
+CREATE INDEX idx_outbox_pending ON outbox (published_at, seq);
@@ -0,0 +1,3 @@
+DROP INDEX idx_webhook_deliveries_subscription;
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_subscriptions;
@@ -0,0 +1,23 @@
+-- event_types es una lista separada por comas ("user.created,user.deleted" o "*")
+CREATE TABLE webhook_subscriptions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL,
+	event_types TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	consecutive_failures INTEGER NOT NULL DEFAULT 0,
+	disabled_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	subscription_id INTEGER NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+	event_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	attempt INTEGER NOT NULL,
+	status_code INTEGER NOT NULL,
+	error TEXT NOT NULL,
+	duration_ns INTEGER NOT NULL,
+	attempted_at TIMESTAMP NOT NULL
+);
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, id);
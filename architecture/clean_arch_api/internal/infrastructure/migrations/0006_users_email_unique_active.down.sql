@@ -0,0 +1,18 @@
+-- Vuelve al UNIQUE sobre toda la columna: falla si un email eliminado ya
+-- fue reutilizado por otro usuario (hay que resolverlo a mano antes)
+CREATE TABLE users_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	password TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00',
+	updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00',
+	deleted_at TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1
+);
+INSERT INTO users_old (id, email, name, password, created_at, updated_at, deleted_at, version)
+	SELECT id, email, name, password, created_at, updated_at, deleted_at, version FROM users;
+UPDATE sqlite_sequence SET seq = (SELECT seq FROM sqlite_sequence WHERE name = 'users')
+	WHERE name = 'users_old' AND EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = 'users');
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
@@ -0,0 +1,21 @@
+-- El email pasa a ser único solo entre usuarios activos: un usuario eliminado
+-- (soft delete) libera su email. SQLite no permite quitar el UNIQUE de una
+-- columna, así que se reconstruye la tabla y se usa un índice parcial.
+CREATE TABLE users_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL,
+	name TEXT NOT NULL,
+	password TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00',
+	updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00',
+	deleted_at TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1
+);
+INSERT INTO users_new (id, email, name, password, created_at, updated_at, deleted_at, version)
+	SELECT id, email, name, password, created_at, updated_at, deleted_at, version FROM users;
+-- Conservar el contador de AUTOINCREMENT: los IDs borrados no se reusan
+UPDATE sqlite_sequence SET seq = (SELECT seq FROM sqlite_sequence WHERE name = 'users')
+	WHERE name = 'users_new' AND EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = 'users');
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+CREATE UNIQUE INDEX idx_users_email_active ON users (email) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX idx_outbox_aggregate;
+ALTER TABLE outbox DROP COLUMN aggregate_type;
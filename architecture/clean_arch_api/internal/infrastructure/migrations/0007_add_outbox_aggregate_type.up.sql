@@ -0,0 +1,6 @@
+-- Los IDs de usuarios y productos se repiten: el orden por agregado pasa a
+-- ser por (aggregate_type, aggregate_id). Las filas existentes se deducen
+-- del tipo de evento.
+ALTER TABLE outbox ADD COLUMN aggregate_type TEXT NOT NULL DEFAULT 'user';
+UPDATE outbox SET aggregate_type = 'product' WHERE type LIKE 'inventory.%';
+CREATE INDEX idx_outbox_aggregate ON outbox (aggregate_type, aggregate_id, seq);
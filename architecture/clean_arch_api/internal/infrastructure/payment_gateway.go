@@ -0,0 +1,46 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+	"github.com/josediaz/go-mastery-lab/finance/payments"
+)
+
+// PaymentGateway adapta finance/payments.Service al puerto
+// usecase.PaymentGateway que usa la saga de pedidos
+type PaymentGateway struct {
+	service *payments.Service
+}
+
+func NewPaymentGateway(service *payments.Service) *PaymentGateway {
+	return &PaymentGateway{service: service}
+}
+
+func (g *PaymentGateway) Charge(ctx context.Context, idempotencyKey string, amount money.Money, description string) (string, error) {
+	result, err := g.service.Charge(ctx, payments.ChargeRequest{
+		IdempotencyKey: idempotencyKey,
+		Amount:         amount,
+		Description:    description,
+	})
+	if errors.Is(err, payments.ErrPaymentFailed) {
+		// Rechazo definitivo del proveedor (tarjeta rechazada, sin fondos...)
+		return "", fmt.Errorf("%w: %w", domain.ErrPaymentDeclined, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return result.PaymentID, nil
+}
+
+func (g *PaymentGateway) Refund(ctx context.Context, paymentID, idempotencyKey string, amount money.Money) error {
+	_, err := g.service.Refund(ctx, payments.RefundRequest{
+		PaymentID:      paymentID,
+		IdempotencyKey: idempotencyKey,
+		Amount:         amount,
+	})
+	return err
+}
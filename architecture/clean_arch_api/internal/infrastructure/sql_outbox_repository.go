@@ -0,0 +1,93 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+	"github.com/josediaz/go-mastery-lab/persistence/sqltime"
+)
+
+// SQLOutboxRepository guarda el outbox en la tabla outbox (ver
+// migrations/0004_create_outbox). Add usa la transacción del ctx, así el
+// evento se confirma o se descarta junto con el cambio del usuario.
+type SQLOutboxRepository struct {
+	db *sql.DB
+}
+
+func NewSQLOutboxRepository(db *sql.DB) *SQLOutboxRepository {
+	return &SQLOutboxRepository{db: db}
+}
+
+func (r *SQLOutboxRepository) Add(ctx context.Context, msgs ...repository.OutboxMessage) error {
+	exec := sqlExecutor(ctx, r.db)
+	query := `INSERT INTO outbox (id, type, aggregate_type, aggregate_id, payload, occurred_at, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	for _, msg := range msgs {
+		_, err := exec.ExecContext(ctx, query,
+			msg.ID, msg.Type, msg.AggregateType, msg.AggregateID, string(msg.Payload),
+			sqltime.Value(msg.OccurredAt), sqltime.Value(msg.NextAttemptAt))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pending excluye con NOT EXISTS los mensajes que tienen uno anterior del
+// mismo agregado (tipo e ID) todavía sin publicar y esperando reintento
+func (r *SQLOutboxRepository) Pending(ctx context.Context, now time.Time, limit int) ([]repository.OutboxMessage, error) {
+	query := `SELECT id, type, aggregate_type, aggregate_id, payload, occurred_at, attempts, next_attempt_at, last_error
+		FROM outbox o
+		WHERE o.published_at IS NULL AND o.next_attempt_at <= ?
+		AND NOT EXISTS (
+			SELECT 1 FROM outbox prev
+			WHERE prev.aggregate_type = o.aggregate_type AND prev.aggregate_id = o.aggregate_id
+			AND prev.seq < o.seq
+			AND prev.published_at IS NULL AND prev.next_attempt_at > ?
+		)
+		ORDER BY o.seq LIMIT ?`
+	nowValue := sqltime.Value(now)
+	rows, err := sqlExecutor(ctx, r.db).QueryContext(ctx, query, nowValue, nowValue, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []repository.OutboxMessage
+	for rows.Next() {
+		var msg repository.OutboxMessage
+		var payload string
+		err := rows.Scan(&msg.ID, &msg.Type, &msg.AggregateType, &msg.AggregateID, &payload,
+			sqltime.Scan(&msg.OccurredAt), &msg.Attempts, sqltime.Scan(&msg.NextAttemptAt), &msg.LastError)
+		if err != nil {
+			return nil, err
+		}
+		msg.Payload = []byte(payload)
+		pending = append(pending, msg)
+	}
+	return pending, rows.Err()
+}
+
+func (r *SQLOutboxRepository) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	_, err := sqlExecutor(ctx, r.db).ExecContext(ctx,
+		`UPDATE outbox SET published_at = ? WHERE id = ?`, sqltime.Value(at), id)
+	return err
+}
+
+func (r *SQLOutboxRepository) MarkFailed(ctx context.Context, id string, nextAttempt time.Time, lastErr string) error {
+	_, err := sqlExecutor(ctx, r.db).ExecContext(ctx,
+		`UPDATE outbox SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+		sqltime.Value(nextAttempt), lastErr, id)
+	return err
+}
+
+func (r *SQLOutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int, error) {
+	result, err := sqlExecutor(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < ?`, sqltime.Value(before))
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,153 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// ============================================================================
+// UNIT OF WORK CON database/sql
+// ============================================================================
+// - La transacción más externa hace BEGIN/COMMIT/ROLLBACK
+// - Las anidadas usan SAVEPOINT / ROLLBACK TO SAVEPOINT / RELEASE SAVEPOINT
+// - Panic dentro de fn => ROLLBACK y se relanza el panic
+// - Conflictos de serialización (SQLite "database is locked"; 40001/40P01
+//   si el driver expone SQLState) => se reintenta fn completa con backoff
+//
+// No usa SQL específico de un motor (SAVEPOINT existe en SQLite, Postgres y
+// MySQL), pero los repositorios SQL de este paquete solo soportan SQLite
+// (ver SQLUserRepository).
+// ============================================================================
+
+// dbtx es lo común entre *sql.DB y *sql.Tx
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type sqlTx struct {
+	txHooks
+	tx         *sql.Tx
+	savepoints int
+}
+
+type SQLTxManager struct {
+	db          *sql.DB
+	txOptions   *sql.TxOptions
+	maxRetries  int
+	baseDelay   time.Duration
+	isRetryable func(error) bool
+}
+
+func NewSQLTxManager(db *sql.DB) *SQLTxManager {
+	return &SQLTxManager{
+		db:          db,
+		maxRetries:  3,
+		baseDelay:   10 * time.Millisecond,
+		isRetryable: IsSerializationFailure,
+	}
+}
+
+func (m *SQLTxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx := sqlTxFromContext(ctx); tx != nil {
+		return m.withSavepoint(ctx, tx, fn)
+	}
+
+	delay := m.baseDelay
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, fn)
+		if err == nil || attempt >= m.maxRetries || !m.isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (m *SQLTxManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	t, err := m.db.BeginTx(ctx, m.txOptions)
+	if err != nil {
+		return err
+	}
+	tx := &sqlTx{tx: t}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(repository.ContextWithTx(ctx, tx)); err != nil {
+		if rbErr := t.Rollback(); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	if err := t.Commit(); err != nil {
+		return err
+	}
+	tx.runOnCommit()
+	return nil
+}
+
+func (m *SQLTxManager) withSavepoint(ctx context.Context, tx *sqlTx, fn func(ctx context.Context) error) error {
+	tx.savepoints++
+	name := fmt.Sprintf("sp_%d", tx.savepoints)
+	hooksMark := len(tx.onCommit)
+
+	if _, err := tx.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := fn(ctx); err != nil {
+		tx.onCommit = tx.onCommit[:hooksMark]
+		if _, rbErr := tx.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	_, err := tx.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func sqlTxFromContext(ctx context.Context) *sqlTx {
+	if current, ok := repository.TxFromContext(ctx); ok {
+		if tx, ok := current.(*sqlTx); ok {
+			return tx
+		}
+	}
+	return nil
+}
+
+// sqlExecutor devuelve la transacción activa o, si no hay, la base de datos
+func sqlExecutor(ctx context.Context, db *sql.DB) dbtx {
+	if tx := sqlTxFromContext(ctx); tx != nil {
+		return tx.tx
+	}
+	return db
+}
+
+// IsSerializationFailure reconoce errores que se resuelven reintentando la
+// transacción. Se evita importar tipos de cada driver: pgx y lib/pq exponen
+// SQLState(); SQLite reporta SQLITE_BUSY como "database is locked".
+func IsSerializationFailure(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		code := state.SQLState()
+		return code == "40001" || code == "40P01" // serialization_failure, deadlock_detected
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
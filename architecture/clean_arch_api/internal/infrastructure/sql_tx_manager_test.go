@@ -0,0 +1,223 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// openTestSQLDB crea una base SQLite en un archivo temporal con el esquema
+// migrado. Un archivo y no :memory:, porque cada conexión del pool vería
+// otra base. busyTimeout 0 hace que un lock tomado falle en el acto con
+// "database is locked" en vez de esperar.
+func openTestSQLDB(t *testing.T, busyTimeout time.Duration) (*sql.DB, string) {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=" + strconv.FormatInt(busyTimeout.Milliseconds(), 10)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := MigrateUserStore(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db, dsn
+}
+
+func countUsers(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSQLTxManagerNestedRollbackToSavepoint(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestSQLDB(t, 5*time.Second)
+	tm := NewSQLTxManager(db)
+	repo := NewSQLUserRepository(db)
+
+	var hooks []string
+	errInner := errors.New("inner failed")
+	err := tm.WithTx(ctx, func(ctx context.Context) error {
+		tx, _ := repository.TxFromContext(ctx)
+		tx.OnCommit(func() { hooks = append(hooks, "outer") })
+		if err := repo.Create(ctx, &domain.User{Email: "outer@example.com", Name: "Outer"}); err != nil {
+			return err
+		}
+
+		// La anidada falla: vuelve al savepoint y la externa sigue
+		err := tm.WithTx(ctx, func(ctx context.Context) error {
+			tx.OnCommit(func() { hooks = append(hooks, "inner") })
+			if err := repo.Create(ctx, &domain.User{Email: "inner@example.com", Name: "Inner"}); err != nil {
+				return err
+			}
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			t.Errorf("inner WithTx err = %v", err)
+		}
+
+		// Una segunda anidada que termina bien se conserva
+		return tm.WithTx(ctx, func(ctx context.Context) error {
+			return repo.Create(ctx, &domain.User{Email: "second@example.com", Name: "Second"})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for email, want := range map[string]bool{"outer@example.com": true, "inner@example.com": false, "second@example.com": true} {
+		_, err := repo.GetByEmail(ctx, email)
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v (err %v)", email, got, want, err)
+		}
+	}
+	if len(hooks) != 1 || hooks[0] != "outer" {
+		t.Errorf("OnCommit hooks run = %v, want only the outer one", hooks)
+	}
+}
+
+func TestSQLTxManagerRollbackAndPanic(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestSQLDB(t, 5*time.Second)
+	tm := NewSQLTxManager(db)
+	repo := NewSQLUserRepository(db)
+
+	errFail := errors.New("fail")
+	err := tm.WithTx(ctx, func(ctx context.Context) error {
+		repo.Create(ctx, &domain.User{Email: "a@example.com", Name: "A"})
+		return tm.WithTx(ctx, func(ctx context.Context) error {
+			repo.Create(ctx, &domain.User{Email: "b@example.com", Name: "B"})
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tm.WithTx(ctx, func(ctx context.Context) error {
+		repo.Create(ctx, &domain.User{Email: "c@example.com", Name: "C"})
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Errorf("WithTx err = %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic inside WithTx was not re-raised")
+			}
+		}()
+		tm.WithTx(ctx, func(ctx context.Context) error {
+			repo.Create(ctx, &domain.User{Email: "d@example.com", Name: "D"})
+			panic("boom")
+		})
+	}()
+
+	if n := countUsers(t, db); n != 2 {
+		t.Errorf("users = %d, want 2 (rolled back and panicked transactions leave nothing)", n)
+	}
+}
+
+func TestSQLTxManagerRetriesWhenDatabaseIsLocked(t *testing.T) {
+	ctx := context.Background()
+	db, dsn := openTestSQLDB(t, 0)
+	tm := NewSQLTxManager(db)
+	repo := NewSQLUserRepository(db)
+
+	// Otro proceso toma el lock de escritura y lo suelta al rato
+	other, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	conn, err := other.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(25*time.Millisecond, func() { conn.ExecContext(ctx, `COMMIT`) })
+
+	attempts := 0
+	err = tm.WithTx(ctx, func(ctx context.Context) error {
+		attempts++
+		return repo.Create(ctx, &domain.User{Email: "a@example.com", Name: "A"})
+	})
+	if err != nil {
+		t.Fatalf("WithTx err = %v after %d attempts", err, attempts)
+	}
+	if attempts < 2 {
+		t.Errorf("attempts = %d, want a retry after SQLITE_BUSY", attempts)
+	}
+	if n := countUsers(t, db); n != 1 {
+		t.Errorf("users = %d, want 1", n)
+	}
+}
+
+// sqlStateError imita los errores de pgx y lib/pq
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pq: " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestSQLTxManagerRetriesSerializationFailures(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestSQLDB(t, 5*time.Second)
+	tm := NewSQLTxManager(db)
+	tm.baseDelay = time.Millisecond
+	repo := NewSQLUserRepository(db)
+
+	// 40001 dos veces y después éxito: cada intento empieza de cero
+	attempts := 0
+	err := tm.WithTx(ctx, func(ctx context.Context) error {
+		attempts++
+		if err := repo.Create(ctx, &domain.User{Email: "a@example.com", Name: "A"}); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return sqlStateError("40001")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("WithTx = %v after %d attempts, want success on the 3rd", err, attempts)
+	}
+	if n := countUsers(t, db); n != 1 {
+		t.Errorf("users = %d, want 1 (failed attempts rolled back)", n)
+	}
+
+	// Agotados los reintentos se devuelve el error
+	attempts = 0
+	err = tm.WithTx(ctx, func(ctx context.Context) error {
+		attempts++
+		return sqlStateError("40P01")
+	})
+	if attempts != tm.maxRetries+1 || err == nil {
+		t.Errorf("WithTx = %v after %d attempts, want %d attempts", err, attempts, tm.maxRetries+1)
+	}
+
+	// Otros errores no se reintentan
+	attempts = 0
+	tm.WithTx(ctx, func(ctx context.Context) error {
+		attempts++
+		return sqlStateError("23505")
+	})
+	if attempts != 1 {
+		t.Errorf("unique violation retried: %d attempts", attempts)
+	}
+}
@@ -0,0 +1,280 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+	"github.com/josediaz/go-mastery-lab/persistence/migrate"
+	"github.com/josediaz/go-mastery-lab/persistence/sqltime"
+)
+
+// ============================================================================
+// SQL USER REPOSITORY
+// ============================================================================
+// Implementación de UserRepository con database/sql. Si el ctx trae una
+// transacción de SQLTxManager, las consultas corren dentro de ella.
+// El esquema se versiona con persistence/migrate (ver migrations/).
+// Los timestamps se escriben en UTC y se leen con persistence/sqltime.
+//
+// Las consultas y migraciones están escritas para SQLite: placeholders ?,
+// LastInsertId y AUTOINCREMENT. Portarlo a Postgres requiere $1, $2...,
+// INSERT ... RETURNING id y migraciones propias (lib/pq no implementa
+// LastInsertId); isUniqueViolation y IsSerializationFailure ya reconocen
+// los códigos de Postgres.
+//
+// El email es único solo entre usuarios activos (índice UNIQUE parcial
+// WHERE deleted_at IS NULL): un usuario eliminado libera su email.
+// ============================================================================
+
+//go:embed migrations/*.sql
+var userMigrations embed.FS
+
+// MigrateUserStore aplica las migraciones pendientes (usuarios, outbox y webhooks)
+func MigrateUserStore(ctx context.Context, db *sql.DB) error {
+	m, err := migrate.New(db, userMigrations, "migrations")
+	if err != nil {
+		return err
+	}
+	_, err = m.Up(ctx)
+	return err
+}
+
+type SQLUserRepository struct {
+	db *sql.DB
+}
+
+func NewSQLUserRepository(db *sql.DB) *SQLUserRepository {
+	return &SQLUserRepository{db: db}
+}
+
+func (r *SQLUserRepository) Create(ctx context.Context, user *domain.User) error {
+	query := `INSERT INTO users (email, name, password, version, created_at, updated_at, deleted_at) VALUES (?, ?, ?, 1, ?, ?, ?)`
+	result, err := sqlExecutor(ctx, r.db).ExecContext(ctx, query,
+		user.Email, user.Name, user.Password,
+		sqltime.Value(user.CreatedAt), sqltime.Value(user.UpdatedAt), sqltime.NullValue(user.DeletedAt))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrUserAlreadyExists
+		}
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	user.ID = int(id)
+	user.Version = 1
+	return nil
+}
+
+func (r *SQLUserRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+	return r.scanOne(sqlExecutor(ctx, r.db).QueryRowContext(ctx, query, id))
+}
+
+func (r *SQLUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	// Los eliminados no ocupan el email (puede haber varios con el mismo)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = ? AND deleted_at IS NULL`
+	return r.scanOne(sqlExecutor(ctx, r.db).QueryRowContext(ctx, query, email))
+}
+
+// Update incluye la versión en el WHERE: si otra transacción ya la cambió,
+// no se actualiza ninguna fila. Es atómico sin necesidad de SELECT ... FOR UPDATE.
+func (r *SQLUserRepository) Update(ctx context.Context, user *domain.User) error {
+	exec := sqlExecutor(ctx, r.db)
+	query := `UPDATE users SET email = ?, name = ?, password = ?, version = version + 1, created_at = ?, updated_at = ?, deleted_at = ? WHERE id = ? AND version = ?`
+	result, err := exec.ExecContext(ctx, query,
+		user.Email, user.Name, user.Password,
+		sqltime.Value(user.CreatedAt), sqltime.Value(user.UpdatedAt), sqltime.NullValue(user.DeletedAt),
+		user.ID, user.Version)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrUserAlreadyExists
+		}
+		return err
+	}
+
+	err = expectOneRow(result)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		// 0 filas: o el usuario no existe o la versión no coincide
+		var exists int
+		err = exec.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ?`, user.ID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return domain.ErrVersionConflict
+	}
+	if err != nil {
+		return err
+	}
+	user.Version++
+	return nil
+}
+
+func (r *SQLUserRepository) Delete(ctx context.Context, id int) error {
+	result, err := sqlExecutor(ctx, r.db).ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
+
+// List usa keyset pagination: WHERE (campo, id) > (cursor) ORDER BY campo, id.
+// Con un índice sobre (campo, id) cada página cuesta lo mismo sin importar
+// cuán profunda sea, a diferencia de OFFSET.
+func (r *SQLUserRepository) List(ctx context.Context, q repository.ListQuery) (*repository.UserPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	cursor, err := repository.DecodeCursor(q)
+	if err != nil {
+		return nil, err
+	}
+
+	// El nombre de columna nunca viene del cliente: Validate ya restringió SortBy
+	column := string(q.SortBy)
+	var where []string
+	var args []any
+
+	if !q.IncludeDeleted {
+		where = append(where, `deleted_at IS NULL`)
+	}
+	if q.EmailPrefix != "" {
+		// LOWER de SQLite solo conoce ASCII: el argumento se pliega igual
+		where = append(where, `LOWER(email) LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLike(repository.FoldCase(q.EmailPrefix))+"%")
+	}
+	if q.NameContains != "" {
+		where = append(where, `LOWER(name) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(repository.FoldCase(q.NameContains))+"%")
+	}
+	if !q.CreatedAfter.IsZero() {
+		where = append(where, `created_at >= ?`)
+		args = append(args, sqltime.Value(q.CreatedAfter))
+	}
+	if !q.CreatedBefore.IsZero() {
+		where = append(where, `created_at < ?`)
+		args = append(args, sqltime.Value(q.CreatedBefore))
+	}
+
+	op, order := ">", "ASC"
+	if q.Descending {
+		op, order = "<", "DESC"
+	}
+	if cursor != nil {
+		key, err := cursorKeyArg(cursor)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, fmt.Sprintf(`(%s %s ? OR (%s = ? AND id %s ?))`, column, op, column, op))
+		args = append(args, key, key, cursor.ID)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	// Se pide un elemento extra para saber si hay otra página
+	query += fmt.Sprintf(` ORDER BY %s %s, id %s LIMIT ?`, column, order, order)
+	args = append(args, q.Limit+1)
+
+	rows, err := sqlExecutor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &repository.UserPage{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Users = append(page.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Users) > q.Limit {
+		page.Users = page.Users[:q.Limit]
+		page.NextCursor = repository.EncodeCursor(q, page.Users[len(page.Users)-1])
+	}
+	return page, nil
+}
+
+func cursorKeyArg(c *repository.Cursor) (any, error) {
+	switch c.SortBy {
+	case repository.SortByID:
+		return c.ID, nil
+	case repository.SortByCreatedAt:
+		t, err := time.Parse(time.RFC3339Nano, c.Key)
+		if err != nil {
+			return nil, repository.ErrInvalidCursor
+		}
+		return sqltime.Value(t), nil
+	default:
+		return c.Key, nil
+	}
+}
+
+// escapeLike evita que % y _ del usuario actúen como comodines
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+const userColumns = `id, email, name, password, version, created_at, updated_at, deleted_at`
+
+// rowScanner es lo común entre *sql.Row y *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*domain.User, error) {
+	var user domain.User
+	err := row.Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Version,
+		sqltime.Scan(&user.CreatedAt), sqltime.Scan(&user.UpdatedAt), sqltime.ScanNull(&user.DeletedAt))
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *SQLUserRepository) scanOne(row *sql.Row) (*domain.User, error) {
+	user, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, err
+}
+
+func expectOneRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// isUniqueViolation: el mensaje de SQLite, o 23505 si el error expone
+// SQLState() (pgx, lib/pq)
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return state.SQLState() == "23505"
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
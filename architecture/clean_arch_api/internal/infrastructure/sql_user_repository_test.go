@@ -0,0 +1,111 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+	"github.com/josediaz/go-mastery-lab/persistence/migrate"
+)
+
+// forEachUserRepository corre el mismo test contra la implementación en
+// memoria y la de SQLite: ambas deben cumplir el mismo contrato
+func forEachUserRepository(t *testing.T, fn func(t *testing.T, repo repository.UserRepository)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemoryUserRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db, _ := openTestSQLDB(t, 5*time.Second)
+		fn(t, NewSQLUserRepository(db))
+	})
+}
+
+func softDelete(t *testing.T, repo repository.UserRepository, id int) {
+	t.Helper()
+	ctx := context.Background()
+	user, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.SoftDelete(time.Now())
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatalf("soft delete %d: %v", id, err)
+	}
+}
+
+func TestSoftDeletedEmailCanBeReused(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo repository.UserRepository) {
+		ctx := context.Background()
+		first := newTestUser(t, repo, "ana@example.com")
+		softDelete(t, repo, first.ID)
+
+		// El eliminado no se encuentra por email y su email queda libre
+		if _, err := repo.GetByEmail(ctx, "ana@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("GetByEmail of a soft-deleted user err = %v", err)
+		}
+		second := newTestUser(t, repo, "ana@example.com")
+		if got, err := repo.GetByEmail(ctx, "ana@example.com"); err != nil || got.ID != second.ID {
+			t.Errorf("GetByEmail = %+v, %v; want the new user %d", got, err, second.ID)
+		}
+
+		// Entre activos el email sigue siendo único
+		err := repo.Create(ctx, &domain.User{Email: "ana@example.com", Name: "Dup"})
+		if !errors.Is(err, domain.ErrUserAlreadyExists) {
+			t.Errorf("Create duplicate of an active email err = %v", err)
+		}
+
+		// Escribir el eliminado (por ejemplo, otra auditoría) no toca al activo
+		deleted, err := repo.GetByID(ctx, first.ID)
+		if err != nil || !deleted.IsDeleted() {
+			t.Fatalf("GetByID(deleted) = %+v, %v", deleted, err)
+		}
+		deleted.Name = "Audited"
+		if err := repo.Update(ctx, deleted); err != nil {
+			t.Errorf("Update of the deleted user: %v", err)
+		}
+		if got, err := repo.GetByEmail(ctx, "ana@example.com"); err != nil || got.ID != second.ID {
+			t.Errorf("GetByEmail after updating the deleted user = %+v, %v", got, err)
+		}
+
+		// Restaurar el eliminado choca con el activo
+		deleted.DeletedAt = nil
+		if err := repo.Update(ctx, deleted); !errors.Is(err, domain.ErrUserAlreadyExists) {
+			t.Errorf("restore with a taken email err = %v", err)
+		}
+	})
+}
+
+func TestEmailUniquenessMigrationKeepsRows(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestSQLDB(t, 5*time.Second)
+	repo := NewSQLUserRepository(db)
+	m, err := migrate.New(db, userMigrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Con el UNIQUE viejo (antes de 0006 y de las migraciones siguientes):
+	// dos usuarios y el último borrado físicamente
+	if _, err := m.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	ana := newTestUser(t, repo, "ana@example.com")
+	bob := newTestUser(t, repo, "bob@example.com")
+	if _, err := db.Exec(`DELETE FROM users WHERE id = ?`, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetByID(ctx, ana.ID); err != nil || got.Email != ana.Email {
+		t.Errorf("GetByID after the migration = %+v, %v", got, err)
+	}
+	// AUTOINCREMENT conserva su contador: el ID borrado no se reusa
+	if next := newTestUser(t, repo, "carl@example.com"); next.ID <= bob.ID {
+		t.Errorf("new ID %d reuses a deleted ID (last was %d)", next.ID, bob.ID)
+	}
+}
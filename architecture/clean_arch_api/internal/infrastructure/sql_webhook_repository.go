@@ -0,0 +1,175 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/persistence/sqltime"
+)
+
+// SQLWebhookRepository guarda suscripciones y entregas en las tablas de
+// migrations/0005_create_webhooks
+type SQLWebhookRepository struct {
+	db *sql.DB
+}
+
+func NewSQLWebhookRepository(db *sql.DB) *SQLWebhookRepository {
+	return &SQLWebhookRepository{db: db}
+}
+
+const subscriptionColumns = `id, url, event_types, secret, consecutive_failures, disabled_at, created_at`
+
+func (r *SQLWebhookRepository) Create(ctx context.Context, sub *domain.WebhookSubscription) error {
+	result, err := sqlExecutor(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO webhook_subscriptions (url, event_types, secret, consecutive_failures, disabled_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		sub.URL, strings.Join(sub.EventTypes, ","), sub.Secret, sub.ConsecutiveFailures,
+		sqltime.NullValue(sub.DisabledAt), sqltime.Value(sub.CreatedAt))
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sub.ID = int(id)
+	return nil
+}
+
+func (r *SQLWebhookRepository) GetByID(ctx context.Context, id int) (*domain.WebhookSubscription, error) {
+	row := sqlExecutor(ctx, r.db).QueryRowContext(ctx,
+		`SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = ?`, id)
+	sub, err := scanSubscription(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	return sub, err
+}
+
+func (r *SQLWebhookRepository) List(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	return r.query(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY id`)
+}
+
+// ListActive filtra el tipo de evento en Go: la lista de tipos es corta y
+// así "*" y los tipos concretos se resuelven con la misma regla (Matches)
+func (r *SQLWebhookRepository) ListActive(ctx context.Context, eventType string) ([]*domain.WebhookSubscription, error) {
+	subs, err := r.query(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE disabled_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	matching := subs[:0]
+	for _, sub := range subs {
+		if sub.Matches(eventType) {
+			matching = append(matching, sub)
+		}
+	}
+	return matching, nil
+}
+
+func (r *SQLWebhookRepository) query(ctx context.Context, query string, args ...any) ([]*domain.WebhookSubscription, error) {
+	rows, err := sqlExecutor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*domain.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (r *SQLWebhookRepository) Update(ctx context.Context, sub *domain.WebhookSubscription) error {
+	result, err := sqlExecutor(ctx, r.db).ExecContext(ctx,
+		`UPDATE webhook_subscriptions SET url = ?, event_types = ?, secret = ?, consecutive_failures = ?, disabled_at = ? WHERE id = ?`,
+		sub.URL, strings.Join(sub.EventTypes, ","), sub.Secret, sub.ConsecutiveFailures,
+		sqltime.NullValue(sub.DisabledAt), sub.ID)
+	if err != nil {
+		return err
+	}
+	return expectOneSubscription(result)
+}
+
+func (r *SQLWebhookRepository) Delete(ctx context.Context, id int) error {
+	exec := sqlExecutor(ctx, r.db)
+	// SQLite solo aplica ON DELETE CASCADE con PRAGMA foreign_keys = ON
+	if _, err := exec.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE subscription_id = ?`, id); err != nil {
+		return err
+	}
+	result, err := exec.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectOneSubscription(result)
+}
+
+func (r *SQLWebhookRepository) RecordDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	result, err := sqlExecutor(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, attempt, status_code, error, duration_ns, attempted_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.SubscriptionID, d.EventID, d.EventType, d.Attempt, d.StatusCode, d.Error,
+		int64(d.Duration), sqltime.Value(d.AttemptedAt))
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	d.ID = int(id)
+	return nil
+}
+
+func (r *SQLWebhookRepository) Deliveries(ctx context.Context, subscriptionID, limit int) ([]*domain.WebhookDelivery, error) {
+	rows, err := sqlExecutor(ctx, r.db).QueryContext(ctx,
+		`SELECT id, subscription_id, event_id, event_type, attempt, status_code, error, duration_ns, attempted_at
+		FROM webhook_deliveries WHERE subscription_id = ? ORDER BY id DESC LIMIT ?`, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*domain.WebhookDelivery
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var durationNs int64
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Attempt,
+			&d.StatusCode, &d.Error, &durationNs, sqltime.Scan(&d.AttemptedAt))
+		if err != nil {
+			return nil, err
+		}
+		d.Duration = time.Duration(durationNs)
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanSubscription(row rowScanner) (*domain.WebhookSubscription, error) {
+	var sub domain.WebhookSubscription
+	var eventTypes string
+	err := row.Scan(&sub.ID, &sub.URL, &eventTypes, &sub.Secret, &sub.ConsecutiveFailures,
+		sqltime.ScanNull(&sub.DisabledAt), sqltime.Scan(&sub.CreatedAt))
+	if err != nil {
+		return nil, err
+	}
+	sub.EventTypes = strings.Split(eventTypes, ",")
+	return &sub, nil
+}
+
+func expectOneSubscription(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrSubscriptionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+var listBase = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// seedListUsers crea usuarios con nombres y created_at repetidos (el id
+// tiene que desempatar) y fracciones de segundo de distinto largo, que en
+// SQLite se comparan como texto. El último queda eliminado.
+func seedListUsers(t *testing.T, repo repository.UserRepository) []*domain.User {
+	t.Helper()
+	seeds := []struct {
+		email, name string
+		created     time.Duration
+	}{
+		{"carl@example.com", "Carl", time.Second},
+		{"ana@example.com", "Ana", 0},
+		{"bob@example.com", "Bob", time.Second},
+		{"ana.b@example.com", "Ana", 1500 * time.Millisecond},
+		{"dave@other.com", "Bob", 250 * time.Millisecond},
+		{"eve@example.com", "Ana", time.Second},
+		{"zed@example.com", "Bob", 1250 * time.Millisecond},
+		{"gone@example.com", "Ana", 2 * time.Second},
+	}
+	var users []*domain.User
+	for _, s := range seeds {
+		u := &domain.User{Email: s.email, Name: s.name, Password: "x"}
+		u.MarkCreated(listBase.Add(s.created))
+		if err := repo.Create(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+	softDelete(t, repo, users[len(users)-1].ID)
+	users[len(users)-1].DeletedAt = &listBase
+	return users
+}
+
+// expectedOrder ordena por (campo, id) sin pasar por el repositorio
+func expectedOrder(users []*domain.User, q repository.ListQuery, keep func(*domain.User) bool) []int {
+	var matched []*domain.User
+	for _, u := range users {
+		if (q.IncludeDeleted || u.DeletedAt == nil) && keep(u) {
+			matched = append(matched, u)
+		}
+	}
+	less := func(a, b *domain.User) bool {
+		switch q.SortBy {
+		case repository.SortByEmail:
+			if a.Email != b.Email {
+				return a.Email < b.Email
+			}
+		case repository.SortByName:
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+		case repository.SortByCreatedAt:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		return a.ID < b.ID
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if q.Descending {
+			return less(matched[j], matched[i])
+		}
+		return less(matched[i], matched[j])
+	})
+	ids := make([]int, len(matched))
+	for i, u := range matched {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+// listAll recorre todas las páginas siguiendo NextCursor
+func listAll(t *testing.T, repo repository.UserRepository, q repository.ListQuery) (ids []int, pages int) {
+	t.Helper()
+	for {
+		page, err := repo.List(context.Background(), q)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", q, err)
+		}
+		pages++
+		if len(page.Users) > q.Limit {
+			t.Fatalf("page with %d users, limit %d", len(page.Users), q.Limit)
+		}
+		for _, u := range page.Users {
+			ids = append(ids, u.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, pages
+		}
+		if pages > 20 {
+			t.Fatal("pagination does not end")
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func TestListPagesThroughDuplicateSortValues(t *testing.T) {
+	all := func(*domain.User) bool { return true }
+	tests := []struct {
+		name string
+		q    repository.ListQuery
+		keep func(*domain.User) bool
+	}{
+		{"default sort by id", repository.ListQuery{}, all},
+		{"id desc", repository.ListQuery{SortBy: repository.SortByID, Descending: true}, all},
+		{"email", repository.ListQuery{SortBy: repository.SortByEmail}, all},
+		{"email desc", repository.ListQuery{SortBy: repository.SortByEmail, Descending: true}, all},
+		{"name with ties", repository.ListQuery{SortBy: repository.SortByName}, all},
+		{"name desc with ties", repository.ListQuery{SortBy: repository.SortByName, Descending: true}, all},
+		{"created_at with ties", repository.ListQuery{SortBy: repository.SortByCreatedAt}, all},
+		{"created_at desc with ties", repository.ListQuery{SortBy: repository.SortByCreatedAt, Descending: true}, all},
+		{"include deleted", repository.ListQuery{SortBy: repository.SortByName, IncludeDeleted: true}, all},
+		{"email prefix ignores case", repository.ListQuery{SortBy: repository.SortByName, EmailPrefix: "ANA"},
+			func(u *domain.User) bool { return strings.HasPrefix(u.Email, "ana") }},
+		{"name contains", repository.ListQuery{SortBy: repository.SortByEmail, NameContains: "o"},
+			func(u *domain.User) bool { return u.Name == "Bob" }},
+		{"created range", repository.ListQuery{SortBy: repository.SortByCreatedAt,
+			CreatedAfter: listBase.Add(250 * time.Millisecond), CreatedBefore: listBase.Add(1500 * time.Millisecond)},
+			func(u *domain.User) bool {
+				return !u.CreatedAt.Before(listBase.Add(250*time.Millisecond)) && u.CreatedAt.Before(listBase.Add(1500*time.Millisecond))
+			}},
+	}
+
+	forEachUserRepository(t, func(t *testing.T, repo repository.UserRepository) {
+		users := seedListUsers(t, repo)
+		for _, tt := range tests {
+			for _, limit := range []int{1, 2, 3, 100} {
+				t.Run(fmt.Sprintf("%s/limit %d", tt.name, limit), func(t *testing.T) {
+					q := tt.q
+					q.Limit = limit
+					want := expectedOrder(users, q, tt.keep)
+					got, pages := listAll(t, repo, q)
+					if fmt.Sprint(got) != fmt.Sprint(want) {
+						t.Errorf("ids = %v, want %v", got, want)
+					}
+					if wantPages := max(1, (len(want)+limit-1)/limit); pages != wantPages {
+						t.Errorf("pages = %d, want %d", pages, wantPages)
+					}
+				})
+			}
+		}
+	})
+}
+
+func TestListFoldsOnlyASCIICase(t *testing.T) {
+	tests := []struct {
+		name string
+		q    repository.ListQuery
+		want string
+	}{
+		{"ascii email prefix", repository.ListQuery{EmailPrefix: "ZO"}, "[zoë@example.com]"},
+		{"ascii prefix, non-ascii other case", repository.ListQuery{EmailPrefix: "ZOË"}, "[]"},
+		{"non-ascii email prefix exact", repository.ListQuery{EmailPrefix: "élise"}, "[élise@example.com]"},
+		{"non-ascii email prefix other case", repository.ListQuery{EmailPrefix: "Élise"}, "[]"},
+		{"ascii part of a non-ascii name", repository.ListQuery{NameContains: "LISE"}, "[élise@example.com]"},
+		{"non-ascii name same case", repository.ListQuery{NameContains: "Éli"}, "[élise@example.com]"},
+		{"non-ascii name other case", repository.ListQuery{NameContains: "éli"}, "[]"},
+		{"non-ascii name ascii folded", repository.ListQuery{NameContains: "ËL"}, "[zoë@example.com]"},
+	}
+
+	forEachUserRepository(t, func(t *testing.T, repo repository.UserRepository) {
+		ctx := context.Background()
+		for _, seed := range [][2]string{{"élise@example.com", "Élise"}, {"zoë@example.com", "ZOËL"}} {
+			if err := repo.Create(ctx, &domain.User{Email: seed[0], Name: seed[1], Password: "x"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				q := tt.q
+				q.Limit = 10
+				page, err := repo.List(ctx, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var emails []string
+				for _, u := range page.Users {
+					emails = append(emails, u.Email)
+				}
+				if fmt.Sprint(emails) != tt.want {
+					t.Errorf("emails = %v, want %s", emails, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestListCursorIsStableUnderInserts(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo repository.UserRepository) {
+		ctx := context.Background()
+		seedListUsers(t, repo)
+		q := repository.ListQuery{SortBy: repository.SortByName, Limit: 3}
+		first, err := repo.List(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Un usuario que ordena antes del cursor no aparece ni desplaza la página siguiente
+		aaron := &domain.User{Email: "aaron@example.com", Name: "Aaron"}
+		if err := repo.Create(ctx, aaron); err != nil {
+			t.Fatal(err)
+		}
+		q.Cursor = first.NextCursor
+		rest, _ := listAll(t, repo, q)
+
+		seen := map[int]bool{}
+		for _, u := range first.Users {
+			seen[u.ID] = true
+		}
+		for _, id := range rest {
+			if seen[id] {
+				t.Errorf("user %d returned twice", id)
+			}
+			seen[id] = true
+		}
+		if len(seen) != 7 || seen[aaron.ID] {
+			t.Errorf("saw %d users, want the 7 active ones without the new user", len(seen))
+		}
+	})
+}
+
+func TestListRejectsMismatchedCursor(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo repository.UserRepository) {
+		ctx := context.Background()
+		seedListUsers(t, repo)
+		q := repository.ListQuery{SortBy: repository.SortByName, NameContains: "a", Limit: 2}
+		page, err := repo.List(ctx, q)
+		if err != nil || page.NextCursor == "" {
+			t.Fatalf("List = %+v, %v", page, err)
+		}
+
+		tests := []struct {
+			name   string
+			modify func(q *repository.ListQuery)
+		}{
+			{"other sort field", func(q *repository.ListQuery) { q.SortBy = repository.SortByEmail }},
+			{"other direction", func(q *repository.ListQuery) { q.Descending = true }},
+			{"other name filter", func(q *repository.ListQuery) { q.NameContains = "b" }},
+			{"added email filter", func(q *repository.ListQuery) { q.EmailPrefix = "a" }},
+			{"include deleted", func(q *repository.ListQuery) { q.IncludeDeleted = true }},
+			{"created range", func(q *repository.ListQuery) { q.CreatedAfter = listBase }},
+			{"garbage", func(q *repository.ListQuery) { q.Cursor = "not-a-cursor!" }},
+			{"valid base64, not JSON", func(q *repository.ListQuery) { q.Cursor = "bm9wZQ" }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				next := q
+				next.Cursor = page.NextCursor
+				tt.modify(&next)
+				if _, err := repo.List(ctx, next); !errors.Is(err, repository.ErrInvalidCursor) {
+					t.Errorf("err = %v, want ErrInvalidCursor", err)
+				}
+			})
+		}
+
+		// Filtros que solo difieren en mayúsculas son el mismo filtro
+		next := q
+		next.NameContains = "A"
+		next.Cursor = page.NextCursor
+		if _, err := repo.List(ctx, next); err != nil {
+			t.Errorf("cursor with the same filter in upper case: %v", err)
+		}
+	})
+}
+
+func TestListValidatesQuery(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo repository.UserRepository) {
+		for _, q := range []repository.ListQuery{
+			{SortBy: "password"},
+			{Limit: repository.MaxPageSize + 1},
+			{Limit: -1},
+			{CreatedAfter: listBase, CreatedBefore: listBase},
+		} {
+			if _, err := repo.List(context.Background(), q); !errors.Is(err, repository.ErrInvalidListQuery) {
+				t.Errorf("List(%+v) err = %v, want ErrInvalidListQuery", q, err)
+			}
+		}
+	})
+}
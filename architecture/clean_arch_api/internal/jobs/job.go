@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+)
+
+// Status es el estado de un job
+//
+//	queued ──> running ──> succeeded | failed | cancelled
+//	   └──────────────────────────────────────> cancelled
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Finished indica si el job ya no va a cambiar
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// Progress es el avance informado por la función del job. Total 0 = no se
+// sabe de antemano (p. ej. un archivo que se lee en streaming).
+type Progress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total,omitempty"`
+}
+
+// Job es la foto de un job que devuelven Get y Submit
+type Job struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Status     Status     `json:"status"`
+	Progress   Progress   `json:"progress"`
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// HasArtifact indica que hay un archivo para descargar (ver Manager.Artifact)
+	HasArtifact bool `json:"has_artifact,omitempty"`
+}
+
+// Handle es lo que recibe la función del job para informar su avance
+type Handle struct {
+	mu          sync.Mutex
+	progress    Progress
+	artifact    string
+	contentType string
+}
+
+// SetTotal fija el total esperado (0 = desconocido)
+func (h *Handle) SetTotal(total int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.progress.Total = total
+}
+
+// Add suma n unidades hechas
+func (h *Handle) Add(n int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.progress.Done += n
+}
+
+// Attach registra un archivo generado por el job (una exportación, p. ej.).
+// El Manager lo borra cuando el job sale de la retención.
+func (h *Handle) Attach(path, contentType string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.artifact = path
+	h.contentType = contentType
+}
+
+func (h *Handle) snapshot() (Progress, string, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.progress, h.artifact, h.contentType
+}
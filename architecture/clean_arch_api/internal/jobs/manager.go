@@ -0,0 +1,382 @@
+// Package jobs ejecuta operaciones largas (importaciones, exportaciones)
+// fuera del request HTTP: el cliente recibe 202 con el ID del job y consulta
+// su estado, avance y resultado en /jobs/{id}.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// MANAGER
+// ============================================================================
+//
+//	Submit -> queue -> N workers -> fn(ctx, handle) -> succeeded/failed/cancelled
+//	                                                       |
+//	            Sweep (cada SweepInterval) <---------------+ pasada la retención
+//
+// - Un worker pool (como en concurrency/worker_pool y webhook.Dispatcher)
+//   limita cuántos jobs corren a la vez; si la cola está llena Submit
+//   devuelve ErrQueueFull en vez de bloquear el request
+// - Cancel cancela el ctx del job; la función debe respetarlo. Un job en
+//   cola se cancela sin llegar a correr
+// - Los jobs terminados se guardan Retention y después se borran junto con
+//   su archivo (Handle.Attach)
+//
+// Los jobs viven en memoria: si el proceso se detiene se pierden (Close
+// cancela los que estaban corriendo). Similar a un ExecutorService con
+// Future en Java, con el estado consultable por HTTP.
+// ============================================================================
+
+var (
+	ErrNotFound    = errors.New("jobs: job not found")
+	ErrQueueFull   = errors.New("jobs: queue is full")
+	ErrClosed      = errors.New("jobs: manager is closed")
+	ErrFinished    = errors.New("jobs: job already finished")
+	ErrNotFinished = errors.New("jobs: job has not finished")
+	ErrNoArtifact  = errors.New("jobs: job has no artifact")
+)
+
+// Func es el trabajo de un job. Su resultado (serializable a JSON) queda en
+// Job.Result; un error lo deja en failed.
+type Func func(ctx context.Context, h *Handle) (any, error)
+
+type entry struct {
+	job             Job
+	fn              Func
+	handle          *Handle
+	cancel          context.CancelFunc // nil hasta que arranca
+	cancelRequested bool
+	onRemove        []func()
+}
+
+type Manager struct {
+	workers       int
+	queueSize     int
+	retention     time.Duration
+	sweepInterval time.Duration
+	now           func() time.Time
+	logger        *log.Logger
+
+	queue  chan *entry
+	ctx    context.Context // Padre de los ctx de los jobs; Close lo cancela
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	closed bool
+	jobs   map[string]*entry
+}
+
+type Option func(*Manager)
+
+func WithWorkers(n int) Option {
+	return func(m *Manager) {
+		m.workers = n
+	}
+}
+
+func WithQueueSize(n int) Option {
+	return func(m *Manager) {
+		m.queueSize = n
+	}
+}
+
+// WithRetention define cuánto se guarda un job terminado
+func WithRetention(d time.Duration) Option {
+	return func(m *Manager) {
+		m.retention = d
+	}
+}
+
+// WithSweepInterval define cada cuánto se borran los jobs vencidos (0 = solo
+// con Sweep)
+func WithSweepInterval(d time.Duration) Option {
+	return func(m *Manager) {
+		m.sweepInterval = d
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(m *Manager) {
+		m.now = now
+	}
+}
+
+func WithLogger(l *log.Logger) Option {
+	return func(m *Manager) {
+		m.logger = l
+	}
+}
+
+// SubmitOption configura un job en particular
+type SubmitOption func(*entry)
+
+// OnRemove registra fn para cuando el job se borra (retención o Close), p.
+// ej. para borrar un archivo temporal con la entrada del job
+func OnRemove(fn func()) SubmitOption {
+	return func(e *entry) {
+		e.onRemove = append(e.onRemove, fn)
+	}
+}
+
+// NewManager crea el manager y arranca sus workers
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{
+		workers:       2,
+		queueSize:     100,
+		retention:     time.Hour,
+		sweepInterval: time.Minute,
+		now:           func() time.Time { return time.Now().UTC() },
+		logger:        log.Default(),
+		jobs:          make(map[string]*entry),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.queue = make(chan *entry, m.queueSize)
+	m.ctx, m.stop = context.WithCancel(context.Background())
+
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	if m.sweepInterval > 0 {
+		m.wg.Add(1)
+		go m.sweeper()
+	}
+	return m
+}
+
+// Submit encola un job de tipo typ (p. ej. "users.import")
+func (m *Manager) Submit(typ string, fn Func, opts ...SubmitOption) (Job, error) {
+	e := &entry{
+		job:    Job{ID: newJobID(), Type: typ, Status: StatusQueued, CreatedAt: m.now()},
+		fn:     fn,
+		handle: &Handle{},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Job{}, ErrClosed
+	}
+	select {
+	case m.queue <- e:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[e.job.ID] = e
+	return e.snapshot(), nil
+}
+
+// Get devuelve el estado actual del job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return e.snapshot(), nil
+}
+
+// Cancel cancela el job. Uno en cola queda cancelled enseguida; uno que está
+// corriendo pasa a cancelled cuando su función vuelve.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	switch {
+	case e.job.Status.Finished():
+		return e.snapshot(), ErrFinished
+	case e.job.Status == StatusQueued:
+		m.finish(e, StatusCancelled, nil, context.Canceled)
+	default:
+		e.cancelRequested = true
+		e.cancel()
+	}
+	return e.snapshot(), nil
+}
+
+// Artifact devuelve el archivo que generó un job terminado
+func (m *Manager) Artifact(id string) (path, contentType string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return "", "", ErrNotFound
+	}
+	if e.job.Status != StatusSucceeded {
+		return "", "", ErrNotFinished
+	}
+	_, path, contentType = e.handle.snapshot()
+	if path == "" {
+		return "", "", ErrNoArtifact
+	}
+	return path, contentType, nil
+}
+
+// Sweep borra los jobs que terminaron hace más de Retention y devuelve
+// cuántos borró
+func (m *Manager) Sweep() int {
+	m.mu.Lock()
+	var expired []*entry
+	cutoff := m.now().Add(-m.retention)
+	for id, e := range m.jobs {
+		if e.job.FinishedAt != nil && !e.job.FinishedAt.After(cutoff) {
+			expired = append(expired, e)
+			delete(m.jobs, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range expired {
+		m.remove(e)
+	}
+	return len(expired)
+}
+
+// Close deja de aceptar jobs, cancela los que están corriendo o en cola,
+// espera a los workers y borra los archivos de todos los jobs
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.queue)
+	m.mu.Unlock()
+
+	m.stop()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	all := make([]*entry, 0, len(m.jobs))
+	for id, e := range m.jobs {
+		all = append(all, e)
+		delete(m.jobs, id)
+	}
+	m.mu.Unlock()
+	for _, e := range all {
+		m.remove(e)
+	}
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for e := range m.queue {
+		m.run(e)
+	}
+}
+
+func (m *Manager) run(e *entry) {
+	m.mu.Lock()
+	if e.job.Status != StatusQueued {
+		m.mu.Unlock() // Cancelado mientras esperaba
+		return
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+	if m.ctx.Err() != nil {
+		// Close: los que quedaban en cola no llegan a correr
+		m.finish(e, StatusCancelled, nil, m.ctx.Err())
+		m.mu.Unlock()
+		return
+	}
+	now := m.now()
+	e.job.Status = StatusRunning
+	e.job.StartedAt = &now
+	e.cancel = cancel
+	m.mu.Unlock()
+
+	result, err := m.call(ctx, e)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case e.cancelRequested || (err != nil && m.ctx.Err() != nil):
+		m.finish(e, StatusCancelled, result, context.Canceled)
+	case err != nil:
+		m.finish(e, StatusFailed, result, err)
+	default:
+		m.finish(e, StatusSucceeded, result, nil)
+	}
+}
+
+// call ejecuta la función del job; un panic lo deja en failed sin tirar el worker
+func (m *Manager) call(ctx context.Context, e *entry) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.logger.Printf("jobs: job %s (%s) panicked: %v", e.job.ID, e.job.Type, r)
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return e.fn(ctx, e.handle)
+}
+
+// finish cierra el job (con m.mu tomado)
+func (m *Manager) finish(e *entry, status Status, result any, err error) {
+	now := m.now()
+	e.job.Status = status
+	e.job.Result = result
+	e.job.FinishedAt = &now
+	if err != nil {
+		e.job.Error = err.Error()
+	}
+}
+
+// remove ejecuta las limpiezas del job (sin m.mu)
+func (m *Manager) remove(e *entry) {
+	if _, path, _ := e.handle.snapshot(); path != "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			m.logger.Printf("jobs: removing artifact of %s: %v", e.job.ID, err)
+		}
+	}
+	for _, fn := range e.onRemove {
+		fn()
+	}
+}
+
+func (m *Manager) sweeper() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.Sweep()
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// snapshot copia el estado con el avance actual (con m.mu tomado)
+func (e *entry) snapshot() Job {
+	job := e.job
+	progress, artifact, _ := e.handle.snapshot()
+	job.Progress = progress
+	job.HasArtifact = artifact != "" && job.Status == StatusSucceeded
+	return job
+}
+
+func newJobID() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return "job_" + hex.EncodeToString(b[:])
+}
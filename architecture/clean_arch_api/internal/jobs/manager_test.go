@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, m *Manager, id string, want Status) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == want {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s status = %s, want %s", id, job.Status, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobReportsProgressAndResult(t *testing.T) {
+	m := NewManager()
+	defer m.Close()
+
+	job, err := m.Submit("count", func(ctx context.Context, h *Handle) (any, error) {
+		h.SetTotal(3)
+		for i := 0; i < 3; i++ {
+			h.Add(1)
+		}
+		return map[string]int{"counted": 3}, nil
+	})
+	if err != nil || job.Status != StatusQueued {
+		t.Fatalf("Submit = %+v, %v", job, err)
+	}
+
+	done := waitFor(t, m, job.ID, StatusSucceeded)
+	if done.Progress != (Progress{Done: 3, Total: 3}) || done.StartedAt == nil || done.FinishedAt == nil {
+		t.Errorf("job = %+v", done)
+	}
+	if done.Result.(map[string]int)["counted"] != 3 {
+		t.Errorf("result = %v", done.Result)
+	}
+
+	failed, _ := m.Submit("boom", func(ctx context.Context, h *Handle) (any, error) {
+		panic("boom")
+	})
+	if job := waitFor(t, m, failed.ID, StatusFailed); job.Error == "" {
+		t.Error("panicking job has no error")
+	}
+}
+
+func TestCancelRunningAndQueuedJobs(t *testing.T) {
+	m := NewManager(WithWorkers(1))
+	defer m.Close()
+
+	started := make(chan struct{})
+	running, _ := m.Submit("wait", func(ctx context.Context, h *Handle) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	var ran bool
+	queued, _ := m.Submit("never", func(ctx context.Context, h *Handle) (any, error) {
+		ran = true
+		return nil, nil
+	})
+	<-started
+
+	if job, err := m.Cancel(queued.ID); err != nil || job.Status != StatusCancelled {
+		t.Fatalf("Cancel(queued) = %+v, %v", job, err)
+	}
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, m, running.ID, StatusCancelled)
+	if _, err := m.Cancel(running.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("Cancel(finished) = %v, want ErrFinished", err)
+	}
+
+	// El worker ya tomó y descartó el job cancelado en cola
+	last, _ := m.Submit("after", func(ctx context.Context, h *Handle) (any, error) { return nil, nil })
+	waitFor(t, m, last.ID, StatusSucceeded)
+	if ran {
+		t.Error("cancelled queued job ran")
+	}
+}
+
+func TestSubmitFailsWhenQueueIsFull(t *testing.T) {
+	m := NewManager(WithWorkers(1), WithQueueSize(1))
+	defer m.Close()
+
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	m.Submit("busy", func(ctx context.Context, h *Handle) (any, error) {
+		close(started)
+		<-block
+		return nil, nil
+	})
+	<-started
+	if _, err := m.Submit("queued", func(ctx context.Context, h *Handle) (any, error) { return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Submit("overflow", func(ctx context.Context, h *Handle) (any, error) { return nil, nil }); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestSweepRemovesExpiredJobsAndArtifacts(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	m := NewManager(WithRetention(time.Hour), WithSweepInterval(0), WithClock(clock))
+	defer m.Close()
+
+	path := filepath.Join(t.TempDir(), "export.csv")
+	var removed bool
+	job, _ := m.Submit("export", func(ctx context.Context, h *Handle) (any, error) {
+		h.Attach(path, "text/csv")
+		return nil, os.WriteFile(path, []byte("id\n"), 0o600)
+	}, OnRemove(func() { removed = true }))
+	waitFor(t, m, job.ID, StatusSucceeded)
+	if got, _, err := m.Artifact(job.ID); err != nil || got != path {
+		t.Fatalf("Artifact = %q, %v", got, err)
+	}
+
+	if n := m.Sweep(); n != 0 {
+		t.Fatalf("Sweep before retention = %d, want 0", n)
+	}
+	mu.Lock()
+	now = now.Add(time.Hour)
+	mu.Unlock()
+	if n := m.Sweep(); n != 1 {
+		t.Fatalf("Sweep = %d, want 1", n)
+	}
+	if _, err := m.Get(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after sweep = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) || !removed {
+		t.Errorf("artifact stat = %v, OnRemove called = %v", err, removed)
+	}
+}
@@ -0,0 +1,26 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/concurrency/cache"
+)
+
+// Deduplicate envuelve un Handler para que cada ID se procese una sola vez
+// mientras siga en la ventana (las últimas capacity IDs, hasta ttl).
+// Solo se recuerda un ID si el handler terminó bien: si falla, la próxima
+// entrega lo vuelve a intentar. Entregas concurrentes del mismo ID se
+// coalescen en una sola ejecución (cache.GetOrLoad).
+func Deduplicate(h Handler, capacity int, ttl time.Duration) Handler {
+	seen := cache.New[string, struct{}](
+		cache.WithMaxEntries(capacity),
+		cache.WithTTL(ttl),
+	)
+	return func(ctx context.Context, env Envelope) error {
+		_, err := seen.GetOrLoad(ctx, env.ID, func(ctx context.Context, _ string) (struct{}, error) {
+			return struct{}{}, h(ctx, env)
+		})
+		return err
+	}
+}
@@ -0,0 +1,172 @@
+// Package outbox publica los eventos del outbox (repository.OutboxRepository)
+// con un Relay y publishers intercambiables: log, bus en proceso o webhook.
+//
+// La entrega es at-least-once: un evento puede llegar más de una vez (por
+// ejemplo si el proceso cae entre publicar y marcarlo como publicado).
+// Cada evento lleva un ID único; los consumidores deduplican con él
+// (ver Deduplicate).
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+	"github.com/josediaz/go-mastery-lab/concurrency/pubsub"
+)
+
+// Publisher entrega un mensaje a su destino. Un error hace que el Relay
+// lo reintente más tarde.
+type Publisher interface {
+	Publish(ctx context.Context, msg repository.OutboxMessage) error
+}
+
+// PublisherFunc adapta una función a Publisher (como http.HandlerFunc)
+type PublisherFunc func(ctx context.Context, msg repository.OutboxMessage) error
+
+func (f PublisherFunc) Publish(ctx context.Context, msg repository.OutboxMessage) error {
+	return f(ctx, msg)
+}
+
+// Envelope es la forma en que un evento sale del servicio
+type Envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   int             `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func NewEnvelope(msg repository.OutboxMessage) Envelope {
+	return Envelope{
+		ID:            msg.ID,
+		Type:          msg.Type,
+		AggregateType: msg.AggregateType,
+		AggregateID:   msg.AggregateID,
+		OccurredAt:    msg.OccurredAt.UTC(),
+		Data:          json.RawMessage(msg.Payload),
+	}
+}
+
+// ============================================================================
+// LOG
+// ============================================================================
+
+// LogPublisher escribe cada evento en un log (útil en desarrollo)
+type LogPublisher struct {
+	Logger *log.Logger // nil = log estándar
+}
+
+func (p LogPublisher) Publish(ctx context.Context, msg repository.OutboxMessage) error {
+	logf := log.Printf
+	if p.Logger != nil {
+		logf = p.Logger.Printf
+	}
+	logf("event %s id=%s aggregate=%s:%d data=%s", msg.Type, msg.ID, msg.AggregateType, msg.AggregateID, msg.Payload)
+	return nil
+}
+
+// ============================================================================
+// BUS EN PROCESO
+// ============================================================================
+
+// Handler procesa un evento dentro del mismo proceso
+type Handler func(ctx context.Context, env Envelope) error
+
+// Bus reparte los eventos a handlers suscritos por tipo, en el mismo proceso.
+// Usa concurrency/pubsub con entrega síncrona: si un handler falla, el Relay
+// reintenta el evento y los demás handlers lo reciben otra vez (por eso
+// conviene Deduplicate).
+type Bus struct {
+	bus *pubsub.Bus[Envelope]
+}
+
+func NewBus() *Bus {
+	return &Bus{bus: pubsub.New[Envelope]()}
+}
+
+// Subscribe registra h para eventType ("user.created"), para un patrón de
+// pubsub ("user.*") o para todos ("*")
+func (b *Bus) Subscribe(eventType string, h Handler) error {
+	pattern := eventType
+	if pattern == "*" {
+		pattern = ">"
+	}
+	_, err := b.bus.Subscribe(pattern, func(ctx context.Context, msg pubsub.Message[Envelope]) error {
+		return h(ctx, msg.Payload)
+	}, pubsub.WithSync())
+	return err
+}
+
+func (b *Bus) Publish(ctx context.Context, msg repository.OutboxMessage) error {
+	return b.bus.Publish(ctx, msg.Type, NewEnvelope(msg))
+}
+
+// ============================================================================
+// WEBHOOK
+// ============================================================================
+
+// WebhookPublisher hace POST del Envelope en JSON a una URL fija.
+// El header X-Event-ID lleva el dedup ID. Cualquier respuesta no 2xx es error.
+type WebhookPublisher struct {
+	URL    string
+	Client *http.Client // nil = cliente con timeout de 10s
+}
+
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+func (p WebhookPublisher) Publish(ctx context.Context, msg repository.OutboxMessage) error {
+	body, err := json.Marshal(NewEnvelope(msg))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", msg.ID)
+	req.Header.Set("X-Event-Type", msg.Type)
+
+	client := p.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096)) // Permite reusar la conexión
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: unexpected status %s", p.URL, resp.Status)
+	}
+	return nil
+}
+
+// ============================================================================
+// VARIOS DESTINOS
+// ============================================================================
+
+// Multi publica en todos los publishers. Si alguno falla el mensaje se
+// reintenta completo, así que los que ya lo recibieron lo verán de nuevo.
+func Multi(publishers ...Publisher) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg repository.OutboxMessage) error {
+		var errs []error
+		for _, p := range publishers {
+			if err := p.Publish(ctx, msg); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
@@ -0,0 +1,179 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// ============================================================================
+// RELAY
+// ============================================================================
+// Goroutine que lee el outbox y publica los mensajes pendientes:
+//
+//  1. Pending: mensajes sin publicar, en orden de creación
+//  2. Publish: si funciona, MarkPublished; si falla, MarkFailed con el
+//     próximo intento calculado con backoff exponencial (ver
+//     patterns/retry_backoff) y los mensajes siguientes del mismo agregado
+//     (tipo e ID) esperan, para que un consumidor nunca vea "deleted" antes
+//     que "created"
+//  3. Cada tanto borra los mensajes publicados más viejos que la retención
+//
+// Si el proceso cae entre Publish y MarkPublished, el mensaje se publica
+// otra vez al volver: at-least-once. Varias réplicas con su propio Relay
+// también pueden duplicar; los consumidores deduplican por ID.
+// ============================================================================
+
+type Relay struct {
+	repo      repository.OutboxRepository
+	publisher Publisher
+
+	pollInterval time.Duration
+	batchSize    int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	retention    time.Duration
+	now          func() time.Time
+	logger       *log.Logger
+}
+
+type RelayOption func(*Relay)
+
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = d
+	}
+}
+
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = n
+	}
+}
+
+// WithBackoff define el primer reintento y el tope entre reintentos
+func WithBackoff(base, max time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.baseBackoff = base
+		r.maxBackoff = max
+	}
+}
+
+// WithRetention define cuánto se guardan los mensajes ya publicados
+// (0 = no borrar nunca)
+func WithRetention(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.retention = d
+	}
+}
+
+// WithNow reemplaza el reloj (para tests)
+func WithNow(now func() time.Time) RelayOption {
+	return func(r *Relay) {
+		r.now = now
+	}
+}
+
+func WithLogger(l *log.Logger) RelayOption {
+	return func(r *Relay) {
+		r.logger = l
+	}
+}
+
+func NewRelay(repo repository.OutboxRepository, publisher Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{
+		repo:         repo,
+		publisher:    publisher,
+		pollInterval: time.Second,
+		batchSize:    100,
+		baseBackoff:  time.Second,
+		maxBackoff:   5 * time.Minute,
+		retention:    24 * time.Hour,
+		now:          func() time.Time { return time.Now().UTC() },
+		logger:       log.Default(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run publica hasta que ctx se cancele
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	lastCleanup := r.now()
+
+	for {
+		// Vaciar lo pendiente antes de volver a esperar al ticker
+		for {
+			n, err := r.RunOnce(ctx)
+			if err != nil {
+				r.logger.Printf("outbox relay: %v", err)
+			}
+			if err != nil || n < r.batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		if r.retention > 0 && r.now().Sub(lastCleanup) >= time.Hour {
+			lastCleanup = r.now()
+			if _, err := r.repo.DeletePublished(ctx, lastCleanup.Add(-r.retention)); err != nil {
+				r.logger.Printf("outbox relay: cleanup: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce procesa un lote y devuelve cuántos mensajes leyó
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	msgs, err := r.repo.Pending(ctx, r.now(), r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := make(map[repository.AggregateKey]bool) // agregados con un fallo en este lote
+	for _, msg := range msgs {
+		if ctx.Err() != nil {
+			return len(msgs), ctx.Err()
+		}
+		if failed[msg.Aggregate()] {
+			continue // Respetar el orden: esperar al mensaje anterior
+		}
+
+		if err := r.publisher.Publish(ctx, msg); err != nil {
+			failed[msg.Aggregate()] = true
+			next := r.now().Add(r.backoff(msg.Attempts))
+			r.logger.Printf("outbox relay: publish %s (%s) attempt %d failed, retrying at %s: %v",
+				msg.ID, msg.Type, msg.Attempts+1, next.Format(time.RFC3339), err)
+			if err := r.repo.MarkFailed(ctx, msg.ID, next, err.Error()); err != nil {
+				return len(msgs), err
+			}
+			continue
+		}
+		if err := r.repo.MarkPublished(ctx, msg.ID, r.now()); err != nil {
+			return len(msgs), err
+		}
+	}
+	return len(msgs), nil
+}
+
+// backoff = base * 2^attempts, con tope en maxBackoff
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.baseBackoff
+	for i := 0; i < attempts && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.maxBackoff {
+		d = r.maxBackoff
+	}
+	return d
+}
@@ -0,0 +1,287 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+var relayBase = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// flakyPublisher registra cada intento y falla los IDs marcados en failing
+type flakyPublisher struct {
+	mu       sync.Mutex
+	attempts []string
+	failing  map[string]bool
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, msg repository.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts = append(p.attempts, msg.ID)
+	if p.failing[msg.ID] {
+		return errors.New("broker down")
+	}
+	return nil
+}
+
+func (p *flakyPublisher) take() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	attempts := p.attempts
+	p.attempts = nil
+	return fmt.Sprint(attempts)
+}
+
+func (p *flakyPublisher) setFailing(ids ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failing = make(map[string]bool)
+	for _, id := range ids {
+		p.failing[id] = true
+	}
+}
+
+func newTestRelay(repo repository.OutboxRepository, publisher Publisher, clock *testClock, opts ...RelayOption) *Relay {
+	opts = append([]RelayOption{
+		WithNow(clock.Now),
+		WithBackoff(time.Second, 4*time.Second),
+		WithLogger(log.New(io.Discard, "", 0)),
+	}, opts...)
+	return NewRelay(repo, publisher, opts...)
+}
+
+func addMessages(t *testing.T, repo repository.OutboxRepository, at time.Time, msgs ...repository.OutboxMessage) {
+	t.Helper()
+	for _, msg := range msgs {
+		msg.Type, msg.Payload = "user.updated", []byte(`{}`)
+		msg.OccurredAt, msg.NextAttemptAt = at, at
+		if msg.AggregateType == "" {
+			msg.AggregateType = domain.AggregateUser
+		}
+		if err := repo.Add(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// unpublished devuelve los mensajes sin publicar, ignorando el backoff
+func unpublished(t *testing.T, repo repository.OutboxRepository) map[string]repository.OutboxMessage {
+	t.Helper()
+	pending, err := repo.Pending(context.Background(), relayBase.AddDate(1, 0, 0), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]repository.OutboxMessage)
+	for _, msg := range pending {
+		byID[msg.ID] = msg
+	}
+	return byID
+}
+
+func runOnce(t *testing.T, relay *Relay) {
+	t.Helper()
+	if _, err := relay.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayBackoffAndMarkFailed(t *testing.T) {
+	repo := infrastructure.NewMemoryOutboxRepository()
+	clock := &testClock{now: relayBase}
+	publisher := &flakyPublisher{}
+	publisher.setFailing("m1")
+	relay := newTestRelay(repo, publisher, clock)
+	addMessages(t, repo, relayBase, repository.OutboxMessage{ID: "m1", AggregateID: 1})
+
+	// 1s, 2s, 4s y después el tope de 4s
+	for i, wait := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		runOnce(t, relay)
+		if got := publisher.take(); got != "[m1]" {
+			t.Fatalf("attempt %d published %s", i+1, got)
+		}
+		msg := unpublished(t, repo)["m1"]
+		if msg.Attempts != i+1 || !msg.NextAttemptAt.Equal(clock.Now().Add(wait)) || msg.LastError != "broker down" {
+			t.Fatalf("after attempt %d: attempts=%d next=%s err=%q, want retry in %s",
+				i+1, msg.Attempts, msg.NextAttemptAt.Sub(clock.Now()), msg.LastError, wait)
+		}
+
+		// Antes de que venza el backoff no se reintenta
+		clock.advance(wait - time.Millisecond)
+		runOnce(t, relay)
+		if got := publisher.take(); got != "[]" {
+			t.Fatalf("retried %s before the backoff expired", got)
+		}
+		clock.advance(time.Millisecond)
+	}
+
+	publisher.setFailing()
+	runOnce(t, relay)
+	if got := publisher.take(); got != "[m1]" {
+		t.Errorf("final attempt published %s", got)
+	}
+	if len(unpublished(t, repo)) != 0 {
+		t.Error("message still pending after a successful publish")
+	}
+}
+
+func TestRelayKeepsOrderPerAggregate(t *testing.T) {
+	repo := infrastructure.NewMemoryOutboxRepository()
+	clock := &testClock{now: relayBase}
+	publisher := &flakyPublisher{}
+	relay := newTestRelay(repo, publisher, clock)
+	addMessages(t, repo, relayBase,
+		repository.OutboxMessage{ID: "user1-created", AggregateID: 1},
+		repository.OutboxMessage{ID: "user2-created", AggregateID: 2},
+		repository.OutboxMessage{ID: "user1-deleted", AggregateID: 1},
+		repository.OutboxMessage{ID: "product1-low", AggregateType: domain.AggregateProduct, AggregateID: 1},
+	)
+
+	// El fallo de user1-created frena solo al usuario 1, dentro del lote
+	// y en los lotes siguientes mientras dure el backoff
+	publisher.setFailing("user1-created")
+	runOnce(t, relay)
+	if got := publisher.take(); got != "[user1-created user2-created product1-low]" {
+		t.Errorf("first batch published %s", got)
+	}
+	runOnce(t, relay)
+	if got := publisher.take(); got != "[]" {
+		t.Errorf("second batch published %s during the backoff", got)
+	}
+
+	publisher.setFailing()
+	clock.advance(time.Second)
+	runOnce(t, relay)
+	if got := publisher.take(); got != "[user1-created user1-deleted]" {
+		t.Errorf("after the backoff published %s, want the user 1 messages in order", got)
+	}
+}
+
+// cleanupRecorder avisa cada DeletePublished del Relay
+type cleanupRecorder struct {
+	repository.OutboxRepository
+	cleanups chan time.Time
+}
+
+func (r cleanupRecorder) DeletePublished(ctx context.Context, before time.Time) (int, error) {
+	n, err := r.OutboxRepository.DeletePublished(ctx, before)
+	if n > 0 {
+		r.cleanups <- before
+	}
+	return n, err
+}
+
+func TestRelayDeletesPublishedAfterRetention(t *testing.T) {
+	memory := infrastructure.NewMemoryOutboxRepository()
+	repo := cleanupRecorder{OutboxRepository: memory, cleanups: make(chan time.Time, 10)}
+	clock := &testClock{now: relayBase}
+	relay := newTestRelay(repo, &flakyPublisher{}, clock,
+		WithPollInterval(time.Millisecond), WithRetention(24*time.Hour))
+	addMessages(t, repo, relayBase, repository.OutboxMessage{ID: "old", AggregateID: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor(t, "old published", func() bool { return len(unpublished(t, repo)) == 0 })
+	addMessages(t, repo, relayBase, repository.OutboxMessage{ID: "recent", AggregateID: 2})
+	clock.advance(25 * time.Hour)
+
+	select {
+	case before := <-repo.cleanups:
+		if want := relayBase.Add(time.Hour); !before.Equal(want) {
+			t.Errorf("cleanup before %s, want now - retention = %s", before, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no cleanup after the retention period")
+	}
+	// "recent" se publicó con el reloj adelantado: sigue guardado
+	waitFor(t, "recent published", func() bool { return len(unpublished(t, repo)) == 0 })
+	if n, _ := memory.DeletePublished(context.Background(), relayBase.AddDate(1, 0, 0)); n != 1 {
+		t.Errorf("%d messages left after cleanup, want only the recent one", n)
+	}
+}
+
+func TestMultiRepublishesToEveryDestination(t *testing.T) {
+	repo := infrastructure.NewMemoryOutboxRepository()
+	clock := &testClock{now: relayBase}
+	healthy, flaky := &flakyPublisher{}, &flakyPublisher{}
+	flaky.setFailing("m1")
+	relay := newTestRelay(repo, Multi(healthy, flaky), clock)
+	addMessages(t, repo, relayBase, repository.OutboxMessage{ID: "m1", AggregateID: 1})
+
+	runOnce(t, relay)
+	if msg := unpublished(t, repo)["m1"]; msg.Attempts != 1 {
+		t.Fatalf("attempts = %d, want the whole message to fail when one destination fails", msg.Attempts)
+	}
+
+	flaky.setFailing()
+	clock.advance(time.Second)
+	runOnce(t, relay)
+	// El destino sano lo recibe otra vez: por eso los consumidores deduplican
+	if got := healthy.take(); got != "[m1 m1]" {
+		t.Errorf("healthy destination received %s", got)
+	}
+	if got := flaky.take(); got != "[m1 m1]" {
+		t.Errorf("flaky destination received %s", got)
+	}
+	if len(unpublished(t, repo)) != 0 {
+		t.Error("message still pending after every destination succeeded")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := NewRelay(nil, nil, WithBackoff(time.Second, 10*time.Second))
+	for attempts, want := range []time.Duration{1, 2, 4, 8, 10, 10} {
+		if got := r.backoff(attempts); got != want*time.Second {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want*time.Second)
+		}
+	}
+	if got := r.backoff(1000); got != 10*time.Second {
+		t.Errorf("backoff(1000) = %s, want the cap", got)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+)
+
+// InventoryRepository guarda productos y reservas. No serializa nada: las
+// operaciones de varios pasos (leer stock, validar, escribir) las protege
+// InventoryUsecase con sus locks.
+type InventoryRepository interface {
+	// CreateProduct asigna el ID; falla con ErrProductAlreadyExists si el SKU existe
+	CreateProduct(ctx context.Context, p *domain.Product) error
+	GetProduct(ctx context.Context, sku string) (*domain.Product, error)
+	// ListProducts devuelve los productos ordenados por SKU
+	ListProducts(ctx context.Context) ([]*domain.Product, error)
+	UpdateProduct(ctx context.Context, p *domain.Product) error
+
+	// CreateReservation asigna el ID
+	CreateReservation(ctx context.Context, r *domain.Reservation) error
+	GetReservation(ctx context.Context, id int) (*domain.Reservation, error)
+	UpdateReservation(ctx context.Context, r *domain.Reservation) error
+	// ExpiredReservations devuelve hasta limit reservas activas con
+	// ExpiresAt <= now, las que vencieron primero al principio
+	ExpiredReservations(ctx context.Context, now time.Time, limit int) ([]*domain.Reservation, error)
+	// DeleteFinishedReservations borra las reservas cerradas antes de before
+	DeleteFinishedReservations(ctx context.Context, before time.Time) (int, error)
+}
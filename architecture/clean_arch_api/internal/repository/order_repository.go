@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+)
+
+// OrderRepository guarda los pedidos con su historial de saga
+type OrderRepository interface {
+	// Create asigna el ID
+	Create(ctx context.Context, o *domain.Order) error
+	GetByID(ctx context.Context, id int) (*domain.Order, error)
+	Update(ctx context.Context, o *domain.Order) error
+	// ListByCustomer devuelve los pedidos del cliente, el más reciente primero
+	ListByCustomer(ctx context.Context, customerID int) ([]*domain.Order, error)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// ============================================================================
+// OUTBOX
+// ============================================================================
+// Transactional outbox: el usecase guarda los eventos en la misma
+// transacción que el cambio; un relay los publica después. Así nunca se
+// publica un evento de un cambio que hizo rollback, ni se pierde el evento
+// de un cambio confirmado.
+// ============================================================================
+
+// OutboxMessage es un evento serializado pendiente de publicar
+type OutboxMessage struct {
+	// ID es único por evento: la entrega es at-least-once y los
+	// consumidores deduplican con él
+	ID            string
+	Type          string
+	AggregateType string // domain.AggregateUser, domain.AggregateProduct...
+	AggregateID   int
+	Payload       []byte // JSON del evento
+	OccurredAt    time.Time
+
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	PublishedAt   *time.Time
+}
+
+// AggregateKey identifica un agregado: el orden de publicación se respeta
+// por agregado, y un usuario y un producto con el mismo ID son distintos
+type AggregateKey struct {
+	Type string
+	ID   int
+}
+
+func (m OutboxMessage) Aggregate() AggregateKey {
+	return AggregateKey{Type: m.AggregateType, ID: m.AggregateID}
+}
+
+// OutboxRepository guarda y entrega los mensajes del outbox.
+// Add participa de la transacción del ctx, igual que UserRepository.
+type OutboxRepository interface {
+	Add(ctx context.Context, msgs ...OutboxMessage) error
+	// Pending devuelve hasta limit mensajes sin publicar con NextAttemptAt <= now,
+	// en orden de creación. Omite los de un agregado que tiene un mensaje
+	// anterior esperando reintento, para no publicar fuera de orden.
+	Pending(ctx context.Context, now time.Time, limit int) ([]OutboxMessage, error)
+	MarkPublished(ctx context.Context, id string, at time.Time) error
+	MarkFailed(ctx context.Context, id string, nextAttempt time.Time, lastErr string) error
+	// DeletePublished borra los mensajes publicados antes de before
+	DeletePublished(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,50 @@
+package repository
+
+import "context"
+
+// ============================================================================
+// UNIT OF WORK - Transacciones sin acoplar los usecases a la base de datos
+// ============================================================================
+// El usecase solo dice "esto es atómico":
+//
+//	txManager.WithTx(ctx, func(ctx context.Context) error {
+//		... llamadas a repositorios usando ESTE ctx ...
+//	})
+//
+// La transacción viaja dentro del context, así que los repositorios que
+// reciben ese ctx participan en ella sin cambiar sus firmas.
+// Similar a @Transactional en Spring, pero explícito.
+// ============================================================================
+
+// TxManager ejecuta fn dentro de una transacción.
+//   - Si fn devuelve error o hace panic, se hace rollback
+//   - Llamadas anidadas usan savepoints: un error interno solo deshace
+//     el trabajo del bloque interno si el llamador lo maneja
+//   - Las implementaciones pueden reintentar fn ante conflictos de
+//     serialización, así que fn no debe tener efectos fuera de la transacción
+//     (usar Tx.OnCommit para eso)
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Tx es la vista genérica de la transacción activa
+type Tx interface {
+	// OnCommit registra fn para ejecutarse después del commit de la
+	// transacción más externa (invalidar caches, publicar eventos, etc.).
+	// Si la transacción (o el savepoint donde se registró) hace rollback,
+	// fn no se ejecuta.
+	OnCommit(fn func())
+}
+
+type txKey struct{}
+
+// ContextWithTx guarda la transacción activa en el context
+func ContextWithTx(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext devuelve la transacción activa, si hay una
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(Tx)
+	return tx, ok
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+)
+
+// ============================================================================
+// LISTADO CON PAGINACIÓN POR CURSOR (KEYSET PAGINATION)
+// ============================================================================
+// OFFSET/LIMIT se degrada con tablas grandes y duplica o salta filas si
+// alguien inserta mientras el cliente pagina. Con keyset pagination el
+// cursor guarda la clave de orden del último elemento devuelto y la
+// siguiente página pide "los que vienen después de esa clave".
+//
+// El orden siempre es (campo, id): id desempata, así el orden es total y
+// estable aunque varios usuarios compartan nombre o created_at.
+// El cursor es opaco para el cliente (base64 de JSON).
+// ============================================================================
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	ErrInvalidCursor    = &domain.DomainError{Message: "invalid cursor"}
+	ErrInvalidListQuery = &domain.DomainError{Message: "invalid list query"}
+)
+
+// UserSortField son los campos por los que se puede ordenar
+type UserSortField string
+
+const (
+	SortByID        UserSortField = "id"
+	SortByEmail     UserSortField = "email"
+	SortByName      UserSortField = "name"
+	SortByCreatedAt UserSortField = "created_at"
+)
+
+// ListQuery describe una página del listado de usuarios
+type ListQuery struct {
+	// Filtros (vacío = sin filtro). Las comparaciones de texto ignoran
+	// mayúsculas solo en ASCII (ver FoldCase): "É" no coincide con "é".
+	EmailPrefix   string
+	NameContains  string
+	CreatedAfter  time.Time // Inclusivo
+	CreatedBefore time.Time // Exclusivo
+
+	IncludeDeleted bool
+
+	SortBy     UserSortField
+	Descending bool
+
+	Limit  int
+	Cursor string // NextCursor de la página anterior
+}
+
+// UserPage es el resultado de List
+type UserPage struct {
+	Users      []*domain.User
+	NextCursor string // Vacío si no hay más páginas
+}
+
+// Validate completa defaults y rechaza valores fuera de rango
+func (q *ListQuery) Validate() error {
+	if q.SortBy == "" {
+		q.SortBy = SortByID
+	}
+	switch q.SortBy {
+	case SortByID, SortByEmail, SortByName, SortByCreatedAt:
+	default:
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidListQuery, q.SortBy)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit < 1 || q.Limit > MaxPageSize {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, MaxPageSize)
+	}
+	if !q.CreatedAfter.IsZero() && !q.CreatedBefore.IsZero() && !q.CreatedAfter.Before(q.CreatedBefore) {
+		return fmt.Errorf("%w: created_after must be before created_before", ErrInvalidListQuery)
+	}
+	return nil
+}
+
+// Cursor es la posición decodificada: clave de orden e id del último elemento
+type Cursor struct {
+	SortBy     UserSortField `json:"s"`
+	Descending bool          `json:"d"`
+	Key        string        `json:"k"`
+	ID         int           `json:"i"`
+	Filters    string        `json:"f"` // Hash de los filtros de la primera página
+}
+
+// SortKey devuelve la clave de orden de un usuario como string comparable
+// en el mismo orden que la base de datos
+func SortKey(user *domain.User, field UserSortField) string {
+	switch field {
+	case SortByEmail:
+		return user.Email
+	case SortByName:
+		return user.Name
+	case SortByCreatedAt:
+		return user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return strconv.Itoa(user.ID)
+	}
+}
+
+// EncodeCursor genera el cursor que apunta después de last
+func EncodeCursor(q ListQuery, last *domain.User) string {
+	data, _ := json.Marshal(Cursor{
+		SortBy:     q.SortBy,
+		Descending: q.Descending,
+		Key:        SortKey(last, q.SortBy),
+		ID:         last.ID,
+		Filters:    q.filtersHash(),
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor valida que el cursor corresponda al mismo orden y filtros:
+// reutilizar un cursor con otra consulta daría páginas inconsistentes
+func DecodeCursor(q ListQuery) (*Cursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.SortBy != q.SortBy || c.Descending != q.Descending || c.Filters != q.filtersHash() {
+		return nil, fmt.Errorf("%w: cursor does not match sort or filters", ErrInvalidCursor)
+	}
+	if c.SortBy == SortByCreatedAt {
+		if _, err := time.Parse(time.RFC3339Nano, c.Key); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &c, nil
+}
+
+func (q ListQuery) filtersHash() string {
+	raw := strings.Join([]string{
+		FoldCase(q.EmailPrefix),
+		FoldCase(q.NameContains),
+		q.CreatedAfter.UTC().Format(time.RFC3339Nano),
+		q.CreatedBefore.UTC().Format(time.RFC3339Nano),
+		strconv.FormatBool(q.IncludeDeleted),
+	}, "\x00")
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:8])
+}
+
+// FoldCase pasa a minúsculas solo A-Z, igual que LOWER de SQLite (sin ICU).
+// Todos los repositorios comparan los filtros de texto con esta regla para
+// que el mismo filtro devuelva las mismas páginas en cualquier store.
+func FoldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, s)
+}
+
+// IsInvalidQuery indica si err proviene de parámetros inválidos del cliente
+func IsInvalidQuery(err error) bool {
+	return errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrInvalidListQuery)
+}
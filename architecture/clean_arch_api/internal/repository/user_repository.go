@@ -1,6 +1,10 @@
 package repository
 
-import "github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+import (
+	"context"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+)
 
 // ============================================================================
 // REPOSITORY LAYER - Interfaces para acceso a datos
@@ -9,12 +13,18 @@ import "github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/
 // Las implementaciones concretas están en infrastructure
 // ============================================================================
 
-// UserRepository define el contrato para acceso a usuarios
+// UserRepository define el contrato para acceso a usuarios.
+// El ctx lleva la cancelación y, si existe, la transacción activa (ver TxManager).
 type UserRepository interface {
-	Create(user *domain.User) error
-	GetByID(id int) (*domain.User, error)
-	GetByEmail(email string) (*domain.User, error)
-	Update(user *domain.User) error
-	Delete(id int) error
+	// Create asigna ID y Version inicial
+	Create(ctx context.Context, user *domain.User) error
+	GetByID(ctx context.Context, id int) (*domain.User, error)
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	// Update es compare-and-swap: solo escribe si user.Version coincide con la
+	// versión almacenada (si no, domain.ErrVersionConflict) y al terminar deja
+	// en user.Version la versión nueva
+	Update(ctx context.Context, user *domain.User) error
+	Delete(ctx context.Context, id int) error
+	// List devuelve una página filtrada y ordenada (ver ListQuery)
+	List(ctx context.Context, q ListQuery) (*UserPage, error)
 }
-
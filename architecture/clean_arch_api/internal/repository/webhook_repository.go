@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+)
+
+// WebhookRepository guarda las suscripciones y el log de entregas
+type WebhookRepository interface {
+	Create(ctx context.Context, sub *domain.WebhookSubscription) error
+	GetByID(ctx context.Context, id int) (*domain.WebhookSubscription, error)
+	List(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	// ListActive devuelve las suscripciones activas que quieren eventType
+	ListActive(ctx context.Context, eventType string) ([]*domain.WebhookSubscription, error)
+	Update(ctx context.Context, sub *domain.WebhookSubscription) error
+	Delete(ctx context.Context, id int) error
+
+	RecordDelivery(ctx context.Context, d *domain.WebhookDelivery) error
+	// Deliveries devuelve las últimas limit entregas, la más reciente primero
+	Deliveries(ctx context.Context, subscriptionID, limit int) ([]*domain.WebhookDelivery, error)
+}
@@ -0,0 +1,23 @@
+package usecase
+
+import "time"
+
+// Clock abstrae la hora actual. En producción es SystemClock; en tests se
+// inyecta un reloj fijo para que CreatedAt/UpdatedAt sean predecibles.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock usa la hora del sistema, en UTC
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// ClockFunc adapta una función a Clock (como http.HandlerFunc)
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// WithOutbox hace que el usecase emita eventos de dominio (domain.UserCreated,
+// etc.) guardándolos en el outbox dentro de la misma transacción del cambio.
+// Sin outbox no se emiten eventos.
+func WithOutbox(outbox repository.OutboxRepository) Option {
+	return func(uc *UserUsecase) {
+		uc.outbox = outbox
+	}
+}
+
+// emit serializa el evento y lo agrega al outbox. Debe llamarse con el ctx
+// de la transacción: si la transacción hace rollback, el evento desaparece.
+func (uc *UserUsecase) emit(ctx context.Context, event domain.Event) error {
+	return emitEvent(ctx, uc.outbox, uc.clock, event)
+}
+
+// emitEvent es emit para cualquier usecase (outbox nil = no emitir)
+func emitEvent(ctx context.Context, outbox repository.OutboxRepository, clock Clock, event domain.Event) error {
+	if outbox == nil {
+		return nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := clock.Now()
+	return outbox.Add(ctx, repository.OutboxMessage{
+		ID:            newEventID(),
+		Type:          event.EventType(),
+		AggregateType: event.AggregateType(),
+		AggregateID:   event.AggregateID(),
+		Payload:       payload,
+		OccurredAt:    now,
+		NextAttemptAt: now,
+	})
+}
+
+// newEventID genera un UUID v4 (RFC 4122)
+func newEventID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // versión 4
+	b[8] = b[8]&0x3f | 0x80 // variante RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
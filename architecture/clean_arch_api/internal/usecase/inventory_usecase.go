@@ -0,0 +1,415 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+	"github.com/josediaz/go-mastery-lab/concurrency/stripelock"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// INVENTARIO
+// ============================================================================
+// Versión concurrente de fundamentals/collections.Inventory. Allí UpdateStock
+// lee, suma y escribe sin lock: dos requests a la vez pierden una
+// actualización. Aquí cada operación toma los locks de los productos que
+// toca, siempre en el mismo orden (concurrency/stripelock, igual que
+// finance/ledger), así dos reservas de A y B en orden distinto no hacen
+// deadlock.
+//
+// Reserve, Commit, Release y el expirer escriben productos y reserva en una
+// transacción (repository.TxManager): si fallan a mitad no queda stock
+// movido sin que la reserva cambie de estado. Los avisos de stock bajo de
+// Reserve se emiten recién después del commit.
+//
+// Las reservas vencidas las libera RunExpirer; Commit también detecta una
+// reserva vencida aunque el expirer todavía no haya pasado.
+// ============================================================================
+
+const (
+	defaultReservationTTL = 15 * time.Minute
+	expireBatchSize       = 100
+)
+
+type InventoryUsecase struct {
+	repo           repository.InventoryRepository
+	txManager      repository.TxManager
+	clock          Clock
+	outbox         repository.OutboxRepository // nil = no emitir InventoryLowStock
+	reservationTTL time.Duration
+	locks          stripelock.Set
+}
+
+// InventoryOption configura dependencias opcionales de InventoryUsecase
+type InventoryOption func(*InventoryUsecase)
+
+func WithInventoryClock(clock Clock) InventoryOption {
+	return func(uc *InventoryUsecase) {
+		uc.clock = clock
+	}
+}
+
+// WithInventoryOutbox emite domain.InventoryLowStock por el outbox (y de ahí
+// a los publishers y a las suscripciones de /webhooks)
+func WithInventoryOutbox(outbox repository.OutboxRepository) InventoryOption {
+	return func(uc *InventoryUsecase) {
+		uc.outbox = outbox
+	}
+}
+
+// WithReservationTTL cambia el TTL de las reservas que no piden uno (15m)
+func WithReservationTTL(ttl time.Duration) InventoryOption {
+	return func(uc *InventoryUsecase) {
+		uc.reservationTTL = ttl
+	}
+}
+
+func NewInventoryUsecase(repo repository.InventoryRepository, txManager repository.TxManager, opts ...InventoryOption) *InventoryUsecase {
+	uc := &InventoryUsecase{
+		repo:           repo,
+		txManager:      txManager,
+		clock:          SystemClock{},
+		reservationTTL: defaultReservationTTL,
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// CreateProduct registra un producto con stock inicial
+func (uc *InventoryUsecase) CreateProduct(ctx context.Context, sku, name string, price money.Money, stock, lowStockThreshold int) (*domain.Product, error) {
+	now := uc.clock.Now()
+	p := &domain.Product{
+		SKU:               sku,
+		Name:              name,
+		Price:             price,
+		OnHand:            stock,
+		LowStockThreshold: lowStockThreshold,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.CreateProduct(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (uc *InventoryUsecase) GetProduct(ctx context.Context, sku string) (*domain.Product, error) {
+	return uc.repo.GetProduct(ctx, sku)
+}
+
+func (uc *InventoryUsecase) ListProducts(ctx context.Context) ([]*domain.Product, error) {
+	return uc.repo.ListProducts(ctx)
+}
+
+// AdjustStock suma delta al stock físico (reposición o merma). No puede
+// dejar menos unidades que las reservadas.
+func (uc *InventoryUsecase) AdjustStock(ctx context.Context, sku string, delta int) (*domain.Product, error) {
+	if delta == 0 {
+		return nil, domain.ErrInvalidQuantity
+	}
+	unlock := uc.locks.Lock("product:" + sku)
+	defer unlock()
+
+	p, err := uc.repo.GetProduct(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+	if p.OnHand+delta < p.Reserved {
+		return nil, fmt.Errorf("%w: %s has %d on hand, %d reserved", domain.ErrInsufficientStock, sku, p.OnHand, p.Reserved)
+	}
+
+	wasLow := p.IsLowStock()
+	p.OnHand += delta
+	p.UpdatedAt = uc.clock.Now()
+	if err := uc.repo.UpdateProduct(ctx, p); err != nil {
+		return nil, err
+	}
+	uc.notifyLowStock(ctx, p, wasLow)
+	return p, nil
+}
+
+// Reserve aparta stock de todos los items por ttl (0 = el TTL por defecto).
+// Es todo o nada: si un producto no alcanza no se reserva ninguno.
+func (uc *InventoryUsecase) Reserve(ctx context.Context, items []domain.ReservationItem, ttl time.Duration) (*domain.Reservation, error) {
+	items, err := mergeItems(items)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = uc.reservationTTL
+	}
+
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = "product:" + item.SKU
+	}
+	unlock := uc.locks.Lock(keys...)
+	defer unlock()
+
+	// Los avisos de stock bajo salen después del commit: con sqlite el
+	// inventario usa una unit of work en memoria y el outbox SQL no vería
+	// su rollback
+	var res *domain.Reservation
+	var crossed []*domain.Product
+	err = uc.txManager.WithTx(ctx, func(ctx context.Context) error {
+		// Validar todo antes de escribir nada
+		products := make([]*domain.Product, len(items))
+		for i, item := range items {
+			p, err := uc.repo.GetProduct(ctx, item.SKU)
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, item.SKU)
+			}
+			if p.Available() < item.Quantity {
+				return fmt.Errorf("%w: %s (available %d, requested %d)", domain.ErrInsufficientStock, item.SKU, p.Available(), item.Quantity)
+			}
+			products[i] = p
+		}
+
+		now := uc.clock.Now()
+		for i, p := range products {
+			wasLow := p.IsLowStock()
+			p.Reserved += items[i].Quantity
+			p.UpdatedAt = now
+			if err := uc.repo.UpdateProduct(ctx, p); err != nil {
+				return err
+			}
+			if !wasLow && p.IsLowStock() {
+				crossed = append(crossed, p)
+			}
+		}
+
+		res = &domain.Reservation{
+			Items:     items,
+			Status:    domain.ReservationActive,
+			ExpiresAt: now.Add(ttl),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		return uc.repo.CreateReservation(ctx, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range crossed {
+		uc.notifyLowStock(ctx, p, false)
+	}
+	return res, nil
+}
+
+func (uc *InventoryUsecase) GetReservation(ctx context.Context, id int) (*domain.Reservation, error) {
+	return uc.repo.GetReservation(ctx, id)
+}
+
+// Commit confirma la reserva: las unidades salen del almacén. Confirmar una
+// reserva ya confirmada no hace nada (se puede reintentar).
+func (uc *InventoryUsecase) Commit(ctx context.Context, id int) (*domain.Reservation, error) {
+	res, unlock, err := uc.lockReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	now := uc.clock.Now()
+	switch {
+	case res.Status == domain.ReservationCommitted:
+		return res, nil
+	case res.ExpiredAt(now):
+		if err := uc.release(ctx, res, domain.ReservationExpired, now); err != nil {
+			return nil, err
+		}
+		return res, domain.ErrReservationExpired
+	case !res.Active():
+		return res, domain.ErrReservationNotActive
+	}
+
+	err = uc.txManager.WithTx(ctx, func(ctx context.Context) error {
+		for _, item := range res.Items {
+			p, err := uc.repo.GetProduct(ctx, item.SKU)
+			if err != nil {
+				return err
+			}
+			p.OnHand -= item.Quantity
+			p.Reserved -= item.Quantity
+			p.UpdatedAt = now
+			if err := uc.repo.UpdateProduct(ctx, p); err != nil {
+				return err
+			}
+		}
+		if err := res.Finish(domain.ReservationCommitted, now); err != nil {
+			return err
+		}
+		return uc.repo.UpdateReservation(ctx, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Release cancela la reserva y devuelve el stock. Liberar una reserva ya
+// liberada o vencida no hace nada; una confirmada no se puede liberar.
+func (uc *InventoryUsecase) Release(ctx context.Context, id int) (*domain.Reservation, error) {
+	res, unlock, err := uc.lockReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	switch res.Status {
+	case domain.ReservationReleased, domain.ReservationExpired:
+		return res, nil
+	case domain.ReservationCommitted:
+		return res, domain.ErrReservationNotActive
+	}
+	if err := uc.release(ctx, res, domain.ReservationReleased, uc.clock.Now()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ExpireReservations libera las reservas vencidas y devuelve cuántas liberó
+func (uc *InventoryUsecase) ExpireReservations(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		batch, err := uc.repo.ExpiredReservations(ctx, uc.clock.Now(), expireBatchSize)
+		if err != nil || len(batch) == 0 {
+			return expired, err
+		}
+		for _, candidate := range batch {
+			res, unlock, err := uc.lockReservation(ctx, candidate.ID)
+			if err != nil {
+				return expired, err
+			}
+			// Pudo confirmarse o liberarse entre la consulta y el lock
+			now := uc.clock.Now()
+			if res.ExpiredAt(now) {
+				err = uc.release(ctx, res, domain.ReservationExpired, now)
+				if err == nil {
+					expired++
+				}
+			}
+			unlock()
+			if err != nil {
+				return expired, err
+			}
+		}
+	}
+}
+
+// RunExpirer llama a ExpireReservations cada interval hasta que se cancele
+// ctx, y borra las reservas cerradas hace más de retention (0 = nunca)
+func (uc *InventoryUsecase) RunExpirer(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := uc.ExpireReservations(ctx); err != nil {
+			log.Printf("inventory expirer: %v", err)
+		} else if n > 0 {
+			log.Printf("inventory expirer: released %d expired reservations", n)
+		}
+		if retention > 0 {
+			if _, err := uc.repo.DeleteFinishedReservations(ctx, uc.clock.Now().Add(-retention)); err != nil {
+				log.Printf("inventory expirer: cleanup: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// release devuelve el stock de una reserva activa (con sus locks tomados).
+// Productos y reserva van en una transacción: si falla a mitad, la reserva
+// sigue activa con todo su stock y se puede volver a liberar.
+func (uc *InventoryUsecase) release(ctx context.Context, res *domain.Reservation, status domain.ReservationStatus, now time.Time) error {
+	return uc.txManager.WithTx(ctx, func(ctx context.Context) error {
+		for _, item := range res.Items {
+			p, err := uc.repo.GetProduct(ctx, item.SKU)
+			if err != nil {
+				return err
+			}
+			p.Reserved -= item.Quantity
+			p.UpdatedAt = now
+			if err := uc.repo.UpdateProduct(ctx, p); err != nil {
+				return err
+			}
+		}
+		if err := res.Finish(status, now); err != nil {
+			return err
+		}
+		return uc.repo.UpdateReservation(ctx, res)
+	})
+}
+
+// notifyLowStock emite InventoryLowStock si p acaba de cruzar su umbral.
+// El evento es un aviso: si el outbox falla se registra y la operación sigue.
+func (uc *InventoryUsecase) notifyLowStock(ctx context.Context, p *domain.Product, wasLow bool) {
+	if wasLow || !p.IsLowStock() {
+		return
+	}
+	err := emitEvent(ctx, uc.outbox, uc.clock, domain.InventoryLowStock{
+		ProductID:  p.ID,
+		SKU:        p.SKU,
+		Available:  p.Available(),
+		Threshold:  p.LowStockThreshold,
+		OccurredAt: p.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("inventory: emit low stock for %s: %v", p.SKU, err)
+	}
+}
+
+// lockReservation toma los locks de la reserva y de sus productos y la
+// vuelve a leer ya con los locks (los items no cambian, el status sí)
+func (uc *InventoryUsecase) lockReservation(ctx context.Context, id int) (*domain.Reservation, func(), error) {
+	res, err := uc.repo.GetReservation(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := []string{fmt.Sprintf("reservation:%d", id)}
+	for _, item := range res.Items {
+		keys = append(keys, "product:"+item.SKU)
+	}
+	unlock := uc.locks.Lock(keys...)
+
+	if res, err = uc.repo.GetReservation(ctx, id); err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return res, unlock, nil
+}
+
+// mergeItems suma las líneas repetidas del mismo SKU y valida cantidades
+func mergeItems(items []domain.ReservationItem) ([]domain.ReservationItem, error) {
+	if len(items) == 0 {
+		return nil, domain.ErrInvalidQuantity
+	}
+	var merged []domain.ReservationItem
+	index := make(map[string]int)
+	for _, item := range items {
+		if item.SKU == "" || item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: %q x %d", domain.ErrInvalidQuantity, item.SKU, item.Quantity)
+		}
+		if i, ok := index[item.SKU]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.SKU] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged, nil
+}
@@ -0,0 +1,347 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+type inventoryFixture struct {
+	uc     *InventoryUsecase
+	outbox *infrastructure.MemoryOutboxRepository
+	now    time.Time
+	mu     sync.Mutex
+}
+
+func (f *inventoryFixture) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// clock es el reloj de los tests (lo mueve advance)
+func (f *inventoryFixture) clock() Clock {
+	return ClockFunc(func() time.Time {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.now
+	})
+}
+
+func newInventoryFixture(t *testing.T, stock map[string]int) *inventoryFixture {
+	t.Helper()
+	f := &inventoryFixture{outbox: infrastructure.NewMemoryOutboxRepository(), now: time.Unix(0, 0).UTC()}
+	f.uc = NewInventoryUsecase(infrastructure.NewMemoryInventoryRepository(), infrastructure.NewMemoryTxManager(),
+		WithInventoryOutbox(f.outbox),
+		WithReservationTTL(time.Minute),
+		WithInventoryClock(f.clock()))
+	for sku, n := range stock {
+		if _, err := f.uc.CreateProduct(context.Background(), sku, sku, money.MustParse("1.00", "USD"), n, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func (f *inventoryFixture) product(t *testing.T, sku string) *domain.Product {
+	t.Helper()
+	p, err := f.uc.GetProduct(context.Background(), sku)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReserveIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	f := newInventoryFixture(t, map[string]int{"A": 10, "B": 1})
+
+	_, err := f.uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 5}, {SKU: "B", Quantity: 2}}, 0)
+	if !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Fatalf("Reserve = %v, want ErrInsufficientStock", err)
+	}
+	if p := f.product(t, "A"); p.Reserved != 0 {
+		t.Errorf("A reserved = %d after failed batch, want 0", p.Reserved)
+	}
+
+	// Líneas repetidas del mismo SKU se suman
+	res, err := f.uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 3}, {SKU: "A", Quantity: 4}}, 0)
+	if err != nil || len(res.Items) != 1 || res.Items[0].Quantity != 7 {
+		t.Fatalf("Reserve = %+v, %v; want one line of 7", res, err)
+	}
+}
+
+func TestCommitAndRelease(t *testing.T) {
+	ctx := context.Background()
+	f := newInventoryFixture(t, map[string]int{"A": 10})
+
+	committed, _ := f.uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 3}}, 0)
+	released, _ := f.uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 2}}, 0)
+	if _, err := f.uc.Commit(ctx, committed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.uc.Release(ctx, released.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Reintentos: no cambian nada
+	f.uc.Commit(ctx, committed.ID)
+	f.uc.Release(ctx, released.ID)
+
+	if p := f.product(t, "A"); p.OnHand != 7 || p.Reserved != 0 {
+		t.Errorf("A = %d on hand, %d reserved; want 7, 0", p.OnHand, p.Reserved)
+	}
+	if _, err := f.uc.Release(ctx, committed.ID); !errors.Is(err, domain.ErrReservationNotActive) {
+		t.Errorf("Release(committed) = %v, want ErrReservationNotActive", err)
+	}
+}
+
+func TestExpiredReservationsAreReleased(t *testing.T) {
+	ctx := context.Background()
+	f := newInventoryFixture(t, map[string]int{"A": 10})
+
+	first, _ := f.uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 4}}, 0)
+	second, _ := f.uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 4}}, time.Hour)
+	f.advance(time.Minute)
+
+	n, err := f.uc.ExpireReservations(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("ExpireReservations = %d, %v; want 1", n, err)
+	}
+	if p := f.product(t, "A"); p.Reserved != 4 {
+		t.Errorf("A reserved = %d, want 4 (only the long reservation)", p.Reserved)
+	}
+	if _, err := f.uc.Commit(ctx, first.ID); !errors.Is(err, domain.ErrReservationNotActive) {
+		t.Errorf("Commit(expired) = %v, want ErrReservationNotActive", err)
+	}
+
+	// Commit detecta el vencimiento aunque el expirer no haya pasado
+	f.advance(time.Hour)
+	if _, err := f.uc.Commit(ctx, second.ID); !errors.Is(err, domain.ErrReservationExpired) {
+		t.Errorf("Commit(second) = %v, want ErrReservationExpired", err)
+	}
+	if p := f.product(t, "A"); p.Reserved != 0 || p.OnHand != 10 {
+		t.Errorf("A = %d on hand, %d reserved; want 10, 0", p.OnHand, p.Reserved)
+	}
+}
+
+func TestLowStockIsEmittedOncePerCrossing(t *testing.T) {
+	ctx := context.Background()
+	f := newInventoryFixture(t, map[string]int{"A": 5}) // umbral 2
+
+	for i := 0; i < 4; i++ {
+		f.uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 1}}, 0)
+	}
+	f.uc.AdjustStock(ctx, "A", 10) // Sale del umbral
+	f.uc.AdjustStock(ctx, "A", -9) // Vuelve a cruzarlo
+
+	msgs, _ := f.outbox.Pending(ctx, f.now, 100)
+	if len(msgs) != 2 {
+		t.Fatalf("low stock events = %d, want 2", len(msgs))
+	}
+	for _, msg := range msgs {
+		if msg.Type != domain.EventInventoryLowStock {
+			t.Errorf("event type = %s", msg.Type)
+		}
+	}
+}
+
+// failingReservations falla al guardar la reserva, después de que Reserve
+// ya escribió los productos
+type failingReservations struct {
+	*infrastructure.MemoryInventoryRepository
+}
+
+func (r failingReservations) CreateReservation(ctx context.Context, res *domain.Reservation) error {
+	return errors.New("disk full")
+}
+
+// detachedOutbox guarda fuera de cualquier transacción en memoria, como el
+// outbox SQL cuando el inventario usa su propia unit of work
+type detachedOutbox struct {
+	*infrastructure.MemoryOutboxRepository
+}
+
+func (o detachedOutbox) Add(ctx context.Context, msgs ...repository.OutboxMessage) error {
+	return o.MemoryOutboxRepository.Add(context.Background(), msgs...)
+}
+
+func TestReserveRollsBackWhenReservationFails(t *testing.T) {
+	tests := []struct {
+		name   string
+		outbox func(*infrastructure.MemoryOutboxRepository) repository.OutboxRepository
+	}{
+		{"outbox in the transaction", func(o *infrastructure.MemoryOutboxRepository) repository.OutboxRepository { return o }},
+		{"outbox outside the transaction", func(o *infrastructure.MemoryOutboxRepository) repository.OutboxRepository { return detachedOutbox{o} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := infrastructure.NewMemoryInventoryRepository()
+			outbox := infrastructure.NewMemoryOutboxRepository()
+			uc := NewInventoryUsecase(failingReservations{repo}, infrastructure.NewMemoryTxManager(), WithInventoryOutbox(tt.outbox(outbox)))
+			for _, sku := range []string{"A", "B"} {
+				if _, err := uc.CreateProduct(ctx, sku, sku, money.MustParse("1.00", "USD"), 3, 2); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Las dos líneas cruzan el umbral: se escriben los productos y se emiten eventos
+			_, err := uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 2}, {SKU: "B", Quantity: 1}}, 0)
+			if err == nil {
+				t.Fatal("Reserve succeeded with a failing repository")
+			}
+			for _, sku := range []string{"A", "B"} {
+				if p, _ := uc.GetProduct(ctx, sku); p.Reserved != 0 {
+					t.Errorf("%s reserved = %d after the rollback, want 0", sku, p.Reserved)
+				}
+			}
+			if msgs, _ := outbox.Pending(ctx, time.Now(), 10); len(msgs) != 0 {
+				t.Errorf("low stock events from a rolled-back reservation: %d", len(msgs))
+			}
+		})
+	}
+}
+
+// failingReservationUpdates falla al actualizar la reserva mientras fail
+// sea true, después de que ya se escribieron los productos
+type failingReservationUpdates struct {
+	*infrastructure.MemoryInventoryRepository
+	fail *bool
+}
+
+func (r failingReservationUpdates) UpdateReservation(ctx context.Context, res *domain.Reservation) error {
+	if *r.fail {
+		return errors.New("disk full")
+	}
+	return r.MemoryInventoryRepository.UpdateReservation(ctx, res)
+}
+
+func TestFinishingReservationRollsBackWhenUpdateFails(t *testing.T) {
+	tests := []struct {
+		name    string
+		expired bool
+		run     func(uc *InventoryUsecase, id int) error
+	}{
+		{"commit", false, func(uc *InventoryUsecase, id int) error {
+			_, err := uc.Commit(context.Background(), id)
+			return err
+		}},
+		{"release", false, func(uc *InventoryUsecase, id int) error {
+			_, err := uc.Release(context.Background(), id)
+			return err
+		}},
+		{"commit after expiry", true, func(uc *InventoryUsecase, id int) error {
+			_, err := uc.Commit(context.Background(), id)
+			if errors.Is(err, domain.ErrReservationExpired) {
+				return nil
+			}
+			return err
+		}},
+		{"expirer", true, func(uc *InventoryUsecase, id int) error {
+			_, err := uc.ExpireReservations(context.Background())
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fail := false
+			now := time.Unix(0, 0).UTC()
+			uc := NewInventoryUsecase(
+				failingReservationUpdates{infrastructure.NewMemoryInventoryRepository(), &fail},
+				infrastructure.NewMemoryTxManager(),
+				WithInventoryClock(ClockFunc(func() time.Time { return now })))
+			for _, sku := range []string{"A", "B"} {
+				if _, err := uc.CreateProduct(ctx, sku, sku, money.MustParse("1.00", "USD"), 5, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			res, err := uc.Reserve(ctx, []domain.ReservationItem{{SKU: "A", Quantity: 2}, {SKU: "B", Quantity: 1}}, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.expired {
+				now = now.Add(2 * time.Minute)
+			}
+
+			fail = true
+			if err := tt.run(uc, res.ID); err == nil {
+				t.Fatal("succeeded with a failing repository")
+			}
+			want := map[string]int{"A": 2, "B": 1}
+			for sku, reserved := range want {
+				if p, _ := uc.GetProduct(ctx, sku); p.OnHand != 5 || p.Reserved != reserved {
+					t.Errorf("%s on hand %d reserved %d after the rollback, want 5 and %d", sku, p.OnHand, p.Reserved, reserved)
+				}
+			}
+			if got, _ := uc.GetReservation(ctx, res.ID); got.Status != domain.ReservationActive {
+				t.Errorf("reservation %s after the rollback, want active", got.Status)
+			}
+
+			// Reintentar no descuenta el stock dos veces
+			fail = false
+			if err := tt.run(uc, res.ID); err != nil {
+				t.Fatal(err)
+			}
+			for sku := range want {
+				if p, _ := uc.GetProduct(ctx, sku); p.Reserved != 0 {
+					t.Errorf("%s reserved = %d after the retry, want 0", sku, p.Reserved)
+				}
+			}
+		})
+	}
+}
+
+func TestConcurrentReservationsNeverOversell(t *testing.T) {
+	ctx := context.Background()
+	f := newInventoryFixture(t, map[string]int{"A": 50, "B": 50})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Mitad pide A+B y mitad B+A: con locks sin orden haría deadlock
+			items := []domain.ReservationItem{{SKU: "A", Quantity: 1}, {SKU: "B", Quantity: 1}}
+			if i%2 == 0 {
+				items[0], items[1] = items[1], items[0]
+			}
+			res, err := f.uc.Reserve(ctx, items, 0)
+			if errors.Is(err, domain.ErrInsufficientStock) {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			reserved++
+			mu.Unlock()
+			if i%3 == 0 {
+				f.uc.Release(ctx, res.ID)
+			} else {
+				f.uc.Commit(ctx, res.ID)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, sku := range []string{"A", "B"} {
+		p := f.product(t, sku)
+		if p.OnHand < 0 || p.Reserved != 0 || p.Available() < 0 {
+			t.Errorf("%s = %d on hand, %d reserved", sku, p.OnHand, p.Reserved)
+		}
+	}
+	if reserved < 50 {
+		t.Errorf("only %d reservations succeeded, want at least the initial stock", reserved)
+	}
+}
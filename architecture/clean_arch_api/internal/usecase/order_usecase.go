@@ -0,0 +1,344 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// PEDIDOS - saga reservar stock -> cobrar -> confirmar
+// ============================================================================
+// OrderUsecase no conoce UserUsecase, InventoryUsecase ni finance/payments:
+// depende de los puertos de abajo (como UserUsecase depende de
+// UserRepository). main.go conecta los adaptadores reales y los tests
+// pueden usar dobles.
+//
+// La saga corre dentro del request y guarda el pedido después de cada paso.
+// Un cobro que falla sin un rechazo (timeout, proveedor caído) pudo haberse
+// hecho: la compensación lo reintenta con la misma key para saber si se
+// cobró y, si se cobró, lo reembolsa.
+//
+// Si el proceso se cae a mitad de camino:
+//   - la reserva vence sola por su TTL (RunExpirer la libera)
+//   - un cobro que quedó pending no avanza si nadie reintenta su key
+//
+// Similar a una saga orquestada con Axon o Eventuate en Java, sin el
+// framework: la lista de pasos y sus compensaciones está en PlaceOrder.
+// ============================================================================
+
+// Customers verifica que el cliente exista (UserUsecase lo implementa)
+type Customers interface {
+	GetUser(ctx context.Context, id int) (*domain.User, error)
+}
+
+// Stock reserva y descuenta stock (InventoryUsecase lo implementa)
+type Stock interface {
+	GetProduct(ctx context.Context, sku string) (*domain.Product, error)
+	Reserve(ctx context.Context, items []domain.ReservationItem, ttl time.Duration) (*domain.Reservation, error)
+	Commit(ctx context.Context, id int) (*domain.Reservation, error)
+	Release(ctx context.Context, id int) (*domain.Reservation, error)
+}
+
+// PaymentGateway cobra y reembolsa. Las dos operaciones deben ser
+// idempotentes por key: la saga reintenta con las mismas keys. Un cobro
+// rechazado devuelve un error que envuelve domain.ErrPaymentDeclined.
+type PaymentGateway interface {
+	Charge(ctx context.Context, idempotencyKey string, amount money.Money, description string) (paymentID string, err error)
+	Refund(ctx context.Context, paymentID, idempotencyKey string, amount money.Money) error
+}
+
+// OrderItem es un producto pedido por el cliente (el precio lo pone el inventario)
+type OrderItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+type OrderUsecase struct {
+	repo      repository.OrderRepository
+	customers Customers
+	stock     Stock
+	payments  PaymentGateway
+	clock     Clock
+
+	// Reintentos del cobro para saber si un cobro fallido se hizo
+	chargeAttempts int
+	chargeBackoff  time.Duration
+}
+
+// OrderOption configura dependencias opcionales de OrderUsecase
+type OrderOption func(*OrderUsecase)
+
+func WithOrderClock(clock Clock) OrderOption {
+	return func(uc *OrderUsecase) {
+		uc.clock = clock
+	}
+}
+
+// WithChargeRetries define cuántas veces se reintenta un cobro que falló sin
+// un rechazo y cuánto se espera antes del primer reintento (se duplica en
+// cada uno). Por defecto 3 intentos desde 200ms.
+func WithChargeRetries(attempts int, backoff time.Duration) OrderOption {
+	return func(uc *OrderUsecase) {
+		uc.chargeAttempts = attempts
+		uc.chargeBackoff = backoff
+	}
+}
+
+func NewOrderUsecase(repo repository.OrderRepository, customers Customers, stock Stock, payments PaymentGateway, opts ...OrderOption) *OrderUsecase {
+	uc := &OrderUsecase{
+		repo:           repo,
+		customers:      customers,
+		stock:          stock,
+		payments:       payments,
+		clock:          SystemClock{},
+		chargeAttempts: 3,
+		chargeBackoff:  200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	uc.chargeAttempts = max(uc.chargeAttempts, 1)
+	return uc
+}
+
+// sagaStep es un paso de la saga con la acción que deshace su efecto
+// (compensate nil = no hay nada que deshacer). uncertain indica si la acción
+// pudo tener efecto aunque devolvió ese error: en ese caso también se
+// compensa el propio paso.
+type sagaStep struct {
+	name       string
+	action     func(ctx context.Context, o *domain.Order) error
+	compensate func(ctx context.Context, o *domain.Order) error
+	uncertain  func(err error) bool
+}
+
+// PlaceOrder crea el pedido y corre la saga. Si un paso falla compensa los
+// anteriores en orden inverso y devuelve el pedido en failed junto con el
+// error del paso (el pedido queda guardado para consultarlo después). Si el
+// pedido no se puede guardar después de un paso, ese paso también se
+// compensa, salvo confirm, que no tiene vuelta atrás.
+func (uc *OrderUsecase) PlaceOrder(ctx context.Context, customerID int, items []OrderItem) (*domain.Order, error) {
+	order, err := uc.newOrder(ctx, customerID, items)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.Create(ctx, order); err != nil {
+		return nil, err
+	}
+
+	steps := []sagaStep{
+		{domain.StepReserveStock, uc.reserveStock, uc.releaseStock, nil},
+		{domain.StepChargePayment, uc.chargePayment, uc.refundPayment, chargeUncertain},
+		{domain.StepConfirm, uc.confirm, nil, nil},
+	}
+
+	for i, step := range steps {
+		stepErr := step.action(ctx, order)
+		done := steps[:i]
+		if stepErr == nil {
+			order.RecordStep(step.name, domain.StepDone, nil, uc.clock.Now())
+			err := uc.repo.Update(ctx, order)
+			if err == nil {
+				continue
+			}
+			if step.compensate == nil {
+				// Paso sin compensación (confirm): el pedido ya no se puede
+				// deshacer aunque no se haya guardado
+				return order, fmt.Errorf("order %d: saving after %s: %w", order.ID, step.name, err)
+			}
+			// El paso tuvo efecto pero el pedido no lo registra: se deshace también
+			stepErr = fmt.Errorf("saving order: %w", err)
+			done = steps[:i+1]
+		} else {
+			order.RecordStep(step.name, domain.StepFailed, stepErr, uc.clock.Now())
+			if step.uncertain != nil && step.uncertain(stepErr) {
+				done = steps[:i+1]
+			}
+		}
+
+		// Las compensaciones y el registro del fallo corren aunque el cliente
+		// haya cortado el request
+		ctx := context.WithoutCancel(ctx)
+		uc.compensate(ctx, order, done)
+		order.FailureReason = stepErr.Error()
+		if err := order.Transition(domain.OrderFailed, uc.clock.Now()); err != nil {
+			return nil, err
+		}
+		if err := uc.repo.Update(ctx, order); err != nil {
+			return order, fmt.Errorf("order %d: %s: %w (saving the failure: %v)", order.ID, step.name, stepErr, err)
+		}
+		return order, fmt.Errorf("order %d: %s: %w", order.ID, step.name, stepErr)
+	}
+	return order, nil
+}
+
+// compensate deshace los pasos hechos, del último al primero. Una
+// compensación que falla queda en el historial (compensation_failed) para
+// revisarla a mano; las demás se intentan igual.
+func (uc *OrderUsecase) compensate(ctx context.Context, o *domain.Order, done []sagaStep) {
+	for i := len(done) - 1; i >= 0; i-- {
+		step := done[i]
+		if step.compensate == nil {
+			continue
+		}
+		if err := step.compensate(ctx, o); err != nil {
+			log.Printf("order %d: compensating %s: %v", o.ID, step.name, err)
+			o.RecordStep(step.name, domain.StepCompensationFailed, err, uc.clock.Now())
+			continue
+		}
+		o.RecordStep(step.name, domain.StepCompensated, nil, uc.clock.Now())
+	}
+}
+
+func (uc *OrderUsecase) reserveStock(ctx context.Context, o *domain.Order) error {
+	res, err := uc.stock.Reserve(ctx, o.ReservationItems(), 0)
+	if err != nil {
+		return err
+	}
+	o.ReservationID = res.ID
+	return o.Transition(domain.OrderStockReserved, uc.clock.Now())
+}
+
+// releaseStock libera la reserva. Release ya ignora una reserva vencida o
+// liberada (p. ej. Commit la liberó al detectar el vencimiento); si estaba
+// confirmada devuelve ErrReservationNotActive y la compensación queda para
+// revisar a mano.
+func (uc *OrderUsecase) releaseStock(ctx context.Context, o *domain.Order) error {
+	_, err := uc.stock.Release(ctx, o.ReservationID)
+	return err
+}
+
+func (uc *OrderUsecase) chargePayment(ctx context.Context, o *domain.Order) error {
+	paymentID, err := uc.payments.Charge(ctx, orderKey(o, "charge"), o.Total, "order "+strconv.Itoa(o.ID))
+	if err != nil {
+		return err
+	}
+	o.PaymentID = paymentID
+	return o.Transition(domain.OrderPaid, uc.clock.Now())
+}
+
+// chargeUncertain: solo un rechazo asegura que no se cobró
+func chargeUncertain(err error) bool {
+	return !errors.Is(err, domain.ErrPaymentDeclined)
+}
+
+func (uc *OrderUsecase) refundPayment(ctx context.Context, o *domain.Order) error {
+	if o.PaymentID == "" {
+		charged, err := uc.settleCharge(ctx, o)
+		if err != nil || !charged {
+			return err
+		}
+	}
+	return uc.payments.Refund(ctx, o.PaymentID, orderKey(o, "refund"), o.Total)
+}
+
+// settleCharge averigua si un cobro que falló sin rechazo se hizo,
+// reintentándolo con la misma key: el gateway devuelve el mismo pago o
+// termina el que quedó a medias. Si sigue fallando devuelve el error y la
+// compensación queda para revisar a mano.
+func (uc *OrderUsecase) settleCharge(ctx context.Context, o *domain.Order) (charged bool, err error) {
+	backoff := uc.chargeBackoff
+	for attempt := 0; attempt < uc.chargeAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+		}
+		var paymentID string
+		paymentID, err = uc.payments.Charge(ctx, orderKey(o, "charge"), o.Total, "order "+strconv.Itoa(o.ID))
+		switch {
+		case err == nil:
+			o.PaymentID = paymentID
+			return true, nil
+		case errors.Is(err, domain.ErrPaymentDeclined):
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("charge outcome unknown after %d attempts: %w", uc.chargeAttempts, err)
+}
+
+// confirm descuenta el stock reservado. Es el último paso: si la reserva
+// venció mientras se cobraba, falla y la saga reembolsa.
+func (uc *OrderUsecase) confirm(ctx context.Context, o *domain.Order) error {
+	if _, err := uc.stock.Commit(ctx, o.ReservationID); err != nil {
+		return err
+	}
+	return o.Transition(domain.OrderConfirmed, uc.clock.Now())
+}
+
+func (uc *OrderUsecase) GetOrder(ctx context.Context, id int) (*domain.Order, error) {
+	return uc.repo.GetByID(ctx, id)
+}
+
+// ListCustomerOrders devuelve los pedidos del cliente, el más reciente primero
+func (uc *OrderUsecase) ListCustomerOrders(ctx context.Context, customerID int) ([]*domain.Order, error) {
+	return uc.repo.ListByCustomer(ctx, customerID)
+}
+
+// newOrder valida el pedido y fija los precios con los del inventario
+func (uc *OrderUsecase) newOrder(ctx context.Context, customerID int, items []OrderItem) (*domain.Order, error) {
+	if customerID <= 0 || len(items) == 0 {
+		return nil, domain.ErrInvalidOrder
+	}
+	if _, err := uc.customers.GetUser(ctx, customerID); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, fmt.Errorf("%w: unknown customer %d", domain.ErrInvalidOrder, customerID)
+		}
+		return nil, err
+	}
+
+	lines := make([]domain.OrderLine, 0, len(items))
+	subtotals := make([]money.Money, 0, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: %s", domain.ErrInvalidQuantity, item.SKU)
+		}
+		p, err := uc.stock.GetProduct(ctx, item.SKU)
+		if err != nil {
+			if errors.Is(err, domain.ErrProductNotFound) {
+				return nil, fmt.Errorf("%w: unknown sku %q", domain.ErrInvalidOrder, item.SKU)
+			}
+			return nil, err
+		}
+		subtotal, err := p.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, domain.OrderLine{SKU: p.SKU, Quantity: item.Quantity, UnitPrice: p.Price})
+		subtotals = append(subtotals, subtotal)
+	}
+
+	total, err := money.Sum(lines[0].UnitPrice.Currency(), subtotals...)
+	if err != nil {
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			return nil, fmt.Errorf("%w: products priced in different currencies", domain.ErrInvalidOrder)
+		}
+		return nil, err
+	}
+
+	now := uc.clock.Now()
+	return &domain.Order{
+		CustomerID: customerID,
+		Lines:      lines,
+		Total:      total,
+		Status:     domain.OrderPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, nil
+}
+
+// orderKey es la idempotency key de una operación de pago del pedido
+func orderKey(o *domain.Order, op string) string {
+	return "order-" + strconv.Itoa(o.ID) + "-" + op
+}
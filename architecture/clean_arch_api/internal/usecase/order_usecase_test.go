@@ -0,0 +1,289 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+	"github.com/josediaz/go-mastery-lab/finance/payments"
+)
+
+// Tests de la saga con el inventario y finance/payments reales, en memoria.
+// Los productos A y B (5 unidades a 1.00) y el reloj son los de inventoryFixture.
+
+type orderFixture struct {
+	*inventoryFixture
+	orders   *OrderUsecase
+	repo     *failingOrders
+	provider *payments.FakeProvider
+	payments *payments.Service
+	// onCharge corre antes de cada cobro (para simular demoras)
+	onCharge func()
+	// lostCommit hace que Commit confirme la reserva pero devuelva un error
+	lostCommit bool
+}
+
+// lossyStock envuelve el inventario real para simular lostCommit
+type lossyStock struct {
+	*InventoryUsecase
+	f *orderFixture
+}
+
+func (s lossyStock) Commit(ctx context.Context, id int) (*domain.Reservation, error) {
+	res, err := s.InventoryUsecase.Commit(ctx, id)
+	if err == nil && s.f.lostCommit {
+		return nil, context.DeadlineExceeded
+	}
+	return res, err
+}
+
+// slowGateway envuelve el gateway real para correr onCharge antes de cobrar
+type slowGateway struct {
+	*infrastructure.PaymentGateway
+	f *orderFixture
+}
+
+func (g slowGateway) Charge(ctx context.Context, key string, amount money.Money, description string) (string, error) {
+	if g.f.onCharge != nil {
+		g.f.onCharge()
+	}
+	return g.PaymentGateway.Charge(ctx, key, amount, description)
+}
+
+var errDiskFull = errors.New("disk full")
+
+// failingOrders falla una vez el Update número failAt (0 = ninguno)
+type failingOrders struct {
+	*infrastructure.MemoryOrderRepository
+	updates int
+	failAt  int
+}
+
+func (r *failingOrders) Update(ctx context.Context, o *domain.Order) error {
+	r.updates++
+	if r.updates == r.failAt {
+		return errDiskFull
+	}
+	return r.MemoryOrderRepository.Update(ctx, o)
+}
+
+func newOrderFixture(t *testing.T) *orderFixture {
+	t.Helper()
+	f := &orderFixture{inventoryFixture: newInventoryFixture(t, map[string]int{"A": 5, "B": 5})}
+	users := NewUserUsecase(infrastructure.NewMemoryUserRepository(), infrastructure.NewMemoryTxManager())
+	if _, err := users.CreateUser(context.Background(), "ana@example.com", "Ana", "secret123"); err != nil {
+		t.Fatal(err)
+	}
+	f.provider = payments.NewFakeProvider("fake")
+	f.payments = payments.NewService(payments.NewMemoryRepository(), f.provider)
+	f.repo = &failingOrders{MemoryOrderRepository: infrastructure.NewMemoryOrderRepository()}
+	f.orders = NewOrderUsecase(f.repo, users, lossyStock{f.uc, f},
+		slowGateway{infrastructure.NewPaymentGateway(f.payments), f},
+		WithOrderClock(f.clock()), WithChargeRetries(3, time.Millisecond))
+	return f
+}
+
+func stepStatuses(o *domain.Order) string {
+	var out []string
+	for _, s := range o.Steps {
+		out = append(out, s.Name+":"+string(s.Status))
+	}
+	return strings.Join(out, " ")
+}
+
+func TestPlaceOrderConfirms(t *testing.T) {
+	f := newOrderFixture(t)
+
+	order, err := f.orders.PlaceOrder(context.Background(), 1, []OrderItem{{SKU: "A", Quantity: 2}, {SKU: "B", Quantity: 1}})
+	if err != nil || order.Status != domain.OrderConfirmed {
+		t.Fatalf("PlaceOrder = %v, %v; want confirmed", order, err)
+	}
+	if got := order.Total.Decimal(); got != "3.00" {
+		t.Errorf("total = %s, want 3.00", got)
+	}
+	if p := f.product(t, "A"); p.OnHand != 3 || p.Reserved != 0 {
+		t.Errorf("A = %d on hand, %d reserved; want 3, 0", p.OnHand, p.Reserved)
+	}
+	payment, err := f.payments.Get(context.Background(), order.PaymentID)
+	if err != nil || payment.Status != payments.StatusCaptured || payment.Amount != order.Total {
+		t.Errorf("payment = %+v, %v; want captured for the order total", payment, err)
+	}
+}
+
+func TestPlaceOrderFailures(t *testing.T) {
+	unavailable := payments.ErrProviderUnavailable
+	tests := []struct {
+		name  string
+		setup func(f *orderFixture)
+		items []OrderItem
+		err   error
+		steps string
+		// Estado final del pago ("" = no hubo cobro)
+		payment payments.Status
+		// Llamadas a Authorize del proveedor, contando los reintentos
+		authorizeCalls int
+		// Stock físico de A al final (0 = sin cambios)
+		onHand int
+	}{
+		{
+			name:           "no stock charges nothing",
+			items:          []OrderItem{{SKU: "A", Quantity: 1}, {SKU: "B", Quantity: 6}},
+			err:            domain.ErrInsufficientStock,
+			steps:          "reserve_stock:failed",
+			authorizeCalls: 0,
+		},
+		{
+			name:           "declined payment releases stock",
+			setup:          func(f *orderFixture) { f.provider.Decline(50) },
+			err:            domain.ErrPaymentDeclined,
+			steps:          "reserve_stock:done charge_payment:failed reserve_stock:compensated",
+			authorizeCalls: 1,
+		},
+		{
+			// El proveedor se cae en el cobro: el reintento con la misma key
+			// lo termina y después se reembolsa
+			name:           "transient charge failure is settled and refunded",
+			setup:          func(f *orderFixture) { f.provider.FailNext(unavailable) },
+			err:            unavailable,
+			steps:          "reserve_stock:done charge_payment:failed charge_payment:compensated reserve_stock:compensated",
+			payment:        payments.StatusRefunded,
+			authorizeCalls: 2,
+		},
+		{
+			// El cobro sigue sin resolverse: queda marcado y el stock se libera igual
+			name:           "unsettled charge needs manual review",
+			setup:          func(f *orderFixture) { f.provider.FailNext(unavailable, unavailable, unavailable, unavailable) },
+			err:            unavailable,
+			steps:          "reserve_stock:done charge_payment:failed charge_payment:compensation_failed reserve_stock:compensated",
+			authorizeCalls: 4,
+		},
+		{
+			// Commit se aplicó aunque falló: la reserva confirmada no se puede
+			// liberar y queda para revisar a mano
+			name:           "committed reservation cannot be released",
+			setup:          func(f *orderFixture) { f.lostCommit = true },
+			err:            context.DeadlineExceeded,
+			steps:          "reserve_stock:done charge_payment:done confirm:failed charge_payment:compensated reserve_stock:compensation_failed",
+			payment:        payments.StatusRefunded,
+			authorizeCalls: 1,
+			onHand:         3,
+		},
+		{
+			// El cobro tarda más que el TTL de la reserva: confirm falla
+			name:           "expired reservation refunds payment",
+			setup:          func(f *orderFixture) { f.onCharge = func() { f.advance(2 * time.Minute) } },
+			err:            domain.ErrReservationExpired,
+			steps:          "reserve_stock:done charge_payment:done confirm:failed charge_payment:compensated reserve_stock:compensated",
+			payment:        payments.StatusRefunded,
+			authorizeCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newOrderFixture(t)
+			if tt.setup != nil {
+				tt.setup(f)
+			}
+			items := tt.items
+			if items == nil {
+				items = []OrderItem{{SKU: "A", Quantity: 2}}
+			}
+
+			order, err := f.orders.PlaceOrder(ctx, 1, items)
+			if !errors.Is(err, tt.err) || order == nil || order.Status != domain.OrderFailed {
+				t.Fatalf("PlaceOrder = %v, %v; want failed with %v", order, err, tt.err)
+			}
+			if got := stepStatuses(order); got != tt.steps {
+				t.Errorf("steps = %s, want %s", got, tt.steps)
+			}
+			if tt.payment != "" {
+				payment, err := f.payments.Get(ctx, order.PaymentID)
+				if err != nil || payment.Status != tt.payment {
+					t.Errorf("payment = %+v, %v; want %s", payment, err, tt.payment)
+				}
+			} else if order.PaymentID != "" {
+				t.Errorf("payment %s recorded, want none", order.PaymentID)
+			}
+			if n := f.provider.Calls("Authorize"); n != tt.authorizeCalls {
+				t.Errorf("provider Authorize calls = %d, want %d", n, tt.authorizeCalls)
+			}
+			onHand := map[string]int{"A": 5, "B": 5}
+			if tt.onHand != 0 {
+				onHand["A"] = tt.onHand
+			}
+			for sku, want := range onHand {
+				if p := f.product(t, sku); p.OnHand != want || p.Reserved != 0 {
+					t.Errorf("%s = %d on hand, %d reserved; want %d, 0", sku, p.OnHand, p.Reserved, want)
+				}
+			}
+
+			// El pedido queda guardado con el motivo del fallo
+			stored, err := f.orders.GetOrder(ctx, order.ID)
+			if err != nil || stored.Status != domain.OrderFailed || stored.FailureReason == "" {
+				t.Errorf("stored order = %+v, %v; want failed with a reason", stored, err)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderCompensatesWhenSavingFails(t *testing.T) {
+	tests := []struct {
+		name    string
+		failAt  int // Update que falla: 1 después de reservar, 2 después de cobrar
+		steps   string
+		payment payments.Status
+	}{
+		{"after reserving", 1, "reserve_stock:done reserve_stock:compensated", ""},
+		{"after charging", 2, "reserve_stock:done charge_payment:done charge_payment:compensated reserve_stock:compensated", payments.StatusRefunded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newOrderFixture(t)
+			f.repo.failAt = tt.failAt
+
+			order, err := f.orders.PlaceOrder(ctx, 1, []OrderItem{{SKU: "A", Quantity: 2}})
+			if !errors.Is(err, errDiskFull) || order == nil || order.Status != domain.OrderFailed {
+				t.Fatalf("PlaceOrder = %v, %v; want failed with the save error", order, err)
+			}
+			if got := stepStatuses(order); got != tt.steps {
+				t.Errorf("steps = %s, want %s", got, tt.steps)
+			}
+			if p := f.product(t, "A"); p.OnHand != 5 || p.Reserved != 0 {
+				t.Errorf("A = %d on hand, %d reserved; want 5, 0", p.OnHand, p.Reserved)
+			}
+			if tt.payment != "" {
+				if payment, err := f.payments.Get(ctx, order.PaymentID); err != nil || payment.Status != tt.payment {
+					t.Errorf("payment = %+v, %v; want %s", payment, err, tt.payment)
+				}
+			}
+			stored, err := f.orders.GetOrder(ctx, order.ID)
+			if err != nil || stored.Status != domain.OrderFailed || stepStatuses(stored) != tt.steps {
+				t.Errorf("stored order = %+v, %v; want the failure record", stored, err)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderSaveFailureAfterConfirmKeepsOrder(t *testing.T) {
+	f := newOrderFixture(t)
+	f.repo.failAt = 3
+
+	// Confirm no se puede deshacer: el pedido sigue confirmado y cobrado
+	order, err := f.orders.PlaceOrder(context.Background(), 1, []OrderItem{{SKU: "A", Quantity: 2}})
+	if !errors.Is(err, errDiskFull) || order == nil || order.Status != domain.OrderConfirmed {
+		t.Fatalf("PlaceOrder = %v, %v; want confirmed with the save error", order, err)
+	}
+	if p := f.product(t, "A"); p.OnHand != 3 || p.Reserved != 0 {
+		t.Errorf("A = %d on hand, %d reserved; want 3, 0", p.OnHand, p.Reserved)
+	}
+	if payment, err := f.payments.Get(context.Background(), order.PaymentID); err != nil || payment.Status != payments.StatusCaptured {
+		t.Errorf("payment = %+v, %v; want captured", payment, err)
+	}
+}
@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// ============================================================================
+// IMPORTACIÓN Y EXPORTACIÓN MASIVA DE USUARIOS
+// ============================================================================
+// ImportUsers es un pipeline de tres etapas (ver concurrency/pipeline):
+//
+//	leer filas ──> validar ──> insertar por lotes
+//
+// Cada etapa es una goroutine unida a la siguiente por un channel, así que
+// el archivo nunca está entero en memoria: se lee mientras se inserta. Cada
+// lote va en una transacción (una sola ida al WAL o a SQLite por lote en
+// vez de una por fila). Las filas inválidas no cortan la importación: quedan
+// en el reporte con su número de línea.
+//
+// ExportUsers recorre el listado por cursor página a página, igual que un
+// cliente de GET /users.
+// ============================================================================
+
+const (
+	DefaultImportBatchSize = 500
+	// maxImportErrors limita el reporte; el resto solo se cuenta
+	maxImportErrors = 1000
+	exportPageSize  = repository.MaxPageSize
+)
+
+// ImportRow es una fila del archivo. Err != nil si no se pudo decodificar
+// (el lector sigue con la próxima fila).
+type ImportRow struct {
+	Line     int
+	Email    string
+	Name     string
+	Password string
+	Err      error
+}
+
+// UserRowReader lee filas de un formato concreto (CSV, NDJSON...). Next
+// devuelve io.EOF al terminar; cualquier otro error corta la importación.
+type UserRowReader interface {
+	Next() (ImportRow, error)
+}
+
+// ImportOptions configura ImportUsers
+type ImportOptions struct {
+	// DryRun valida todo (incluso emails ya registrados) sin escribir
+	DryRun    bool
+	BatchSize int // 0 = DefaultImportBatchSize
+	// Progress, si no es nil, recibe las filas procesadas después de cada lote
+	Progress func(rows int)
+}
+
+// RowError es una fila rechazada
+type RowError struct {
+	Line  int    `json:"line"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport es el resultado de ImportUsers
+type ImportReport struct {
+	DryRun bool `json:"dry_run"`
+	Rows   int  `json:"rows"`
+	// Imported son las filas insertadas (en dry run, las que se insertarían)
+	Imported        int        `json:"imported"`
+	Failed          int        `json:"failed"`
+	Errors          []RowError `json:"errors"`
+	ErrorsTruncated bool       `json:"errors_truncated,omitempty"`
+}
+
+func (r *ImportReport) reject(row ImportRow, err error) {
+	r.Failed++
+	if len(r.Errors) >= maxImportErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, RowError{Line: row.Line, Email: row.Email, Error: err.Error()})
+}
+
+// ImportUsers importa las filas de rows. Devuelve el reporte aunque haya
+// error (las filas de los lotes ya confirmados quedan importadas).
+func (uc *UserUsecase) ImportUsers(ctx context.Context, rows UserRowReader, opts ImportOptions) (*ImportReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultImportBatchSize
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Si la etapa final corta, las otras terminan
+
+	// Stage 1: leer
+	read := make(chan ImportRow, opts.BatchSize)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(read)
+		for {
+			row, err := rows.Next()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr <- err
+				}
+				return
+			}
+			select {
+			case read <- row:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Stage 2: validar
+	validated := make(chan ImportRow, opts.BatchSize)
+	go func() {
+		defer close(validated)
+		for row := range read {
+			if row.Err == nil {
+				row.Email = strings.TrimSpace(row.Email)
+				row.Name = strings.TrimSpace(row.Name)
+				user := domain.User{Email: row.Email, Name: row.Name}
+				row.Err = user.Validate()
+			}
+			select {
+			case validated <- row:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Stage 3: insertar por lotes
+	report := &ImportReport{DryRun: opts.DryRun, Errors: []RowError{}}
+	// Emails vistos en este archivo: dos filas con el mismo email se
+	// rechazan también en dry run, donde no se escribe nada
+	seen := make(map[string]bool)
+	batch := make([]ImportRow, 0, opts.BatchSize)
+	for row := range validated {
+		report.Rows++
+		if row.Err == nil && seen[row.Email] {
+			row.Err = fmt.Errorf("%w: duplicated in file", domain.ErrUserAlreadyExists)
+		}
+		if row.Err != nil {
+			report.reject(row, row.Err)
+			continue
+		}
+		seen[row.Email] = true
+		batch = append(batch, row)
+		if len(batch) == opts.BatchSize {
+			if err := uc.importBatch(ctx, batch, opts.DryRun, report); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+			opts.progress(report.Rows)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	select {
+	case err := <-readErr:
+		return report, err
+	default:
+	}
+	if len(batch) > 0 {
+		if err := uc.importBatch(ctx, batch, opts.DryRun, report); err != nil {
+			return report, err
+		}
+	}
+	opts.progress(report.Rows)
+	return report, nil
+}
+
+func (o ImportOptions) progress(rows int) {
+	if o.Progress != nil {
+		o.Progress(rows)
+	}
+}
+
+// importBatch inserta un lote en una transacción. Los emails ya registrados
+// se rechazan fila por fila; un error de infraestructura deshace el lote.
+// En dry run solo verifica los emails.
+func (uc *UserUsecase) importBatch(ctx context.Context, batch []ImportRow, dryRun bool, report *ImportReport) error {
+	if dryRun {
+		for _, row := range batch {
+			if existing, _ := uc.userRepo.GetByEmail(ctx, row.Email); existing != nil {
+				report.reject(row, domain.ErrUserAlreadyExists)
+				continue
+			}
+			report.Imported++
+		}
+		return nil
+	}
+
+	var created int
+	var rejected []ImportRow
+	err := uc.txManager.WithTx(ctx, func(ctx context.Context) error {
+		// WithTx puede reintentar fn: contar desde cero en cada intento
+		created, rejected = 0, rejected[:0]
+		now := uc.clock.Now()
+		for _, row := range batch {
+			if existing, _ := uc.userRepo.GetByEmail(ctx, row.Email); existing != nil {
+				rejected = append(rejected, row)
+				continue
+			}
+			user := &domain.User{Email: row.Email, Name: row.Name, Password: row.Password}
+			user.MarkCreated(now)
+			if err := uc.userRepo.Create(ctx, user); err != nil {
+				return fmt.Errorf("line %d: %w", row.Line, err)
+			}
+			err := uc.emit(ctx, domain.UserCreated{
+				UserID:    user.ID,
+				Email:     user.Email,
+				Name:      user.Name,
+				CreatedAt: user.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.Imported += created
+	for _, row := range rejected {
+		report.reject(row, domain.ErrUserAlreadyExists)
+	}
+	return nil
+}
+
+// ExportUsers llama a fn con cada usuario activo, en orden de ID, leyendo de
+// a una página por vez. Si fn devuelve error la exportación se corta.
+func (uc *UserUsecase) ExportUsers(ctx context.Context, fn func(*domain.User) error) error {
+	q := repository.ListQuery{SortBy: repository.SortByID, Limit: exportPageSize}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := uc.userRepo.List(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, user := range page.Users {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
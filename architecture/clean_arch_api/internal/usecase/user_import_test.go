@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// sliceRows es un UserRowReader sobre filas en memoria; err se devuelve
+// después de la última fila (nil = io.EOF)
+type sliceRows struct {
+	rows []ImportRow
+	err  error
+}
+
+func (s *sliceRows) Next() (ImportRow, error) {
+	if len(s.rows) == 0 {
+		if s.err != nil {
+			return ImportRow{}, s.err
+		}
+		return ImportRow{}, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+func newImportUsecase(t *testing.T) (*UserUsecase, repository.UserRepository) {
+	t.Helper()
+	repo := infrastructure.NewMemoryUserRepository()
+	uc := NewUserUsecase(repo, infrastructure.NewMemoryTxManager())
+	if _, err := uc.CreateUser(context.Background(), "taken@example.com", "Taken", ""); err != nil {
+		t.Fatal(err)
+	}
+	return uc, repo
+}
+
+func importRows(n int) []ImportRow {
+	rows := make([]ImportRow, n)
+	for i := range rows {
+		rows[i] = ImportRow{Line: i + 2, Email: fmt.Sprintf("user%d@example.com", i), Name: fmt.Sprintf("User %d", i)}
+	}
+	return rows
+}
+
+func TestImportUsersReportsRowErrors(t *testing.T) {
+	uc, _ := newImportUsecase(t)
+	rows := importRows(7)
+	rows[1].Name = ""                      // Inválida
+	rows[3].Email = "taken@example.com"    // Ya registrado
+	rows[5].Email = rows[0].Email          // Repetido en el archivo
+	rows[6].Err = errors.New("bad record") // No se pudo decodificar
+
+	report, err := uc.ImportUsers(context.Background(), &sliceRows{rows: rows}, ImportOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 7 || report.Imported != 3 || report.Failed != 4 {
+		t.Fatalf("report = %+v, want 7 rows, 3 imported, 4 failed", report)
+	}
+	lines := map[int]bool{}
+	for _, e := range report.Errors {
+		lines[e.Line] = true
+	}
+	for _, line := range []int{3, 5, 7, 8} {
+		if !lines[line] {
+			t.Errorf("line %d missing from errors %+v", line, report.Errors)
+		}
+	}
+
+	page, _ := uc.ListUsers(context.Background(), repository.ListQuery{})
+	if len(page.Users) != 4 {
+		t.Errorf("users = %d, want 4", len(page.Users))
+	}
+}
+
+func TestImportUsersDryRunWritesNothing(t *testing.T) {
+	uc, _ := newImportUsecase(t)
+	rows := importRows(5)
+	rows[2].Email = "taken@example.com"
+
+	report, err := uc.ImportUsers(context.Background(), &sliceRows{rows: rows}, ImportOptions{DryRun: true, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Imported != 4 || report.Failed != 1 {
+		t.Fatalf("report = %+v, want 4 importable, 1 failed", report)
+	}
+	page, _ := uc.ListUsers(context.Background(), repository.ListQuery{})
+	if len(page.Users) != 1 {
+		t.Errorf("users = %d after dry run, want 1", len(page.Users))
+	}
+}
+
+func TestImportUsersStopsOnReadError(t *testing.T) {
+	uc, _ := newImportUsecase(t)
+	readErr := errors.New("connection reset")
+
+	report, err := uc.ImportUsers(context.Background(), &sliceRows{rows: importRows(5), err: readErr}, ImportOptions{BatchSize: 2})
+	if !errors.Is(err, readErr) {
+		t.Fatalf("ImportUsers = %v, want the read error", err)
+	}
+	// Los lotes completos ya se confirmaron; el último incompleto no
+	if report.Imported != 4 {
+		t.Errorf("imported = %d, want 4", report.Imported)
+	}
+}
+
+func TestExportUsersWalksAllPages(t *testing.T) {
+	uc, repo := newImportUsecase(t)
+	if _, err := uc.ImportUsers(context.Background(), &sliceRows{rows: importRows(2*exportPageSize + 5)}, ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	deleted, _ := repo.GetByEmail(context.Background(), "user0@example.com")
+	if err := uc.DeleteUser(context.Background(), deleted.ID, deleted.Version); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	err := uc.ExportUsers(context.Background(), func(u *domain.User) error {
+		ids = append(ids, u.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2*exportPageSize + 5; len(ids) != want {
+		t.Fatalf("exported %d users, want %d (active only)", len(ids), want)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("ids not increasing at %d: %d after %d", i, ids[i], ids[i-1])
+		}
+	}
+}
@@ -14,13 +14,32 @@ import (
 // ============================================================================
 
 type UserUsecase struct {
-	userRepo repository.UserRepository
+	userRepo  repository.UserRepository
+	txManager repository.TxManager
+	clock     Clock
+	outbox    repository.OutboxRepository // nil = no emitir eventos
 }
 
-func NewUserUsecase(userRepo repository.UserRepository) *UserUsecase {
-	return &UserUsecase{
-		userRepo: userRepo,
+// Option configura dependencias opcionales del usecase
+type Option func(*UserUsecase)
+
+// WithClock reemplaza el reloj del sistema
+func WithClock(clock Clock) Option {
+	return func(uc *UserUsecase) {
+		uc.clock = clock
+	}
+}
+
+func NewUserUsecase(userRepo repository.UserRepository, txManager repository.TxManager, opts ...Option) *UserUsecase {
+	uc := &UserUsecase{
+		userRepo:  userRepo,
+		txManager: txManager,
+		clock:     SystemClock{},
 	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
 }
 
 // CreateUser crea un nuevo usuario
@@ -35,27 +54,138 @@ func (uc *UserUsecase) CreateUser(ctx context.Context, email, name, password str
 	if err := user.Validate(); err != nil {
 		return nil, err
 	}
+	user.MarkCreated(uc.clock.Now())
 
-	// Verificar si el usuario ya existe
-	existing, _ := uc.userRepo.GetByEmail(email)
-	if existing != nil {
-		return nil, domain.ErrUserNotFound // En producción, usaría otro error
-	}
-
-	// Crear usuario
-	if err := uc.userRepo.Create(user); err != nil {
+	// Verificar y crear en la misma transacción: sin ella, dos requests
+	// concurrentes con el mismo email pueden pasar ambas la verificación
+	err := uc.txManager.WithTx(ctx, func(ctx context.Context) error {
+		existing, _ := uc.userRepo.GetByEmail(ctx, email)
+		if existing != nil {
+			return domain.ErrUserAlreadyExists
+		}
+		if err := uc.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		return uc.emit(ctx, domain.UserCreated{
+			UserID:    user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			CreatedAt: user.CreatedAt,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-// GetUser obtiene un usuario por ID
+// GetUser obtiene un usuario por ID (los eliminados lógicamente no existen)
 func (uc *UserUsecase) GetUser(ctx context.Context, id int) (*domain.User, error) {
-	user, err := uc.userRepo.GetByID(id)
+	user, err := uc.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if user.IsDeleted() {
+		return nil, domain.ErrUserNotFound
+	}
 	return user, nil
 }
 
+// ListUsers devuelve una página de usuarios (ver repository.ListQuery)
+func (uc *UserUsecase) ListUsers(ctx context.Context, q repository.ListQuery) (*repository.UserPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return uc.userRepo.List(ctx, q)
+}
+
+// AnyVersion desactiva la verificación de versión (no se envió If-Match).
+// El repositorio igual hace compare-and-swap contra la versión leída.
+const AnyVersion = 0
+
+// UserChanges son los campos modificables; nil = no cambiar
+type UserChanges struct {
+	Email *string
+	Name  *string
+}
+
+// UpdateUser aplica cambios parciales si la versión del cliente sigue siendo
+// la actual (domain.ErrVersionConflict si no)
+func (uc *UserUsecase) UpdateUser(ctx context.Context, id, expectedVersion int, changes UserChanges) (*domain.User, error) {
+	var updated *domain.User
+	err := uc.txManager.WithTx(ctx, func(ctx context.Context) error {
+		current, err := uc.loadForWrite(ctx, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		// Trabajar sobre una copia para no tocar lo que devolvió el repositorio
+		user := *current
+		if changes.Email != nil {
+			user.Email = *changes.Email
+		}
+		if changes.Name != nil {
+			user.Name = *changes.Name
+		}
+		if err := user.Validate(); err != nil {
+			return err
+		}
+		if user.Email != current.Email {
+			if existing, _ := uc.userRepo.GetByEmail(ctx, user.Email); existing != nil {
+				return domain.ErrUserAlreadyExists
+			}
+		}
+
+		user.MarkUpdated(uc.clock.Now())
+		if err := uc.userRepo.Update(ctx, &user); err != nil {
+			return err
+		}
+		updated = &user
+		return uc.emit(ctx, domain.UserUpdated{
+			UserID:    user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			Version:   user.Version,
+			UpdatedAt: user.UpdatedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteUser hace soft delete: el registro queda con DeletedAt para auditoría
+func (uc *UserUsecase) DeleteUser(ctx context.Context, id, expectedVersion int) error {
+	return uc.txManager.WithTx(ctx, func(ctx context.Context) error {
+		current, err := uc.loadForWrite(ctx, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+		user := *current
+		user.SoftDelete(uc.clock.Now())
+		if err := uc.userRepo.Update(ctx, &user); err != nil {
+			return err
+		}
+		return uc.emit(ctx, domain.UserDeleted{
+			UserID:    user.ID,
+			DeletedAt: *user.DeletedAt,
+		})
+	})
+}
+
+// loadForWrite lee un usuario activo y verifica la versión esperada
+func (uc *UserUsecase) loadForWrite(ctx context.Context, id, expectedVersion int) (*domain.User, error) {
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted() {
+		return nil, domain.ErrUserNotFound
+	}
+	if expectedVersion != AnyVersion && user.Version != expectedVersion {
+		return nil, domain.ErrVersionConflict
+	}
+	return user, nil
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// ============================================================================
+// WEBHOOKS
+// ============================================================================
+// Administración de suscripciones. La entrega de eventos la hace
+// webhook.Dispatcher, conectado al relay del outbox.
+// ============================================================================
+
+const defaultDeliveriesLimit = 50
+
+type WebhookUsecase struct {
+	repo      repository.WebhookRepository
+	clock     Clock
+	newSecret func() (string, error)
+}
+
+// NewWebhookUsecase recibe el generador de secretos (webhook.GenerateSecret)
+// para no depender del paquete de entrega
+func NewWebhookUsecase(repo repository.WebhookRepository, newSecret func() (string, error), clock Clock) *WebhookUsecase {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &WebhookUsecase{repo: repo, clock: clock, newSecret: newSecret}
+}
+
+// CreateSubscription registra un destino. Si secret es vacío se genera uno;
+// el llamador debe mostrarlo ahora porque después no se devuelve.
+func (uc *WebhookUsecase) CreateSubscription(ctx context.Context, url string, eventTypes []string, secret string) (*domain.WebhookSubscription, error) {
+	sub := &domain.WebhookSubscription{URL: url, EventTypes: eventTypes, Secret: secret}
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+	if sub.Secret == "" {
+		generated, err := uc.newSecret()
+		if err != nil {
+			return nil, err
+		}
+		sub.Secret = generated
+	}
+	sub.CreatedAt = uc.clock.Now()
+
+	if err := uc.repo.Create(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (uc *WebhookUsecase) GetSubscription(ctx context.Context, id int) (*domain.WebhookSubscription, error) {
+	return uc.repo.GetByID(ctx, id)
+}
+
+func (uc *WebhookUsecase) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	return uc.repo.List(ctx)
+}
+
+func (uc *WebhookUsecase) DeleteSubscription(ctx context.Context, id int) error {
+	return uc.repo.Delete(ctx, id)
+}
+
+// EnableSubscription reactiva una suscripción desactivada por fallos
+func (uc *WebhookUsecase) EnableSubscription(ctx context.Context, id int) (*domain.WebhookSubscription, error) {
+	sub, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Enable()
+	if err := uc.repo.Update(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Deliveries devuelve el log de entregas, la más reciente primero
+func (uc *WebhookUsecase) Deliveries(ctx context.Context, id, limit int) ([]*domain.WebhookDelivery, error) {
+	if _, err := uc.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultDeliveriesLimit
+	}
+	return uc.repo.Deliveries(ctx, id, limit)
+}
@@ -0,0 +1,382 @@
+// Package webhook entrega eventos de dominio a las suscripciones de webhook:
+// firma HMAC, pool de workers, reintentos con backoff, log de entregas y
+// desactivación automática de destinos que fallan siempre.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/outbox"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// ============================================================================
+// DISPATCHER
+// ============================================================================
+// Implementa outbox.Publisher: el relay del outbox le pasa cada evento y el
+// Dispatcher encola una entrega por cada suscripción activa que lo quiere.
+//
+//	relay -> Publish -> queue -> N workers -> POST firmado -> log de entregas
+//	                      ^                         |
+//	                      +---- reintento (timer) <-+ si falla
+//
+// - Un worker pool (como en concurrency/worker_pool) limita las conexiones
+//   simultáneas; un receptor lento no frena a los demás más allá de N
+// - Reintentos con backoff exponencial (patterns/retry_backoff), respetando
+//   Retry-After si el receptor lo envía. Se programan con un timer para no
+//   dejar un worker dormido
+// - Una entrega que agota sus intentos suma un fallo a la suscripción; con
+//   MaxConsecutiveFailures fallos seguidos la suscripción se desactiva
+//
+// Los reintentos viven en memoria: si el proceso se detiene, los que estaban
+// esperando se pierden (el evento ya salió del outbox).
+// ============================================================================
+
+var ErrClosed = errors.New("webhook: dispatcher is closed")
+
+type job struct {
+	subscriptionID int
+	eventID        string
+	eventType      string
+	body           []byte
+	attempt        int // 1 = primer intento
+}
+
+type Dispatcher struct {
+	repo repository.WebhookRepository
+
+	workers                int
+	queueSize              int
+	maxAttempts            int
+	baseBackoff            time.Duration
+	maxBackoff             time.Duration
+	maxConsecutiveFailures int
+	client                 *http.Client
+	now                    func() time.Time
+	logger                 *log.Logger
+
+	queue chan job
+	quit  chan struct{}
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	retries map[*time.Timer]struct{}
+	firing  sync.WaitGroup // reintentos que ya salieron del timer y se están encolando
+	stateMu sync.Mutex     // serializa lectura-modificación de contadores de fallos
+}
+
+type Option func(*Dispatcher)
+
+func WithWorkers(n int) Option {
+	return func(d *Dispatcher) {
+		d.workers = n
+	}
+}
+
+func WithQueueSize(n int) Option {
+	return func(d *Dispatcher) {
+		d.queueSize = n
+	}
+}
+
+// WithMaxAttempts define cuántos intentos tiene cada entrega (incluye el primero)
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = n
+	}
+}
+
+func WithBackoff(base, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.baseBackoff = base
+		d.maxBackoff = max
+	}
+}
+
+// WithMaxConsecutiveFailures define tras cuántas entregas fallidas seguidas se
+// desactiva una suscripción (0 = nunca)
+func WithMaxConsecutiveFailures(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxConsecutiveFailures = n
+	}
+}
+
+func WithHTTPClient(c *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = c
+	}
+}
+
+func WithLogger(l *log.Logger) Option {
+	return func(d *Dispatcher) {
+		d.logger = l
+	}
+}
+
+// NewDispatcher crea el dispatcher y arranca sus workers
+func NewDispatcher(repo repository.WebhookRepository, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		repo:                   repo,
+		workers:                4,
+		queueSize:              256,
+		maxAttempts:            5,
+		baseBackoff:            time.Second,
+		maxBackoff:             10 * time.Minute,
+		maxConsecutiveFailures: 5,
+		client:                 &http.Client{Timeout: 10 * time.Second},
+		now:                    func() time.Time { return time.Now().UTC() },
+		logger:                 log.Default(),
+		quit:                   make(chan struct{}),
+		retries:                make(map[*time.Timer]struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.queue = make(chan job, d.queueSize)
+
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	return d
+}
+
+// Publish encola una entrega por cada suscripción activa interesada en el
+// evento. Bloquea si la cola está llena (backpressure hacia el relay).
+func (d *Dispatcher) Publish(ctx context.Context, msg repository.OutboxMessage) error {
+	subs, err := d.repo.ListActive(ctx, msg.Type)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(outbox.NewEnvelope(msg))
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		j := job{subscriptionID: sub.ID, eventID: msg.ID, eventType: msg.Type, body: body, attempt: 1}
+		if err := d.enqueue(ctx, j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, j job) error {
+	select {
+	case <-d.quit:
+		return ErrClosed
+	default:
+	}
+	select {
+	case d.queue <- j:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.quit:
+		return ErrClosed
+	}
+}
+
+// Close deja de aceptar eventos, espera a que los workers vacíen la cola y
+// descarta los reintentos programados. Un reintento cuyo timer ya disparó
+// se encola y se entrega antes de que los workers terminen; ninguno se
+// pierde sin quedar en el log como descartado.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for t := range d.retries {
+		t.Stop()
+	}
+	dropped := len(d.retries)
+	d.retries = nil
+	d.mu.Unlock()
+
+	// quit sigue abierto: los workers consumen y estos enqueue no se traban
+	d.firing.Wait()
+	close(d.quit)
+	d.wg.Wait()
+	if dropped > 0 {
+		d.logger.Printf("webhook: dropped %d scheduled retries on close", dropped)
+	}
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case j := <-d.queue:
+			d.deliver(j)
+		case <-d.quit:
+			// Vaciar lo que ya estaba encolado antes de salir
+			for {
+				select {
+				case j := <-d.queue:
+					d.deliver(j)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver hace un intento y decide: éxito, reintento o fallo definitivo
+func (d *Dispatcher) deliver(j job) {
+	ctx := context.Background()
+
+	// La suscripción pudo borrarse o desactivarse mientras esperaba
+	sub, err := d.repo.GetByID(ctx, j.subscriptionID)
+	if err != nil || !sub.Active() {
+		return
+	}
+
+	delivery, retryAfter := d.send(ctx, sub, j)
+	if err := d.repo.RecordDelivery(ctx, delivery); err != nil {
+		d.logger.Printf("webhook: recording delivery: %v", err)
+	}
+
+	if delivery.Succeeded() {
+		d.updateSubscription(ctx, sub.ID, true)
+		return
+	}
+	if j.attempt < d.maxAttempts {
+		j.attempt++
+		d.scheduleRetry(j, d.backoff(j.attempt-1, retryAfter))
+		return
+	}
+	d.updateSubscription(ctx, sub.ID, false)
+}
+
+// send hace el POST y devuelve el registro de la entrega y el Retry-After
+// del receptor (0 si no lo envió)
+func (d *Dispatcher) send(ctx context.Context, sub *domain.WebhookSubscription, j job) (*domain.WebhookDelivery, time.Duration) {
+	start := d.now()
+	delivery := &domain.WebhookDelivery{
+		SubscriptionID: sub.ID,
+		EventID:        j.eventID,
+		EventType:      j.eventType,
+		Attempt:        j.attempt,
+		AttemptedAt:    start,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(j.body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "clean-arch-api-webhooks/1")
+	req.Header.Set("X-Event-ID", j.eventID)
+	req.Header.Set("X-Event-Type", j.eventType)
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(j.attempt))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, start, j.body))
+
+	resp, err := d.client.Do(req)
+	delivery.Duration = d.now().Sub(start)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, 0
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	delivery.StatusCode = resp.StatusCode
+	if !delivery.Succeeded() {
+		delivery.Error = fmt.Sprintf("unexpected status %s", resp.Status)
+	}
+	var retryAfter time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+	return delivery, retryAfter
+}
+
+// scheduleRetry programa j para dentro de delay. La decisión de encolar se
+// toma bajo d.mu: o el timer se registra en firing antes de que Close lo vea
+// (y Close espera a que se encole), o Close ya lo contó como descartado.
+func (d *Dispatcher) scheduleRetry(j job, delay time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		// Una entrega que falló mientras Close vaciaba la cola
+		d.logger.Printf("webhook: retry of event %s for subscription %d dropped: %v", j.eventID, j.subscriptionID, ErrClosed)
+		return
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		if d.closed {
+			d.mu.Unlock()
+			return // Estaba en retries: Close lo contó entre los descartados
+		}
+		delete(d.retries, t)
+		d.firing.Add(1)
+		d.mu.Unlock()
+		defer d.firing.Done()
+
+		if err := d.enqueue(context.Background(), j); err != nil {
+			d.logger.Printf("webhook: retry of event %s for subscription %d dropped: %v", j.eventID, j.subscriptionID, err)
+		}
+	})
+	d.retries[t] = struct{}{}
+}
+
+// updateSubscription registra el resultado final de una entrega en los
+// contadores de la suscripción (y la desactiva si corresponde)
+func (d *Dispatcher) updateSubscription(ctx context.Context, id int, success bool) {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+
+	sub, err := d.repo.GetByID(ctx, id)
+	if err != nil {
+		return
+	}
+	if success {
+		if sub.ConsecutiveFailures == 0 {
+			return
+		}
+		sub.RecordSuccess()
+	} else if sub.RecordFailure(d.now(), d.maxConsecutiveFailures) {
+		d.logger.Printf("webhook: subscription %d disabled after %d consecutive failed deliveries", sub.ID, sub.ConsecutiveFailures)
+	}
+	if err := d.repo.Update(ctx, sub); err != nil {
+		d.logger.Printf("webhook: updating subscription %d: %v", id, err)
+	}
+}
+
+// backoff = base * 2^(attempt-1), con tope en maxBackoff. Si el receptor pidió
+// Retry-After se usa ese valor (también con tope).
+func (d *Dispatcher) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay == 0 {
+		delay = d.baseBackoff
+		for i := 1; i < attempt && delay < d.maxBackoff; i++ {
+			delay *= 2
+		}
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,331 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/domain"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/infrastructure"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/outbox"
+	"github.com/josediaz/go-mastery-lab/architecture/clean_arch_api/internal/repository"
+)
+
+// ============================================================================
+// TESTS DEL DISPATCHER CONTRA UN RECEPTOR httptest
+// ============================================================================
+
+const testSecret = "whsec_test"
+
+func newTestDispatcher(t *testing.T, repo repository.WebhookRepository, opts ...Option) *Dispatcher {
+	t.Helper()
+	opts = append([]Option{
+		WithWorkers(2),
+		WithBackoff(time.Millisecond, 5*time.Millisecond),
+		WithLogger(log.New(io.Discard, "", 0)),
+	}, opts...)
+	d := NewDispatcher(repo, opts...)
+	t.Cleanup(d.Close)
+	return d
+}
+
+func newTestSubscription(t *testing.T, repo repository.WebhookRepository, url string, eventTypes ...string) *domain.WebhookSubscription {
+	t.Helper()
+	sub := &domain.WebhookSubscription{URL: url, EventTypes: eventTypes, Secret: testSecret, CreatedAt: time.Now()}
+	if err := repo.Create(context.Background(), sub); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return sub
+}
+
+func testMessage(id, eventType string) repository.OutboxMessage {
+	return repository.OutboxMessage{
+		ID:          id,
+		Type:        eventType,
+		AggregateID: 1,
+		Payload:     []byte(`{"user_id":1}`),
+		OccurredAt:  time.Now(),
+	}
+}
+
+// waitFor reintenta cond hasta que sea true o pase un segundo
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func deliveries(t *testing.T, repo repository.WebhookRepository, subID int) []*domain.WebhookDelivery {
+	t.Helper()
+	got, err := repo.Deliveries(context.Background(), subID, 100)
+	if err != nil {
+		t.Fatalf("Deliveries: %v", err)
+	}
+	return got
+}
+
+func TestDispatcherDeliversSignedEvents(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var mu sync.Mutex
+	var got []received
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, received{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	repo := infrastructure.NewMemoryWebhookRepository()
+	created := newTestSubscription(t, repo, receiver.URL, domain.EventUserCreated)
+	newTestSubscription(t, repo, receiver.URL+"/deleted-only", domain.EventUserDeleted)
+	d := newTestDispatcher(t, repo)
+
+	if err := d.Publish(context.Background(), testMessage("evt-1", domain.EventUserCreated)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, "delivery log", func() bool { return len(deliveries(t, repo, created.ID)) == 1 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Solo la suscripción a user.created recibe el evento
+	if len(got) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(got))
+	}
+	req := got[0]
+	if err := Verify(testSecret, req.header.Get(SignatureHeader), req.body, time.Now(), time.Minute); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := Verify("otro secreto", req.header.Get(SignatureHeader), req.body, time.Now(), time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify with wrong secret = %v, want ErrInvalidSignature", err)
+	}
+	if id := req.header.Get("X-Event-ID"); id != "evt-1" {
+		t.Errorf("X-Event-ID = %q, want evt-1", id)
+	}
+
+	var env outbox.Envelope
+	if err := json.Unmarshal(req.body, &env); err != nil {
+		t.Fatalf("body is not an envelope: %v", err)
+	}
+	if env.Type != domain.EventUserCreated || string(env.Data) != `{"user_id":1}` {
+		t.Errorf("envelope = %+v", env)
+	}
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	repo := infrastructure.NewMemoryWebhookRepository()
+	sub := newTestSubscription(t, repo, receiver.URL, "*")
+	d := newTestDispatcher(t, repo)
+
+	if err := d.Publish(context.Background(), testMessage("evt-1", domain.EventUserUpdated)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, "third attempt", func() bool { return len(deliveries(t, repo, sub.ID)) == 3 })
+
+	// El log queda con la más reciente primero: 3 (ok), 2 y 1 (503)
+	history := deliveries(t, repo, sub.ID)
+	for i, want := range []struct {
+		attempt int
+		ok      bool
+	}{{3, true}, {2, false}, {1, false}} {
+		if history[i].Attempt != want.attempt || history[i].Succeeded() != want.ok {
+			t.Errorf("delivery %d = attempt %d ok=%v, want attempt %d ok=%v",
+				i, history[i].Attempt, history[i].Succeeded(), want.attempt, want.ok)
+		}
+	}
+	if history[1].StatusCode != http.StatusServiceUnavailable || history[1].Error == "" {
+		t.Errorf("failed delivery = %+v, want 503 with error", history[1])
+	}
+
+	current, _ := repo.GetByID(context.Background(), sub.ID)
+	if current.ConsecutiveFailures != 0 || !current.Active() {
+		t.Errorf("subscription = %+v, want active without failures", current)
+	}
+}
+
+func TestDispatcherDisablesFailingSubscription(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	repo := infrastructure.NewMemoryWebhookRepository()
+	sub := newTestSubscription(t, repo, receiver.URL, "*")
+	d := newTestDispatcher(t, repo, WithMaxAttempts(2), WithMaxConsecutiveFailures(2))
+	ctx := context.Background()
+
+	isDisabled := func() bool {
+		current, _ := repo.GetByID(ctx, sub.ID)
+		return !current.Active()
+	}
+
+	// Primer evento: agota 2 intentos, 1 fallo consecutivo
+	d.Publish(ctx, testMessage("evt-1", domain.EventUserCreated))
+	waitFor(t, "first failure", func() bool {
+		current, _ := repo.GetByID(ctx, sub.ID)
+		return current.ConsecutiveFailures == 1
+	})
+	if isDisabled() {
+		t.Fatal("subscription disabled after a single failed event")
+	}
+
+	// Segundo evento: llega al límite y se desactiva
+	d.Publish(ctx, testMessage("evt-2", domain.EventUserCreated))
+	waitFor(t, "auto-disable", isDisabled)
+	if n := calls.Load(); n != 4 {
+		t.Errorf("receiver got %d requests, want 4", n)
+	}
+
+	// Desactivada: los eventos siguientes no se envían
+	d.Publish(ctx, testMessage("evt-3", domain.EventUserCreated))
+	time.Sleep(20 * time.Millisecond)
+	if n := calls.Load(); n != 4 {
+		t.Errorf("receiver got %d requests after disable, want 4", n)
+	}
+}
+
+func TestDispatcherPublishAfterClose(t *testing.T) {
+	repo := infrastructure.NewMemoryWebhookRepository()
+	newTestSubscription(t, repo, "http://127.0.0.1:1/unused", "*")
+	d := newTestDispatcher(t, repo)
+	d.Close()
+
+	err := d.Publish(context.Background(), testMessage("evt-1", domain.EventUserCreated))
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close = %v, want ErrClosed", err)
+	}
+}
+
+// lockedBuffer es un io.Writer seguro entre goroutines para leer el log
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+var droppedOnClose = regexp.MustCompile(`dropped (\d+) scheduled retries on close`)
+
+// droppedRetries suma los reintentos que el log reporta como descartados
+func droppedRetries(logged string) int {
+	n := strings.Count(logged, "dropped: "+ErrClosed.Error())
+	for _, m := range droppedOnClose.FindAllStringSubmatch(logged, -1) {
+		count, _ := strconv.Atoi(m[1])
+		n += count
+	}
+	return n
+}
+
+func TestDispatcherCloseNeverLosesRetriesSilently(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	for round := 0; round < 20; round++ {
+		repo := infrastructure.NewMemoryWebhookRepository()
+		sub := newTestSubscription(t, repo, receiver.URL, "*")
+		logged := &lockedBuffer{}
+		// Con 2 intentos cada evento tiene exactamente un reintento: se
+		// entrega o queda en el log como descartado
+		d := newTestDispatcher(t, repo, WithMaxAttempts(2), WithMaxConsecutiveFailures(0),
+			WithBackoff(time.Duration(round)*100*time.Microsecond, time.Second),
+			WithLogger(log.New(logged, "", 0)))
+
+		const events = 20
+		for i := 0; i < events; i++ {
+			if err := d.Publish(context.Background(), testMessage(fmt.Sprintf("evt-%d", i), domain.EventUserUpdated)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		time.Sleep(time.Millisecond)
+		d.Close()
+
+		var retried int
+		for _, delivery := range deliveries(t, repo, sub.ID) {
+			if delivery.Attempt == 2 {
+				retried++
+			}
+		}
+		if dropped := droppedRetries(logged.String()); retried+dropped != events {
+			t.Fatalf("round %d: %d retries delivered + %d dropped, want %d\n%s", round, retried, dropped, events, logged)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateSecret()
+	if !strings.HasPrefix(a, "whsec_") || len(a) != len("whsec_")+64 || a == b {
+		t.Errorf("secrets = %q, %q", a, b)
+	}
+}
+
+func TestVerifyRejectsTamperingAndOldTimestamps(t *testing.T) {
+	body := []byte(`{"id":"evt-1"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := Sign(testSecret, signedAt, body)
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"valid", header, body, signedAt.Add(time.Minute), nil},
+		{"tampered body", header, []byte(`{"id":"evt-2"}`), signedAt, ErrInvalidSignature},
+		{"expired", header, body, signedAt.Add(time.Hour), ErrSignatureExpired},
+		{"malformed", "v1=zz", body, signedAt, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(testSecret, tt.header, tt.body, tt.now, 5*time.Minute); !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,444 @@
+// Package cache implementa un cache genérico en memoria, thread-safe, con
+// TTL por entrada, límite por número de entradas o por costo, políticas de
+// desalojo LRU/LFU, janitor en background y carga deduplicada (singleflight).
+//
+// Es la evolución del Cache de concurrency/sync: aquel guarda interface{}
+// para siempre; este usa generics y acota memoria y tiempo de vida.
+// Similar a Caffeine o Guava Cache en Java.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EvictionReason indica por qué una entrada salió del cache
+type EvictionReason int
+
+const (
+	ReasonCapacity EvictionReason = iota // Desalojada por la política (LRU/LFU)
+	ReasonExpired                        // Su TTL venció
+	ReasonDeleted                        // Delete explícito
+	ReasonReplaced                       // Set sobre una clave existente
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	default:
+		return fmt.Sprintf("EvictionReason(%d)", int(r))
+	}
+}
+
+// Stats son contadores acumulados desde la creación del cache
+type Stats struct {
+	Hits        int64
+	Misses      int64
+	Loads       int64
+	LoadErrors  int64
+	Evictions   int64 // Por capacidad
+	Expirations int64
+	Entries     int
+	Cost        int64
+}
+
+// Loader obtiene el valor de una clave ausente (por ejemplo, desde la BD)
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	cost      int64
+	expiresAt time.Time // Zero = no expira
+
+	// Estado de la política de desalojo
+	index     int    // Posición en el heap (solo LFU)
+	frequency uint64 // Accesos (solo LFU)
+	tick      uint64 // Último acceso, para desempatar (solo LFU)
+}
+
+func (e *entry[K, V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// call representa una carga en curso compartida por varios llamadores
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	// stale se marca si un Set o Delete de la clave llegó durante la carga:
+	// el valor cargado ya es viejo y no se guarda
+	stale bool
+}
+
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// Cache es un cache genérico. Crear siempre con New y liberar con Close.
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*entry[K, V]
+	policy  policy[K, V]
+	cost    int64
+
+	loadMu   sync.Mutex
+	inflight map[K]*call[V]
+
+	opts    options
+	onEvict func(K, V, EvictionReason)
+	costFn  func(K, V) int64
+	ttlFn   func(K, V) time.Duration
+
+	hits, misses, loads, loadErrors, evictions, expirations atomic.Int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New crea un cache. Sin opciones no tiene límite ni TTL (como un map con lock).
+func New[K comparable, V any](opts ...Option) *Cache[K, V] {
+	o := options{policy: LRU}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &Cache[K, V]{
+		entries:  make(map[K]*entry[K, V]),
+		inflight: make(map[K]*call[V]),
+		opts:     o,
+		stop:     make(chan struct{}),
+	}
+	c.policy = newPolicy[K, V](o.policy)
+
+	if o.onEvict != nil {
+		fn, ok := o.onEvict.(func(K, V, EvictionReason))
+		if !ok {
+			panic(fmt.Sprintf("cache: eviction callback %T does not match Cache[%T, %T]", o.onEvict, *new(K), *new(V)))
+		}
+		c.onEvict = fn
+	}
+	if o.costFn != nil {
+		fn, ok := o.costFn.(func(K, V) int64)
+		if !ok {
+			panic(fmt.Sprintf("cache: cost function %T does not match Cache[%T, %T]", o.costFn, *new(K), *new(V)))
+		}
+		c.costFn = fn
+	}
+	if o.ttlFn != nil {
+		fn, ok := o.ttlFn.(func(K, V) time.Duration)
+		if !ok {
+			panic(fmt.Sprintf("cache: TTL function %T does not match Cache[%T, %T]", o.ttlFn, *new(K), *new(V)))
+		}
+		c.ttlFn = fn
+	}
+
+	if o.janitorInterval > 0 {
+		c.wg.Add(1)
+		go c.janitor(o.janitorInterval)
+	}
+	return c
+}
+
+// Get devuelve el valor si existe y no expiró
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && e.expired(time.Now()) {
+		evicted := c.removeLocked(e, ReasonExpired)
+		c.mu.Unlock()
+		c.notify([]eviction[K, V]{evicted})
+		ok = false
+	} else {
+		if ok {
+			c.policy.access(e)
+		}
+		c.mu.Unlock()
+	}
+
+	if !ok {
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	c.hits.Add(1)
+	return e.value, true
+}
+
+// Set guarda un valor con el TTL por defecto del cache
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttlFor(key, value))
+}
+
+// SetWithTTL guarda un valor con un TTL propio (0 = no expira)
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.invalidateLoad(key)
+	c.set(key, value, ttl)
+}
+
+// set guarda sin tocar las cargas en curso (lo usa GetOrLoad para su propio valor).
+// Una entrada cuyo costo solo ya supera el máximo no entra: se descarta sin
+// desalojar nada (y el valor anterior de la clave sale, porque quedó viejo).
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) {
+	e := &entry[K, V]{key: key, value: value, cost: 1}
+	if c.costFn != nil {
+		e.cost = c.costFn(key, value)
+	}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	var evicted []eviction[K, V]
+	if old, ok := c.entries[key]; ok {
+		evicted = append(evicted, c.removeLocked(old, ReasonReplaced))
+	}
+	if c.opts.maxCost > 0 && e.cost > c.opts.maxCost {
+		c.evictions.Add(1)
+		evicted = append(evicted, eviction[K, V]{key: key, value: value, reason: ReasonCapacity})
+		c.mu.Unlock()
+		c.notify(evicted)
+		return
+	}
+	c.entries[key] = e
+	c.cost += e.cost
+	c.policy.add(e)
+	evicted = append(evicted, c.enforceLimitsLocked(e)...)
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// Delete elimina una clave; devuelve false si no existía. Una carga de
+// GetOrLoad en curso para la clave no guarda su resultado.
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.invalidateLoad(key)
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	var evicted []eviction[K, V]
+	if ok {
+		evicted = append(evicted, c.removeLocked(e, ReasonDeleted))
+	}
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return ok
+}
+
+// Len devuelve el número de entradas (incluidas las expiradas aún no barridas)
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// GetOrLoad devuelve el valor cacheado o lo carga con loader.
+// Llamadas concurrentes para la misma clave comparten una sola carga
+// (singleflight): loader corre una vez y cada llamador espera su resultado
+// o hasta que su propio ctx se cancele.
+//
+// La carga no depende del ctx de quien la inició (usa context.WithoutCancel):
+// si ese llamador se va, los demás igual reciben el valor. Si durante la
+// carga llega un Set o Delete de la clave, el valor cargado se devuelve
+// pero no se guarda. Los errores del loader no se cachean.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader Loader[K, V]) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+
+	c.loadMu.Lock()
+	cl, ok := c.inflight[key]
+	if !ok {
+		// Otra carga pudo terminar entre el Get y el lock: guarda el valor
+		// antes de soltar inflight, así que alcanza con mirar de nuevo
+		if v, ok := c.peek(key); ok {
+			c.loadMu.Unlock()
+			return v, nil
+		}
+		cl = &call[V]{done: make(chan struct{})}
+		c.inflight[key] = cl
+		c.loads.Add(1)
+		go c.load(context.WithoutCancel(ctx), key, cl, loader)
+	}
+	c.loadMu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (c *Cache[K, V]) load(ctx context.Context, key K, cl *call[V], loader Loader[K, V]) {
+	func() {
+		// Si loader hace panic, los que esperan no deben quedar bloqueados
+		defer func() {
+			if r := recover(); r != nil {
+				cl.err = fmt.Errorf("cache: loader panic: %v", r)
+			}
+		}()
+		cl.value, cl.err = loader(ctx, key)
+	}()
+	if cl.err != nil {
+		c.loadErrors.Add(1)
+	}
+
+	// Guardar y soltar inflight bajo loadMu: un Delete que llega después
+	// ve el valor en el cache y lo borra; uno que llegó antes marcó stale
+	c.loadMu.Lock()
+	if cl.err == nil && !cl.stale {
+		c.set(key, cl.value, c.ttlFor(key, cl.value))
+	}
+	delete(c.inflight, key)
+	c.loadMu.Unlock()
+	close(cl.done)
+}
+
+// ttlFor es el TTL de Set y GetOrLoad: el de WithTTLFunc o el de WithTTL
+func (c *Cache[K, V]) ttlFor(key K, value V) time.Duration {
+	if c.ttlFn != nil {
+		return c.ttlFn(key, value)
+	}
+	return c.opts.ttl
+}
+
+// invalidateLoad marca como vieja la carga en curso de key, si la hay
+func (c *Cache[K, V]) invalidateLoad(key K) {
+	c.loadMu.Lock()
+	if cl, ok := c.inflight[key]; ok {
+		cl.stale = true
+	}
+	c.loadMu.Unlock()
+}
+
+// peek es Get sin contadores ni efecto en la política
+func (c *Cache[K, V]) peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && !e.expired(time.Now()) {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Stats devuelve una foto de los contadores
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	entries, cost := len(c.entries), c.cost
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Loads:       c.loads.Load(),
+		LoadErrors:  c.loadErrors.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Entries:     entries,
+		Cost:        cost,
+	}
+}
+
+// DeleteExpired barre todas las entradas vencidas (lo que hace el janitor)
+func (c *Cache[K, V]) DeleteExpired() int {
+	now := time.Now()
+	c.mu.Lock()
+	var evicted []eviction[K, V]
+	for _, e := range c.entries {
+		if e.expired(now) {
+			evicted = append(evicted, c.removeLocked(e, ReasonExpired))
+		}
+	}
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return len(evicted)
+}
+
+// Close detiene el janitor. Es seguro llamarlo más de una vez.
+func (c *Cache[K, V]) Close() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+	c.wg.Wait()
+}
+
+func (c *Cache[K, V]) janitor(interval time.Duration) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// enforceLimitsLocked desaloja víctimas hasta respetar los límites. La
+// entrada recién insertada no se desaloja (set ya descartó las que no
+// entran solas); si no queda otra víctima, sale ella.
+func (c *Cache[K, V]) enforceLimitsLocked(inserted *entry[K, V]) []eviction[K, V] {
+	var evicted []eviction[K, V]
+	for c.overLimitLocked() {
+		victim := c.policy.victim(inserted)
+		if victim == nil {
+			victim = inserted
+		}
+		evicted = append(evicted, c.removeLocked(victim, ReasonCapacity))
+		if victim == inserted {
+			break
+		}
+	}
+	return evicted
+}
+
+func (c *Cache[K, V]) overLimitLocked() bool {
+	if c.opts.maxEntries > 0 && len(c.entries) > c.opts.maxEntries {
+		return true
+	}
+	return c.opts.maxCost > 0 && c.cost > c.opts.maxCost
+}
+
+func (c *Cache[K, V]) removeLocked(e *entry[K, V], reason EvictionReason) eviction[K, V] {
+	delete(c.entries, e.key)
+	c.policy.remove(e)
+	c.cost -= e.cost
+
+	switch reason {
+	case ReasonCapacity:
+		c.evictions.Add(1)
+	case ReasonExpired:
+		c.expirations.Add(1)
+	}
+	return eviction[K, V]{key: e.key, value: e.value, reason: reason}
+}
+
+// notify invoca el callback fuera del lock para que pueda usar el cache
+func (c *Cache[K, V]) notify(evicted []eviction[K, V]) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, ev := range evicted {
+		c.onEvict(ev.key, ev.value, ev.reason)
+	}
+}
@@ -0,0 +1,306 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	c := New[string, int](WithMaxEntries(2), WithEvictionCallback(func(k string, v int, r EvictionReason) {
+		if r == ReasonCapacity {
+			evicted = append(evicted, k)
+		}
+	}))
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // b pasa a ser el menos reciente
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a was evicted, want it kept")
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("evicted = %v, want [b]", evicted)
+	}
+}
+
+func TestLFUEvictsLeastFrequentlyUsed(t *testing.T) {
+	c := New[string, int](WithMaxEntries(2), WithPolicy(LFU))
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	for i := 0; i < 3; i++ {
+		c.Get("a")
+	}
+	c.Get("b")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a was evicted, want it kept")
+	}
+	if got := c.Stats().Evictions; got != 1 {
+		t.Errorf("Evictions = %d, want 1", got)
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	cost := WithCost(func(k string, v int) int64 { return int64(v) })
+	c := New[string, int](WithMaxCost(10), cost)
+	defer c.Close()
+
+	c.Set("a", 3)
+	c.Set("b", 3)
+	c.Set("c", 3)
+	c.Set("d", 4) // 13 > 10: sale a (el menos reciente)
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have been evicted")
+	}
+	if st := c.Stats(); st.Cost != 10 || st.Entries != 3 {
+		t.Errorf("stats = %+v, want cost 10 with 3 entries", st)
+	}
+
+	// Una entrada que sola supera el máximo no desaloja a las demás
+	c.Set("big", 50)
+	if _, ok := c.Get("big"); ok {
+		t.Error("oversize entry was stored")
+	}
+	if st := c.Stats(); st.Cost != 10 || st.Entries != 3 {
+		t.Errorf("after oversize Set: stats = %+v, want the previous 3 entries", st)
+	}
+
+	// Reemplazar una clave con un valor que no entra la deja sin valor viejo
+	c.Set("b", 50)
+	if _, ok := c.Get("b"); ok {
+		t.Error("stale value of b kept after an oversize replace")
+	}
+}
+
+func TestTTLExpiration(t *testing.T) {
+	var expired atomic.Int32
+	c := New[string, int](WithTTL(20*time.Millisecond), WithEvictionCallback(func(k string, v int, r EvictionReason) {
+		if r == ReasonExpired {
+			expired.Add(1)
+		}
+	}))
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.SetWithTTL("forever", 2, 0)
+	c.SetWithTTL("b", 3, time.Hour)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a missing before its TTL")
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("a still there after its TTL")
+	}
+	if n := c.DeleteExpired(); n != 0 {
+		t.Errorf("DeleteExpired = %d, want 0 (a was removed on Get)", n)
+	}
+	for _, k := range []string{"forever", "b"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("%s expired too early", k)
+		}
+	}
+	if expired.Load() != 1 {
+		t.Errorf("expired callbacks = %d, want 1", expired.Load())
+	}
+}
+
+func TestJanitorRemovesExpiredEntries(t *testing.T) {
+	c := New[string, int](WithTTL(10*time.Millisecond), WithJanitor(5*time.Millisecond))
+	defer c.Close()
+
+	c.Set("a", 1)
+	deadline := time.Now().Add(time.Second)
+	for c.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("janitor did not remove the expired entry")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGetOrLoadSharesOneLoad(t *testing.T) {
+	c := New[string, int]()
+	defer c.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.GetOrLoad(context.Background(), "k", loader); v != 42 || err != nil {
+				t.Errorf("GetOrLoad = %d, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// Una vez cargado no se vuelve a llamar al loader
+	c.GetOrLoad(context.Background(), "k", loader)
+	if calls.Load() != 1 {
+		t.Errorf("loader calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestGetOrLoadDoesNotCacheErrors(t *testing.T) {
+	c := New[string, int]()
+	defer c.Close()
+
+	boom := errors.New("boom")
+	if _, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (int, error) {
+		return 0, boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	if _, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (int, error) {
+		panic("loader bug")
+	}); err == nil {
+		t.Fatal("panicking loader returned no error")
+	}
+	if c.Len() != 0 || c.Stats().LoadErrors != 2 {
+		t.Errorf("Len = %d, LoadErrors = %d", c.Len(), c.Stats().LoadErrors)
+	}
+}
+
+func TestDeleteDuringLoadDiscardsLoadedValue(t *testing.T) {
+	c := New[string, int]()
+	defer c.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan int)
+	go func() {
+		v, _ := c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (int, error) {
+			close(started)
+			<-release
+			return 1, nil // Leído antes de la invalidación: viejo
+		})
+		done <- v
+	}()
+	<-started
+	c.Delete("k")
+	close(release)
+	if v := <-done; v != 1 {
+		t.Errorf("GetOrLoad = %d, want the loaded value", v)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("stale loaded value was cached after Delete")
+	}
+
+	// Un Set durante la carga gana sobre el valor cargado
+	started, release = make(chan struct{}), make(chan struct{})
+	go func() {
+		v, _ := c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		done <- v
+	}()
+	<-started
+	c.Set("k", 2)
+	close(release)
+	<-done
+	if v, _ := c.Get("k"); v != 2 {
+		t.Errorf("Get = %d, want the value from Set", v)
+	}
+}
+
+func TestLeaderCancellationDoesNotFailWaiters(t *testing.T) {
+	c := New[string, int]()
+	defer c.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		close(started)
+		select {
+		case <-release:
+			return 7, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(leaderCtx, "k", loader)
+		leader <- err
+	}()
+	<-started
+
+	waiter := make(chan int)
+	go func() {
+		v, err := c.GetOrLoad(context.Background(), "k", loader)
+		if err != nil {
+			t.Errorf("waiter err = %v", err)
+		}
+		waiter <- v
+	}()
+
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader err = %v, want context.Canceled", err)
+	}
+	close(release)
+	if v := <-waiter; v != 7 {
+		t.Errorf("waiter = %d, want 7", v)
+	}
+	if v, ok := c.Get("k"); !ok || v != 7 {
+		t.Errorf("Get = %d, %v, want the loaded value cached", v, ok)
+	}
+}
+
+func TestTTLFuncPerValue(t *testing.T) {
+	c := New[string, int](WithTTL(time.Hour), WithTTLFunc(func(k string, v int) time.Duration {
+		if v == 0 {
+			return 10 * time.Millisecond
+		}
+		return 0
+	}))
+	defer c.Close()
+
+	c.Set("missing", 0)
+	if _, err := c.GetOrLoad(context.Background(), "loaded", func(ctx context.Context, key string) (int, error) {
+		return 0, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("forever", 1)
+	time.Sleep(20 * time.Millisecond)
+
+	for _, k := range []string{"missing", "loaded"} {
+		if _, ok := c.Get(k); ok {
+			t.Errorf("%s still there after its TTL", k)
+		}
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Error("forever expired, want no TTL")
+	}
+}
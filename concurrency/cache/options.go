@@ -0,0 +1,85 @@
+package cache
+
+import "time"
+
+// Policy elige qué entrada desalojar cuando el cache está lleno
+type Policy int
+
+const (
+	LRU Policy = iota // Least Recently Used
+	LFU               // Least Frequently Used
+)
+
+type options struct {
+	ttl             time.Duration
+	maxEntries      int
+	maxCost         int64
+	policy          Policy
+	janitorInterval time.Duration
+	onEvict         any // func(K, V, EvictionReason)
+	costFn          any // func(K, V) int64
+	ttlFn           any // func(K, V) time.Duration
+}
+
+// Option configura un Cache (functional options, ver patterns/functional_options)
+type Option func(*options)
+
+// WithTTL define el TTL por defecto de Set y GetOrLoad (0 = sin expiración)
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithMaxEntries limita el número de entradas
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// WithMaxCost limita la suma de costos (ver WithCost)
+func WithMaxCost(cost int64) Option {
+	return func(o *options) {
+		o.maxCost = cost
+	}
+}
+
+// WithPolicy elige la política de desalojo (LRU por defecto)
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// WithJanitor arranca una goroutine que barre entradas expiradas cada interval.
+// Sin janitor las entradas expiradas solo se eliminan al leerlas.
+func WithJanitor(interval time.Duration) Option {
+	return func(o *options) {
+		o.janitorInterval = interval
+	}
+}
+
+// WithEvictionCallback registra fn para cada entrada que sale del cache.
+// Los tipos se infieren de fn y deben coincidir con los de New.
+func WithEvictionCallback[K comparable, V any](fn func(key K, value V, reason EvictionReason)) Option {
+	return func(o *options) {
+		o.onEvict = fn
+	}
+}
+
+// WithCost define el costo de cada entrada (por defecto 1), p. ej. su tamaño en bytes
+func WithCost[K comparable, V any](fn func(key K, value V) int64) Option {
+	return func(o *options) {
+		o.costFn = fn
+	}
+}
+
+// WithTTLFunc calcula el TTL de cada entrada guardada con Set o GetOrLoad
+// según su valor, p. ej. un TTL más corto para resultados "no existe".
+// Reemplaza a WithTTL; SetWithTTL sigue usando el TTL que recibe.
+func WithTTLFunc[K comparable, V any](fn func(key K, value V) time.Duration) Option {
+	return func(o *options) {
+		o.ttlFn = fn
+	}
+}
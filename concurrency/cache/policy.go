@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// policy mantiene el orden de desalojo. Siempre se usa con Cache.mu tomado.
+type policy[K comparable, V any] interface {
+	add(e *entry[K, V])
+	access(e *entry[K, V])
+	remove(e *entry[K, V])
+	// victim devuelve la próxima entrada a desalojar distinta de skip
+	victim(skip *entry[K, V]) *entry[K, V]
+}
+
+func newPolicy[K comparable, V any](p Policy) policy[K, V] {
+	if p == LFU {
+		return &lfuPolicy[K, V]{}
+	}
+	return &lruPolicy[K, V]{ll: list.New(), elems: make(map[*entry[K, V]]*list.Element)}
+}
+
+// ============================================================================
+// LRU: lista doblemente enlazada, el frente es el más reciente
+// ============================================================================
+
+type lruPolicy[K comparable, V any] struct {
+	ll    *list.List
+	elems map[*entry[K, V]]*list.Element
+}
+
+func (p *lruPolicy[K, V]) add(e *entry[K, V]) {
+	p.elems[e] = p.ll.PushFront(e)
+}
+
+func (p *lruPolicy[K, V]) access(e *entry[K, V]) {
+	if el, ok := p.elems[e]; ok {
+		p.ll.MoveToFront(el)
+	}
+}
+
+func (p *lruPolicy[K, V]) remove(e *entry[K, V]) {
+	if el, ok := p.elems[e]; ok {
+		p.ll.Remove(el)
+		delete(p.elems, e)
+	}
+}
+
+func (p *lruPolicy[K, V]) victim(skip *entry[K, V]) *entry[K, V] {
+	for el := p.ll.Back(); el != nil; el = el.Prev() {
+		if e := el.Value.(*entry[K, V]); e != skip {
+			return e
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// LFU: min-heap por (frecuencia, último acceso)
+// ============================================================================
+// Empatar por último acceso evita que entradas viejas con la misma
+// frecuencia sobrevivan a las nuevas para siempre.
+
+type lfuPolicy[K comparable, V any] struct {
+	items []*entry[K, V]
+	clock uint64
+}
+
+func (p *lfuPolicy[K, V]) Len() int { return len(p.items) }
+
+func (p *lfuPolicy[K, V]) Less(i, j int) bool {
+	a, b := p.items[i], p.items[j]
+	if a.frequency != b.frequency {
+		return a.frequency < b.frequency
+	}
+	return a.tick < b.tick
+}
+
+func (p *lfuPolicy[K, V]) Swap(i, j int) {
+	p.items[i], p.items[j] = p.items[j], p.items[i]
+	p.items[i].index = i
+	p.items[j].index = j
+}
+
+func (p *lfuPolicy[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.index = len(p.items)
+	p.items = append(p.items, e)
+}
+
+func (p *lfuPolicy[K, V]) Pop() any {
+	last := len(p.items) - 1
+	e := p.items[last]
+	p.items[last] = nil
+	p.items = p.items[:last]
+	e.index = -1
+	return e
+}
+
+func (p *lfuPolicy[K, V]) add(e *entry[K, V]) {
+	p.clock++
+	e.frequency = 1
+	e.tick = p.clock
+	heap.Push(p, e)
+}
+
+func (p *lfuPolicy[K, V]) access(e *entry[K, V]) {
+	p.clock++
+	e.frequency++
+	e.tick = p.clock
+	heap.Fix(p, e.index)
+}
+
+func (p *lfuPolicy[K, V]) remove(e *entry[K, V]) {
+	if e.index >= 0 && e.index < len(p.items) && p.items[e.index] == e {
+		heap.Remove(p, e.index)
+	}
+}
+
+func (p *lfuPolicy[K, V]) victim(skip *entry[K, V]) *entry[K, V] {
+	if len(p.items) == 0 {
+		return nil
+	}
+	if p.items[0] != skip {
+		return p.items[0]
+	}
+	// La raíz es la recién insertada: la víctima es el menor de sus hijos
+	var best *entry[K, V]
+	for _, i := range []int{1, 2} {
+		if i < len(p.items) && (best == nil || p.Less(i, best.index)) {
+			best = p.items[i]
+		}
+	}
+	return best
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math"
 	"sync"
@@ -360,7 +361,6 @@ func bufferedPipeline() {
 	// Buffers permiten que stages trabajen en paralelo
 	stage1 := make(chan int, 5)
 	stage2 := make(chan int, 5)
-	stage3 := make(chan int, 5)
 
 	// Stage 1: Generar
 	go func() {
@@ -393,7 +393,11 @@ func bufferedPipeline() {
 // ============================================================================
 
 func main() {
-	fmt.Println("=== CONCURRENCIA: PIPELINES ===\n")
+	metricsAddr := flag.String("metrics-addr", "localhost:6061", "dirección de /debug/vars (vacío para no servir métricas)")
+	flag.Parse()
+
+	fmt.Println("=== CONCURRENCIA: PIPELINES ===")
+	fmt.Println()
 
 	basicPipeline()
 	multiStagePipeline()
@@ -401,8 +405,11 @@ func main() {
 	fanOutFanIn()
 	rateLimitedPipeline()
 	dataTransformationPipeline()
+	windowedAggregationPipeline()
 	pipelineWithErrorHandling()
 	bufferedPipeline()
+	observablePipeline(*metricsAddr)
+	notifyingPipeline()
 
 	fmt.Println("=== FIN DE EJEMPLOS ===")
 }
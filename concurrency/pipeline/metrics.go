@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================================
+// OBSERVABILIDAD DE PIPELINES
+// ============================================================================
+// bufferedPipeline muestra que los buffers ayudan, pero en un pipeline real
+// necesitamos datos para saber DÓNDE está el cuello de botella:
+//
+// - items in/out:     cuántos elementos entraron y salieron de cada etapa
+// - latency:          cuánto tarda la función de la etapa por elemento
+// - blocked on recv:  tiempo esperando input (la etapa anterior es lenta)
+// - blocked on send:  tiempo esperando que la siguiente etapa consuma
+//                     (backpressure: la etapa siguiente es lenta)
+// - buffer occupancy: len/cap del channel de salida
+//
+// Regla práctica: la etapa con más "blocked on recv" está detrás del cuello
+// de botella; la que tiene más "blocked on send" está delante de él.
+// Los números se exponen con expvar en /debug/vars (similar a JMX en Java),
+// en la dirección de -metrics-addr (por defecto localhost:6061).
+// ============================================================================
+
+// StageMetrics acumula las métricas de una etapa usando operaciones atómicas
+// para que leer un snapshot no bloquee al pipeline
+type StageMetrics struct {
+	name string
+
+	itemsIn        atomic.Int64
+	itemsOut       atomic.Int64
+	processingNs   atomic.Int64
+	maxProcessNs   atomic.Int64
+	blockedRecvNs  atomic.Int64
+	blockedSendNs  atomic.Int64
+	bufferLen      func() int
+	bufferCapacity int
+}
+
+// StageSnapshot es una foto inmutable de las métricas de una etapa
+type StageSnapshot struct {
+	Name          string        `json:"name"`
+	ItemsIn       int64         `json:"items_in"`
+	ItemsOut      int64         `json:"items_out"`
+	AvgLatency    time.Duration `json:"avg_latency_ns"`
+	MaxLatency    time.Duration `json:"max_latency_ns"`
+	BlockedOnRecv time.Duration `json:"blocked_on_recv_ns"`
+	BlockedOnSend time.Duration `json:"blocked_on_send_ns"`
+	BufferLen     int           `json:"buffer_len"`
+	BufferCap     int           `json:"buffer_cap"`
+}
+
+func (m *StageMetrics) observeLatency(d time.Duration) {
+	m.processingNs.Add(int64(d))
+	for {
+		current := m.maxProcessNs.Load()
+		if int64(d) <= current || m.maxProcessNs.CompareAndSwap(current, int64(d)) {
+			return
+		}
+	}
+}
+
+func (m *StageMetrics) Snapshot() StageSnapshot {
+	s := StageSnapshot{
+		Name:          m.name,
+		ItemsIn:       m.itemsIn.Load(),
+		ItemsOut:      m.itemsOut.Load(),
+		MaxLatency:    time.Duration(m.maxProcessNs.Load()),
+		BlockedOnRecv: time.Duration(m.blockedRecvNs.Load()),
+		BlockedOnSend: time.Duration(m.blockedSendNs.Load()),
+		BufferCap:     m.bufferCapacity,
+	}
+	if s.ItemsIn > 0 {
+		s.AvgLatency = time.Duration(m.processingNs.Load() / s.ItemsIn)
+	}
+	if m.bufferLen != nil {
+		s.BufferLen = m.bufferLen()
+	}
+	return s
+}
+
+// PipelineMetrics agrupa las métricas de todas las etapas de un pipeline
+type PipelineMetrics struct {
+	mu     sync.Mutex
+	stages []*StageMetrics
+}
+
+func NewPipelineMetrics() *PipelineMetrics {
+	return &PipelineMetrics{}
+}
+
+func (pm *PipelineMetrics) newStage(name string) *StageMetrics {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	m := &StageMetrics{name: name}
+	pm.stages = append(pm.stages, m)
+	return m
+}
+
+// Snapshot devuelve las métricas de todas las etapas en orden de creación
+func (pm *PipelineMetrics) Snapshot() []StageSnapshot {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	snapshots := make([]StageSnapshot, 0, len(pm.stages))
+	for _, m := range pm.stages {
+		snapshots = append(snapshots, m.Snapshot())
+	}
+	return snapshots
+}
+
+// Publish expone el snapshot en /debug/vars bajo el nombre dado.
+// expvar.Publish hace panic si el nombre ya existe, igual que registrar
+// dos veces el mismo MBean en Java.
+func (pm *PipelineMetrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return pm.Snapshot()
+	}))
+}
+
+// instrumentedStage es como pipelineStage pero midiendo cada paso.
+// fn devuelve false para descartar el item (etapas de filtrado).
+func instrumentedStage[In, Out any](pm *PipelineMetrics, name string, input <-chan In, bufferSize int, fn func(In) (Out, bool)) <-chan Out {
+	output := make(chan Out, bufferSize)
+	m := pm.newStage(name)
+	m.bufferCapacity = bufferSize
+	m.bufferLen = func() int { return len(output) }
+
+	go func() {
+		defer close(output)
+		for {
+			waitStart := time.Now()
+			item, ok := <-input
+			m.blockedRecvNs.Add(int64(time.Since(waitStart)))
+			if !ok {
+				return
+			}
+			m.itemsIn.Add(1)
+
+			start := time.Now()
+			result, keep := fn(item)
+			m.observeLatency(time.Since(start))
+			if !keep {
+				continue
+			}
+
+			sendStart := time.Now()
+			output <- result
+			m.blockedSendNs.Add(int64(time.Since(sendStart)))
+			m.itemsOut.Add(1)
+		}
+	}()
+
+	return output
+}
+
+func printSnapshot(snapshots []StageSnapshot) {
+	fmt.Printf("%-10s %6s %6s %10s %10s %12s %12s %8s\n",
+		"stage", "in", "out", "avg", "max", "blocked-recv", "blocked-send", "buffer")
+	for _, s := range snapshots {
+		fmt.Printf("%-10s %6d %6d %10v %10v %12v %12v %4d/%-3d\n",
+			s.Name, s.ItemsIn, s.ItemsOut,
+			s.AvgLatency.Round(time.Microsecond), s.MaxLatency.Round(time.Microsecond),
+			s.BlockedOnRecv.Round(time.Millisecond), s.BlockedOnSend.Round(time.Millisecond),
+			s.BufferLen, s.BufferCap)
+	}
+}
+
+// ============================================================================
+// EJEMPLO: MEDIR UN PIPELINE CON UNA ETAPA LENTA
+// ============================================================================
+
+// observablePipeline sirve /debug/vars en metricsAddr; vacío no levanta
+// el servidor (las métricas se siguen imprimiendo)
+func observablePipeline(metricsAddr string) {
+	fmt.Println("=== Observable Pipeline (metrics) ===")
+
+	// Endpoint de métricas, igual que pprof_demo expone /debug/pprof/
+	if metricsAddr != "" {
+		go func() {
+			log.Println(http.ListenAndServe(metricsAddr, nil))
+		}()
+		fmt.Printf("Metrics available at http://%s/debug/vars\n", metricsAddr)
+	}
+
+	for _, bufferSize := range []int{0, 10} {
+		fmt.Printf("-- buffer size %d --\n", bufferSize)
+
+		pm := NewPipelineMetrics()
+		pm.Publish(fmt.Sprintf("pipeline_buffer_%d", bufferSize))
+
+		source := make(chan int)
+		go func() {
+			defer close(source)
+			for i := 1; i <= 30; i++ {
+				source <- i
+			}
+		}()
+
+		// parse: rápida
+		parsed := instrumentedStage(pm, "parse", source, bufferSize, func(n int) (DataPoint, bool) {
+			return DataPoint{Value: float64(n), Time: time.Now()}, true
+		})
+		// filter: descarta múltiplos de 3
+		filtered := instrumentedStage(pm, "filter", parsed, bufferSize, func(p DataPoint) (DataPoint, bool) {
+			return p, int(p.Value)%3 != 0
+		})
+		// enrich: lenta (cuello de botella)
+		enriched := instrumentedStage(pm, "enrich", filtered, bufferSize, func(p DataPoint) (DataPoint, bool) {
+			time.Sleep(10 * time.Millisecond)
+			p.Value *= 10
+			return p, true
+		})
+
+		// Sink con snapshot a mitad de camino para ver la ocupación de buffers
+		count := 0
+		for range enriched {
+			count++
+			if count == 10 {
+				fmt.Println("Mid-run snapshot:")
+				printSnapshot(pm.Snapshot())
+			}
+		}
+
+		fmt.Printf("Final snapshot (%d items consumed):\n", count)
+		printSnapshot(pm.Snapshot())
+
+		data, _ := json.Marshal(pm.Snapshot()[2])
+		fmt.Printf("JSON (as served by /debug/vars): %s\n", data)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func feed(items ...int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for _, n := range items {
+			out <- n
+		}
+	}()
+	return out
+}
+
+func TestInstrumentedStageCountsItems(t *testing.T) {
+	pm := NewPipelineMetrics()
+	evens := instrumentedStage(pm, "evens", feed(1, 2, 3, 4, 5, 6), 2, func(n int) (int, bool) {
+		return n, n%2 == 0
+	})
+	doubled := instrumentedStage(pm, "double", evens, 0, func(n int) (int, bool) {
+		return n * 2, true
+	})
+
+	var got []int
+	for n := range doubled {
+		got = append(got, n)
+	}
+	if len(got) != 3 || got[0] != 4 || got[2] != 12 {
+		t.Errorf("output = %v", got)
+	}
+
+	snapshots := pm.Snapshot()
+	if len(snapshots) != 2 || snapshots[0].Name != "evens" || snapshots[1].Name != "double" {
+		t.Fatalf("stages = %+v, want evens then double", snapshots)
+	}
+	tests := []struct {
+		s       StageSnapshot
+		in, out int64
+		cap     int
+	}{
+		{snapshots[0], 6, 3, 2},
+		{snapshots[1], 3, 3, 0},
+	}
+	for _, tt := range tests {
+		if tt.s.ItemsIn != tt.in || tt.s.ItemsOut != tt.out || tt.s.BufferCap != tt.cap || tt.s.BufferLen != 0 {
+			t.Errorf("%s = %+v, want in=%d out=%d cap=%d", tt.s.Name, tt.s, tt.in, tt.out, tt.cap)
+		}
+	}
+}
+
+func TestInstrumentedStageLatency(t *testing.T) {
+	pm := NewPipelineMetrics()
+	out := instrumentedStage(pm, "sleep", feed(1, 2, 3), 0, func(n int) (int, bool) {
+		time.Sleep(time.Duration(n) * 5 * time.Millisecond)
+		return n, true
+	})
+	for range out {
+	}
+
+	s := pm.Snapshot()[0]
+	// 5ms, 10ms y 15ms: promedio >= 10ms, máximo >= 15ms
+	if s.AvgLatency < 10*time.Millisecond || s.MaxLatency < 15*time.Millisecond {
+		t.Errorf("avg = %v, max = %v", s.AvgLatency, s.MaxLatency)
+	}
+	if s.MaxLatency < s.AvgLatency {
+		t.Errorf("max %v < avg %v", s.MaxLatency, s.AvgLatency)
+	}
+}
+
+func TestObserveLatencyKeepsMaxUnderContention(t *testing.T) {
+	var m StageMetrics
+	var wg sync.WaitGroup
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func(d time.Duration) {
+			defer wg.Done()
+			m.observeLatency(d)
+		}(time.Duration(i))
+	}
+	wg.Wait()
+	m.itemsIn.Store(100)
+	s := m.Snapshot()
+	if s.MaxLatency != 100 || s.AvgLatency != 50 { // (1+...+100)/100 = 50 (división entera)
+		t.Errorf("max = %d, avg = %d", s.MaxLatency, s.AvgLatency)
+	}
+}
+
+func TestBlockedTimeShowsBottleneck(t *testing.T) {
+	const wait = 20 * time.Millisecond
+
+	t.Run("slow consumer blocks send", func(t *testing.T) {
+		pm := NewPipelineMetrics()
+		out := instrumentedStage(pm, "fast", feed(1, 2, 3), 1, func(n int) (int, bool) { return n, true })
+
+		// Con el buffer lleno la etapa espera al consumidor: backpressure
+		time.Sleep(wait)
+		if s := pm.Snapshot()[0]; s.BufferLen != 1 {
+			t.Errorf("buffer = %d/%d, want full", s.BufferLen, s.BufferCap)
+		}
+		for range out {
+			time.Sleep(wait)
+		}
+		if s := pm.Snapshot()[0]; s.BlockedOnSend < wait {
+			t.Errorf("blocked on send = %v, want >= %v", s.BlockedOnSend, wait)
+		}
+	})
+
+	t.Run("slow producer blocks recv", func(t *testing.T) {
+		pm := NewPipelineMetrics()
+		input := make(chan int)
+		go func() {
+			defer close(input)
+			for i := 0; i < 3; i++ {
+				time.Sleep(wait)
+				input <- i
+			}
+		}()
+		for range instrumentedStage(pm, "waiting", input, 0, func(n int) (int, bool) { return n, true }) {
+		}
+		s := pm.Snapshot()[0]
+		if s.BlockedOnRecv < 3*wait {
+			t.Errorf("blocked on recv = %v, want >= %v", s.BlockedOnRecv, 3*wait)
+		}
+		if s.BlockedOnSend >= s.BlockedOnRecv {
+			t.Errorf("blocked on send %v >= recv %v with a fast consumer", s.BlockedOnSend, s.BlockedOnRecv)
+		}
+	})
+}
+
+func TestPublishExposesSnapshot(t *testing.T) {
+	pm := NewPipelineMetrics()
+	for range instrumentedStage(pm, "stage", feed(1, 2), 0, func(n int) (int, bool) { return n, true }) {
+	}
+	// expvar es global: un nombre por PipelineMetrics para que -count=N no
+	// publique dos veces el mismo
+	name := fmt.Sprintf("test_pipeline_%p", pm)
+	pm.Publish(name)
+
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatal("not published")
+	}
+	var got []StageSnapshot
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "stage" || got[0].ItemsOut != 2 {
+		t.Errorf("published = %+v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/concurrency/pubsub"
+)
+
+// ============================================================================
+// NOTIFICACIONES DESACOPLADAS CON PUBSUB
+// ============================================================================
+// Las etapas publican lo que pasa ("pipeline.<etapa>.<evento>") en un
+// pubsub.Bus sin saber quién escucha. Cada suscriptor elige su entrega:
+//
+// - audit (Sync):             cuenta rechazos dentro de Publish, nunca pierde nada
+// - dashboard (DropOldest):   lento a propósito; solo le importa lo último
+// - alerts (Block):           recibe todo, con backpressure si se atrasa
+//
+// Un dashboard lento no frena al pipeline: pierde mensajes viejos.
+// ============================================================================
+
+type StageEvent struct {
+	Stage string
+	Value int
+}
+
+func notifyingPipeline() {
+	fmt.Println("=== Pipeline con notificaciones (pubsub) ===")
+	ctx := context.Background()
+	bus := pubsub.New[StageEvent]()
+
+	var rejected atomic.Int64
+	bus.Subscribe("pipeline.*.rejected", func(ctx context.Context, msg pubsub.Message[StageEvent]) error {
+		rejected.Add(1)
+		return nil
+	}, pubsub.WithSync())
+
+	var lastSeen atomic.Int64
+	dashboard, _ := bus.Subscribe("pipeline.>", func(ctx context.Context, msg pubsub.Message[StageEvent]) error {
+		time.Sleep(5 * time.Millisecond) // Un consumidor lento
+		lastSeen.Store(int64(msg.Payload.Value))
+		return nil
+	}, pubsub.WithBuffer(4), pubsub.WithOverflow(pubsub.DropOldest))
+
+	alerts, _ := bus.Subscribe("pipeline.validate.rejected", func(ctx context.Context, msg pubsub.Message[StageEvent]) error {
+		fmt.Printf("  alert: %s rejected value %d\n", msg.Payload.Stage, msg.Payload.Value)
+		return nil
+	})
+
+	// Pipeline: generar -> validar (múltiplos de 7 no pasan) -> duplicar
+	numbers := make(chan int)
+	go func() {
+		defer close(numbers)
+		for i := 1; i <= 30; i++ {
+			numbers <- i
+		}
+	}()
+
+	valid := make(chan int)
+	go func() {
+		defer close(valid)
+		for n := range numbers {
+			if n%7 == 0 {
+				bus.Publish(ctx, "pipeline.validate.rejected", StageEvent{Stage: "validate", Value: n})
+				continue
+			}
+			bus.Publish(ctx, "pipeline.validate.passed", StageEvent{Stage: "validate", Value: n})
+			valid <- n
+		}
+	}()
+
+	sum := 0
+	for n := range valid {
+		sum += n * 2
+		bus.Publish(ctx, "pipeline.double.done", StageEvent{Stage: "double", Value: n * 2})
+	}
+
+	// Close vacía las colas de los suscriptores async antes de volver
+	closeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := bus.Close(closeCtx); err != nil {
+		fmt.Println("  close:", err)
+	}
+
+	fmt.Printf("Sum: %d, rejected: %d\n", sum, rejected.Load())
+	fmt.Printf("Dashboard: %+v (último valor visto: %d)\n", dashboard.Stats(), lastSeen.Load())
+	fmt.Printf("Alerts:    %+v\n", alerts.Stats())
+	fmt.Println()
+}
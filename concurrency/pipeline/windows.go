@@ -0,0 +1,393 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ============================================================================
+// WINDOWED AGGREGATION (EVENT TIME)
+// ============================================================================
+// Las etapas anteriores transforman item por item. En telemetría normalmente
+// necesitamos agregar por ventanas de tiempo usando DataPoint.Time (event time),
+// no el momento en que el dato llega al pipeline.
+// Similar a las ventanas de Kafka Streams o Apache Flink en Java.
+//
+// - Tumbling: ventanas fijas sin solapamiento      [0,10) [10,20) ...
+// - Sliding:  ventanas fijas que se solapan         [0,10) [5,15) ...
+// - Session:  ventanas dinámicas separadas por inactividad (gap)
+//
+// Watermark: marca "ya no espero datos anteriores a T".
+// Se calcula como (máximo event time visto - allowed lateness).
+// Una ventana se emite cuando watermark >= fin de la ventana.
+// Los datos que llegan para ventanas ya emitidas son "late data".
+// ============================================================================
+
+// Window es un intervalo semiabierto [Start, End)
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("[%s, %s)", w.Start.Format("15:04:05"), w.End.Format("15:04:05"))
+}
+
+// WindowAssigner decide a qué ventanas pertenece un timestamp
+type WindowAssigner interface {
+	AssignWindows(t time.Time) []Window
+	// Mergeable indica si ventanas solapadas deben fusionarse (sessions)
+	Mergeable() bool
+	// Validate rechaza tamaños que harían que AssignWindows no termine
+	// o no tenga sentido (Size, Slide o Gap <= 0)
+	Validate() error
+}
+
+var ErrInvalidWindow = errors.New("invalid window")
+
+// TumblingWindows asigna cada punto a exactamente una ventana de tamaño fijo
+type TumblingWindows struct {
+	Size time.Duration
+}
+
+func (tw TumblingWindows) AssignWindows(t time.Time) []Window {
+	start := t.Truncate(tw.Size)
+	return []Window{{Start: start, End: start.Add(tw.Size)}}
+}
+
+func (tw TumblingWindows) Mergeable() bool { return false }
+
+func (tw TumblingWindows) Validate() error {
+	if tw.Size <= 0 {
+		return fmt.Errorf("%w: tumbling size must be > 0, got %s", ErrInvalidWindow, tw.Size)
+	}
+	return nil
+}
+
+// SlidingWindows asigna cada punto a Size/Slide ventanas solapadas
+type SlidingWindows struct {
+	Size  time.Duration
+	Slide time.Duration
+}
+
+func (sw SlidingWindows) AssignWindows(t time.Time) []Window {
+	var windows []Window
+	// La última ventana que contiene t empieza en t truncado al slide
+	last := t.Truncate(sw.Slide)
+	for start := last; start.After(t.Add(-sw.Size)); start = start.Add(-sw.Slide) {
+		windows = append(windows, Window{Start: start, End: start.Add(sw.Size)})
+	}
+	return windows
+}
+
+func (sw SlidingWindows) Mergeable() bool { return false }
+
+func (sw SlidingWindows) Validate() error {
+	switch {
+	case sw.Size <= 0:
+		return fmt.Errorf("%w: sliding size must be > 0, got %s", ErrInvalidWindow, sw.Size)
+	case sw.Slide <= 0:
+		return fmt.Errorf("%w: sliding slide must be > 0, got %s", ErrInvalidWindow, sw.Slide)
+	case sw.Slide > sw.Size:
+		// Con Slide > Size quedarían huecos sin ventana entre una y otra
+		return fmt.Errorf("%w: sliding slide %s is larger than size %s", ErrInvalidWindow, sw.Slide, sw.Size)
+	}
+	return nil
+}
+
+// SessionWindows agrupa puntos mientras la separación entre ellos sea < Gap
+type SessionWindows struct {
+	Gap time.Duration
+}
+
+func (sw SessionWindows) AssignWindows(t time.Time) []Window {
+	// Cada punto abre una sesión propia; el operador las fusiona si se solapan
+	return []Window{{Start: t, End: t.Add(sw.Gap)}}
+}
+
+func (sw SessionWindows) Mergeable() bool { return true }
+
+func (sw SessionWindows) Validate() error {
+	if sw.Gap <= 0 {
+		return fmt.Errorf("%w: session gap must be > 0, got %s", ErrInvalidWindow, sw.Gap)
+	}
+	return nil
+}
+
+// ============================================================================
+// AGREGADORES
+// ============================================================================
+
+// Aggregator calcula un valor a partir de los valores de una ventana
+type Aggregator struct {
+	Name string
+	Fn   func(values []float64) float64
+}
+
+func CountAgg() Aggregator {
+	return Aggregator{Name: "count", Fn: func(values []float64) float64 {
+		return float64(len(values))
+	}}
+}
+
+func SumAgg() Aggregator {
+	return Aggregator{Name: "sum", Fn: sum}
+}
+
+func MinAgg() Aggregator {
+	return Aggregator{Name: "min", Fn: func(values []float64) float64 {
+		min := math.Inf(1)
+		for _, v := range values {
+			min = math.Min(min, v)
+		}
+		return min
+	}}
+}
+
+func MaxAgg() Aggregator {
+	return Aggregator{Name: "max", Fn: func(values []float64) float64 {
+		max := math.Inf(-1)
+		for _, v := range values {
+			max = math.Max(max, v)
+		}
+		return max
+	}}
+}
+
+func AvgAgg() Aggregator {
+	return Aggregator{Name: "avg", Fn: func(values []float64) float64 {
+		if len(values) == 0 {
+			return 0
+		}
+		return sum(values) / float64(len(values))
+	}}
+}
+
+// PercentileAgg usa el método nearest-rank (p entre 0 y 100)
+func PercentileAgg(p float64) Aggregator {
+	return Aggregator{Name: fmt.Sprintf("p%g", p), Fn: func(values []float64) float64 {
+		if len(values) == 0 {
+			return 0
+		}
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		if rank > len(sorted) {
+			rank = len(sorted)
+		}
+		return sorted[rank-1]
+	}}
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// ============================================================================
+// OPERADOR DE VENTANAS
+// ============================================================================
+
+// WindowResult es lo que la etapa emite aguas abajo
+type WindowResult struct {
+	Window  Window
+	Count   int
+	Results map[string]float64
+}
+
+type WindowConfig struct {
+	Assigner        WindowAssigner
+	Aggregators     []Aggregator
+	AllowedLateness time.Duration
+	// Late recibe los puntos descartados por llegar tarde (opcional).
+	// El envío no bloquea: si nadie lo lee y el buffer está lleno, el
+	// punto se pierde. La etapa no lo cierra: el dueño del channel es
+	// quien lo creó.
+	Late chan<- DataPoint
+}
+
+// windowStage es una etapa de pipeline: consume DataPoints y emite
+// WindowResults ordenados por inicio de ventana. Valida la configuración
+// antes de arrancar la goroutine: una ventana mal configurada falla acá
+// en lugar de colgar el pipeline.
+func windowStage(input <-chan DataPoint, cfg WindowConfig) (<-chan WindowResult, error) {
+	if cfg.Assigner == nil {
+		return nil, fmt.Errorf("%w: no assigner", ErrInvalidWindow)
+	}
+	if err := cfg.Assigner.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.AllowedLateness < 0 {
+		return nil, fmt.Errorf("%w: allowed lateness must be >= 0, got %s", ErrInvalidWindow, cfg.AllowedLateness)
+	}
+
+	output := make(chan WindowResult)
+
+	go func() {
+		defer close(output)
+
+		open := make(map[Window][]float64)
+		var maxEventTime, watermark time.Time
+
+		// emit envía (en orden) las ventanas cuyo fin ya pasó el watermark
+		emit := func(all bool) {
+			var ready []Window
+			for w := range open {
+				if all || !w.End.After(watermark) {
+					ready = append(ready, w)
+				}
+			}
+			sort.Slice(ready, func(i, j int) bool {
+				return ready[i].Start.Before(ready[j].Start)
+			})
+			for _, w := range ready {
+				output <- aggregate(w, open[w], cfg.Aggregators)
+				delete(open, w)
+			}
+		}
+
+		for point := range input {
+			accepted := false
+			for _, w := range cfg.Assigner.AssignWindows(point.Time) {
+				// Una sesión se fusiona antes de decidir si es tarde: el punto
+				// puede caer detrás del watermark y aun así tocar una sesión
+				// abierta, que la extiende hacia atrás
+				if cfg.Assigner.Mergeable() {
+					w = mergeSessions(open, w)
+				}
+				if !watermark.IsZero() && !w.End.After(watermark) {
+					delete(open, w) // Esta ventana ya se emitió
+					continue
+				}
+				open[w] = append(open[w], point.Value)
+				accepted = true
+			}
+
+			if !accepted && cfg.Late != nil {
+				select {
+				case cfg.Late <- point:
+				default: // Nadie lo lee: se pierde en lugar de frenar la etapa
+				}
+			}
+
+			// Avanzar watermark (nunca retrocede)
+			if point.Time.After(maxEventTime) {
+				maxEventTime = point.Time
+				if wm := maxEventTime.Add(-cfg.AllowedLateness); wm.After(watermark) {
+					watermark = wm
+					emit(false)
+				}
+			}
+		}
+
+		// Input cerrado: no llegarán más datos, emitir todo lo pendiente
+		emit(true)
+	}()
+
+	return output, nil
+}
+
+// mergeSessions fusiona w con todas las sesiones abiertas que se solapan
+// (repitiendo mientras la ventana crezca) y devuelve la ventana resultante
+func mergeSessions(open map[Window][]float64, w Window) Window {
+	var values []float64
+	for merged := true; merged; {
+		merged = false
+		for existing, vals := range open {
+			if existing.Start.Before(w.End) && w.Start.Before(existing.End) {
+				if existing.Start.Before(w.Start) {
+					w.Start = existing.Start
+				}
+				if existing.End.After(w.End) {
+					w.End = existing.End
+				}
+				values = append(values, vals...)
+				delete(open, existing)
+				merged = true
+			}
+		}
+	}
+	open[w] = values
+	return w
+}
+
+func aggregate(w Window, values []float64, aggregators []Aggregator) WindowResult {
+	results := make(map[string]float64, len(aggregators))
+	for _, agg := range aggregators {
+		results[agg.Name] = agg.Fn(values)
+	}
+	return WindowResult{Window: w, Count: len(values), Results: results}
+}
+
+// ============================================================================
+// EJEMPLO: TELEMETRÍA CON VENTANAS
+// ============================================================================
+
+func windowedAggregationPipeline() {
+	fmt.Println("=== Windowed Aggregation Pipeline ===")
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Offsets en segundos; algunos llegan desordenados y uno llega tarde
+	offsets := []int{0, 2, 1, 4, 6, 5, 11, 12, 3, 14, 25, 26, 40}
+
+	source := func() <-chan DataPoint {
+		out := make(chan DataPoint)
+		go func() {
+			defer close(out)
+			for i, off := range offsets {
+				out <- DataPoint{
+					Value: float64(i + 1),
+					Time:  base.Add(time.Duration(off) * time.Second),
+				}
+			}
+		}()
+		return out
+	}
+
+	aggregators := []Aggregator{CountAgg(), SumAgg(), MinAgg(), MaxAgg(), AvgAgg(), PercentileAgg(95)}
+
+	configs := []struct {
+		name     string
+		assigner WindowAssigner
+	}{
+		{"Tumbling 5s", TumblingWindows{Size: 5 * time.Second}},
+		{"Sliding 10s/5s", SlidingWindows{Size: 10 * time.Second, Slide: 5 * time.Second}},
+		{"Session gap 5s", SessionWindows{Gap: 5 * time.Second}},
+	}
+
+	for _, c := range configs {
+		fmt.Printf("-- %s --\n", c.name)
+
+		late := make(chan DataPoint, len(offsets))
+		results, err := windowStage(source(), WindowConfig{
+			Assigner:        c.assigner,
+			Aggregators:     aggregators,
+			AllowedLateness: 2 * time.Second,
+			Late:            late,
+		})
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+
+		for r := range results {
+			fmt.Printf("%s count=%d sum=%.0f min=%.0f max=%.0f avg=%.2f p95=%.0f\n",
+				r.Window, r.Count, r.Results["sum"], r.Results["min"],
+				r.Results["max"], r.Results["avg"], r.Results["p95"])
+		}
+		close(late)
+		for p := range late {
+			fmt.Printf("Late data dropped: value=%.0f time=%s\n", p.Value, p.Time.Format("15:04:05"))
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func at(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+
+// span describe una ventana como "start-end" en segundos desde base
+func span(w Window) string {
+	return fmt.Sprintf("%g-%g", w.Start.Sub(base).Seconds(), w.End.Sub(base).Seconds())
+}
+
+func spans(windows []Window) string {
+	var out []string
+	for _, w := range windows {
+		out = append(out, span(w))
+	}
+	return strings.Join(out, " ")
+}
+
+// runWindows manda los puntos (segundo, valor) por la etapa y devuelve los
+// resultados como "start-end:count" y los valores que llegaron tarde
+func runWindows(t *testing.T, assigner WindowAssigner, lateness time.Duration, points [][2]int) (string, []float64) {
+	t.Helper()
+	input := make(chan DataPoint)
+	late := make(chan DataPoint, len(points))
+	results, err := windowStage(input, WindowConfig{
+		Assigner:        assigner,
+		Aggregators:     []Aggregator{SumAgg()},
+		AllowedLateness: lateness,
+		Late:            late,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer close(input)
+		for _, p := range points {
+			input <- DataPoint{Value: float64(p[1]), Time: at(p[0])}
+		}
+	}()
+
+	var got []string
+	for r := range results {
+		got = append(got, fmt.Sprintf("%s:%d", span(r.Window), r.Count))
+	}
+	close(late)
+	var dropped []float64
+	for p := range late {
+		dropped = append(dropped, p.Value)
+	}
+	return strings.Join(got, " "), dropped
+}
+
+func TestAssignWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		assigner WindowAssigner
+		sec      int
+		want     string
+	}{
+		{"tumbling start", TumblingWindows{Size: 5 * time.Second}, 0, "0-5"},
+		{"tumbling middle", TumblingWindows{Size: 5 * time.Second}, 7, "5-10"},
+		{"tumbling end is exclusive", TumblingWindows{Size: 5 * time.Second}, 5, "5-10"},
+		{"sliding two windows", SlidingWindows{Size: 10 * time.Second, Slide: 5 * time.Second}, 7, "5-15 0-10"},
+		{"sliding on boundary", SlidingWindows{Size: 10 * time.Second, Slide: 5 * time.Second}, 10, "10-20 5-15"},
+		{"sliding slide == size", SlidingWindows{Size: 5 * time.Second, Slide: 5 * time.Second}, 7, "5-10"},
+		{"sliding three windows", SlidingWindows{Size: 3 * time.Second, Slide: time.Second}, 4, "4-7 3-6 2-5"},
+		{"session", SessionWindows{Gap: 5 * time.Second}, 3, "3-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spans(tt.assigner.AssignWindows(at(tt.sec))); got != tt.want {
+				t.Errorf("windows = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowStageRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  WindowConfig
+	}{
+		{"no assigner", WindowConfig{}},
+		{"tumbling zero size", WindowConfig{Assigner: TumblingWindows{}}},
+		{"sliding zero size", WindowConfig{Assigner: SlidingWindows{Slide: time.Second}}},
+		{"sliding zero slide", WindowConfig{Assigner: SlidingWindows{Size: time.Second}}},
+		{"sliding negative slide", WindowConfig{Assigner: SlidingWindows{Size: time.Second, Slide: -time.Second}}},
+		{"sliding slide > size", WindowConfig{Assigner: SlidingWindows{Size: time.Second, Slide: 2 * time.Second}}},
+		{"session zero gap", WindowConfig{Assigner: SessionWindows{}}},
+		{"negative lateness", WindowConfig{Assigner: TumblingWindows{Size: time.Second}, AllowedLateness: -time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := windowStage(make(chan DataPoint), tt.cfg); !errors.Is(err, ErrInvalidWindow) {
+				t.Errorf("err = %v, want ErrInvalidWindow", err)
+			}
+		})
+	}
+}
+
+func TestWindowStage(t *testing.T) {
+	tests := []struct {
+		name     string
+		assigner WindowAssigner
+		lateness time.Duration
+		points   [][2]int // {segundo, valor}
+		want     string
+		late     []float64
+	}{
+		{
+			name:     "tumbling in order",
+			assigner: TumblingWindows{Size: 5 * time.Second},
+			points:   [][2]int{{0, 1}, {4, 2}, {5, 3}, {12, 4}},
+			want:     "0-5:2 5-10:1 10-15:1",
+		},
+		{
+			name:     "out of order within lateness",
+			assigner: TumblingWindows{Size: 5 * time.Second},
+			lateness: 2 * time.Second,
+			points:   [][2]int{{0, 1}, {6, 2}, {3, 3}, {12, 4}},
+			want:     "0-5:2 5-10:1 10-15:1",
+		},
+		{
+			name:     "late data is dropped",
+			assigner: TumblingWindows{Size: 5 * time.Second},
+			points:   [][2]int{{0, 1}, {6, 2}, {3, 3}, {12, 4}},
+			want:     "0-5:1 5-10:1 10-15:1",
+			late:     []float64{3},
+		},
+		{
+			name:     "sliding late in one window only",
+			assigner: SlidingWindows{Size: 10 * time.Second, Slide: 5 * time.Second},
+			points:   [][2]int{{0, 1}, {10, 2}, {7, 3}},
+			// 7 cae en [0,10), ya emitida, y en [5,15), todavía abierta
+			want: "-5-5:1 0-10:1 5-15:2 10-20:1",
+		},
+		{
+			name:     "sessions split by gap",
+			assigner: SessionWindows{Gap: 5 * time.Second},
+			points:   [][2]int{{0, 1}, {3, 2}, {20, 3}, {22, 4}},
+			want:     "0-8:2 20-27:2",
+		},
+		{
+			name:     "point bridges two sessions",
+			assigner: SessionWindows{Gap: 5 * time.Second},
+			lateness: 10 * time.Second,
+			points:   [][2]int{{0, 1}, {8, 2}, {4, 3}},
+			want:     "0-13:3",
+		},
+		{
+			name:     "late point joins an open session",
+			assigner: SessionWindows{Gap: 5 * time.Second},
+			lateness: 2 * time.Second,
+			// Watermark = 10: [3,8) está detrás, pero se fusiona con [6,11),
+			// que sigue abierta
+			points: [][2]int{{0, 1}, {6, 2}, {12, 3}, {3, 4}},
+			want:   "0-5:1 3-11:2 12-17:1",
+		},
+		{
+			name:     "late point with no open session",
+			assigner: SessionWindows{Gap: 2 * time.Second},
+			points:   [][2]int{{0, 1}, {10, 2}, {5, 3}},
+			want:     "0-2:1 10-12:1",
+			late:     []float64{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, late := runWindows(t, tt.assigner, tt.lateness, tt.points)
+			if got != tt.want {
+				t.Errorf("results = %s, want %s", got, tt.want)
+			}
+			if fmt.Sprint(late) != fmt.Sprint(tt.late) {
+				t.Errorf("late = %v, want %v", late, tt.late)
+			}
+		})
+	}
+}
+
+func TestLateChannelDoesNotBlock(t *testing.T) {
+	input := make(chan DataPoint, 3)
+	input <- DataPoint{Value: 1, Time: at(0)}
+	input <- DataPoint{Value: 2, Time: at(10)}
+	input <- DataPoint{Value: 3, Time: at(1)} // Tarde, y nadie lee Late
+	close(input)
+
+	results, err := windowStage(input, WindowConfig{
+		Assigner: TumblingWindows{Size: 5 * time.Second},
+		Late:     make(chan DataPoint),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range results {
+			n++
+		}
+		done <- n
+	}()
+	select {
+	case n := <-done:
+		if n != 2 {
+			t.Errorf("got %d windows, want 2", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stage blocked on an undrained Late channel")
+	}
+}
+
+func TestAggregators(t *testing.T) {
+	values := []float64{15, 20, 35, 40, 50}
+	tests := []struct {
+		agg    Aggregator
+		values []float64
+		want   float64
+	}{
+		{CountAgg(), values, 5},
+		{SumAgg(), values, 160},
+		{MinAgg(), values, 15},
+		{MaxAgg(), values, 50},
+		{AvgAgg(), values, 32},
+		{AvgAgg(), nil, 0},
+		// Nearest-rank: ceil(p/100 * n)
+		{PercentileAgg(0), values, 15},
+		{PercentileAgg(5), values, 15},
+		{PercentileAgg(30), values, 20},
+		{PercentileAgg(40), values, 20},
+		{PercentileAgg(50), values, 35},
+		{PercentileAgg(95), values, 50},
+		{PercentileAgg(100), values, 50},
+		{PercentileAgg(50), []float64{3, 1, 2}, 2},
+		{PercentileAgg(99), []float64{7}, 7},
+		{PercentileAgg(50), nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %v", tt.agg.Name, tt.values), func(t *testing.T) {
+			if got := tt.agg.Fn(tt.values); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("%s = %v, want %v", tt.agg.Name, got, tt.want)
+			}
+		})
+	}
+	if got := PercentileAgg(95).Name; got != "p95" {
+		t.Errorf("name = %q", got)
+	}
+}
@@ -0,0 +1,221 @@
+// Package pubsub implementa un bus de mensajes en proceso, tipado y por topics.
+//
+// concurrency/channels y concurrency/goroutines hacen fan-out a mano con
+// channels; aquí queda como primitiva reutilizable: quien publica no conoce
+// a los suscriptores y cada suscriptor elige cómo recibe.
+// Similar a EventBus/AsyncEventBus de Guava o ApplicationEventPublisher de
+// Spring en Java.
+//
+//   - Topics con wildcards: "user.*", "orders.>" (ver topic.go)
+//   - Entrega Sync (dentro de Publish) o Async (cola + goroutine por suscriptor)
+//   - Cola acotada por suscriptor con política de desborde: Block,
+//     DropNewest o DropOldest
+//   - Unsubscribe y Close ordenado: las colas se vacían antes de terminar
+//
+// El orden de entrega se mantiene por suscriptor: cada uno recibe los
+// mensajes en el orden en que se publicaron (desde una misma goroutine).
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrClosed lo devuelven Publish y Subscribe después de Close
+var ErrClosed = errors.New("pubsub: bus is closed")
+
+// Message es lo que recibe un handler
+type Message[T any] struct {
+	Topic       string
+	Payload     T
+	PublishedAt time.Time
+}
+
+// Handler procesa un mensaje. Con entrega Sync el error vuelve a Publish;
+// con Async va al ErrorHandler del bus.
+type Handler[T any] func(ctx context.Context, msg Message[T]) error
+
+// ErrorHandler recibe los errores (y panics) de los handlers async
+type ErrorHandler func(pattern, topic string, err error)
+
+type options struct {
+	buffer  int
+	onError ErrorHandler
+}
+
+// Option configura un Bus (functional options, ver patterns/functional_options)
+type Option func(*options)
+
+// WithDefaultBuffer define el tamaño de cola de los suscriptores async que
+// no usan WithBuffer (64 por defecto)
+func WithDefaultBuffer(n int) Option {
+	return func(o *options) {
+		o.buffer = n
+	}
+}
+
+// WithErrorHandler reemplaza el handler de errores async (por defecto, log)
+func WithErrorHandler(fn ErrorHandler) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// Bus reparte mensajes de tipo T. El valor cero no es usable: crear con New.
+type Bus[T any] struct {
+	opts options
+
+	// ctx llega a los handlers async; se cancela si Close se queda sin tiempo
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	subs   []*Subscription[T] // en orden de suscripción
+	closed bool
+}
+
+// New crea un Bus vacío
+func New[T any](opts ...Option) *Bus[T] {
+	o := options{
+		buffer: 64,
+		onError: func(pattern, topic string, err error) {
+			log.Printf("pubsub: subscriber %q failed on %q: %v", pattern, topic, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bus[T]{opts: o, ctx: ctx, cancel: cancel}
+}
+
+// Subscribe registra h para los topics que cumplen pattern
+func (b *Bus[T]) Subscribe(pattern string, h Handler[T], opts ...SubscribeOption) (*Subscription[T], error) {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	o := subscribeOptions{buffer: b.opts.buffer}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.buffer = max(o.buffer, 1) // DropOldest necesita al menos un lugar
+
+	s := &Subscription[T]{
+		bus:      b,
+		pattern:  pattern,
+		segments: segments,
+		handler:  h,
+		opts:     o,
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	s.start()
+	b.subs = append(b.subs, s)
+	return s, nil
+}
+
+// Publish entrega payload a los suscriptores de topic. Los Sync se ejecutan
+// aquí mismo y sus errores se devuelven juntos; a los Async solo se les encola.
+// Con un suscriptor Block lleno, Publish espera hasta que haya lugar o hasta
+// que se cancele ctx.
+func (b *Bus[T]) Publish(ctx context.Context, topic string, payload T) error {
+	segments, err := parseTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	var targets []*Subscription[T]
+	for _, s := range b.subs {
+		if matches(s.segments, segments) {
+			targets = append(targets, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	msg := Message[T]{Topic: topic, Payload: payload, PublishedAt: time.Now()}
+	var errs []error
+	for _, s := range targets {
+		if s.opts.delivery == Async {
+			if err := s.enqueue(ctx, msg); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+			continue
+		}
+		select {
+		case <-s.stopping: // Se desuscribió después de tomar el snapshot
+			continue
+		default:
+		}
+		if err := s.deliver(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close deja de aceptar mensajes y suscripciones y espera a que los
+// suscriptores async vacíen sus colas. Si ctx vence antes, cancela el ctx que
+// reciben los handlers y devuelve ctx.Err() sin esperar más.
+func (b *Bus[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.stop()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for _, s := range subs {
+			<-s.done
+		}
+	}()
+
+	select {
+	case <-drained:
+		b.cancel()
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+func (b *Bus[T]) remove(s *Subscription[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, sub := range b.subs {
+		if sub == s {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *Bus[T]) onError(pattern string, msg Message[T], err error) {
+	b.opts.onError(pattern, msg.Topic, err)
+}
@@ -0,0 +1,349 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector guarda los payloads recibidos; es seguro entre goroutines
+type collector struct {
+	mu  sync.Mutex
+	got []int
+}
+
+func (c *collector) handle(ctx context.Context, msg Message[int]) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.got = append(c.got, msg.Payload)
+	return nil
+}
+
+func (c *collector) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprint(c.got)
+}
+
+// gated es un handler que avisa en started y no termina hasta que se cierra
+// release: deja la goroutine del suscriptor ocupada para llenar su cola
+type gated struct {
+	collector
+	started chan int
+	release chan struct{}
+}
+
+func newGated() *gated {
+	return &gated{started: make(chan int, 100), release: make(chan struct{})}
+}
+
+func (g *gated) handle(ctx context.Context, msg Message[int]) error {
+	g.started <- msg.Payload
+	<-g.release
+	return g.collector.handle(ctx, msg)
+}
+
+func publishAll(t *testing.T, bus *Bus[int], topic string, payloads ...int) {
+	t.Helper()
+	for _, p := range payloads {
+		if err := bus.Publish(context.Background(), topic, p); err != nil {
+			t.Fatalf("Publish(%d): %v", p, err)
+		}
+	}
+}
+
+func closeBus(t *testing.T, bus *Bus[int]) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestWildcardRouting(t *testing.T) {
+	bus := New[int]()
+	subs := map[string]*collector{"user.*": {}, "user.created": {}, "orders.>": {}, ">": {}}
+	for pattern, c := range subs {
+		if _, err := bus.Subscribe(pattern, c.handle); err != nil {
+			t.Fatal(err)
+		}
+	}
+	publishAll(t, bus, "user.created", 1)
+	publishAll(t, bus, "user.deleted", 2)
+	publishAll(t, bus, "orders.eu.paid", 3)
+	publishAll(t, bus, "orders", 4)
+	closeBus(t, bus)
+
+	want := map[string]string{"user.*": "[1 2]", "user.created": "[1]", "orders.>": "[3]", ">": "[1 2 3 4]"}
+	for pattern, c := range subs {
+		if c.String() != want[pattern] {
+			t.Errorf("%s received %v, want %s", pattern, c, want[pattern])
+		}
+	}
+
+	if _, err := bus.Subscribe("a..b", (&collector{}).handle); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Subscribe with an invalid pattern err = %v", err)
+	}
+	if err := New[int]().Publish(context.Background(), "user.*", 1); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("Publish to a wildcard topic err = %v", err)
+	}
+}
+
+func TestSyncDeliveryReturnsErrors(t *testing.T) {
+	bus := New[int]()
+	defer closeBus(t, bus)
+	var order []string
+	bus.Subscribe("a.*", func(ctx context.Context, msg Message[int]) error {
+		order = append(order, "first")
+		return errors.New("boom")
+	}, WithSync())
+	bus.Subscribe("a.b", func(ctx context.Context, msg Message[int]) error {
+		order = append(order, "second")
+		panic("broken handler")
+	}, WithSync())
+	ok, _ := bus.Subscribe("a.b", func(ctx context.Context, msg Message[int]) error {
+		order = append(order, "third")
+		return nil
+	}, WithSync())
+
+	// Un handler que falla no impide que corran los demás
+	err := bus.Publish(context.Background(), "a.b", 1)
+	if err == nil || !strings.Contains(err.Error(), "boom") || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("Publish err = %v, want both handler errors", err)
+	}
+	if fmt.Sprint(order) != "[first second third]" {
+		t.Errorf("handlers ran as %v", order)
+	}
+	if stats := ok.Stats(); stats.Delivered != 1 || stats.Failed != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestAsyncDeliveryKeepsOrderAndReportsErrors(t *testing.T) {
+	var mu sync.Mutex
+	var reported []string
+	bus := New[int](WithErrorHandler(func(pattern, topic string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, pattern+" "+topic+": "+err.Error())
+	}))
+
+	c := &collector{}
+	bus.Subscribe("t", c.handle)
+	failing, _ := bus.Subscribe("t", func(ctx context.Context, msg Message[int]) error {
+		if msg.Payload%2 == 0 {
+			return errors.New("even")
+		}
+		return nil
+	})
+
+	var want []int
+	for i := 0; i < 100; i++ {
+		want = append(want, i)
+	}
+	publishAll(t, bus, "t", want...)
+	closeBus(t, bus)
+
+	if c.String() != fmt.Sprint(want) {
+		t.Errorf("received %v, want all messages in publish order", c)
+	}
+	if len(reported) != 50 || reported[0] != "t t: even" {
+		t.Errorf("reported %d errors: %v", len(reported), reported)
+	}
+	if stats := failing.Stats(); stats.Delivered != 100 || stats.Failed != 50 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestBlockAppliesBackpressure(t *testing.T) {
+	bus := New[int]()
+	g := newGated()
+	sub, _ := bus.Subscribe("t", g.handle, WithBuffer(1))
+
+	publishAll(t, bus, "t", 1)
+	<-g.started // El handler tiene el 1: la cola está vacía
+	publishAll(t, bus, "t", 2)
+
+	// Cola llena: Publish espera hasta que vence su ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.Publish(ctx, "t", 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Publish on a full queue err = %v, want DeadlineExceeded", err)
+	}
+
+	// Con lugar en la cola, el Publish bloqueado sigue
+	published := make(chan error)
+	go func() { published <- bus.Publish(context.Background(), "t", 4) }()
+	select {
+	case err := <-published:
+		t.Fatalf("Publish returned %v before the queue had room", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(g.release)
+	if err := <-published; err != nil {
+		t.Fatal(err)
+	}
+	closeBus(t, bus)
+
+	if g.String() != "[1 2 4]" {
+		t.Errorf("received %v", g)
+	}
+	if stats := sub.Stats(); stats.Dropped != 0 || stats.Queued != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		want   string
+	}{
+		{DropNewest, "[1 2 3]"},
+		{DropOldest, "[1 4 5]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			bus := New[int]()
+			g := newGated()
+			sub, _ := bus.Subscribe("t", g.handle, WithBuffer(2), WithOverflow(tt.policy))
+
+			publishAll(t, bus, "t", 1)
+			<-g.started
+			// Cola de 2 con el handler ocupado: 2 de los 4 se descartan
+			// y Publish nunca espera
+			publishAll(t, bus, "t", 2, 3, 4, 5)
+			if stats := sub.Stats(); stats.Dropped != 2 || stats.Queued != 2 {
+				t.Errorf("stats = %+v", stats)
+			}
+			close(g.release)
+			closeBus(t, bus)
+
+			if g.String() != tt.want {
+				t.Errorf("received %v, want %s", g, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeDrainsQueue(t *testing.T) {
+	bus := New[int]()
+	defer closeBus(t, bus)
+	g := newGated()
+	sub, _ := bus.Subscribe("t", g.handle)
+	other := &collector{}
+	bus.Subscribe("t", other.handle, WithSync())
+
+	publishAll(t, bus, "t", 1, 2, 3)
+	<-g.started
+	sub.Unsubscribe()
+	sub.Unsubscribe() // Idempotente
+	publishAll(t, bus, "t", 4)
+
+	select {
+	case <-sub.Done():
+		t.Fatal("Done closed while the queue still had messages")
+	default:
+	}
+	close(g.release)
+	<-sub.Done()
+
+	if g.String() != "[1 2 3]" {
+		t.Errorf("unsubscribed received %v, want the queued messages only", g)
+	}
+	if other.String() != "[1 2 3 4]" {
+		t.Errorf("other subscriber received %v", other)
+	}
+}
+
+func TestUnsubscribeReleasesBlockedPublish(t *testing.T) {
+	bus := New[int]()
+	defer closeBus(t, bus)
+	g := newGated()
+	defer close(g.release)
+	sub, _ := bus.Subscribe("t", g.handle, WithBuffer(1))
+	publishAll(t, bus, "t", 1)
+	<-g.started
+	publishAll(t, bus, "t", 2)
+
+	published := make(chan error)
+	go func() { published <- bus.Publish(context.Background(), "t", 3) }()
+	time.Sleep(10 * time.Millisecond)
+	sub.Unsubscribe()
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Errorf("Publish err = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish still blocked after Unsubscribe")
+	}
+}
+
+func TestUnsubscribeFromHandler(t *testing.T) {
+	for name, opt := range map[string]SubscribeOption{"sync": WithSync(), "async": WithBuffer(4)} {
+		bus := New[int]()
+		c := &collector{}
+		var sub *Subscription[int]
+		sub, _ = bus.Subscribe("t", func(ctx context.Context, msg Message[int]) error {
+			c.handle(ctx, msg)
+			sub.Unsubscribe()
+			return nil
+		}, opt)
+		publishAll(t, bus, "t", 1)
+		<-sub.Done()
+		publishAll(t, bus, "t", 2)
+		closeBus(t, bus)
+		if c.String() != "[1]" {
+			t.Errorf("%s received %v", name, c)
+		}
+	}
+}
+
+func TestCloseDrainsAndRejects(t *testing.T) {
+	bus := New[int]()
+	c := &collector{}
+	bus.Subscribe("t", func(ctx context.Context, msg Message[int]) error {
+		time.Sleep(time.Millisecond)
+		return c.handle(ctx, msg)
+	})
+	publishAll(t, bus, "t", 1, 2, 3, 4, 5)
+	closeBus(t, bus)
+	closeBus(t, bus) // Idempotente
+
+	if c.String() != "[1 2 3 4 5]" {
+		t.Errorf("received %v, want the whole queue before Close returns", c)
+	}
+	if err := bus.Publish(context.Background(), "t", 6); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close err = %v", err)
+	}
+	if _, err := bus.Subscribe("t", c.handle); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe after Close err = %v", err)
+	}
+}
+
+func TestCloseTimeoutCancelsHandlers(t *testing.T) {
+	bus := New[int]()
+	canceled := make(chan struct{})
+	bus.Subscribe("t", func(ctx context.Context, msg Message[int]) error {
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	}, WithBuffer(1))
+	publishAll(t, bus, "t", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close err = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler ctx was not canceled")
+	}
+}
@@ -0,0 +1,221 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Delivery elige cómo recibe los mensajes un suscriptor
+type Delivery int
+
+const (
+	// Async: cada suscriptor tiene su cola y su goroutine. Publish solo
+	// encola; un handler lento no frena a los demás (hasta que su cola se llena).
+	Async Delivery = iota
+	// Sync: el handler corre dentro de Publish, en la goroutine que publica.
+	// Sus errores vuelven a quien publicó.
+	Sync
+)
+
+// OverflowPolicy decide qué hacer cuando la cola de un suscriptor async está llena
+type OverflowPolicy int
+
+const (
+	// Block: Publish espera a que haya lugar (backpressure), o a que se
+	// cancele su ctx
+	Block OverflowPolicy = iota
+	// DropNewest: se descarta el mensaje que llega
+	DropNewest
+	// DropOldest: se descarta el mensaje más viejo de la cola para hacer lugar
+	// (útil para estados donde solo importa lo último, como un dashboard)
+	DropOldest
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+type subscribeOptions struct {
+	delivery Delivery
+	buffer   int
+	overflow OverflowPolicy
+}
+
+// SubscribeOption configura una suscripción
+type SubscribeOption func(*subscribeOptions)
+
+// WithSync entrega los mensajes dentro de Publish (ver Sync)
+func WithSync() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.delivery = Sync
+	}
+}
+
+// WithBuffer define el tamaño de la cola de un suscriptor async (mínimo 1)
+func WithBuffer(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.buffer = n
+	}
+}
+
+// WithOverflow define qué pasa cuando la cola está llena (Block por defecto)
+func WithOverflow(p OverflowPolicy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.overflow = p
+	}
+}
+
+// SubscriptionStats son contadores acumulados de una suscripción
+type SubscriptionStats struct {
+	Delivered int64 // Llamadas al handler
+	Failed    int64 // Llamadas que devolvieron error o hicieron panic
+	Dropped   int64 // Descartados por la OverflowPolicy
+	Queued    int   // Esperando en la cola ahora
+}
+
+// Subscription es el resultado de Subscribe; sirve para cancelarla
+type Subscription[T any] struct {
+	bus      *Bus[T]
+	pattern  string
+	segments []string
+	handler  Handler[T]
+	opts     subscribeOptions
+
+	queue    chan Message[T] // nil con Sync
+	stopping chan struct{}   // se cierra al empezar a detenerse
+	stopOnce sync.Once
+	done     chan struct{} // se cierra cuando la cola quedó vacía
+
+	// mu protege el cierre de queue: los que encolan toman RLock, stop toma Lock
+	mu     sync.RWMutex
+	closed bool
+
+	delivered atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+}
+
+// Pattern devuelve el patrón con que se suscribió
+func (s *Subscription[T]) Pattern() string {
+	return s.pattern
+}
+
+// Unsubscribe deja de recibir mensajes nuevos. Los que ya estaban en la cola
+// se siguen entregando; Done indica cuándo terminó. Se puede llamar más de
+// una vez y desde el propio handler.
+func (s *Subscription[T]) Unsubscribe() {
+	s.bus.remove(s)
+	s.stop()
+}
+
+// Done se cierra cuando la suscripción terminó de entregar su cola tras
+// Unsubscribe o Bus.Close. No esperarlo desde el propio handler (deadlock).
+func (s *Subscription[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Subscription[T]) Stats() SubscriptionStats {
+	return SubscriptionStats{
+		Delivered: s.delivered.Load(),
+		Failed:    s.failed.Load(),
+		Dropped:   s.dropped.Load(),
+		Queued:    len(s.queue),
+	}
+}
+
+func (s *Subscription[T]) start() {
+	if s.opts.delivery == Sync {
+		close(s.done)
+		return
+	}
+	s.queue = make(chan Message[T], s.opts.buffer)
+	go func() {
+		defer close(s.done)
+		for msg := range s.queue {
+			if err := s.deliver(s.bus.ctx, msg); err != nil {
+				s.bus.onError(s.pattern, msg, err)
+			}
+		}
+	}()
+}
+
+// stop cierra la cola: primero avisa a los Publish bloqueados (stopping) para
+// que suelten el RLock, después cierra queue con el Lock
+func (s *Subscription[T]) stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopping)
+		s.mu.Lock()
+		s.closed = true
+		if s.queue != nil {
+			close(s.queue)
+		}
+		s.mu.Unlock()
+	})
+}
+
+// enqueue agrega msg a la cola según la OverflowPolicy. Solo devuelve error
+// si ctx se cancela mientras espera con Block.
+func (s *Subscription[T]) enqueue(ctx context.Context, msg Message[T]) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil
+	}
+
+	switch s.opts.overflow {
+	case DropNewest:
+		select {
+		case s.queue <- msg:
+		default:
+			s.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.queue <- msg:
+				return nil
+			default:
+			}
+			// Llena: sacar el más viejo (si el consumidor no se adelantó) y reintentar
+			select {
+			case <-s.queue:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.queue <- msg:
+		case <-s.stopping:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// deliver llama al handler convirtiendo un panic en error, para que un
+// suscriptor roto no tire abajo al que publica ni a su goroutine
+func (s *Subscription[T]) deliver(ctx context.Context, msg Message[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("pubsub: handler for %q panicked: %v", s.pattern, r)
+		}
+		s.delivered.Add(1)
+		if err != nil {
+			s.failed.Add(1)
+		}
+	}()
+	return s.handler(ctx, msg)
+}
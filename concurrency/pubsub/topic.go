@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"errors"
+	"strings"
+)
+
+// ============================================================================
+// TOPICS Y WILDCARDS
+// ============================================================================
+// Un topic son segmentos separados por puntos: "user.created", "orders.eu.paid".
+// Los patrones de suscripción admiten dos comodines (como NATS):
+//
+//	*  exactamente un segmento:        "user.*"   -> user.created, user.deleted
+//	>  uno o más segmentos, al final:  "orders.>" -> orders.eu.paid, orders.new
+//
+// ">" solo suscribe a todo lo publicado. Los topics publicados no pueden
+// contener comodines.
+// ============================================================================
+
+var (
+	ErrInvalidTopic   = errors.New("pubsub: invalid topic")
+	ErrInvalidPattern = errors.New("pubsub: invalid subscription pattern")
+)
+
+func parseTopic(topic string) ([]string, error) {
+	segments := strings.Split(topic, ".")
+	for _, s := range segments {
+		if s == "" || s == "*" || s == ">" {
+			return nil, ErrInvalidTopic
+		}
+	}
+	return segments, nil
+}
+
+func parsePattern(pattern string) ([]string, error) {
+	segments := strings.Split(pattern, ".")
+	for i, s := range segments {
+		if s == "" || (s == ">" && i != len(segments)-1) {
+			return nil, ErrInvalidPattern
+		}
+	}
+	return segments, nil
+}
+
+// matches indica si el topic (ya separado en segmentos) cumple el patrón
+func matches(pattern, topic []string) bool {
+	for i, p := range pattern {
+		switch {
+		case p == ">":
+			return len(topic) > i
+		case i >= len(topic):
+			return false
+		case p != "*" && p != topic[i]:
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"user.created", "user.created", true},
+		{"user.created", "user.deleted", false},
+		{"user.created", "user.created.v2", false},
+		{"user.*", "user.created", true},
+		{"user.*", "user", false},
+		{"user.*", "user.created.v2", false},
+		{"*.created", "order.created", true},
+		{"*.*", "a.b", true},
+		{"*", "a.b", false},
+		{"orders.>", "orders.new", true},
+		{"orders.>", "orders.eu.paid", true},
+		// ">" pide al menos un segmento más
+		{"orders.>", "orders", false},
+		{"orders.*.>", "orders.eu.paid.v2", true},
+		{"orders.*.>", "orders.eu", false},
+		{">", "anything", true},
+		{">", "a.b.c", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.topic, func(t *testing.T) {
+			pattern, err := parsePattern(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			topic, err := parseTopic(tt.topic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matches(pattern, topic); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidTopicsAndPatterns(t *testing.T) {
+	for _, topic := range []string{"", "user.", ".user", "user..created", "user.*", "orders.>"} {
+		if _, err := parseTopic(topic); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("parseTopic(%q) err = %v, want ErrInvalidTopic", topic, err)
+		}
+	}
+	for _, pattern := range []string{"", "user.", "user..created", ">.user", "orders.>.paid"} {
+		if _, err := parsePattern(pattern); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("parsePattern(%q) err = %v, want ErrInvalidPattern", pattern, err)
+		}
+	}
+}
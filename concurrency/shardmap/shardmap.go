@@ -0,0 +1,271 @@
+// Package shardmap implementa un map concurrente genérico dividido en shards.
+//
+// SafeMap y Cache (concurrency/sync) o DataProcessor (fundamentals/packages)
+// protegen un solo map con un solo sync.RWMutex: con muchas escrituras todas
+// las goroutines compiten por el mismo lock. Aquí cada clave se asigna por
+// hash a uno de N shards, cada uno con su propio lock, así que escrituras
+// sobre claves distintas casi nunca se bloquean entre sí.
+// Similar a ConcurrentHashMap en Java (versión Java 7, con segmentos).
+//
+// Cuándo usar qué:
+//   - sync.Map: claves que se escriben una vez y se leen mucho, o conjuntos
+//     de claves disjuntos por goroutine
+//   - shardmap.Map: cargas con muchas escrituras y operaciones atómicas
+//     de leer-modificar-escribir (Compute, Upsert)
+package shardmap
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"sync"
+)
+
+// DefaultShards es el número de shards si no se usa WithShards
+const DefaultShards = 32
+
+type shard[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+	// Los shards van contiguos en un slice: el padding separa los locks de
+	// shards vecinos en líneas de cache distintas (false sharing)
+	_ [64]byte
+}
+
+// Map es un map concurrente. El valor cero no es usable: crear con New.
+type Map[K comparable, V any] struct {
+	shards []shard[K, V]
+	mask   uint64
+	hash   func(K) uint64
+}
+
+type options struct {
+	shards int
+	hasher any // func(K) uint64
+}
+
+// Option configura un Map
+type Option func(*options)
+
+// WithShards define el número de shards; se redondea a la siguiente
+// potencia de 2 para poder elegir el shard con una máscara en vez de módulo
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithHasher reemplaza la función de hash por defecto. Es obligatorio para
+// claves que no sean de un tipo básico (structs, arrays, interfaces): el
+// hash tiene que ser igual para claves ==, y eso depende del tipo.
+func WithHasher[K comparable](fn func(key K) uint64) Option {
+	return func(o *options) {
+		o.hasher = fn
+	}
+}
+
+// New crea un Map vacío
+func New[K comparable, V any](opts ...Option) *Map[K, V] {
+	o := options{shards: DefaultShards}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	n := 1
+	for n < o.shards {
+		n <<= 1
+	}
+
+	m := &Map[K, V]{
+		shards: make([]shard[K, V], n),
+		mask:   uint64(n - 1),
+	}
+	for i := range m.shards {
+		m.shards[i].m = make(map[K]V)
+	}
+
+	if o.hasher != nil {
+		fn, ok := o.hasher.(func(K) uint64)
+		if !ok {
+			panic(fmt.Sprintf("shardmap: hasher %T does not match key type %T", o.hasher, *new(K)))
+		}
+		m.hash = fn
+	} else if m.hash = defaultHasher[K](maphash.MakeSeed()); m.hash == nil {
+		panic(fmt.Sprintf("shardmap: no default hash for key type %T, use WithHasher", *new(K)))
+	}
+	return m
+}
+
+// defaultHasher elige la función de hash una sola vez según el tipo de clave:
+// maphash para strings, una mezcla de bits para números y bool, y la
+// dirección para punteros y channels (la identidad, como hace ==; nunca el
+// valor apuntado, que puede cambiar). Para otros tipos devuelve nil.
+func defaultHasher[K comparable](seed maphash.Seed) func(K) uint64 {
+	// Caminos rápidos sin reflection para los tipos más comunes
+	switch any(*new(K)).(type) {
+	case string:
+		return func(key K) uint64 { return maphash.String(seed, any(key).(string)) }
+	case int:
+		return func(key K) uint64 { return mix(uint64(any(key).(int))) }
+	case int64:
+		return func(key K) uint64 { return mix(uint64(any(key).(int64))) }
+	case uint64:
+		return func(key K) uint64 { return mix(any(key).(uint64)) }
+	}
+
+	switch reflect.TypeOf((*K)(nil)).Elem().Kind() {
+	case reflect.String:
+		return func(key K) uint64 { return maphash.String(seed, reflect.ValueOf(key).String()) }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(key K) uint64 { return mix(uint64(reflect.ValueOf(key).Int())) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(key K) uint64 { return mix(reflect.ValueOf(key).Uint()) }
+	case reflect.Bool:
+		return func(key K) uint64 {
+			if reflect.ValueOf(key).Bool() {
+				return mix(1)
+			}
+			return mix(0)
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(key K) uint64 {
+			f := reflect.ValueOf(key).Float()
+			if f == 0 {
+				f = 0 // -0 == +0: mismo shard
+			}
+			return mix(math.Float64bits(f))
+		}
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		return func(key K) uint64 { return mix(uint64(reflect.ValueOf(key).Pointer())) }
+	default:
+		return nil
+	}
+}
+
+// mix es el finalizador de SplitMix64: reparte enteros consecutivos entre shards
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func (m *Map[K, V]) shardFor(key K) *shard[K, V] {
+	return &m.shards[m.hash(key)&m.mask]
+}
+
+// ShardCount devuelve el número real de shards (potencia de 2)
+func (m *Map[K, V]) ShardCount() int {
+	return len(m.shards)
+}
+
+func (m *Map[K, V]) Get(key K) (V, bool) {
+	s := m.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+func (m *Map[K, V]) Set(key K, value V) {
+	s := m.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = value
+}
+
+// Delete elimina la clave y devuelve si existía
+func (m *Map[K, V]) Delete(key K) bool {
+	s := m.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.m[key]
+	delete(s.m, key)
+	return ok
+}
+
+// GetOrSet devuelve el valor existente (loaded=true) o guarda value
+func (m *Map[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	s := m.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.m[key]; ok {
+		return v, true
+	}
+	s.m[key] = value
+	return value, false
+}
+
+// Compute ejecuta fn con el lock del shard tomado, así que leer-modificar-
+// escribir es atómico. Si fn devuelve keep=false la clave se elimina.
+// fn no debe llamar a otros métodos del Map (deadlock si cae en el mismo shard).
+func (m *Map[K, V]) Compute(key K, fn func(current V, exists bool) (newValue V, keep bool)) (V, bool) {
+	s := m.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.m[key]
+	newValue, keep := fn(current, exists)
+	if !keep {
+		delete(s.m, key)
+		var zero V
+		return zero, false
+	}
+	s.m[key] = newValue
+	return newValue, true
+}
+
+// Upsert inserta value o, si la clave ya existe, guarda merge(existente, value).
+// Ejemplo: contadores con Upsert(k, 1, func(a, b int) int { return a + b }).
+func (m *Map[K, V]) Upsert(key K, value V, merge func(existing, value V) V) V {
+	v, _ := m.Compute(key, func(current V, exists bool) (V, bool) {
+		if exists {
+			return merge(current, value), true
+		}
+		return value, true
+	})
+	return v
+}
+
+// Len suma el tamaño de cada shard. Con escrituras concurrentes es una
+// aproximación: los shards no se bloquean todos a la vez.
+func (m *Map[K, V]) Len() int {
+	total := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		total += len(s.m)
+		s.mu.RUnlock()
+	}
+	return total
+}
+
+// Range llama a fn por cada par hasta que devuelva false.
+// Copia cada shard antes de iterarlo, así fn puede modificar el Map sin
+// deadlock; a cambio la vista es consistente por shard, no global
+// (igual que sync.Map.Range).
+func (m *Map[K, V]) Range(fn func(key K, value V) bool) {
+	type pair struct {
+		key   K
+		value V
+	}
+	var buf []pair
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		buf = buf[:0]
+		for k, v := range s.m {
+			buf = append(buf, pair{k, v})
+		}
+		s.mu.RUnlock()
+
+		for _, p := range buf {
+			if !fn(p.key, p.value) {
+				return
+			}
+		}
+	}
+}
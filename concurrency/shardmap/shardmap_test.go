@@ -0,0 +1,207 @@
+package shardmap
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestBasicOperations(t *testing.T) {
+	m := New[string, int](WithShards(5))
+	if m.ShardCount() != 8 {
+		t.Errorf("ShardCount = %d, want 8 (next power of 2)", m.ShardCount())
+	}
+
+	m.Set("a", 1)
+	if v, ok := m.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v", v, ok)
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Error("Get(missing) found a value")
+	}
+
+	if v, loaded := m.GetOrSet("a", 2); !loaded || v != 1 {
+		t.Errorf("GetOrSet(existing) = %d, %v", v, loaded)
+	}
+	if v, loaded := m.GetOrSet("b", 2); loaded || v != 2 {
+		t.Errorf("GetOrSet(new) = %d, %v", v, loaded)
+	}
+	if m.Len() != 2 {
+		t.Errorf("Len = %d, want 2", m.Len())
+	}
+
+	if !m.Delete("a") || m.Delete("a") {
+		t.Error("Delete should report true once, then false")
+	}
+	if m.Len() != 1 {
+		t.Errorf("Len after Delete = %d, want 1", m.Len())
+	}
+}
+
+func TestCompute(t *testing.T) {
+	m := New[string, int]()
+
+	v, kept := m.Compute("k", func(current int, exists bool) (int, bool) {
+		if exists {
+			t.Error("exists = true for a new key")
+		}
+		return 10, true
+	})
+	if !kept || v != 10 {
+		t.Errorf("Compute(insert) = %d, %v", v, kept)
+	}
+
+	v, _ = m.Compute("k", func(current int, exists bool) (int, bool) {
+		return current * 2, true
+	})
+	if v != 20 {
+		t.Errorf("Compute(update) = %d, want 20", v)
+	}
+
+	// keep=false elimina la clave
+	if _, kept := m.Compute("k", func(int, bool) (int, bool) { return 0, false }); kept {
+		t.Error("Compute(delete) kept the key")
+	}
+	if _, ok := m.Get("k"); ok {
+		t.Error("key still present after Compute returned keep=false")
+	}
+}
+
+func TestUpsertIsAtomic(t *testing.T) {
+	m := New[int, int](WithShards(4))
+	add := func(a, b int) int { return a + b }
+
+	const goroutines, perGoroutine = 8, 1000
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				m.Upsert(i%10, 1, add)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for k := 0; k < 10; k++ {
+		if v, _ := m.Get(k); v != goroutines*perGoroutine/10 {
+			t.Errorf("counter %d = %d, want %d", k, v, goroutines*perGoroutine/10)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	m := New[int, string]()
+	for i := 0; i < 100; i++ {
+		m.Set(i, "v")
+	}
+
+	var keys []int
+	m.Range(func(k int, v string) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Ints(keys)
+	if len(keys) != 100 || keys[0] != 0 || keys[99] != 99 {
+		t.Errorf("Range visited %d keys", len(keys))
+	}
+
+	// Corte temprano
+	n := 0
+	m.Range(func(int, string) bool {
+		n++
+		return n < 5
+	})
+	if n != 5 {
+		t.Errorf("Range after false visited %d, want 5", n)
+	}
+
+	// fn puede modificar el Map sin deadlock
+	m.Range(func(k int, _ string) bool {
+		m.Delete(k)
+		return true
+	})
+	if m.Len() != 0 {
+		t.Errorf("Len after deleting in Range = %d", m.Len())
+	}
+}
+
+type node struct {
+	name string
+}
+
+func TestPointerKeysHashByIdentity(t *testing.T) {
+	m := New[*node, int](WithShards(64))
+	keys := make([]*node, 100)
+	for i := range keys {
+		keys[i] = &node{name: "n"}
+		m.Set(keys[i], i)
+	}
+
+	// Cambiar el valor apuntado no mueve la clave de shard
+	for i, k := range keys {
+		k.name = "changed"
+		if v, ok := m.Get(k); !ok || v != i {
+			t.Fatalf("Get(keys[%d]) after mutating the pointee = %d, %v", i, v, ok)
+		}
+	}
+	if _, ok := m.Get(&node{name: "changed"}); ok {
+		t.Error("a different pointer with an equal pointee was found")
+	}
+}
+
+type userID int32
+
+type status string
+
+func TestNamedAndSmallBasicKeys(t *testing.T) {
+	ids := New[userID, bool](WithShards(16))
+	for i := userID(0); i < 50; i++ {
+		ids.Set(i, true)
+	}
+	for i := userID(0); i < 50; i++ {
+		if _, ok := ids.Get(i); !ok {
+			t.Fatalf("Get(%d) missing", i)
+		}
+	}
+
+	statuses := New[status, int]()
+	statuses.Set("active", 1)
+	if v, ok := statuses.Get("active"); !ok || v != 1 {
+		t.Errorf("named string key: Get = %d, %v", v, ok)
+	}
+
+	floats := New[float64, string](WithShards(64))
+	floats.Set(math.Copysign(0, -1), "zero")
+	if v, ok := floats.Get(0); !ok || v != "zero" {
+		t.Errorf("-0 and +0 are == but Get(+0) = %q, %v", v, ok)
+	}
+}
+
+type point struct{ x, y int }
+
+func TestCompositeKeysNeedHasher(t *testing.T) {
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("New with a struct key and no hasher did not panic")
+			}
+		}()
+		New[point, int]()
+	}()
+
+	m := New[point, int](WithHasher(func(p point) uint64 { return uint64(p.x)*31 + uint64(p.y) }))
+	m.Set(point{1, 2}, 3)
+	if v, ok := m.Get(point{1, 2}); !ok || v != 3 {
+		t.Errorf("Get = %d, %v", v, ok)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("hasher for the wrong key type did not panic")
+		}
+	}()
+	New[string, int](WithHasher(func(p point) uint64 { return 0 }))
+}
@@ -0,0 +1,103 @@
+package stripelock
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// keysWithStripes busca dos keys en el mismo stripe (same = true) o en
+// stripes distintos
+func keysWithStripes(t *testing.T, same bool) (string, string) {
+	t.Helper()
+	first := "key-0"
+	for i := 1; i < 10000; i++ {
+		other := fmt.Sprintf("key-%d", i)
+		if (stripeOf(first) == stripeOf(other)) == same {
+			return first, other
+		}
+	}
+	t.Fatal("no keys found")
+	return "", ""
+}
+
+func withTimeout(t *testing.T, what string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s: still blocked (deadlock?)", what)
+	}
+}
+
+func TestLockSerializesSameKey(t *testing.T) {
+	var s Set
+	var wg sync.WaitGroup
+	counter := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				unlock := s.Lock("account:1")
+				counter++ // Sin el lock, go test -race lo detecta
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 5000 {
+		t.Errorf("counter = %d, want 5000", counter)
+	}
+}
+
+func TestLockRepeatedAndCollidingKeys(t *testing.T) {
+	var s Set
+	a, b := keysWithStripes(t, true)
+	// Una key repetida o dos keys del mismo stripe no se bloquean a sí mismas
+	withTimeout(t, "repeated keys", func() {
+		unlock := s.Lock(a, a, b)
+		unlock()
+	})
+	withTimeout(t, "lock after unlock", func() {
+		s.Lock(b)()
+	})
+}
+
+func TestLockDifferentStripesDoNotBlock(t *testing.T) {
+	var s Set
+	a, b := keysWithStripes(t, false)
+	unlock := s.Lock(a)
+	defer unlock()
+	withTimeout(t, "other stripe", func() {
+		s.Lock(b)()
+	})
+}
+
+func TestLockOppositeOrderDoesNotDeadlock(t *testing.T) {
+	var s Set
+	keys := []string{"account:a", "account:b", "account:c", "account:d"}
+	withTimeout(t, "opposite order", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			from, to := keys[i%len(keys)], keys[(i+1)%len(keys)]
+			if i%2 == 1 {
+				from, to = to, from
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				unlock := s.Lock(from, to)
+				time.Sleep(time.Microsecond)
+				unlock()
+			}()
+		}
+		wg.Wait()
+	})
+}
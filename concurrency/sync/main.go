@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/josediaz/go-mastery-lab/concurrency/cache"
 )
 
 // ============================================================================
@@ -337,6 +340,58 @@ func cacheExample() {
 	fmt.Println()
 }
 
+// ============================================================================
+// CACHE GENÉRICO CON TTL, DESALOJO Y SINGLEFLIGHT
+// ============================================================================
+// El Cache anterior guarda interface{} para siempre. El paquete
+// concurrency/cache agrega generics, TTL, límite de tamaño (LRU/LFU),
+// janitor y GetOrLoad, que deduplica cargas concurrentes de la misma clave
+
+func genericCacheExample() {
+	fmt.Println("=== Generic Cache Example ===")
+
+	c := cache.New[string, string](
+		cache.WithMaxEntries(3),
+		cache.WithTTL(200*time.Millisecond),
+		cache.WithJanitor(50*time.Millisecond),
+		cache.WithEvictionCallback(func(key, value string, reason cache.EvictionReason) {
+			fmt.Printf("Evicted %s (%s)\n", key, reason)
+		}),
+	)
+	defer c.Close()
+
+	// Desalojo LRU: "a" se usa, así que la víctima es "b"
+	c.Set("a", "1")
+	c.Set("b", "2")
+	c.Set("c", "3")
+	c.Get("a")
+	c.Set("d", "4")
+
+	// GetOrLoad: 10 goroutines piden la misma clave, el loader corre una vez
+	var loads atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.GetOrLoad(context.Background(), "user:42", func(ctx context.Context, key string) (string, error) {
+				loads.Add(1)
+				time.Sleep(50 * time.Millisecond) // Simular consulta a la BD
+				return "Jane", nil
+			})
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("Loader calls for 10 concurrent GetOrLoad: %d\n", loads.Load())
+
+	// El janitor elimina todo cuando vence el TTL
+	time.Sleep(300 * time.Millisecond)
+	stats := c.Stats()
+	fmt.Printf("Stats: hits=%d misses=%d loads=%d evictions=%d expirations=%d entries=%d\n",
+		stats.Hits, stats.Misses, stats.Loads, stats.Evictions, stats.Expirations, stats.Entries)
+	fmt.Println()
+}
+
 // ============================================================================
 // DETECTAR DATA RACES
 // ============================================================================
@@ -372,7 +427,8 @@ func dataRaceExample() {
 // ============================================================================
 
 func main() {
-	fmt.Println("=== CONCURRENCIA: SYNC ===\n")
+	fmt.Println("=== CONCURRENCIA: SYNC ===")
+	fmt.Println()
 
 	mutexExample()
 	rwMutexExample()
@@ -382,6 +438,7 @@ func main() {
 	poolExample()
 	atomicExample()
 	cacheExample()
+	genericCacheExample()
 	dataRaceExample()
 
 	fmt.Println("=== FIN DE EJEMPLOS ===")
@@ -0,0 +1,14 @@
+// Package ids genera los IDs de finance/ledger y finance/payments
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// New genera IDs como "pay_3f9a..." (prefijo por tipo, como Stripe)
+func New(prefix string) string {
+	var b [12]byte
+	rand.Read(b[:])
+	return prefix + "_" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,78 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// PARTIDA DOBLE
+// ============================================================================
+// Cada movimiento de dinero es un asiento (Entry) con dos o más Postings que
+// suman cero en cada moneda: lo que sale de una cuenta entra en otra. El
+// dinero no se crea ni se destruye, solo se mueve.
+//
+//	Transfer 25.00 USD de alice a bob:
+//	  alice  -25.00 USD
+//	  bob    +25.00 USD
+//	         ---------
+//	           0.00
+//
+// El balance de una cuenta no es un campo que se edita (como
+// fundamentals/methods.Account.Balance): es la suma de sus postings. Así
+// siempre hay historia y se puede auditar.
+//
+// Los depósitos y retiros desde fuera del sistema se registran contra una
+// cuenta externa con AllowNegative (p. ej. "cash" o "bank:bcp").
+// ============================================================================
+
+// Account es una cuenta del libro mayor
+type Account struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	// AllowNegative permite balance negativo: cuentas externas o de
+	// contrapartida. Las cuentas de clientes no lo permiten.
+	AllowNegative bool      `json:"allow_negative"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Posting es una línea de un asiento: amount positivo suma al balance de la
+// cuenta, negativo resta
+type Posting struct {
+	AccountID string      `json:"account_id"`
+	Amount    money.Money `json:"amount"`
+}
+
+// Entry es un asiento contable: inmutable una vez registrado
+type Entry struct {
+	ID             string    `json:"id"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	Description    string    `json:"description,omitempty"`
+	Postings       []Posting `json:"postings"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Movement es un posting visto desde su cuenta: una línea del extracto
+type Movement struct {
+	EntryID     string      `json:"entry_id"`
+	AccountID   string      `json:"account_id"`
+	Amount      money.Money `json:"amount"`
+	Balance     money.Money `json:"balance"` // Balance de la cuenta después del movimiento
+	Description string      `json:"description,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// samePostings compara dos listas de postings (para idempotencia)
+func samePostings(a, b []Posting) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
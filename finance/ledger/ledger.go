@@ -0,0 +1,249 @@
+// Package ledger es un libro mayor de partida doble: cuentas, asientos que
+// siempre cuadran, transferencias atómicas e idempotentes y balances que se
+// derivan de los movimientos (ver entry.go).
+//
+// Es la versión "de producción" del Account de fundamentals/methods.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/concurrency/stripelock"
+	"github.com/josediaz/go-mastery-lab/finance/internal/ids"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+var (
+	ErrMissingIdempotencyKey = errors.New("ledger: idempotency key is required")
+	// ErrIdempotencyKeyReused: la key ya se usó con otros postings
+	ErrIdempotencyKeyReused = errors.New("ledger: idempotency key reused with different postings")
+	ErrInvalidAccount       = errors.New("ledger: invalid account")
+	ErrInvalidAmount        = errors.New("ledger: amount must be positive")
+	ErrSameAccount          = errors.New("ledger: cannot transfer to the same account")
+	// ErrUnbalancedEntry: los postings no suman cero en alguna moneda
+	ErrUnbalancedEntry   = errors.New("ledger: entry does not balance")
+	ErrInsufficientFunds = errors.New("ledger: insufficient funds")
+)
+
+// EntryRequest es un asiento a registrar
+type EntryRequest struct {
+	// IdempotencyKey identifica el pedido: repetirlo devuelve el mismo asiento
+	IdempotencyKey string
+	Description    string
+	Postings       []Posting
+}
+
+// TransferRequest mueve Amount de From a To (un asiento de dos postings)
+type TransferRequest struct {
+	IdempotencyKey string
+	From, To       string
+	Amount         money.Money
+	Description    string
+}
+
+// Service registra asientos sobre un Repository
+type Service struct {
+	repo Repository
+	now  func() time.Time
+	// locks serializa por cuenta y por key, en orden global (ver concurrency/stripelock)
+	locks stripelock.Set
+}
+
+// Option configura un Service
+type Option func(*Service)
+
+// WithClock reemplaza time.Now (para tests)
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{
+		repo: repo,
+		now:  func() time.Time { return time.Now().UTC() },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// OpenAccount crea una cuenta con balance cero
+func (s *Service) OpenAccount(ctx context.Context, a Account) (*Account, error) {
+	if a.ID == "" {
+		return nil, fmt.Errorf("%w: id is required", ErrInvalidAccount)
+	}
+	if _, err := money.LookupCurrency(a.Currency); err != nil {
+		return nil, err
+	}
+	a.CreatedAt = s.now()
+	if err := s.repo.CreateAccount(ctx, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Transfer mueve dinero entre dos cuentas de forma atómica: o se registran
+// los dos postings o ninguno
+func (s *Service) Transfer(ctx context.Context, req TransferRequest) (*Entry, error) {
+	if !req.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	if req.From == req.To {
+		return nil, ErrSameAccount
+	}
+	return s.Post(ctx, EntryRequest{
+		IdempotencyKey: req.IdempotencyKey,
+		Description:    req.Description,
+		Postings: []Posting{
+			{AccountID: req.From, Amount: req.Amount.Neg()},
+			{AccountID: req.To, Amount: req.Amount},
+		},
+	})
+}
+
+// Post registra un asiento. Es idempotente por req.IdempotencyKey: la misma
+// key con los mismos postings devuelve el asiento ya registrado.
+//
+// Falla sin registrar nada si los postings no suman cero por moneda, si la
+// moneda no coincide con la de la cuenta o si una cuenta sin AllowNegative
+// quedaría en negativo.
+func (s *Service) Post(ctx context.Context, req EntryRequest) (*Entry, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+
+	// Todas las cuentas del asiento más la key, bloqueadas en orden
+	keys := []string{"entry:" + req.IdempotencyKey}
+	for _, p := range req.Postings {
+		keys = append(keys, "account:"+p.AccountID)
+	}
+	unlock := s.locks.Lock(keys...)
+	defer unlock()
+
+	existing, err := s.repo.GetEntryByIdempotencyKey(ctx, req.IdempotencyKey)
+	switch {
+	case err == nil:
+		return replay(existing, req)
+	case !errors.Is(err, ErrEntryNotFound):
+		return nil, err
+	}
+
+	now := s.now()
+	entry := &Entry{
+		ID:             ids.New("ent"),
+		IdempotencyKey: req.IdempotencyKey,
+		Description:    req.Description,
+		Postings:       append([]Posting(nil), req.Postings...),
+		CreatedAt:      now,
+	}
+
+	// Calcular el balance de cada cuenta después del asiento
+	balances := make(map[string]money.Money)
+	movements := make([]Movement, 0, len(req.Postings))
+	for _, p := range req.Postings {
+		account, err := s.repo.GetAccount(ctx, p.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, p.AccountID)
+		}
+		if p.Amount.Currency() != account.Currency {
+			return nil, fmt.Errorf("%w: %s in account %s (%s)", money.ErrCurrencyMismatch, p.Amount.Currency(), account.ID, account.Currency)
+		}
+
+		balance, ok := balances[account.ID]
+		if !ok {
+			if balance, err = s.repo.Balance(ctx, account.ID); err != nil {
+				return nil, err
+			}
+		}
+		if balance, err = balance.Add(p.Amount); err != nil {
+			return nil, err
+		}
+		if balance.IsNegative() && !account.AllowNegative {
+			return nil, fmt.Errorf("%w: account %s", ErrInsufficientFunds, account.ID)
+		}
+		balances[account.ID] = balance
+
+		movements = append(movements, Movement{
+			EntryID:     entry.ID,
+			AccountID:   account.ID,
+			Amount:      p.Amount,
+			Balance:     balance,
+			Description: req.Description,
+			CreatedAt:   now,
+		})
+	}
+
+	if err := s.repo.Append(ctx, entry, movements); err != nil {
+		if errors.Is(err, ErrDuplicateKey) {
+			// Otro proceso lo registró entre GetEntryByIdempotencyKey y Append
+			existing, getErr := s.repo.GetEntryByIdempotencyKey(ctx, req.IdempotencyKey)
+			if getErr != nil {
+				return nil, getErr
+			}
+			return replay(existing, req)
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// validate revisa lo que no depende del estado: key, montos y que cuadre
+func validate(req EntryRequest) error {
+	if req.IdempotencyKey == "" {
+		return ErrMissingIdempotencyKey
+	}
+	if len(req.Postings) < 2 {
+		return fmt.Errorf("%w: needs at least two postings", ErrUnbalancedEntry)
+	}
+
+	totals := make(map[string]money.Money)
+	for _, p := range req.Postings {
+		if p.Amount.IsZero() {
+			return fmt.Errorf("%w: zero posting for account %s", ErrInvalidAmount, p.AccountID)
+		}
+		total, ok := totals[p.Amount.Currency()]
+		if !ok {
+			var err error
+			if total, err = money.Zero(p.Amount.Currency()); err != nil {
+				return err
+			}
+		}
+		total, err := total.Add(p.Amount)
+		if err != nil {
+			return err
+		}
+		totals[p.Amount.Currency()] = total
+	}
+	for currency, total := range totals {
+		if !total.IsZero() {
+			return fmt.Errorf("%w: %s postings add up to %s", ErrUnbalancedEntry, currency, total)
+		}
+	}
+	return nil
+}
+
+func replay(existing *Entry, req EntryRequest) (*Entry, error) {
+	if !samePostings(existing.Postings, req.Postings) {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return existing, nil
+}
+
+func (s *Service) Balance(ctx context.Context, accountID string) (money.Money, error) {
+	return s.repo.Balance(ctx, accountID)
+}
+
+// History devuelve los movimientos de la cuenta, del más antiguo al más nuevo
+func (s *Service) History(ctx context.Context, accountID string) ([]Movement, error) {
+	return s.repo.Movements(ctx, accountID)
+}
+
+func (s *Service) Entry(ctx context.Context, entryID string) (*Entry, error) {
+	return s.repo.GetEntry(ctx, entryID)
+}
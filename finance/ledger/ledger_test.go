@@ -0,0 +1,178 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+func newTestLedger(t *testing.T, accounts ...string) *Service {
+	t.Helper()
+	ctx := context.Background()
+	svc := NewService(NewMemoryRepository())
+	if _, err := svc.OpenAccount(ctx, Account{ID: "cash", Currency: "USD", AllowNegative: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range accounts {
+		if _, err := svc.OpenAccount(ctx, Account{ID: id, Currency: "USD"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return svc
+}
+
+func usd(amount string) money.Money {
+	return money.MustParse(amount, "USD")
+}
+
+func TestTransferAndHistory(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestLedger(t, "alice", "bob")
+
+	steps := []TransferRequest{
+		{IdempotencyKey: "deposit", From: "cash", To: "alice", Amount: usd("100.00")},
+		{IdempotencyKey: "t1", From: "alice", To: "bob", Amount: usd("30.50")},
+		{IdempotencyKey: "t2", From: "bob", To: "alice", Amount: usd("0.50")},
+	}
+	for _, req := range steps {
+		if _, err := svc.Transfer(ctx, req); err != nil {
+			t.Fatalf("Transfer %s: %v", req.IdempotencyKey, err)
+		}
+	}
+
+	for id, want := range map[string]money.Money{"alice": usd("70.00"), "bob": usd("30.00"), "cash": usd("-100.00")} {
+		if got, _ := svc.Balance(ctx, id); got != want {
+			t.Errorf("Balance(%s) = %s, want %s", id, got, want)
+		}
+	}
+
+	history, _ := svc.History(ctx, "alice")
+	var running []string
+	for _, m := range history {
+		running = append(running, m.Amount.Decimal()+"="+m.Balance.Decimal())
+	}
+	if got := fmt.Sprint(running); got != "[100.00=100.00 -30.50=69.50 0.50=70.00]" {
+		t.Errorf("alice history = %s", got)
+	}
+}
+
+func TestPostRejectsInvalidEntries(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestLedger(t, "alice", "bob")
+	svc.Transfer(ctx, TransferRequest{IdempotencyKey: "deposit", From: "cash", To: "alice", Amount: usd("10.00")})
+
+	tests := []struct {
+		name    string
+		req     EntryRequest
+		wantErr error
+	}{
+		{"unbalanced", EntryRequest{IdempotencyKey: "a", Postings: []Posting{
+			{"alice", usd("-5.00")}, {"bob", usd("4.99")},
+		}}, ErrUnbalancedEntry},
+		{"single posting", EntryRequest{IdempotencyKey: "b", Postings: []Posting{{"bob", usd("1.00")}}}, ErrUnbalancedEntry},
+		{"overdraft", EntryRequest{IdempotencyKey: "c", Postings: []Posting{
+			{"alice", usd("-10.01")}, {"bob", usd("10.01")},
+		}}, ErrInsufficientFunds},
+		{"unknown account", EntryRequest{IdempotencyKey: "d", Postings: []Posting{
+			{"alice", usd("-1.00")}, {"carol", usd("1.00")},
+		}}, ErrAccountNotFound},
+		{"wrong currency", EntryRequest{IdempotencyKey: "e", Postings: []Posting{
+			{"alice", money.MustParse("-1", "EUR")}, {"bob", money.MustParse("1", "EUR")},
+		}}, money.ErrCurrencyMismatch},
+		{"missing key", EntryRequest{Postings: []Posting{{"alice", usd("-1")}, {"bob", usd("1")}}}, ErrMissingIdempotencyKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Post(ctx, tt.req); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Post = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Nada de lo anterior quedó registrado a medias
+	if got, _ := svc.Balance(ctx, "bob"); !got.IsZero() {
+		t.Errorf("bob balance = %s after rejected entries, want 0", got)
+	}
+	if history, _ := svc.History(ctx, "alice"); len(history) != 1 {
+		t.Errorf("alice has %d movements, want 1", len(history))
+	}
+}
+
+func TestTransferIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestLedger(t, "alice")
+	req := TransferRequest{IdempotencyKey: "deposit-1", From: "cash", To: "alice", Amount: usd("25.00")}
+
+	first, err := svc.Transfer(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Transfer(ctx, req)
+	if err != nil || second.ID != first.ID {
+		t.Fatalf("retry = %v, %v; want entry %s", second, err, first.ID)
+	}
+	if got, _ := svc.Balance(ctx, "alice"); got != usd("25.00") {
+		t.Errorf("balance = %s after retry, want 25.00 USD", got)
+	}
+
+	req.Amount = usd("26.00")
+	if _, err := svc.Transfer(ctx, req); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("reused key = %v, want ErrIdempotencyKeyReused", err)
+	}
+}
+
+func TestConcurrentTransfers(t *testing.T) {
+	ctx := context.Background()
+	accounts := []string{"a", "b", "c", "d"}
+	svc := newTestLedger(t, accounts...)
+	for _, id := range accounts {
+		svc.Transfer(ctx, TransferRequest{IdempotencyKey: "seed-" + id, From: "cash", To: id, Amount: usd("100.00")})
+	}
+
+	// Transferencias cruzadas en las dos direcciones: con locks en orden
+	// arbitrario esto hace deadlock; sin locks, los balances no cuadran
+	var wg sync.WaitGroup
+	for i := 0; i < 400; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from, to := accounts[i%4], accounts[(i+1+i/4)%4]
+			if from == to {
+				return
+			}
+			_, err := svc.Transfer(ctx, TransferRequest{
+				IdempotencyKey: fmt.Sprintf("t-%d", i), From: from, To: to, Amount: usd("7.00"),
+			})
+			if err != nil && !errors.Is(err, ErrInsufficientFunds) {
+				t.Errorf("Transfer: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	total := usd("0")
+	for _, id := range accounts {
+		balance, _ := svc.Balance(ctx, id)
+		if balance.IsNegative() {
+			t.Errorf("%s balance = %s, want >= 0", id, balance)
+		}
+
+		// El balance guardado es la suma de los movimientos
+		history, _ := svc.History(ctx, id)
+		sum := usd("0")
+		for _, m := range history {
+			sum, _ = sum.Add(m.Amount)
+		}
+		if sum != balance {
+			t.Errorf("%s: movements add up to %s, balance is %s", id, sum, balance)
+		}
+		total, _ = total.Add(balance)
+	}
+	if total != usd("400.00") {
+		t.Errorf("total = %s, want 400.00 USD (money created or destroyed)", total)
+	}
+}
@@ -0,0 +1,155 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+var (
+	ErrAccountNotFound = errors.New("ledger: account not found")
+	ErrAccountExists   = errors.New("ledger: account already exists")
+	ErrEntryNotFound   = errors.New("ledger: entry not found")
+	// ErrDuplicateKey: ya existe un asiento con esa idempotency key
+	ErrDuplicateKey = errors.New("ledger: duplicate idempotency key")
+)
+
+// Repository persiste cuentas y asientos.
+//
+// Append debe guardar el asiento y todos sus movimientos de forma atómica
+// (en SQL, una transacción) y rechazar una idempotency key repetida con
+// ErrDuplicateKey (un índice UNIQUE).
+type Repository interface {
+	CreateAccount(ctx context.Context, a *Account) error
+	GetAccount(ctx context.Context, id string) (*Account, error)
+
+	Append(ctx context.Context, e *Entry, movements []Movement) error
+	GetEntry(ctx context.Context, id string) (*Entry, error)
+	// GetEntryByIdempotencyKey devuelve ErrEntryNotFound si no existe
+	GetEntryByIdempotencyKey(ctx context.Context, key string) (*Entry, error)
+
+	// Balance devuelve el balance del último movimiento (cero si no tiene)
+	Balance(ctx context.Context, accountID string) (money.Money, error)
+	// Movements devuelve la historia de la cuenta en orden de registro
+	Movements(ctx context.Context, accountID string) ([]Movement, error)
+}
+
+// ============================================================================
+// IMPLEMENTACIÓN EN MEMORIA
+// ============================================================================
+
+// MemoryRepository guarda copias: modificar lo que devuelve no altera el store
+type MemoryRepository struct {
+	mu        sync.RWMutex
+	accounts  map[string]*Account
+	entries   map[string]*Entry
+	byKey     map[string]string     // idempotency key -> entry ID
+	movements map[string][]Movement // account ID -> movimientos, en orden
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		accounts:  make(map[string]*Account),
+		entries:   make(map[string]*Entry),
+		byKey:     make(map[string]string),
+		movements: make(map[string][]Movement),
+	}
+}
+
+func (r *MemoryRepository) CreateAccount(ctx context.Context, a *Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[a.ID]; ok {
+		return ErrAccountExists
+	}
+	stored := *a
+	r.accounts[a.ID] = &stored
+	return nil
+}
+
+func (r *MemoryRepository) GetAccount(ctx context.Context, id string) (*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (r *MemoryRepository) Append(ctx context.Context, e *Entry, movements []Movement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byKey[e.IdempotencyKey]; ok {
+		return ErrDuplicateKey
+	}
+	for _, m := range movements {
+		if _, ok := r.accounts[m.AccountID]; !ok {
+			return ErrAccountNotFound
+		}
+	}
+	r.entries[e.ID] = copyEntry(e)
+	r.byKey[e.IdempotencyKey] = e.ID
+	for _, m := range movements {
+		r.movements[m.AccountID] = append(r.movements[m.AccountID], m)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetEntry(ctx context.Context, id string) (*Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.entries[id]
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	return copyEntry(e), nil
+}
+
+func (r *MemoryRepository) GetEntryByIdempotencyKey(ctx context.Context, key string) (*Entry, error) {
+	r.mu.RLock()
+	id, ok := r.byKey[key]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	return r.GetEntry(ctx, id)
+}
+
+func (r *MemoryRepository) Balance(ctx context.Context, accountID string) (money.Money, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.accounts[accountID]
+	if !ok {
+		return money.Money{}, ErrAccountNotFound
+	}
+	history := r.movements[accountID]
+	if len(history) == 0 {
+		return money.Zero(a.Currency)
+	}
+	return history[len(history)-1].Balance, nil
+}
+
+func (r *MemoryRepository) Movements(ctx context.Context, accountID string) ([]Movement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.accounts[accountID]; !ok {
+		return nil, ErrAccountNotFound
+	}
+	return append([]Movement(nil), r.movements[accountID]...), nil
+}
+
+func copyEntry(e *Entry) *Entry {
+	copied := *e
+	copied.Postings = append([]Posting(nil), e.Postings...)
+	return &copied
+}
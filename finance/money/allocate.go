@@ -0,0 +1,82 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// ============================================================================
+// REPARTIR SIN PERDER CENTAVOS
+// ============================================================================
+// 10.00 USD entre 3 no es 3.33 * 3 = 9.99: el centavo que sobra tiene que ir
+// a alguna parte. Allocate reparte la parte entera proporcional y después da
+// los centavos sobrantes, uno por uno, a las primeras partes. La suma de las
+// partes siempre es exactamente el monto original (algoritmo de Martin
+// Fowler, "Patterns of Enterprise Application Architecture").
+// ============================================================================
+
+// Allocate reparte m en partes proporcionales a ratios:
+//
+//	MustParse("100.00", "USD").Allocate(70, 20, 10) // 70.00, 20.00, 10.00
+//	MustParse("0.05", "USD").Allocate(1, 1)         // 0.03, 0.02
+//
+// Un monto negativo se reparte igual (con los centavos en negativo). Si la
+// suma de ratios no entra en un int64 devuelve ErrOverflow.
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	if len(ratios) == 0 {
+		return nil, fmt.Errorf("%w: no ratios to allocate", ErrInvalidAmount)
+	}
+	var total int64
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, fmt.Errorf("%w: negative ratio %d", ErrInvalidAmount, r)
+		}
+		if total > math.MaxInt64-r {
+			return nil, fmt.Errorf("%w: ratios add up to more than %d", ErrOverflow, int64(math.MaxInt64))
+		}
+		total += r
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: ratios add up to zero", ErrInvalidAmount)
+	}
+
+	parts := make([]Money, len(ratios))
+	remainder := m.amount
+	for i, r := range ratios {
+		// amount * r / total truncado hacia el cero: nunca se reparte de más.
+		// Con big.Int el producto intermedio no desborda.
+		share := new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(r))
+		share.Quo(share, big.NewInt(total))
+		parts[i] = Money{amount: share.Int64(), currency: m.currency}
+		remainder -= parts[i].amount
+	}
+
+	// Lo que sobra son menos unidades mínimas que partes
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i++ {
+		if ratios[i] == 0 {
+			continue // una parte con ratio 0 siempre recibe 0
+		}
+		parts[i].amount += step
+		remainder -= step
+	}
+	return parts, nil
+}
+
+// Split reparte m en n partes iguales:
+//
+//	MustParse("10.00", "USD").Split(3) // 3.34, 3.33, 3.33
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: cannot split in %d parts", ErrInvalidAmount, n)
+	}
+	ratios := make([]int64, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
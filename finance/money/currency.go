@@ -0,0 +1,48 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownCurrency = errors.New("money: unknown currency (want ISO 4217 code)")
+
+// Currency describe una moneda ISO 4217
+type Currency struct {
+	Code   string // "USD"
+	Digits int    // Decimales de la unidad mínima: 2 para USD, 0 para JPY
+	Symbol string // Para Format: "$", "S/", "€"
+}
+
+// currencies son las monedas soportadas. Agregar una es agregar una línea.
+var currencies = map[string]Currency{
+	"USD": {"USD", 2, "$"},
+	"EUR": {"EUR", 2, "€"},
+	"GBP": {"GBP", 2, "£"},
+	"PEN": {"PEN", 2, "S/"},
+	"MXN": {"MXN", 2, "MX$"},
+	"BRL": {"BRL", 2, "R$"},
+	"COP": {"COP", 2, "COL$"},
+	"ARS": {"ARS", 2, "AR$"},
+	"CLP": {"CLP", 0, "CLP$"},
+	"JPY": {"JPY", 0, "¥"},
+	"KWD": {"KWD", 3, "KD"},
+}
+
+// LookupCurrency devuelve la moneda con ese código
+func LookupCurrency(code string) (Currency, error) {
+	c, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// scale devuelve 10^Digits (unidades mínimas por unidad)
+func (c Currency) scale() int64 {
+	s := int64(1)
+	for i := 0; i < c.Digits; i++ {
+		s *= 10
+	}
+	return s
+}
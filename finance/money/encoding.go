@@ -0,0 +1,75 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// JSON Y SQL
+// ============================================================================
+// En JSON el monto va como string decimal: {"amount":"10.50","currency":"USD"}.
+// Como number, un cliente JavaScript lo leería como float64 y podría perder
+// precisión (y "10.5" vs 1050 centavos sería ambiguo).
+//
+// En SQL se guarda como texto "10.50 USD" en una sola columna (Valuer/Scanner,
+// como una columna DECIMAL con su moneda). Si se necesita sumar en SQL, guardar
+// MinorUnits() y Currency() en dos columnas (BIGINT, CHAR(3)) y leer con New.
+// ============================================================================
+
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON implementa json.Marshaler
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.Decimal(), Currency: m.currency})
+}
+
+// UnmarshalJSON implementa json.Unmarshaler. Valida la moneda y los decimales.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v jsonMoney
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAmount, err)
+	}
+	parsed, err := Parse(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value implementa driver.Valuer: "10.50 USD"
+func (m Money) Value() (driver.Value, error) {
+	if m.currency == "" {
+		return nil, fmt.Errorf("%w: money without currency", ErrInvalidAmount)
+	}
+	return m.String(), nil
+}
+
+// Scan implementa sql.Scanner (lee lo que escribe Value)
+func (m *Money) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("%w: cannot scan %T into Money", ErrInvalidAmount, src)
+	}
+	amount, currency, ok := strings.Cut(s, " ")
+	if !ok {
+		return fmt.Errorf("%w: %q (want \"10.50 USD\")", ErrInvalidAmount, s)
+	}
+	parsed, err := Parse(amount, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
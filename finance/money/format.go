@@ -0,0 +1,78 @@
+package money
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// FORMATO
+// ============================================================================
+// String es para logs y APIs: sin ambigüedad ("1234.50 USD").
+// Format es para mostrar a una persona: símbolo y miles ("$1,234.50").
+// Ambos usan los decimales de la moneda: JPY no tiene centavos ("¥1,500").
+// ============================================================================
+
+// Decimal devuelve el monto como decimal sin moneda: "1234.50", "-0.05", "1500"
+func (m Money) Decimal() string {
+	c, err := LookupCurrency(m.currency)
+	if err != nil {
+		return strconv.FormatInt(m.amount, 10) // Valor cero sin moneda
+	}
+	return formatDecimal(m.amount, c.Digits, "")
+}
+
+// String implementa fmt.Stringer: "1234.50 USD"
+func (m Money) String() string {
+	if m.currency == "" {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + m.currency
+}
+
+// Format devuelve el monto con el símbolo de la moneda y separador de miles:
+// "$1,234.50", "-S/0.05", "¥1,500"
+func (m Money) Format() string {
+	c, err := LookupCurrency(m.currency)
+	if err != nil {
+		return m.String()
+	}
+	s := formatDecimal(m.amount, c.Digits, ",")
+	if strings.HasPrefix(s, "-") {
+		return "-" + c.Symbol + s[1:]
+	}
+	return c.Symbol + s
+}
+
+// formatDecimal pone el punto decimal a digits posiciones del final y, si
+// thousands no es vacío, agrupa la parte entera de a tres
+func formatDecimal(minor int64, digits int, thousands string) string {
+	// FormatUint sobre el valor absoluto para que MinInt64 no desborde
+	abs := uint64(minor)
+	sign := ""
+	if minor < 0 {
+		abs = -abs
+		sign = "-"
+	}
+	s := strconv.FormatUint(abs, 10)
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	intPart, fracPart := s[:len(s)-digits], s[len(s)-digits:]
+
+	if thousands != "" {
+		var b strings.Builder
+		for i, ch := range intPart {
+			if i > 0 && (len(intPart)-i)%3 == 0 {
+				b.WriteString(thousands)
+			}
+			b.WriteRune(ch)
+		}
+		intPart = b.String()
+	}
+
+	if digits == 0 {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
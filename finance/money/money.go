@@ -0,0 +1,269 @@
+// Package money representa montos exactos: un entero de unidades mínimas
+// (centavos) más la moneda. Nunca float64: 0.1 + 0.2 != 0.3 en binario, y un
+// centavo perdido en cada operación termina siendo un descuadre en el balance.
+//
+// Similar a javax.money (JSR 354) o a BigDecimal con scale fijo en Java.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	ErrInvalidAmount    = errors.New("money: invalid amount")
+	// ErrPrecision: el monto tiene más decimales de los que admite la moneda
+	// (p. ej. "10.005 USD"); usar ParseRound para redondear explícitamente
+	ErrPrecision = errors.New("money: too many decimal places for currency")
+	ErrOverflow  = errors.New("money: amount overflows int64 minor units")
+)
+
+// Money es un monto en unidades mínimas de una moneda: {1050, "USD"} es
+// 10.50 USD. Es un valor inmutable y comparable (se puede usar ==).
+//
+// El valor cero no tiene moneda; se construye con New, Parse o Zero.
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// New crea un monto a partir de unidades mínimas (centavos para USD)
+func New(minor int64, currency string) (Money, error) {
+	if _, err := LookupCurrency(currency); err != nil {
+		return Money{}, err
+	}
+	return Money{amount: minor, currency: currency}, nil
+}
+
+// MustNew es New pero hace panic con una moneda desconocida (para constantes y tests)
+func MustNew(minor int64, currency string) Money {
+	m, err := New(minor, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Zero devuelve 0 en currency
+func Zero(currency string) (Money, error) {
+	return New(0, currency)
+}
+
+// Parse convierte un decimal como "1234.5" o "-0.75" en un monto exacto.
+// Rechaza más decimales de los que tiene la moneda (ErrPrecision).
+func Parse(amount, currency string) (Money, error) {
+	return parse(amount, currency, nil)
+}
+
+// ParseRound es Parse pero redondea los decimales de más con mode
+func ParseRound(amount, currency string, mode RoundingMode) (Money, error) {
+	return parse(amount, currency, &mode)
+}
+
+// MustParse es Parse pero hace panic si falla
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func parse(amount, currency string, mode *RoundingMode) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	r, err := parseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	r.Mul(r, new(big.Rat).SetInt64(c.scale()))
+	if !r.IsInt() && mode == nil {
+		return Money{}, fmt.Errorf("%w: %q in %s (%d decimals)", ErrPrecision, amount, currency, c.Digits)
+	}
+	rounding := HalfEven
+	if mode != nil {
+		rounding = *mode
+	}
+	minor, err := toInt64(round(r, rounding))
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{amount: minor, currency: currency}, nil
+}
+
+// parseDecimal acepta solo decimales simples: signo opcional, dígitos y punto.
+// big.Rat.SetString también acepta "1/3" y "1e9", que no son montos.
+func parseDecimal(s string) (*big.Rat, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	intPart, fracPart, hasDot := strings.Cut(digits, ".")
+	valid := (intPart != "" || fracPart != "") && !(hasDot && fracPart == "")
+	for _, ch := range intPart + fracPart {
+		if ch < '0' || ch > '9' {
+			valid = false
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	return r, nil
+}
+
+func toInt64(i *big.Int) (int64, error) {
+	if !i.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return i.Int64(), nil
+}
+
+// ============================================================================
+// ACCESSORS
+// ============================================================================
+
+// MinorUnits devuelve el monto en unidades mínimas (1050 para 10.50 USD)
+func (m Money) MinorUnits() int64 { return m.amount }
+
+// Currency devuelve el código ISO 4217 ("USD")
+func (m Money) Currency() string { return m.currency }
+
+func (m Money) IsZero() bool     { return m.amount == 0 }
+func (m Money) IsPositive() bool { return m.amount > 0 }
+func (m Money) IsNegative() bool { return m.amount < 0 }
+
+// Sign devuelve -1, 0 o +1
+func (m Money) Sign() int {
+	switch {
+	case m.amount < 0:
+		return -1
+	case m.amount > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ============================================================================
+// ARITMÉTICA
+// ============================================================================
+// Sumar o comparar monedas distintas es un error (no hay tipo de cambio
+// implícito). Todas las operaciones detectan overflow de int64 en vez de
+// dar la vuelta en silencio.
+// ============================================================================
+
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	sum := m.amount + other.amount
+	if (other.amount > 0 && sum < m.amount) || (other.amount < 0 && sum > m.amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{amount: sum, currency: m.currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(Money{amount: -other.amount, currency: other.currency})
+}
+
+// Neg devuelve -m
+func (m Money) Neg() Money {
+	return Money{amount: -m.amount, currency: m.currency}
+}
+
+// Abs devuelve |m|
+func (m Money) Abs() Money {
+	if m.amount < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Mul multiplica por una cantidad entera (precio unitario * unidades). Es exacto.
+func (m Money) Mul(n int64) (Money, error) {
+	if n != 0 && m.amount != 0 {
+		product := m.amount * n
+		if product/n != m.amount || (m.amount == -1 && n == math.MinInt64) || (n == -1 && m.amount == math.MinInt64) {
+			return Money{}, ErrOverflow
+		}
+		return Money{amount: product, currency: m.currency}, nil
+	}
+	return Money{currency: m.currency}, nil
+}
+
+// MulRate multiplica por un factor decimal ("0.18" de IGV, "1.05" de
+// recargo) y redondea el resultado a unidades mínimas con mode. El factor
+// es un string para que no pase por float64.
+func (m Money) MulRate(rate string, mode RoundingMode) (Money, error) {
+	r, err := parseDecimal(rate)
+	if err != nil {
+		return Money{}, err
+	}
+	r.Mul(r, new(big.Rat).SetInt64(m.amount))
+	minor, err := toInt64(round(r, mode))
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{amount: minor, currency: m.currency}, nil
+}
+
+// Div divide por n redondeando con mode. Para repartir un monto sin perder
+// centavos usar Split o Allocate: 10.00 / 3 con Div da 3.33 (y se pierde 0.01).
+func (m Money) Div(n int64, mode RoundingMode) (Money, error) {
+	if n == 0 {
+		return Money{}, fmt.Errorf("%w: division by zero", ErrInvalidAmount)
+	}
+	minor, err := toInt64(round(big.NewRat(m.amount, n), mode))
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{amount: minor, currency: m.currency}, nil
+}
+
+// Cmp compara dos montos de la misma moneda: -1 si m < other, 0 si son
+// iguales, +1 si m > other
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.amount < other.amount:
+		return -1, nil
+	case m.amount > other.amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.currency != other.currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, other.currency)
+	}
+	return nil
+}
+
+// Sum suma montos de la misma moneda. Con la lista vacía devuelve Zero(currency).
+func Sum(currency string, amounts ...Money) (Money, error) {
+	total, err := Zero(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	for _, a := range amounts {
+		if total, err = total.Add(a); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,186 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		want             int64
+		wantErr          error
+	}{
+		{"10.50", "USD", 1050, nil},
+		{"10.5", "USD", 1050, nil},
+		{"-0.05", "USD", -5, nil},
+		{"1500", "JPY", 1500, nil},
+		{"1.234", "KWD", 1234, nil},
+		{"10.005", "USD", 0, ErrPrecision},
+		{"1.5", "JPY", 0, ErrPrecision},
+		{"1e3", "USD", 0, ErrInvalidAmount},
+		{"1/3", "USD", 0, ErrInvalidAmount},
+		{"", "USD", 0, ErrInvalidAmount},
+		{"10.", "USD", 0, ErrInvalidAmount},
+		{"10", "XXX", 0, ErrUnknownCurrency},
+		{"92233720368547758.08", "USD", 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.amount, tt.currency)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Parse(%q, %s) error = %v, want %v", tt.amount, tt.currency, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.MinorUnits() != tt.want {
+			t.Errorf("Parse(%q, %s) = %d, want %d", tt.amount, tt.currency, got.MinorUnits(), tt.want)
+		}
+	}
+}
+
+func TestRoundingModes(t *testing.T) {
+	// Cada caso divide minor/10: 25 -> 2.5, -25 -> -2.5, etc.
+	tests := []struct {
+		minor int64
+		mode  RoundingMode
+		want  int64
+	}{
+		{25, HalfEven, 2}, {35, HalfEven, 4}, {-25, HalfEven, -2}, {26, HalfEven, 3},
+		{25, HalfUp, 3}, {-25, HalfUp, -3}, {24, HalfUp, 2},
+		{25, HalfDown, 2}, {-25, HalfDown, -2}, {26, HalfDown, 3},
+		{21, Up, 3}, {-21, Up, -3},
+		{29, Down, 2}, {-29, Down, -2},
+		{21, Ceiling, 3}, {-29, Ceiling, -2},
+		{29, Floor, 2}, {-21, Floor, -3},
+		{30, Floor, 3},
+	}
+	for _, tt := range tests {
+		got, err := MustNew(tt.minor, "USD").Div(10, tt.mode)
+		if err != nil || got.MinorUnits() != tt.want {
+			t.Errorf("%d/10 %s = %d, %v; want %d", tt.minor, tt.mode, got.MinorUnits(), err, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("0.10", "USD")
+	b := MustParse("0.20", "USD")
+	sum, _ := a.Add(b)
+	if sum != MustParse("0.30", "USD") {
+		t.Errorf("0.10 + 0.20 = %s, want 0.30 USD", sum)
+	}
+
+	if _, err := a.Add(MustParse("1", "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("USD + EUR error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := MustNew(math.MaxInt64, "USD").Add(MustNew(1, "USD")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("overflowing Add error = %v, want ErrOverflow", err)
+	}
+	if _, err := MustNew(math.MaxInt64/2+1, "USD").Mul(2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("overflowing Mul error = %v, want ErrOverflow", err)
+	}
+
+	// 18% de IGV sobre 19.99 = 3.5982 -> 3.60
+	tax, err := MustParse("19.99", "PEN").MulRate("0.18", HalfUp)
+	if err != nil || tax != MustParse("3.60", "PEN") {
+		t.Errorf("19.99 * 0.18 = %s, %v; want 3.60 PEN", tax, err)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Money
+		ratios []int64
+		want   []int64
+	}{
+		{"split in three", MustParse("10.00", "USD"), []int64{1, 1, 1}, []int64{334, 333, 333}},
+		{"percentages", MustParse("100.00", "USD"), []int64{70, 20, 10}, []int64{7000, 2000, 1000}},
+		{"cents left over", MustParse("0.05", "USD"), []int64{3, 7}, []int64{2, 3}},
+		{"negative", MustParse("-10.00", "USD"), []int64{1, 1, 1}, []int64{-334, -333, -333}},
+		{"zero ratio", MustParse("0.03", "USD"), []int64{0, 1, 1}, []int64{0, 2, 1}},
+		{"no decimals", MustParse("100", "JPY"), []int64{1, 1, 1}, []int64{34, 33, 33}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := tt.amount.Allocate(tt.ratios...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total, _ := Sum(tt.amount.Currency(), parts...)
+			if total != tt.amount {
+				t.Errorf("parts add up to %s, want %s", total, tt.amount)
+			}
+			for i, p := range parts {
+				if p.MinorUnits() != tt.want[i] {
+					t.Errorf("part %d = %d, want %d", i, p.MinorUnits(), tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := MustParse("1", "USD").Split(0); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Split(0) error = %v, want ErrInvalidAmount", err)
+	}
+	// La suma de ratios daría la vuelta a negativo
+	if _, err := MustParse("1", "USD").Allocate(math.MaxInt64, 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Allocate with overflowing ratios error = %v, want ErrOverflow", err)
+	}
+	if _, err := MustParse("1", "USD").Allocate(math.MaxInt64/2, math.MaxInt64/2, 2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Allocate with overflowing ratios error = %v, want ErrOverflow", err)
+	}
+	// Justo en el límite todavía se puede repartir
+	if parts, err := MustParse("1.00", "USD").Allocate(math.MaxInt64-1, 1); err != nil || parts[0].MinorUnits() != 100 {
+		t.Errorf("Allocate(MaxInt64-1, 1) = %v, %v", parts, err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount         Money
+		str, formatted string
+	}{
+		{MustParse("1234567.5", "USD"), "1234567.50 USD", "$1,234,567.50"},
+		{MustParse("-0.05", "PEN"), "-0.05 PEN", "-S/0.05"},
+		{MustParse("1500", "JPY"), "1500 JPY", "¥1,500"},
+		{MustParse("12.345", "KWD"), "12.345 KWD", "KD12.345"},
+		{MustNew(math.MinInt64, "USD"), "-92233720368547758.08 USD", "-$92,233,720,368,547,758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.String(); got != tt.str {
+			t.Errorf("String() = %q, want %q", got, tt.str)
+		}
+		if got := tt.amount.Format(); got != tt.formatted {
+			t.Errorf("Format() = %q, want %q", got, tt.formatted)
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	original := MustParse("10.50", "USD")
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":"10.50","currency":"USD"}` {
+		t.Errorf("json = %s", data)
+	}
+	var decoded Money
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded != original {
+		t.Errorf("json round trip = %s, %v", decoded, err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"1.001","currency":"USD"}`), &decoded); !errors.Is(err, ErrPrecision) {
+		t.Errorf("json with extra decimals error = %v, want ErrPrecision", err)
+	}
+
+	value, err := original.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scanned Money
+	if err := scanned.Scan([]byte(value.(string))); err != nil || scanned != original {
+		t.Errorf("sql round trip = %s, %v", scanned, err)
+	}
+}
@@ -0,0 +1,98 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// RoundingMode decide qué hacer con las fracciones de unidad mínima
+// (similar a java.math.RoundingMode)
+type RoundingMode int
+
+const (
+	// HalfEven redondea al par más cercano en el empate (banker's rounding):
+	// 2.5 -> 2, 3.5 -> 4. No sesga las sumas; es el default
+	HalfEven RoundingMode = iota
+	HalfUp                // Empate hacia afuera del cero: 2.5 -> 3, -2.5 -> -3
+	HalfDown              // Empate hacia el cero: 2.5 -> 2, -2.5 -> -2
+	Up                    // Siempre hacia afuera del cero: 2.1 -> 3
+	Down                  // Siempre hacia el cero (truncar): 2.9 -> 2
+	Ceiling               // Hacia +infinito: 2.1 -> 3, -2.9 -> -2
+	Floor                 // Hacia -infinito: 2.9 -> 2, -2.1 -> -3
+)
+
+func (m RoundingMode) String() string {
+	switch m {
+	case HalfEven:
+		return "half-even"
+	case HalfUp:
+		return "half-up"
+	case HalfDown:
+		return "half-down"
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	case Ceiling:
+		return "ceiling"
+	case Floor:
+		return "floor"
+	default:
+		return fmt.Sprintf("RoundingMode(%d)", int(m))
+	}
+}
+
+// round redondea r a un entero según mode
+func round(r *big.Rat, mode RoundingMode) *big.Int {
+	num, den := r.Num(), r.Denom() // den > 0 siempre
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+
+	// q está truncado hacia el cero; away lo aleja una unidad
+	negative := num.Sign() < 0
+	away := func() *big.Int {
+		if negative {
+			return q.Sub(q, big.NewInt(1))
+		}
+		return q.Add(q, big.NewInt(1))
+	}
+
+	// Comparar 2*|rem| con den para saber si la fracción es < , = o > 0.5
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	half := twice.Cmp(den)
+
+	switch mode {
+	case Up:
+		return away()
+	case Down:
+		return q
+	case Ceiling:
+		if negative {
+			return q
+		}
+		return away()
+	case Floor:
+		if negative {
+			return away()
+		}
+		return q
+	case HalfUp:
+		if half >= 0 {
+			return away()
+		}
+		return q
+	case HalfDown:
+		if half > 0 {
+			return away()
+		}
+		return q
+	default: // HalfEven
+		if half > 0 || (half == 0 && q.Bit(0) == 1) {
+			return away()
+		}
+		return q
+	}
+}
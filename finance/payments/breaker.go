@@ -0,0 +1,129 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// CIRCUIT BREAKER POR PROVEEDOR
+// ============================================================================
+// Misma máquina de estados que patterns/circuit_breaker (similar a Hystrix o
+// Resilience4j en Java), pero sin tomar el lock durante la llamada: un
+// proveedor lento no serializa todos los pagos.
+//
+//	closed ──(N fallos seguidos)──> open ──(OpenTimeout)──> half-open
+//	   ^                                                       │
+//	   └──────────────(la llamada de prueba funciona)──────────┘
+//
+// En half-open pasa una sola llamada de prueba; si falla, vuelve a open.
+// Solo cuentan como fallos los errores de infraestructura (IsRetryable): un
+// rechazo de tarjeta significa que el proveedor funciona.
+// ============================================================================
+
+var ErrBreakerOpen = errors.New("payments: circuit breaker open")
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerConfig configura un CircuitBreaker
+type BreakerConfig struct {
+	FailureThreshold int           // Fallos seguidos para abrir (default 5)
+	OpenTimeout      time.Duration // Tiempo en open antes de probar (default 30s)
+}
+
+type CircuitBreaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool // Hay una llamada de prueba en curso (half-open)
+}
+
+func NewCircuitBreaker(cfg BreakerConfig, now func() time.Time) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &CircuitBreaker{cfg: cfg, now: now}
+}
+
+// State devuelve el estado actual (open pasa a half-open si venció el timeout)
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh()
+	return cb.state
+}
+
+// Allow indica si se puede llamar al proveedor. Si devuelve true hay que
+// informar el resultado con Record.
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh()
+
+	switch cb.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+	}
+	return true
+}
+
+// Record informa el resultado de una llamada permitida por Allow
+func (cb *CircuitBreaker) Record(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.probing = false
+
+	if err == nil || !IsRetryable(err) {
+		cb.state = BreakerClosed
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.state == BreakerHalfOpen || cb.failures >= cb.cfg.FailureThreshold {
+		cb.state = BreakerOpen
+		cb.openedAt = cb.now()
+	}
+}
+
+func (cb *CircuitBreaker) refresh() {
+	if cb.state == BreakerOpen && cb.now().Sub(cb.openedAt) >= cb.cfg.OpenTimeout {
+		cb.state = BreakerHalfOpen
+		cb.probing = false
+	}
+}
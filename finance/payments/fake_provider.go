@@ -0,0 +1,135 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// FakeProvider es un Provider en memoria para tests y demos. Se comporta como
+// uno real en lo que importa: deduplica por idempotency key, no captura más
+// de lo autorizado y no reembolsa más de lo capturado.
+//
+// Las respuestas se pueden programar con FailNext o Decline.
+type FakeProvider struct {
+	name string
+
+	mu             sync.Mutex
+	nextErrs       []error              // Errores para las próximas llamadas a Authorize
+	declineOver    int64                // Rechazar montos mayores (0 = nunca)
+	authorizations map[string]*fakeAuth // por ref
+	byKey          map[string]string    // idempotency key -> ref de autorización
+	refunds        map[string]string    // idempotency key -> ref de reembolso
+	calls          map[string]int       // llamadas por método
+	seq            int
+}
+
+type fakeAuth struct {
+	amount   money.Money
+	captured int64
+	refunded int64
+}
+
+func NewFakeProvider(name string) *FakeProvider {
+	return &FakeProvider{
+		name:           name,
+		authorizations: make(map[string]*fakeAuth),
+		byKey:          make(map[string]string),
+		refunds:        make(map[string]string),
+		calls:          make(map[string]int),
+	}
+}
+
+func (f *FakeProvider) Name() string {
+	return f.name
+}
+
+// FailNext hace que las próximas llamadas a Authorize devuelvan errs, en orden
+func (f *FakeProvider) FailNext(errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextErrs = append(f.nextErrs, errs...)
+}
+
+// Decline rechaza (ErrCardDeclined) los montos mayores que limit
+func (f *FakeProvider) Decline(limit int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.declineOver = limit
+}
+
+// Calls devuelve cuántas veces se llamó a method ("Authorize", "Capture", "Refund")
+func (f *FakeProvider) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func (f *FakeProvider) Authorize(ctx context.Context, idempotencyKey string, amount money.Money) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["Authorize"]++
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if ref, ok := f.byKey[idempotencyKey]; ok {
+		return ref, nil
+	}
+	if len(f.nextErrs) > 0 {
+		err := f.nextErrs[0]
+		f.nextErrs = f.nextErrs[1:]
+		return "", err
+	}
+	if f.declineOver > 0 && amount.MinorUnits() > f.declineOver {
+		return "", ErrCardDeclined
+	}
+
+	f.seq++
+	ref := fmt.Sprintf("%s_auth_%d", f.name, f.seq)
+	f.authorizations[ref] = &fakeAuth{amount: amount}
+	f.byKey[idempotencyKey] = ref
+	return ref, nil
+}
+
+func (f *FakeProvider) Capture(ctx context.Context, ref string, amount money.Money) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["Capture"]++
+
+	auth, ok := f.authorizations[ref]
+	if !ok {
+		return fmt.Errorf("fake provider: unknown authorization %q", ref)
+	}
+	if amount.Currency() != auth.amount.Currency() || amount.MinorUnits() > auth.amount.MinorUnits() {
+		return errors.New("fake provider: capture exceeds authorization")
+	}
+	auth.captured = amount.MinorUnits() // Capturar dos veces el mismo monto es idempotente
+	return nil
+}
+
+func (f *FakeProvider) Refund(ctx context.Context, ref, idempotencyKey string, amount money.Money) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["Refund"]++
+
+	if refundRef, ok := f.refunds[idempotencyKey]; ok {
+		return refundRef, nil
+	}
+	auth, ok := f.authorizations[ref]
+	if !ok {
+		return "", fmt.Errorf("fake provider: unknown authorization %q", ref)
+	}
+	if amount.Currency() != auth.amount.Currency() || auth.refunded+amount.MinorUnits() > auth.captured {
+		return "", errors.New("fake provider: refund exceeds captured amount")
+	}
+
+	auth.refunded += amount.MinorUnits()
+	f.seq++
+	refundRef := fmt.Sprintf("%s_refund_%d", f.name, f.seq)
+	f.refunds[idempotencyKey] = refundRef
+	return refundRef, nil
+}
@@ -0,0 +1,106 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// MÁQUINA DE ESTADOS DE UN PAGO
+// ============================================================================
+//
+//	pending ──> authorized ──> captured ──> refunded
+//	   │            │
+//	   └────────────┴──> failed
+//
+// - pending:    registrado, todavía sin respuesta del proveedor
+// - authorized: el proveedor reservó el monto (aún no se cobró)
+// - captured:   cobrado. Admite reembolsos parciales: sigue en captured
+//               hasta que se reembolsa el total
+// - refunded:   reembolsado por completo (estado final)
+// - failed:     rechazado o con error definitivo (estado final)
+// ============================================================================
+
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusAuthorized Status = "authorized"
+	StatusCaptured   Status = "captured"
+	StatusRefunded   Status = "refunded"
+	StatusFailed     Status = "failed"
+)
+
+// transitions lista los estados a los que se puede pasar desde cada estado
+var transitions = map[Status][]Status{
+	StatusPending:    {StatusAuthorized, StatusFailed},
+	StatusAuthorized: {StatusCaptured, StatusFailed},
+	StatusCaptured:   {StatusRefunded},
+}
+
+// CanTransition indica si from -> to es válido
+func CanTransition(from, to Status) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Final indica si el estado ya no cambia
+func (s Status) Final() bool {
+	return len(transitions[s]) == 0
+}
+
+var ErrInvalidTransition = errors.New("payments: invalid status transition")
+
+// Payment es un cobro y su historia
+type Payment struct {
+	ID             string      `json:"id"`
+	IdempotencyKey string      `json:"idempotency_key"`
+	Amount         money.Money `json:"amount"`
+	Refunded       money.Money `json:"refunded"` // Suma de reembolsos
+	Description    string      `json:"description,omitempty"`
+	Status         Status      `json:"status"`
+
+	Provider      string `json:"provider,omitempty"`
+	ProviderRef   string `json:"provider_ref,omitempty"` // ID de la autorización en el proveedor
+	FailureReason string `json:"failure_reason,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// transition cambia el estado si la máquina de estados lo permite
+func (p *Payment) transition(to Status, now time.Time) error {
+	if !CanTransition(p.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, p.Status, to)
+	}
+	p.Status = to
+	p.UpdatedAt = now
+	return nil
+}
+
+// Refundable devuelve cuánto se puede reembolsar todavía
+func (p *Payment) Refundable() money.Money {
+	if p.Status != StatusCaptured {
+		zero, _ := money.Zero(p.Amount.Currency())
+		return zero
+	}
+	rest, _ := p.Amount.Sub(p.Refunded)
+	return rest
+}
+
+// Refund es un reembolso (total o parcial) de un pago capturado
+type Refund struct {
+	ID             string      `json:"id"`
+	PaymentID      string      `json:"payment_id"`
+	IdempotencyKey string      `json:"idempotency_key"`
+	Amount         money.Money `json:"amount"`
+	ProviderRef    string      `json:"provider_ref"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
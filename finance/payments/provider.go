@@ -0,0 +1,52 @@
+package payments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// PROVIDER
+// ============================================================================
+// Evolución de fundamentals/interfaces.PaymentProvider:
+//
+//	ProcessPayment(amount money.Money) error
+//
+// no recibe context (no se puede cancelar ni poner timeout), no devuelve la
+// referencia del proveedor (no se puede reembolsar después) y no tiene
+// idempotencia (un reintento tras un timeout cobra dos veces). Aquí cobrar
+// son dos pasos, autorizar y capturar, como en las APIs de tarjetas reales.
+// ============================================================================
+
+// Provider es un procesador de pagos (Stripe, PayPal, un banco...)
+type Provider interface {
+	Name() string
+
+	// Authorize reserva amount y devuelve la referencia de la autorización.
+	// idempotencyKey hace que un reintento devuelva la misma autorización en
+	// vez de crear otra (como el header Idempotency-Key de Stripe).
+	Authorize(ctx context.Context, idempotencyKey string, amount money.Money) (ref string, err error)
+
+	// Capture cobra una autorización
+	Capture(ctx context.Context, ref string, amount money.Money) error
+
+	// Refund devuelve amount de una autorización capturada
+	Refund(ctx context.Context, ref, idempotencyKey string, amount money.Money) (refundRef string, err error)
+}
+
+// Errores que devuelven los proveedores. Los rechazos son definitivos;
+// ErrProviderUnavailable es transitorio y se puede reintentar.
+var (
+	ErrCardDeclined        = errors.New("payments: card declined")
+	ErrInsufficientFunds   = errors.New("payments: insufficient funds")
+	ErrProviderUnavailable = errors.New("payments: provider unavailable")
+)
+
+// IsRetryable indica si vale la pena reintentar err (con este u otro proveedor)
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrProviderUnavailable) ||
+		errors.Is(err, ErrBreakerOpen) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,139 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrPaymentNotFound = errors.New("payments: payment not found")
+	// ErrDuplicateKey: ya existe un pago o reembolso con esa idempotency key
+	ErrDuplicateKey = errors.New("payments: duplicate idempotency key")
+)
+
+// Repository persiste pagos y reembolsos. Create y CreateRefund deben
+// rechazar una idempotency key repetida con ErrDuplicateKey (en SQL, un
+// índice UNIQUE): es lo que evita cobrar dos veces entre procesos.
+type Repository interface {
+	Create(ctx context.Context, p *Payment) error
+	GetByID(ctx context.Context, id string) (*Payment, error)
+	// GetByIdempotencyKey devuelve ErrPaymentNotFound si no existe
+	GetByIdempotencyKey(ctx context.Context, key string) (*Payment, error)
+	Update(ctx context.Context, p *Payment) error
+
+	CreateRefund(ctx context.Context, r *Refund) error
+	// GetRefundByIdempotencyKey devuelve (nil, nil) si no existe
+	GetRefundByIdempotencyKey(ctx context.Context, key string) (*Refund, error)
+	// Refunds devuelve los reembolsos de un pago en orden de creación
+	Refunds(ctx context.Context, paymentID string) ([]*Refund, error)
+}
+
+// ============================================================================
+// IMPLEMENTACIÓN EN MEMORIA
+// ============================================================================
+
+// MemoryRepository guarda copias: modificar lo que devuelve no altera el store
+type MemoryRepository struct {
+	mu           sync.RWMutex
+	payments     map[string]*Payment
+	byKey        map[string]string // idempotency key -> payment ID
+	refunds      map[string]*Refund
+	refundsByKey map[string]string
+	paymentRefs  map[string][]string // payment ID -> IDs de reembolsos, en orden
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		payments:     make(map[string]*Payment),
+		byKey:        make(map[string]string),
+		refunds:      make(map[string]*Refund),
+		refundsByKey: make(map[string]string),
+		paymentRefs:  make(map[string][]string),
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, p *Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byKey[p.IdempotencyKey]; ok {
+		return ErrDuplicateKey
+	}
+	stored := *p
+	r.payments[p.ID] = &stored
+	r.byKey[p.IdempotencyKey] = p.ID
+	return nil
+}
+
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.payments[id]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	copied := *p
+	return &copied, nil
+}
+
+func (r *MemoryRepository) GetByIdempotencyKey(ctx context.Context, key string) (*Payment, error) {
+	r.mu.RLock()
+	id, ok := r.byKey[key]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	return r.GetByID(ctx, id)
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, p *Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.payments[p.ID]; !ok {
+		return ErrPaymentNotFound
+	}
+	stored := *p
+	r.payments[p.ID] = &stored
+	return nil
+}
+
+func (r *MemoryRepository) CreateRefund(ctx context.Context, refund *Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.refundsByKey[refund.IdempotencyKey]; ok {
+		return ErrDuplicateKey
+	}
+	stored := *refund
+	r.refunds[refund.ID] = &stored
+	r.refundsByKey[refund.IdempotencyKey] = refund.ID
+	r.paymentRefs[refund.PaymentID] = append(r.paymentRefs[refund.PaymentID], refund.ID)
+	return nil
+}
+
+func (r *MemoryRepository) GetRefundByIdempotencyKey(ctx context.Context, key string) (*Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.refundsByKey[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *r.refunds[id]
+	return &copied, nil
+}
+
+func (r *MemoryRepository) Refunds(ctx context.Context, paymentID string) ([]*Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var refunds []*Refund
+	for _, id := range r.paymentRefs[paymentID] {
+		copied := *r.refunds[id]
+		refunds = append(refunds, &copied)
+	}
+	return refunds, nil
+}
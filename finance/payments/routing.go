@@ -0,0 +1,446 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+// ============================================================================
+// ROUTING Y FAILOVER ENTRE PROVEEDORES
+// ============================================================================
+// Router es un Provider que reparte entre varios. Service no cambia: recibe
+// un Router en lugar de un proveedor concreto (patrón Composite).
+//
+// Para cada autorización:
+//  1. La Strategy ordena los candidatos (prioridad, round-robin ponderado,
+//     reglas por moneda o monto)
+//  2. Se saltea a los que tienen el circuit breaker abierto
+//  3. Si el proveedor responde ErrProviderUnavailable (seguro que no cobró)
+//     se pasa al siguiente. Un rechazo se devuelve tal cual: otro proveedor
+//     no hace válida una tarjeta rechazada
+//  4. Un timeout es ambiguo (pudo haber cobrado): no se prueba otro; el
+//     reintento con la misma key vuelve al mismo proveedor, que deduplica
+//
+// Cada intento queda en el DecisionLog para conciliar con los reportes de
+// los proveedores.
+// ============================================================================
+
+var ErrNoProvider = errors.New("payments: no provider could process the request")
+
+// Strategy ordena los proveedores a intentar para un monto
+type Strategy interface {
+	Name() string
+	Route(amount money.Money, providers []string) []string
+}
+
+// ----------------------------------------------------------------------------
+// Estrategias
+// ----------------------------------------------------------------------------
+
+type priorityStrategy struct{}
+
+// Priority intenta los proveedores en el orden en que se registraron
+func Priority() Strategy {
+	return priorityStrategy{}
+}
+
+func (priorityStrategy) Name() string { return "priority" }
+
+func (priorityStrategy) Route(amount money.Money, providers []string) []string {
+	return providers
+}
+
+type weightedRoundRobin struct {
+	weights map[string]int
+
+	mu      sync.Mutex
+	current map[string]int
+}
+
+// WeightedRoundRobin reparte el primer intento según weights (smooth weighted
+// round-robin, como nginx: con pesos 5/1/1 la secuencia es A A B A C A A,
+// no A A A A A B C). Los demás proveedores quedan detrás para failover.
+// Un proveedor sin peso solo se usa como failover.
+func WeightedRoundRobin(weights map[string]int) Strategy {
+	return &weightedRoundRobin{weights: weights, current: make(map[string]int)}
+}
+
+func (w *weightedRoundRobin) Name() string { return "weighted_round_robin" }
+
+func (w *weightedRoundRobin) Route(amount money.Money, providers []string) []string {
+	w.mu.Lock()
+	best, total := "", 0
+	for _, name := range providers {
+		weight := w.weights[name]
+		if weight <= 0 {
+			continue
+		}
+		w.current[name] += weight
+		total += weight
+		if best == "" || w.current[name] > w.current[best] {
+			best = name
+		}
+	}
+	if best != "" {
+		w.current[best] -= total
+	}
+	w.mu.Unlock()
+
+	if best == "" {
+		return providers
+	}
+	order := []string{best}
+	for _, name := range providers {
+		if name != best {
+			order = append(order, name)
+		}
+	}
+	return order
+}
+
+// Rule envía los montos que cumplen la condición a Providers, en ese orden.
+// Los campos vacíos no filtran.
+type Rule struct {
+	Currency  string
+	MinAmount int64 // En unidades mínimas, inclusive
+	MaxAmount int64 // En unidades mínimas, inclusive (0 = sin tope)
+	Providers []string
+}
+
+func (r Rule) matches(amount money.Money) bool {
+	return (r.Currency == "" || r.Currency == amount.Currency()) &&
+		amount.MinorUnits() >= r.MinAmount &&
+		(r.MaxAmount == 0 || amount.MinorUnits() <= r.MaxAmount)
+}
+
+type ruleStrategy struct {
+	rules    []Rule
+	fallback Strategy
+}
+
+// ByRules usa la primera regla que coincide; si ninguna coincide, fallback.
+// Ejemplo: soles solo por el procesador local, montos grandes por el banco.
+func ByRules(fallback Strategy, rules ...Rule) Strategy {
+	return ruleStrategy{rules: rules, fallback: fallback}
+}
+
+func (s ruleStrategy) Name() string { return "rules" }
+
+func (s ruleStrategy) Route(amount money.Money, providers []string) []string {
+	for _, rule := range s.rules {
+		if !rule.matches(amount) {
+			continue
+		}
+		var order []string
+		for _, name := range rule.Providers {
+			for _, registered := range providers {
+				if name == registered {
+					order = append(order, name)
+				}
+			}
+		}
+		return order
+	}
+	return s.fallback.Route(amount, providers)
+}
+
+// ----------------------------------------------------------------------------
+// Registro de decisiones
+// ----------------------------------------------------------------------------
+
+// Outcome es el resultado de un intento con un proveedor
+type Outcome string
+
+const (
+	OutcomeSuccess   Outcome = "success"
+	OutcomeFailed    Outcome = "failed"    // Error definitivo (p. ej. rechazo)
+	OutcomeFailover  Outcome = "failover"  // Proveedor caído: se pasó al siguiente
+	OutcomeSkipped   Outcome = "skipped"   // Circuit breaker abierto
+	OutcomeAmbiguous Outcome = "ambiguous" // Timeout: puede haberse procesado
+)
+
+// Decision es un intento de routing. Key es la idempotency key (o la
+// referencia de la autorización en una captura).
+type Decision struct {
+	Key       string        `json:"key"`
+	Operation string        `json:"operation"` // authorize, capture, refund
+	Strategy  string        `json:"strategy"`
+	Provider  string        `json:"provider"`
+	Attempt   int           `json:"attempt"`
+	Outcome   Outcome       `json:"outcome"`
+	Error     string        `json:"error,omitempty"`
+	Amount    money.Money   `json:"amount"`
+	Latency   time.Duration `json:"latency_ns"`
+	At        time.Time     `json:"at"`
+}
+
+// DecisionLog guarda las decisiones de routing
+type DecisionLog interface {
+	Record(ctx context.Context, d Decision) error
+	// ByKey devuelve las decisiones de una key en orden
+	ByKey(ctx context.Context, key string) ([]Decision, error)
+}
+
+// MemoryDecisionLog es un DecisionLog en memoria
+type MemoryDecisionLog struct {
+	mu        sync.RWMutex
+	decisions []Decision
+	byKey     map[string][]int
+}
+
+func NewMemoryDecisionLog() *MemoryDecisionLog {
+	return &MemoryDecisionLog{byKey: make(map[string][]int)}
+}
+
+func (l *MemoryDecisionLog) Record(ctx context.Context, d Decision) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.byKey[d.Key] = append(l.byKey[d.Key], len(l.decisions))
+	l.decisions = append(l.decisions, d)
+	return nil
+}
+
+func (l *MemoryDecisionLog) ByKey(ctx context.Context, key string) ([]Decision, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	decisions := make([]Decision, 0, len(l.byKey[key]))
+	for _, i := range l.byKey[key] {
+		decisions = append(decisions, l.decisions[i])
+	}
+	return decisions, nil
+}
+
+// All devuelve todas las decisiones (para conciliar)
+func (l *MemoryDecisionLog) All() []Decision {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]Decision(nil), l.decisions...)
+}
+
+// ----------------------------------------------------------------------------
+// Router
+// ----------------------------------------------------------------------------
+
+type routedProvider struct {
+	provider Provider
+	breaker  *CircuitBreaker
+}
+
+// Router implementa Provider sobre varios proveedores
+type Router struct {
+	names     []string // en orden de prioridad
+	providers map[string]*routedProvider
+	strategy  Strategy
+	log       DecisionLog
+	now       func() time.Time
+}
+
+type routerOptions struct {
+	strategy Strategy
+	breaker  BreakerConfig
+	log      DecisionLog
+	now      func() time.Time
+}
+
+// RouterOption configura un Router
+type RouterOption func(*routerOptions)
+
+// WithStrategy elige la estrategia (Priority por defecto)
+func WithStrategy(s Strategy) RouterOption {
+	return func(o *routerOptions) {
+		o.strategy = s
+	}
+}
+
+// WithBreakerConfig configura el circuit breaker de cada proveedor
+func WithBreakerConfig(cfg BreakerConfig) RouterOption {
+	return func(o *routerOptions) {
+		o.breaker = cfg
+	}
+}
+
+// WithDecisionLog define dónde se registran las decisiones (por defecto, en memoria)
+func WithDecisionLog(log DecisionLog) RouterOption {
+	return func(o *routerOptions) {
+		o.log = log
+	}
+}
+
+// WithRouterClock reemplaza time.Now (para tests del breaker)
+func WithRouterClock(now func() time.Time) RouterOption {
+	return func(o *routerOptions) {
+		o.now = now
+	}
+}
+
+// NewRouter crea un Router; el orden de providers es la prioridad
+func NewRouter(providers []Provider, opts ...RouterOption) *Router {
+	o := routerOptions{strategy: Priority(), log: NewMemoryDecisionLog(), now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := &Router{
+		providers: make(map[string]*routedProvider, len(providers)),
+		strategy:  o.strategy,
+		log:       o.log,
+		now:       o.now,
+	}
+	for _, p := range providers {
+		r.names = append(r.names, p.Name())
+		r.providers[p.Name()] = &routedProvider{provider: p, breaker: NewCircuitBreaker(o.breaker, o.now)}
+	}
+	return r
+}
+
+func (r *Router) Name() string {
+	return "router"
+}
+
+// BreakerState devuelve el estado del breaker de un proveedor
+func (r *Router) BreakerState(provider string) BreakerState {
+	if rp, ok := r.providers[provider]; ok {
+		return rp.breaker.State()
+	}
+	return BreakerClosed
+}
+
+// ProviderOf devuelve el proveedor que emitió una referencia del Router
+func (r *Router) ProviderOf(ref string) string {
+	provider, _, _ := strings.Cut(ref, ":")
+	return provider
+}
+
+// Authorize prueba los candidatos de la estrategia con failover. La
+// referencia devuelta es "<proveedor>:<ref>" para que Capture y Refund
+// vuelvan al mismo proveedor.
+func (r *Router) Authorize(ctx context.Context, idempotencyKey string, amount money.Money) (string, error) {
+	candidates, err := r.candidates(ctx, idempotencyKey, amount)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w: no route for %s", ErrNoProvider, amount)
+	}
+
+	var lastErr error
+	for attempt, name := range candidates {
+		var ref string
+		outcome, err := r.call(ctx, "authorize", idempotencyKey, name, attempt+1, amount, func(p Provider) error {
+			var err error
+			ref, err = p.Authorize(ctx, idempotencyKey, amount)
+			return err
+		})
+		switch outcome {
+		case OutcomeSuccess:
+			return name + ":" + ref, nil
+		case OutcomeFailover, OutcomeSkipped:
+			lastErr = err
+			continue
+		default:
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: %w", ErrNoProvider, lastErr)
+}
+
+func (r *Router) Capture(ctx context.Context, ref string, amount money.Money) error {
+	name, providerRef, err := r.splitRef(ref)
+	if err != nil {
+		return err
+	}
+	_, err = r.call(ctx, "capture", ref, name, 1, amount, func(p Provider) error {
+		return p.Capture(ctx, providerRef, amount)
+	})
+	return err
+}
+
+func (r *Router) Refund(ctx context.Context, ref, idempotencyKey string, amount money.Money) (string, error) {
+	name, providerRef, err := r.splitRef(ref)
+	if err != nil {
+		return "", err
+	}
+	var refundRef string
+	_, err = r.call(ctx, "refund", idempotencyKey, name, 1, amount, func(p Provider) error {
+		var err error
+		refundRef, err = p.Refund(ctx, providerRef, idempotencyKey, amount)
+		return err
+	})
+	return refundRef, err
+}
+
+// candidates devuelve el orden a intentar. Si la key ya tuvo un intento
+// exitoso o ambiguo, solo ese proveedor: otro podría cobrar dos veces.
+func (r *Router) candidates(ctx context.Context, key string, amount money.Money) ([]string, error) {
+	previous, err := r.log.ByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range previous {
+		if d.Operation == "authorize" && (d.Outcome == OutcomeSuccess || d.Outcome == OutcomeAmbiguous) {
+			return []string{d.Provider}, nil
+		}
+	}
+	return r.strategy.Route(amount, r.names), nil
+}
+
+// call ejecuta fn contra un proveedor a través de su breaker y registra la decisión
+func (r *Router) call(ctx context.Context, operation, key, name string, attempt int, amount money.Money, fn func(Provider) error) (Outcome, error) {
+	d := Decision{
+		Key: key, Operation: operation, Strategy: r.strategy.Name(),
+		Provider: name, Attempt: attempt, Amount: amount, At: r.now(),
+	}
+
+	rp, ok := r.providers[name]
+	var err error
+	switch {
+	case !ok:
+		err = fmt.Errorf("%w: unknown provider %q", ErrNoProvider, name)
+		d.Outcome = OutcomeFailed
+	case !rp.breaker.Allow():
+		err = fmt.Errorf("%s: %w", name, ErrBreakerOpen)
+		d.Outcome = OutcomeSkipped
+	default:
+		err = fn(rp.provider)
+		rp.breaker.Record(err)
+		d.Latency = r.now().Sub(d.At)
+		d.Outcome = classify(err)
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	// Un error del log no cambia el resultado: el proveedor ya respondió
+	if logErr := r.log.Record(ctx, d); logErr != nil {
+		log.Printf("payments: recording routing decision for %s: %v", key, logErr)
+	}
+	return d.Outcome, err
+}
+
+func classify(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, ErrProviderUnavailable):
+		return OutcomeFailover
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return OutcomeAmbiguous
+	default:
+		return OutcomeFailed
+	}
+}
+
+func (r *Router) splitRef(ref string) (provider, providerRef string, err error) {
+	provider, providerRef, ok := strings.Cut(ref, ":")
+	if !ok {
+		return "", "", fmt.Errorf("payments: invalid router reference %q", ref)
+	}
+	return provider, providerRef, nil
+}
@@ -0,0 +1,133 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+func TestRouterFailsOverOnlyOnUnavailable(t *testing.T) {
+	ctx := context.Background()
+	primary, backup := NewFakeProvider("primary"), NewFakeProvider("backup")
+	decisions := NewMemoryDecisionLog()
+	router := NewRouter([]Provider{primary, backup}, WithDecisionLog(decisions))
+	svc := NewService(NewMemoryRepository(), router)
+
+	// Caído: pasa al backup y el pago queda con el proveedor real
+	primary.FailNext(ErrProviderUnavailable)
+	result, err := svc.Charge(ctx, ChargeRequest{IdempotencyKey: "a", Amount: money.MustNew(100, "USD")})
+	if err != nil {
+		t.Fatalf("Charge: %v", err)
+	}
+	p, _ := svc.Get(ctx, result.PaymentID)
+	if p.Provider != "backup" {
+		t.Errorf("payment provider = %q, want backup", p.Provider)
+	}
+	got := decisions.All()
+	if len(got) != 3 || got[0].Outcome != OutcomeFailover || got[1].Outcome != OutcomeSuccess || got[2].Operation != "capture" {
+		t.Errorf("decisions = %+v, want failover, success, capture", got)
+	}
+
+	// Rechazo: no se prueba otro proveedor
+	primary.FailNext(ErrCardDeclined)
+	if _, err := svc.Charge(ctx, ChargeRequest{IdempotencyKey: "b", Amount: money.MustNew(100, "USD")}); !errors.Is(err, ErrCardDeclined) {
+		t.Fatalf("Charge = %v, want ErrCardDeclined", err)
+	}
+	if n := backup.Calls("Authorize"); n != 1 {
+		t.Errorf("backup authorized %d times, want 1 (only the failover)", n)
+	}
+}
+
+func TestRouterStaysWithProviderAfterAmbiguousTimeout(t *testing.T) {
+	ctx := context.Background()
+	primary, backup := NewFakeProvider("primary"), NewFakeProvider("backup")
+	router := NewRouter([]Provider{primary, backup})
+	amount := money.MustNew(100, "USD")
+
+	primary.FailNext(context.DeadlineExceeded)
+	if _, err := router.Authorize(ctx, "k", amount); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Authorize = %v, want DeadlineExceeded", err)
+	}
+	// El reintento vuelve a primary aunque ahora backup esté primero en la estrategia
+	ref, err := router.Authorize(ctx, "k", amount)
+	if err != nil || !strings.HasPrefix(ref, "primary:") {
+		t.Fatalf("retry = %q, %v; want a primary reference", ref, err)
+	}
+	if backup.Calls("Authorize") != 0 {
+		t.Error("backup was called after an ambiguous timeout")
+	}
+}
+
+func TestRouterCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+	primary, backup := NewFakeProvider("primary"), NewFakeProvider("backup")
+	router := NewRouter([]Provider{primary, backup},
+		WithBreakerConfig(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}),
+		WithRouterClock(clock))
+	amount := money.MustNew(100, "USD")
+
+	primary.FailNext(ErrProviderUnavailable, ErrProviderUnavailable)
+	router.Authorize(ctx, "k1", amount)
+	router.Authorize(ctx, "k2", amount)
+	if s := router.BreakerState("primary"); s != BreakerOpen {
+		t.Fatalf("breaker = %s, want open", s)
+	}
+
+	// Abierto: ni se llama a primary
+	calls := primary.Calls("Authorize")
+	router.Authorize(ctx, "k3", amount)
+	if primary.Calls("Authorize") != calls {
+		t.Error("primary called with breaker open")
+	}
+
+	// Pasado el timeout una llamada de prueba lo cierra
+	now = now.Add(time.Minute)
+	ref, err := router.Authorize(ctx, "k4", amount)
+	if err != nil || !strings.HasPrefix(ref, "primary:") {
+		t.Fatalf("probe = %q, %v; want primary", ref, err)
+	}
+	if s := router.BreakerState("primary"); s != BreakerClosed {
+		t.Errorf("breaker = %s, want closed", s)
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	providers := []string{"a", "b", "c"}
+
+	t.Run("weighted round robin", func(t *testing.T) {
+		wrr := WeightedRoundRobin(map[string]int{"a": 5, "b": 1, "c": 1})
+		var firsts []string
+		for i := 0; i < 7; i++ {
+			firsts = append(firsts, wrr.Route(money.MustNew(1, "USD"), providers)[0])
+		}
+		if got := strings.Join(firsts, ""); got != "aabacaa" {
+			t.Errorf("sequence = %s, want aabacaa", got)
+		}
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		rules := ByRules(Priority(),
+			Rule{Currency: "PEN", Providers: []string{"c"}},
+			Rule{MinAmount: 100000, Providers: []string{"b", "a"}},
+		)
+		tests := []struct {
+			amount money.Money
+			want   string
+		}{
+			{money.MustNew(500, "PEN"), "c"},
+			{money.MustNew(250000, "USD"), "ba"},
+			{money.MustNew(500, "USD"), "abc"},
+		}
+		for _, tt := range tests {
+			if got := strings.Join(rules.Route(tt.amount, providers), ""); got != tt.want {
+				t.Errorf("Route(%s) = %s, want %s", tt.amount, got, tt.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,342 @@
+// Package payments procesa cobros con un Provider intercambiable: montos
+// exactos (finance/money), idempotency keys, reembolsos parciales y una máquina de
+// estados explícita (ver payment.go).
+//
+// Es la versión "de producción" del PaymentService de fundamentals/interfaces.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/concurrency/stripelock"
+	"github.com/josediaz/go-mastery-lab/finance/internal/ids"
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+var (
+	ErrMissingIdempotencyKey = errors.New("payments: idempotency key is required")
+	// ErrIdempotencyKeyReused: la key ya se usó con otro monto u otro pago
+	ErrIdempotencyKeyReused = errors.New("payments: idempotency key reused with different parameters")
+	ErrInvalidAmount        = errors.New("payments: amount must be positive")
+	// ErrPaymentFailed envuelve el motivo del rechazo (p. ej. ErrCardDeclined)
+	ErrPaymentFailed         = errors.New("payments: payment failed")
+	ErrNotRefundable         = errors.New("payments: payment is not captured")
+	ErrRefundExceedsCaptured = errors.New("payments: refund exceeds refundable amount")
+)
+
+// ChargeRequest es un pedido de cobro
+type ChargeRequest struct {
+	// IdempotencyKey identifica el pedido del cliente: reintentar con la misma
+	// key devuelve el mismo pago en vez de cobrar otra vez
+	IdempotencyKey string
+	Amount         money.Money
+	Description    string
+	// AuthorizeOnly deja el pago en authorized; se cobra después con Capture
+	// (p. ej. un hotel que autoriza al reservar y cobra al hacer check-out)
+	AuthorizeOnly bool
+}
+
+// ChargeResult es el estado del pago después de Charge
+type ChargeResult struct {
+	PaymentID   string
+	Status      Status
+	Amount      money.Money
+	ProviderRef string
+	// Replayed indica que la key ya existía: es el resultado del primer pedido
+	Replayed bool
+}
+
+// RefundRequest es un pedido de reembolso (total o parcial)
+type RefundRequest struct {
+	PaymentID      string
+	IdempotencyKey string
+	Amount         money.Money
+}
+
+// Service orquesta el Repository y el Provider
+type Service struct {
+	repo     Repository
+	provider Provider
+	now      func() time.Time
+	// locks: dos Charge con la misma key o dos Refund del mismo pago esperan
+	// uno al otro
+	locks stripelock.Set
+}
+
+// Option configura un Service
+type Option func(*Service)
+
+// WithClock reemplaza time.Now (para tests)
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+func NewService(repo Repository, provider Provider, opts ...Option) *Service {
+	s := &Service{
+		repo:     repo,
+		provider: provider,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Charge cobra req.Amount. Es idempotente por req.IdempotencyKey:
+//
+//   - key nueva: crea el pago en pending, autoriza y captura
+//   - key de un pago terminado: devuelve ese resultado (Replayed) sin llamar
+//     al proveedor; si falló, devuelve el mismo ErrPaymentFailed
+//   - key de un pago que quedó pending (timeout, caída del proceso): reanuda;
+//     el proveedor recibe la misma key y no autoriza dos veces
+//   - key de un pago que quedó authorized porque falló la captura: captura
+//     (salvo que el pedido sea AuthorizeOnly)
+//
+// Un error transitorio del proveedor (IsRetryable), o el ctx del cliente
+// cancelado a mitad de la llamada, deja el pago en pending o authorized para
+// que el cliente reintente con la misma key.
+func (s *Service) Charge(ctx context.Context, req ChargeRequest) (ChargeResult, error) {
+	if req.IdempotencyKey == "" {
+		return ChargeResult{}, ErrMissingIdempotencyKey
+	}
+	refunded, err := money.Zero(req.Amount.Currency())
+	if err != nil {
+		return ChargeResult{}, err
+	}
+	if !req.Amount.IsPositive() {
+		return ChargeResult{}, ErrInvalidAmount
+	}
+
+	// Dos requests con la misma key en paralelo: el segundo espera al primero
+	unlock := s.locks.Lock("charge:" + req.IdempotencyKey)
+	defer unlock()
+
+	p, err := s.repo.GetByIdempotencyKey(ctx, req.IdempotencyKey)
+	switch {
+	case err == nil:
+		return s.resume(ctx, p, req)
+	case !errors.Is(err, ErrPaymentNotFound):
+		return ChargeResult{}, err
+	}
+
+	now := s.now()
+	p = &Payment{
+		ID:             ids.New("pay"),
+		IdempotencyKey: req.IdempotencyKey,
+		Amount:         req.Amount,
+		Refunded:       refunded,
+		Description:    req.Description,
+		Status:         StatusPending,
+		Provider:       s.provider.Name(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.repo.Create(ctx, p); err != nil {
+		if errors.Is(err, ErrDuplicateKey) {
+			// Otro proceso lo creó entre GetByIdempotencyKey y Create y puede
+			// seguir en pending: se trata igual que una key ya vista
+			existing, getErr := s.repo.GetByIdempotencyKey(ctx, req.IdempotencyKey)
+			if getErr != nil {
+				return ChargeResult{}, getErr
+			}
+			return s.resume(ctx, existing, req)
+		}
+		return ChargeResult{}, err
+	}
+	return s.process(ctx, p, req.AuthorizeOnly, false)
+}
+
+// resume continúa un pago que ya existe con la key de req: nunca devuelve un
+// pago pending o authorized (si se pidió captura) sin error, porque quien
+// llama lo tomaría como cobrado
+func (s *Service) resume(ctx context.Context, p *Payment, req ChargeRequest) (ChargeResult, error) {
+	if p.Amount != req.Amount {
+		return ChargeResult{}, ErrIdempotencyKeyReused
+	}
+	switch {
+	case p.Status == StatusPending:
+		return s.process(ctx, p, req.AuthorizeOnly, true)
+	case p.Status == StatusAuthorized && !req.AuthorizeOnly:
+		return s.capture(ctx, p, true)
+	}
+	return replayResult(p)
+}
+
+// process lleva un pago pending hasta authorized o captured (o failed)
+func (s *Service) process(ctx context.Context, p *Payment, authorizeOnly, replayed bool) (ChargeResult, error) {
+	ref, err := s.provider.Authorize(ctx, p.IdempotencyKey, p.Amount)
+	if err != nil {
+		return s.fail(ctx, p, replayed, err)
+	}
+	p.ProviderRef = ref
+	if router, ok := s.provider.(interface{ ProviderOf(ref string) string }); ok {
+		p.Provider = router.ProviderOf(ref) // El proveedor real, no "router"
+	}
+	if err := p.transition(StatusAuthorized, s.now()); err != nil {
+		return ChargeResult{}, err
+	}
+	if err := s.repo.Update(ctx, p); err != nil {
+		return ChargeResult{}, err
+	}
+	if authorizeOnly {
+		return newResult(p, replayed), nil
+	}
+	return s.capture(ctx, p, replayed)
+}
+
+func (s *Service) capture(ctx context.Context, p *Payment, replayed bool) (ChargeResult, error) {
+	if err := s.provider.Capture(ctx, p.ProviderRef, p.Amount); err != nil {
+		return s.fail(ctx, p, replayed, err)
+	}
+	if err := p.transition(StatusCaptured, s.now()); err != nil {
+		return ChargeResult{}, err
+	}
+	if err := s.repo.Update(ctx, p); err != nil {
+		return ChargeResult{}, err
+	}
+	return newResult(p, replayed), nil
+}
+
+// fail registra un error del proveedor. Los transitorios no cambian el estado,
+// tampoco context.Canceled: si el cliente se desconecta no sabemos qué hizo
+// el proveedor (como OutcomeAmbiguous del Router).
+func (s *Service) fail(ctx context.Context, p *Payment, replayed bool, cause error) (ChargeResult, error) {
+	if IsRetryable(cause) || errors.Is(cause, context.Canceled) {
+		return newResult(p, replayed), cause
+	}
+	p.FailureReason = cause.Error()
+	if err := p.transition(StatusFailed, s.now()); err != nil {
+		return ChargeResult{}, err
+	}
+	if err := s.repo.Update(ctx, p); err != nil {
+		return ChargeResult{}, err
+	}
+	return newResult(p, replayed), fmt.Errorf("%w: %w", ErrPaymentFailed, cause)
+}
+
+// Capture cobra un pago autorizado con AuthorizeOnly. Sobre un pago ya
+// capturado no hace nada.
+func (s *Service) Capture(ctx context.Context, paymentID string) (ChargeResult, error) {
+	unlock := s.locks.Lock("payment:" + paymentID)
+	defer unlock()
+
+	p, err := s.repo.GetByID(ctx, paymentID)
+	if err != nil {
+		return ChargeResult{}, err
+	}
+	if p.Status == StatusCaptured {
+		return newResult(p, true), nil
+	}
+	if !CanTransition(p.Status, StatusCaptured) {
+		return newResult(p, false), fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, p.Status, StatusCaptured)
+	}
+	return s.capture(ctx, p, false)
+}
+
+// Refund devuelve req.Amount de un pago capturado. Es idempotente por
+// req.IdempotencyKey. Cuando la suma de reembolsos llega al monto del pago,
+// el pago pasa a refunded.
+func (s *Service) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	if req.IdempotencyKey == "" {
+		return nil, ErrMissingIdempotencyKey
+	}
+	if !req.Amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+
+	// Serializar por pago: dos reembolsos en paralelo no pueden superar el total
+	unlock := s.locks.Lock("payment:" + req.PaymentID)
+	defer unlock()
+
+	existing, err := s.repo.GetRefundByIdempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.PaymentID != req.PaymentID || existing.Amount != req.Amount {
+			return nil, ErrIdempotencyKeyReused
+		}
+		return existing, nil
+	}
+
+	p, err := s.repo.GetByID(ctx, req.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != StatusCaptured {
+		return nil, fmt.Errorf("%w (status %s)", ErrNotRefundable, p.Status)
+	}
+	refundable := p.Refundable()
+	cmp, err := req.Amount.Cmp(refundable)
+	if err != nil {
+		return nil, err
+	}
+	if cmp > 0 {
+		return nil, fmt.Errorf("%w (refundable %s)", ErrRefundExceedsCaptured, refundable)
+	}
+
+	// Si el proceso cae después de esta llamada, el reintento con la misma
+	// key recibe el mismo reembolso del proveedor
+	refundRef, err := s.provider.Refund(ctx, p.ProviderRef, req.IdempotencyKey, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	refund := &Refund{
+		ID:             ids.New("re"),
+		PaymentID:      p.ID,
+		IdempotencyKey: req.IdempotencyKey,
+		Amount:         req.Amount,
+		ProviderRef:    refundRef,
+		CreatedAt:      now,
+	}
+	if err := s.repo.CreateRefund(ctx, refund); err != nil {
+		return nil, err
+	}
+
+	p.Refunded, _ = p.Refunded.Add(req.Amount)
+	p.UpdatedAt = now
+	if p.Refunded == p.Amount {
+		if err := p.transition(StatusRefunded, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Update(ctx, p); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+func (s *Service) Get(ctx context.Context, paymentID string) (*Payment, error) {
+	return s.repo.GetByID(ctx, paymentID)
+}
+
+func (s *Service) Refunds(ctx context.Context, paymentID string) ([]*Refund, error) {
+	return s.repo.Refunds(ctx, paymentID)
+}
+
+func newResult(p *Payment, replayed bool) ChargeResult {
+	return ChargeResult{
+		PaymentID:   p.ID,
+		Status:      p.Status,
+		Amount:      p.Amount,
+		ProviderRef: p.ProviderRef,
+		Replayed:    replayed,
+	}
+}
+
+// replayResult repite la respuesta de un Charge anterior, incluido su error
+func replayResult(p *Payment) (ChargeResult, error) {
+	result := newResult(p, true)
+	if p.Status == StatusFailed {
+		return result, fmt.Errorf("%w: %s", ErrPaymentFailed, p.FailureReason)
+	}
+	return result, nil
+}
@@ -0,0 +1,319 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
+)
+
+func newTestService() (*Service, *FakeProvider) {
+	provider := NewFakeProvider("fake")
+	return NewService(NewMemoryRepository(), provider), provider
+}
+
+func TestChargeIsIdempotent(t *testing.T) {
+	svc, provider := newTestService()
+	ctx := context.Background()
+	req := ChargeRequest{IdempotencyKey: "order-1", Amount: money.MustNew(1050, "USD")}
+
+	first, err := svc.Charge(ctx, req)
+	if err != nil {
+		t.Fatalf("Charge: %v", err)
+	}
+	if first.Status != StatusCaptured || first.Replayed {
+		t.Fatalf("first charge = %+v, want captured and not replayed", first)
+	}
+
+	retry, err := svc.Charge(ctx, req)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if retry.PaymentID != first.PaymentID || !retry.Replayed {
+		t.Errorf("retry = %+v, want replay of %s", retry, first.PaymentID)
+	}
+	if n := provider.Calls("Authorize"); n != 1 {
+		t.Errorf("provider authorized %d times, want 1", n)
+	}
+
+	// Misma key con otro monto
+	req.Amount = money.MustNew(2000, "USD")
+	if _, err := svc.Charge(ctx, req); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("charge with reused key = %v, want ErrIdempotencyKeyReused", err)
+	}
+}
+
+func TestConcurrentChargesWithSameKeyChargeOnce(t *testing.T) {
+	svc, provider := newTestService()
+	req := ChargeRequest{IdempotencyKey: "order-1", Amount: money.MustNew(500, "EUR")}
+
+	var wg sync.WaitGroup
+	ids := make([]string, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := svc.Charge(context.Background(), req)
+			if err != nil {
+				t.Errorf("Charge: %v", err)
+			}
+			ids[i] = result.PaymentID
+		}(i)
+	}
+	wg.Wait()
+
+	for _, id := range ids[1:] {
+		if id != ids[0] {
+			t.Fatalf("got different payments %v", ids)
+		}
+	}
+	if n := provider.Calls("Capture"); n != 1 {
+		t.Errorf("provider captured %d times, want 1", n)
+	}
+}
+
+// failingCapture devuelve errs en las próximas llamadas a Capture, en orden,
+// sin llegar al FakeProvider
+type failingCapture struct {
+	*FakeProvider
+	errs []error
+}
+
+func (f *failingCapture) Capture(ctx context.Context, ref string, amount money.Money) error {
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	return f.FakeProvider.Capture(ctx, ref, amount)
+}
+
+// racingRepository simula otro proceso que crea el pago con la misma key
+// entre el GetByIdempotencyKey y el Create de Charge: la primera búsqueda
+// no lo encuentra
+type racingRepository struct {
+	*MemoryRepository
+	hidden bool
+}
+
+func (r *racingRepository) GetByIdempotencyKey(ctx context.Context, key string) (*Payment, error) {
+	if !r.hidden {
+		r.hidden = true
+		return nil, ErrPaymentNotFound
+	}
+	return r.MemoryRepository.GetByIdempotencyKey(ctx, key)
+}
+
+func TestChargeLosingCreateRaceResumesPayment(t *testing.T) {
+	ctx := context.Background()
+	amount := money.MustNew(700, "USD")
+	refunded, _ := money.Zero("USD")
+	tests := []struct {
+		name   string
+		status Status
+		req    ChargeRequest
+		want   Status
+		err    error
+	}{
+		{"pending is processed", StatusPending, ChargeRequest{Amount: amount}, StatusCaptured, nil},
+		{"authorized is captured", StatusAuthorized, ChargeRequest{Amount: amount}, StatusCaptured, nil},
+		{"authorize only keeps authorized", StatusAuthorized, ChargeRequest{Amount: amount, AuthorizeOnly: true}, StatusAuthorized, nil},
+		{"other amount is rejected", StatusPending, ChargeRequest{Amount: money.MustNew(1, "USD")}, "", ErrIdempotencyKeyReused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &racingRepository{MemoryRepository: NewMemoryRepository()}
+			provider := NewFakeProvider("fake")
+			svc := NewService(repo, provider)
+
+			// El otro proceso creó el pago (y autorizó, si corresponde) y se cayó
+			existing := &Payment{ID: "pay_other", IdempotencyKey: "k", Amount: amount, Refunded: refunded, Status: StatusPending}
+			if tt.status == StatusAuthorized {
+				ref, _ := provider.Authorize(ctx, "k", amount)
+				existing.Status, existing.ProviderRef = StatusAuthorized, ref
+			}
+			if err := repo.Create(ctx, existing); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.req.IdempotencyKey = "k"
+			result, err := svc.Charge(ctx, tt.req)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Charge err = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && (result.PaymentID != "pay_other" || result.Status != tt.want || !result.Replayed) {
+				t.Errorf("Charge = %+v, want %s replay of pay_other", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestChargeFailures(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("declined is final", func(t *testing.T) {
+		svc, provider := newTestService()
+		provider.Decline(1000)
+		req := ChargeRequest{IdempotencyKey: "k", Amount: money.MustNew(5000, "USD")}
+
+		result, err := svc.Charge(ctx, req)
+		if !errors.Is(err, ErrPaymentFailed) || !errors.Is(err, ErrCardDeclined) {
+			t.Fatalf("Charge = %v, want ErrPaymentFailed wrapping ErrCardDeclined", err)
+		}
+		if result.Status != StatusFailed {
+			t.Errorf("status = %s, want failed", result.Status)
+		}
+		// El reintento repite el fallo sin volver a llamar al proveedor
+		if _, err := svc.Charge(ctx, req); !errors.Is(err, ErrPaymentFailed) {
+			t.Errorf("retry = %v, want ErrPaymentFailed", err)
+		}
+		if n := provider.Calls("Authorize"); n != 1 {
+			t.Errorf("provider called %d times, want 1", n)
+		}
+	})
+
+	t.Run("unavailable stays pending and resumes", func(t *testing.T) {
+		svc, provider := newTestService()
+		provider.FailNext(ErrProviderUnavailable)
+		req := ChargeRequest{IdempotencyKey: "k", Amount: money.MustNew(100, "PEN")}
+
+		result, err := svc.Charge(ctx, req)
+		if !errors.Is(err, ErrProviderUnavailable) || result.Status != StatusPending {
+			t.Fatalf("Charge = %+v, %v; want pending with ErrProviderUnavailable", result, err)
+		}
+		result, err = svc.Charge(ctx, req)
+		if err != nil || result.Status != StatusCaptured {
+			t.Fatalf("retry = %+v, %v; want captured", result, err)
+		}
+	})
+
+	t.Run("capture failure resumes with capture", func(t *testing.T) {
+		provider := &failingCapture{FakeProvider: NewFakeProvider("fake"), errs: []error{ErrProviderUnavailable}}
+		svc := NewService(NewMemoryRepository(), provider)
+		req := ChargeRequest{IdempotencyKey: "k", Amount: money.MustNew(100, "USD")}
+
+		result, err := svc.Charge(ctx, req)
+		if !errors.Is(err, ErrProviderUnavailable) || result.Status != StatusAuthorized {
+			t.Fatalf("Charge = %+v, %v; want authorized with ErrProviderUnavailable", result, err)
+		}
+		result, err = svc.Charge(ctx, req)
+		if err != nil || result.Status != StatusCaptured || !result.Replayed {
+			t.Fatalf("retry = %+v, %v; want captured", result, err)
+		}
+		if a, c := provider.Calls("Authorize"), provider.Calls("Capture"); a != 1 || c != 1 {
+			t.Errorf("provider calls: Authorize %d, Capture %d; want one successful each", a, c)
+		}
+	})
+
+	t.Run("client cancel stays pending", func(t *testing.T) {
+		svc, _ := newTestService()
+		req := ChargeRequest{IdempotencyKey: "k", Amount: money.MustNew(100, "USD")}
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		result, err := svc.Charge(canceled, req)
+		if !errors.Is(err, context.Canceled) || errors.Is(err, ErrPaymentFailed) || result.Status != StatusPending {
+			t.Fatalf("Charge = %+v, %v; want pending with context.Canceled", result, err)
+		}
+		result, err = svc.Charge(ctx, req)
+		if err != nil || result.Status != StatusCaptured {
+			t.Fatalf("retry = %+v, %v; want captured", result, err)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		svc, _ := newTestService()
+		if _, err := svc.Charge(ctx, ChargeRequest{Amount: money.MustNew(1, "USD")}); !errors.Is(err, ErrMissingIdempotencyKey) {
+			t.Errorf("no key = %v", err)
+		}
+		if _, err := svc.Charge(ctx, ChargeRequest{IdempotencyKey: "k", Amount: money.MustNew(0, "USD")}); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("zero amount = %v", err)
+		}
+		if _, err := svc.Charge(ctx, ChargeRequest{IdempotencyKey: "k", Amount: money.Money{}}); !errors.Is(err, money.ErrUnknownCurrency) {
+			t.Errorf("unknown currency = %v", err)
+		}
+	})
+}
+
+func TestAuthorizeThenCapture(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	result, err := svc.Charge(ctx, ChargeRequest{IdempotencyKey: "hotel", Amount: money.MustNew(20000, "USD"), AuthorizeOnly: true})
+	if err != nil || result.Status != StatusAuthorized {
+		t.Fatalf("Charge = %+v, %v; want authorized", result, err)
+	}
+	if _, err := svc.Refund(ctx, RefundRequest{PaymentID: result.PaymentID, IdempotencyKey: "r", Amount: money.MustNew(1, "USD")}); !errors.Is(err, ErrNotRefundable) {
+		t.Errorf("refund before capture = %v, want ErrNotRefundable", err)
+	}
+
+	result, err = svc.Capture(ctx, result.PaymentID)
+	if err != nil || result.Status != StatusCaptured {
+		t.Fatalf("Capture = %+v, %v; want captured", result, err)
+	}
+}
+
+func TestRefunds(t *testing.T) {
+	svc, provider := newTestService()
+	ctx := context.Background()
+	charge, err := svc.Charge(ctx, ChargeRequest{IdempotencyKey: "k", Amount: money.MustNew(1000, "USD")})
+	if err != nil {
+		t.Fatalf("Charge: %v", err)
+	}
+	refund := func(key string, amount int64) error {
+		_, err := svc.Refund(ctx, RefundRequest{PaymentID: charge.PaymentID, IdempotencyKey: key, Amount: money.MustNew(amount, "USD")})
+		return err
+	}
+
+	if err := refund("r1", 300); err != nil {
+		t.Fatalf("partial refund: %v", err)
+	}
+	if err := refund("r1", 300); err != nil { // Reintento: no reembolsa otra vez
+		t.Fatalf("retried refund: %v", err)
+	}
+	if err := refund("r1", 400); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("reused refund key = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if err := refund("r2", 800); !errors.Is(err, ErrRefundExceedsCaptured) {
+		t.Errorf("over-refund = %v, want ErrRefundExceedsCaptured", err)
+	}
+
+	p, _ := svc.Get(ctx, charge.PaymentID)
+	if p.Status != StatusCaptured || p.Refunded.MinorUnits() != 300 {
+		t.Fatalf("after partial refund = %s refunded %s, want captured with 3.00 USD", p.Status, p.Refunded)
+	}
+
+	if err := refund("r3", 700); err != nil {
+		t.Fatalf("final refund: %v", err)
+	}
+	p, _ = svc.Get(ctx, charge.PaymentID)
+	if p.Status != StatusRefunded {
+		t.Errorf("status = %s, want refunded", p.Status)
+	}
+	refunds, _ := svc.Refunds(ctx, charge.PaymentID)
+	if len(refunds) != 2 || provider.Calls("Refund") != 2 {
+		t.Errorf("got %d refunds and %d provider calls, want 2 and 2", len(refunds), provider.Calls("Refund"))
+	}
+}
+
+func TestStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		want     bool
+	}{
+		{StatusPending, StatusAuthorized, true},
+		{StatusPending, StatusCaptured, false},
+		{StatusAuthorized, StatusCaptured, true},
+		{StatusCaptured, StatusRefunded, true},
+		{StatusCaptured, StatusFailed, false},
+		{StatusRefunded, StatusCaptured, false},
+		{StatusFailed, StatusAuthorized, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
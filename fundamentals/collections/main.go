@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"sort"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
 )
 
 // ============================================================================
@@ -81,6 +83,7 @@ func demonstrateSlices() {
 	fmt.Printf("slice1 [2:5]: %v\n", slice1)
 	fmt.Printf("slice2 [:5]: %v\n", slice2)
 	fmt.Printf("slice3 [5:]: %v\n", slice3)
+	fmt.Printf("slice4 [:]: %v\n", slice4)
 
 	// IMPORTANTE: Los slices comparten el array subyacente
 	slice1[0] = 999
@@ -193,10 +196,13 @@ func demonstrateCapAndLen() {
 type Product struct {
 	ID    string
 	Name  string
-	Price float64
+	Price money.Money // Nunca float64 para dinero (ver finance/money)
 	Stock int
 }
 
+// Inventory es didáctico: sin locks y con UpdateStock como read-modify-write.
+// La versión concurrente con reservas está en architecture/clean_arch_api
+// (InventoryUsecase).
 type Inventory struct {
 	products map[string]*Product
 	orders   []string // IDs de productos ordenados
@@ -209,7 +215,7 @@ func NewInventory() *Inventory {
 	}
 }
 
-func (inv *Inventory) AddProduct(id, name string, price float64, stock int) {
+func (inv *Inventory) AddProduct(id, name string, price money.Money, stock int) {
 	inv.products[id] = &Product{
 		ID:    id,
 		Name:  name,
@@ -246,12 +252,18 @@ func (inv *Inventory) ListProducts() []*Product {
 	return products
 }
 
-func (inv *Inventory) GetTotalValue() float64 {
-	total := 0.0
+// GetTotalValue suma precio * stock de todos los productos. Falla si algún
+// producto tiene otra moneda (no se suman dólares con soles).
+func (inv *Inventory) GetTotalValue(currency string) (money.Money, error) {
+	values := make([]money.Money, 0, len(inv.products))
 	for _, product := range inv.products {
-		total += product.Price * float64(product.Stock)
+		value, err := product.Price.Mul(int64(product.Stock))
+		if err != nil {
+			return money.Money{}, err
+		}
+		values = append(values, value)
 	}
-	return total
+	return money.Sum(currency, values...)
 }
 
 // ============================================================================
@@ -297,7 +309,8 @@ func advancedSliceOperations() {
 // ============================================================================
 
 func main() {
-	fmt.Println("=== FUNDAMENTOS: SLICES, MAPS, ARRAYS ===\n")
+	fmt.Println("=== FUNDAMENTOS: SLICES, MAPS, ARRAYS ===")
+	fmt.Println()
 
 	// 1. Arrays
 	fmt.Println("1. Arrays:")
@@ -322,16 +335,20 @@ func main() {
 	// 5. Ejemplo práctico: Inventario
 	fmt.Println("5. Ejemplo práctico: Sistema de Inventario:")
 	inventory := NewInventory()
-	inventory.AddProduct("P001", "Laptop", 999.99, 10)
-	inventory.AddProduct("P002", "Mouse", 29.99, 50)
-	inventory.AddProduct("P003", "Keyboard", 79.99, 30)
+	inventory.AddProduct("P001", "Laptop", money.MustParse("999.99", "USD"), 10)
+	inventory.AddProduct("P002", "Mouse", money.MustParse("29.99", "USD"), 50)
+	inventory.AddProduct("P003", "Keyboard", money.MustParse("79.99", "USD"), 30)
 
 	fmt.Println("Productos en inventario:")
 	for _, product := range inventory.ListProducts() {
-		fmt.Printf("  %s: %s - $%.2f (Stock: %d)\n", product.ID, product.Name, product.Price, product.Stock)
+		fmt.Printf("  %s: %s - %s (Stock: %d)\n", product.ID, product.Name, product.Price.Format(), product.Stock)
 	}
 
-	fmt.Printf("\nValor total del inventario: $%.2f\n", inventory.GetTotalValue())
+	if total, err := inventory.GetTotalValue("USD"); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	} else {
+		fmt.Printf("\nValor total del inventario: %s\n", total.Format())
+	}
 
 	if err := inventory.UpdateStock("P001", -2); err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"math"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
 )
 
 // ============================================================================
@@ -85,9 +88,12 @@ func (r Rectangle) Perimeter() float64 {
 
 // PaymentProvider define el contrato para procesadores de pago
 // Similar a una interfaz en Java: interface PaymentProvider { ... }
+// Es un contrato didáctico: finance/payments tiene la versión completa
+// (context, montos exactos, idempotency keys y reembolsos)
 type PaymentProvider interface {
-	// ProcessPayment procesa un pago y retorna un error si falla
-	ProcessPayment(amount float64, currency string) error
+	// ProcessPayment procesa un pago y retorna un error si falla.
+	// money.Money lleva el monto exacto y su moneda (nada de float64)
+	ProcessPayment(amount money.Money) error
 
 	// GetName retorna el nombre del proveedor
 	GetName() string
@@ -114,11 +120,11 @@ func NewStripeProvider(apiKey string) *StripeProvider {
 	}
 }
 
-func (s *StripeProvider) ProcessPayment(amount float64, currency string) error {
+func (s *StripeProvider) ProcessPayment(amount money.Money) error {
 	if !s.IsEnabled {
 		return fmt.Errorf("stripe provider is disabled")
 	}
-	fmt.Printf("[Stripe] Processing payment: %.2f %s\n", amount, currency)
+	fmt.Printf("[Stripe] Processing payment: %s\n", amount)
 	fmt.Printf("[Stripe] Using API Key: %s...\n", s.APIKey[:10])
 	// Aquí iría la lógica real de Stripe
 	return nil
@@ -147,11 +153,11 @@ func NewPayPalProvider(clientID, clientSecret string) *PayPalProvider {
 	}
 }
 
-func (p *PayPalProvider) ProcessPayment(amount float64, currency string) error {
+func (p *PayPalProvider) ProcessPayment(amount money.Money) error {
 	if !p.IsAvailable() {
 		return fmt.Errorf("paypal provider is not available")
 	}
-	fmt.Printf("[PayPal] Processing payment: %.2f %s\n", amount, currency)
+	fmt.Printf("[PayPal] Processing payment: %s\n", amount)
 	fmt.Printf("[PayPal] Using Client ID: %s\n", p.ClientID)
 	// Aquí iría la lógica real de PayPal
 	return nil
@@ -180,11 +186,11 @@ func NewBankTransferProvider(bankName, account string) *BankTransferProvider {
 	}
 }
 
-func (b *BankTransferProvider) ProcessPayment(amount float64, currency string) error {
+func (b *BankTransferProvider) ProcessPayment(amount money.Money) error {
 	if !b.IsAvailable() {
 		return fmt.Errorf("bank transfer provider is not available")
 	}
-	fmt.Printf("[Bank Transfer] Processing payment: %.2f %s\n", amount, currency)
+	fmt.Printf("[Bank Transfer] Processing payment: %s\n", amount)
 	fmt.Printf("[Bank Transfer] Bank: %s, Account: %s\n", b.BankName, b.Account)
 	return nil
 }
@@ -215,24 +221,36 @@ func NewPaymentService(providers ...PaymentProvider) *PaymentService {
 }
 
 // ProcessPaymentWithProvider procesa un pago con un proveedor específico
-func (ps *PaymentService) ProcessPaymentWithProvider(providerName string, amount float64, currency string) error {
+func (ps *PaymentService) ProcessPaymentWithProvider(providerName string, amount money.Money) error {
 	for _, provider := range ps.providers {
 		if provider.GetName() == providerName && provider.IsAvailable() {
-			return provider.ProcessPayment(amount, currency)
+			return provider.ProcessPayment(amount)
 		}
 	}
 	return fmt.Errorf("provider %s not found or not available", providerName)
 }
 
 // ProcessPaymentWithFirstAvailable procesa con el primer proveedor disponible
-func (ps *PaymentService) ProcessPaymentWithFirstAvailable(amount float64, currency string) error {
+// y, si falla, pasa al siguiente (failover). Aquí cualquier error cuenta como
+// transitorio; finance/payments.Router distingue rechazos de caídas, agrega
+// circuit breakers y otras estrategias de routing.
+func (ps *PaymentService) ProcessPaymentWithFirstAvailable(amount money.Money) error {
+	var errs []error
 	for _, provider := range ps.providers {
-		if provider.IsAvailable() {
-			fmt.Printf("Using provider: %s\n", provider.GetName())
-			return provider.ProcessPayment(amount, currency)
+		if !provider.IsAvailable() {
+			continue
+		}
+		fmt.Printf("Using provider: %s\n", provider.GetName())
+		err := provider.ProcessPayment(amount)
+		if err == nil {
+			return nil
 		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.GetName(), err))
 	}
-	return fmt.Errorf("no payment provider available")
+	if len(errs) == 0 {
+		return fmt.Errorf("no payment provider available")
+	}
+	return errors.Join(errs...)
 }
 
 // ListAvailableProviders lista todos los proveedores disponibles
@@ -288,7 +306,8 @@ func acceptAnything(value interface{}) {
 // ============================================================================
 
 func main() {
-	fmt.Println("=== FUNDAMENTOS: INTERFACES IMPLÍCITAS ===\n")
+	fmt.Println("=== FUNDAMENTOS: INTERFACES IMPLÍCITAS ===")
+	fmt.Println()
 
 	// 1. Interfaces básicas con formas geométricas
 	fmt.Println("1. Interfaces básicas (Shapes):")
@@ -320,14 +339,14 @@ func main() {
 
 	// Procesar pago con proveedor específico
 	fmt.Println("3. Procesando pago con Stripe:")
-	if err := paymentService.ProcessPaymentWithProvider("Stripe", 99.99, "USD"); err != nil {
+	if err := paymentService.ProcessPaymentWithProvider("Stripe", money.MustParse("99.99", "USD")); err != nil {
 		fmt.Printf("Error: %v\n", err)
 	}
 	fmt.Println()
 
 	// Procesar pago con primer proveedor disponible
 	fmt.Println("4. Procesando pago con primer proveedor disponible:")
-	if err := paymentService.ProcessPaymentWithFirstAvailable(50.00, "EUR"); err != nil {
+	if err := paymentService.ProcessPaymentWithFirstAvailable(money.MustParse("50.00", "EUR")); err != nil {
 		fmt.Printf("Error: %v\n", err)
 	}
 	fmt.Println()
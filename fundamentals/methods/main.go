@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
 )
 
 // ============================================================================
@@ -143,41 +145,53 @@ func (is IntSlice) Append(value int) IntSlice {
 // EJEMPLO PRÁCTICO: BANCO CON CUENTAS
 // ============================================================================
 
+// Balance es money.Money y no float64: con float64, 0.10 + 0.20 da
+// 0.30000000000000004 y los centavos se pierden (ver finance/money)
+// Es un ejemplo didáctico: Balance se modifica directamente, sin historia ni
+// locks. finance/ledger tiene la versión con partida doble.
 type Account struct {
 	ID      string
-	Balance float64
+	Balance money.Money
 	Owner   string
 }
 
 // Deposit deposita dinero (modifica, usa pointer receiver)
-func (a *Account) Deposit(amount float64) error {
-	if amount <= 0 {
+func (a *Account) Deposit(amount money.Money) error {
+	if !amount.IsPositive() {
 		return fmt.Errorf("deposit amount must be positive")
 	}
-	a.Balance += amount
+	balance, err := a.Balance.Add(amount) // Falla si la moneda no coincide
+	if err != nil {
+		return err
+	}
+	a.Balance = balance
 	return nil
 }
 
 // Withdraw retira dinero (modifica, usa pointer receiver)
-func (a *Account) Withdraw(amount float64) error {
-	if amount <= 0 {
+func (a *Account) Withdraw(amount money.Money) error {
+	if !amount.IsPositive() {
 		return fmt.Errorf("withdrawal amount must be positive")
 	}
-	if a.Balance < amount {
+	balance, err := a.Balance.Sub(amount)
+	if err != nil {
+		return err
+	}
+	if balance.IsNegative() {
 		return fmt.Errorf("insufficient funds")
 	}
-	a.Balance -= amount
+	a.Balance = balance
 	return nil
 }
 
 // GetBalance obtiene el balance (lee, pero usa pointer por consistencia)
-func (a *Account) GetBalance() float64 {
+func (a *Account) GetBalance() money.Money {
 	return a.Balance
 }
 
 // String implementa Stringer (value receiver porque solo lee)
 func (a Account) String() string {
-	return fmt.Sprintf("Account{ID: %s, Owner: %s, Balance: $%.2f}", a.ID, a.Owner, a.Balance)
+	return fmt.Sprintf("Account{ID: %s, Owner: %s, Balance: %s}", a.ID, a.Owner, a.Balance.Format())
 }
 
 // ============================================================================
@@ -196,15 +210,15 @@ func (a Account) String() string {
 
 func demonstrateAutomaticConversion() {
 	// Crear un Account
-	acc := Account{ID: "ACC001", Balance: 1000.0, Owner: "John"}
+	acc := Account{ID: "ACC001", Balance: money.MustParse("1000", "USD"), Owner: "John"}
 
 	// Llamar método con pointer receiver usando un value
 	// Go automáticamente convierte: (&acc).Deposit(100)
-	acc.Deposit(100)
+	acc.Deposit(money.MustParse("100", "USD"))
 	fmt.Printf("After deposit: %s\n", acc)
 
 	// Crear un pointer a Account
-	accPtr := &Account{ID: "ACC002", Balance: 500.0, Owner: "Jane"}
+	accPtr := &Account{ID: "ACC002", Balance: money.MustParse("500", "USD"), Owner: "Jane"}
 
 	// Llamar método con value receiver usando un pointer
 	// Go automáticamente convierte: (*accPtr).String()
@@ -216,7 +230,8 @@ func demonstrateAutomaticConversion() {
 // ============================================================================
 
 func main() {
-	fmt.Println("=== FUNDAMENTOS: MÉTODOS Y RECEPTORES ===\n")
+	fmt.Println("=== FUNDAMENTOS: MÉTODOS Y RECEPTORES ===")
+	fmt.Println()
 
 	// 1. Value receiver vs Pointer receiver
 	fmt.Println("1. Value Receiver (no modifica el original):")
@@ -252,24 +267,24 @@ func main() {
 	fmt.Println("5. Ejemplo práctico: Cuenta bancaria:")
 	account := &Account{
 		ID:      "ACC001",
-		Balance: 1000.0,
+		Balance: money.MustParse("1000", "USD"),
 		Owner:   "John Doe",
 	}
 	fmt.Printf("Initial: %s\n", account)
 
-	if err := account.Deposit(500.0); err != nil {
+	if err := account.Deposit(money.MustParse("500", "USD")); err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
 		fmt.Printf("After deposit: %s\n", account)
 	}
 
-	if err := account.Withdraw(200.0); err != nil {
+	if err := account.Withdraw(money.MustParse("200", "USD")); err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
 		fmt.Printf("After withdrawal: %s\n", account)
 	}
 
-	if err := account.Withdraw(2000.0); err != nil {
+	if err := account.Withdraw(money.MustParse("2000", "USD")); err != nil {
 		fmt.Printf("Error (expected): %v\n", err)
 	}
 	fmt.Println()
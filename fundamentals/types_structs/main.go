@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"time"
+
+	"github.com/josediaz/go-mastery-lab/finance/money"
 )
 
 // ============================================================================
@@ -58,12 +60,12 @@ type Employee struct {
 	Person              // Campos de Person están directamente accesibles
 	Address             // Campos de Address también
 	EmployeeID string
-	Salary    float64
+	Salary    money.Money // Montos exactos, no float64 (ver finance/money)
 	Department string
 }
 
 // NewEmployee crea un nuevo empleado
-func NewEmployee(name string, age int, email string, employeeID string, salary float64) *Employee {
+func NewEmployee(name string, age int, email string, employeeID string, salary money.Money) *Employee {
 	return &Employee{
 		Person: Person{
 			Name:      name,
@@ -163,7 +165,7 @@ func anonymousStruct() {
 
 // PaymentMethod es una interfaz (veremos interfaces después)
 type PaymentMethod interface {
-	ProcessPayment(amount float64) error
+	ProcessPayment(amount money.Money) error
 	GetName() string
 }
 
@@ -176,8 +178,8 @@ type CreditCard struct {
 }
 
 // ProcessPayment implementa PaymentMethod para CreditCard
-func (c *CreditCard) ProcessPayment(amount float64) error {
-	fmt.Printf("Processing credit card payment of $%.2f for card ending in %s\n", amount, c.Number[len(c.Number)-4:])
+func (c *CreditCard) ProcessPayment(amount money.Money) error {
+	fmt.Printf("Processing credit card payment of %s for card ending in %s\n", amount.Format(), c.Number[len(c.Number)-4:])
 	return nil
 }
 
@@ -191,8 +193,8 @@ type PayPal struct {
 	Password string // En producción, nunca almacenes passwords en texto plano
 }
 
-func (p *PayPal) ProcessPayment(amount float64) error {
-	fmt.Printf("Processing PayPal payment of $%.2f for account %s\n", amount, p.Email)
+func (p *PayPal) ProcessPayment(amount money.Money) error {
+	fmt.Printf("Processing PayPal payment of %s for account %s\n", amount.Format(), p.Email)
 	return nil
 }
 
@@ -205,7 +207,8 @@ func (p *PayPal) GetName() string {
 // ============================================================================
 
 func main() {
-	fmt.Println("=== FUNDAMENTOS: TIPOS Y STRUCTS ===\n")
+	fmt.Println("=== FUNDAMENTOS: TIPOS Y STRUCTS ===")
+	fmt.Println()
 
 	// 1. Crear structs básicos
 	fmt.Println("1. Creando structs básicos:")
@@ -215,12 +218,13 @@ func main() {
 
 	// 2. Struct embedding
 	fmt.Println("2. Struct embedding (composición):")
-	employee := NewEmployee("Jane Smith", 28, "jane@example.com", "EMP001", 75000.0)
+	employee := NewEmployee("Jane Smith", 28, "jane@example.com", "EMP001", money.MustParse("75000", "USD"))
 	employee.Street = "123 Main St"
 	employee.City = "San Francisco"
 	employee.Country = "USA"
 	fmt.Printf("Employee: %s\n", employee.Name) // Acceso directo a campos embebidos
 	fmt.Printf("Address: %s\n", employee.GetFullAddress())
+	fmt.Printf("Salary: %s\n", employee.Salary.Format())
 	fmt.Println()
 
 	// 3. Métodos
@@ -259,8 +263,8 @@ func main() {
 	}
 
 	// Procesar pagos (veremos interfaces después)
-	creditCard.ProcessPayment(100.50)
-	paypal.ProcessPayment(50.25)
+	creditCard.ProcessPayment(money.MustParse("100.50", "USD"))
+	paypal.ProcessPayment(money.MustParse("50.25", "USD"))
 	fmt.Println()
 
 	fmt.Println("=== FIN DE EJEMPLOS ===")
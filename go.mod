@@ -4,8 +4,8 @@ go 1.21
 
 require (
 	github.com/go-chi/chi/v5 v5.0.10
+	github.com/mattn/go-sqlite3 v1.14.52
 	github.com/spf13/viper v1.17.0
 	google.golang.org/grpc v1.59.0
 	google.golang.org/protobuf v1.31.0
 )
-
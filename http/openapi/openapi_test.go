@@ -0,0 +1,221 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type createUser struct {
+	Email   string   `json:"email"`
+	Name    string   `json:"name"`
+	Age     int      `json:"age,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Address *address `json:"address,omitempty"`
+	Role    role     `json:"role,omitempty"`
+	secret  string
+}
+
+type user struct {
+	ID int `json:"id" openapi:"readonly"`
+	createUser
+	Nickname  *string   `json:"nickname"`
+	CreatedAt time.Time `json:"created_at"`
+	Internal  string    `json:"-"`
+}
+
+type role string
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func newTestAPI(t *testing.T) (*Spec, chi.Router) {
+	t.Helper()
+	spec := New("Test API", "1.0.0")
+	spec.DefineSchema(role(""), *Enum("admin", "member"))
+	spec.Describe("POST", "/users", Operation{
+		Summary:   "Crear usuario",
+		Request:   createUser{},
+		Responses: Responses{201: {Body: user{}}, 400: {Description: "Body inválido"}},
+	})
+	spec.Describe("GET", "/users/{id}", Operation{
+		Params:    []Param{PathParam("id", Integer())},
+		Responses: Responses{200: {Body: user{}}},
+	})
+	spec.Describe("GET", "/users", Operation{
+		Params:    []Param{QueryParam("limit", Integer(), false, ""), QueryParam("active", Boolean(), true, "")},
+		Responses: Responses{200: {Body: []user{}}},
+	})
+	spec.Exclude("/debug/vars")
+
+	r := chi.NewRouter()
+	r.Use(spec.Validator(r))
+	r.Get("/openapi.json", spec.Handler(r))
+	r.Post("/users", ok)
+	r.Get("/users", ok)
+	r.Get("/users/{id}", ok)
+	r.Get("/users/export", ok)
+	r.Handle("/debug/vars", http.HandlerFunc(ok))
+	return spec, r
+}
+
+func TestBuildGeneratesPathsAndSchemas(t *testing.T) {
+	_, r := newTestAPI(t)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d %s", w.Code, w.Body)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["openapi"] != Version {
+		t.Errorf("openapi = %v", doc["openapi"])
+	}
+	paths := doc["paths"].(map[string]any)
+	for _, p := range []string{"/users", "/users/{id}", "/users/export", "/openapi.json"} {
+		if paths[p] == nil {
+			t.Errorf("path %s missing", p)
+		}
+	}
+	if paths["/debug/vars"] != nil {
+		t.Error("excluded path /debug/vars is in the document")
+	}
+
+	post := paths["/users"].(map[string]any)["post"].(map[string]any)
+	if post["operationId"] != "postUsers" {
+		t.Errorf("operationId = %v", post["operationId"])
+	}
+	ref := post["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)["$ref"]
+	if ref != "#/components/schemas/CreateUser" {
+		t.Errorf("request $ref = %v", ref)
+	}
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	u := schemas["User"].(map[string]any)
+	props := u["properties"].(map[string]any)
+	// Embebido aplanado, "-" y campos sin exportar afuera
+	for _, name := range []string{"id", "email", "name", "age", "tags", "address", "role", "nickname", "created_at"} {
+		if props[name] == nil {
+			t.Errorf("user.%s missing", name)
+		}
+	}
+	if props["Internal"] != nil || props["secret"] != nil {
+		t.Errorf("hidden fields leaked: %v", props)
+	}
+	if got := props["nickname"].(map[string]any)["type"]; !equalJSON(got, []any{"string", "null"}) {
+		t.Errorf("nickname type = %v, want nullable string", got)
+	}
+	if got := props["created_at"].(map[string]any)["format"]; got != "date-time" {
+		t.Errorf("created_at format = %v", got)
+	}
+	if props["id"].(map[string]any)["readOnly"] != true {
+		t.Error("id is not readOnly")
+	}
+	// Punteros y omitempty no son requeridos
+	if got := u["required"]; !equalJSON(got, []any{"id", "email", "name", "created_at"}) {
+		t.Errorf("required = %v", got)
+	}
+	if got := schemas["Role"].(map[string]any)["enum"]; !equalJSON(got, []any{"admin", "member"}) {
+		t.Errorf("role enum = %v", got)
+	}
+
+	// Ruta sin Describe: aparece con su parámetro y respuesta default
+	export := paths["/users/export"].(map[string]any)["get"].(map[string]any)
+	if export["responses"].(map[string]any)["default"] == nil {
+		t.Errorf("undocumented route responses = %v", export["responses"])
+	}
+}
+
+func TestBuildReportsDrift(t *testing.T) {
+	spec, r := newTestAPI(t)
+	doc, err := spec.Build(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(doc.Undocumented(), ","); got != "GET /openapi.json,GET /users/export" {
+		t.Errorf("Undocumented = %q", got)
+	}
+
+	spec.Describe("DELETE", "/users/{id}", Operation{})
+	if _, err := spec.Build(r); err == nil || !strings.Contains(err.Error(), "DELETE /users/{id}") {
+		t.Errorf("Build with a stale Describe = %v, want error", err)
+	}
+}
+
+func TestValidatorRejectsInvalidRequests(t *testing.T) {
+	_, r := newTestAPI(t)
+
+	tests := []struct {
+		name, method, target, contentType, body string
+		want                                    int
+		wantMsg                                 string
+	}{
+		{"valid body", "POST", "/users", "application/json", `{"email":"a@x.com","name":"Ana","tags":["x"],"role":"admin"}`, 200, ""},
+		{"unknown fields are allowed", "POST", "/users", "", `{"email":"a@x.com","name":"Ana","extra":1}`, 200, ""},
+		{"missing required", "POST", "/users", "application/json", `{"email":"a@x.com"}`, 400, "body.name is required"},
+		{"wrong types", "POST", "/users", "application/json", `{"email":1,"name":"Ana","age":1.5,"tags":"x"}`, 400, "body.age must be an integer; body.email must be a string; body.tags must be an array"},
+		{"nested object", "POST", "/users", "application/json", `{"email":"a","name":"b","address":{}}`, 400, "body.address.city is required"},
+		{"enum", "POST", "/users", "application/json", `{"email":"a","name":"b","role":"root"}`, 400, "body.role must be one of [admin member]"},
+		{"invalid JSON", "POST", "/users", "application/json", `{"email":`, 400, "not valid JSON"},
+		{"empty body", "POST", "/users", "application/json", ``, 400, "request body is required"},
+		{"not JSON", "POST", "/users", "text/csv", `email,name`, 415, "Content-Type"},
+		{"path param type", "GET", "/users/abc", "", "", 400, `path parameter "id" must be an integer`},
+		{"valid path param", "GET", "/users/42", "", "", 200, ""},
+		{"static route wins over {id}", "GET", "/users/export", "", "", 200, ""},
+		{"required query", "GET", "/users?limit=10", "", "", 400, `query parameter "active" is required`},
+		{"query types", "GET", "/users?active=maybe&limit=x", "", "", 400, `query parameter "limit" must be an integer; query parameter "active" must be a boolean`},
+		{"valid query", "GET", "/users?active=true", "", "", 200, ""},
+		{"unknown route passes through", "GET", "/nope", "", "", 404, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.wantMsg) {
+				t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.target, w.Code, w.Body, tt.want, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestValidatorLeavesBodyForHandler(t *testing.T) {
+	spec := New("Test API", "1.0.0")
+	spec.Describe("POST", "/users", Operation{Request: createUser{}})
+	r := chi.NewRouter()
+	r.Use(spec.Validator(r))
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		var req createUser
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name != "Ana" {
+			t.Errorf("handler decoded %+v, %v", req, err)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"email":"a@x.com","name":"Ana"}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d %s", w.Code, w.Body)
+	}
+}
+
+func equalJSON(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
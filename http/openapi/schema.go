@@ -0,0 +1,272 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema es un JSON Schema (el dialecto de OpenAPI 3.1). Solo tiene las
+// palabras clave que usan los generadores y el validador de este paquete.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"-"`
+	Nullable             bool               `json:"-"` // 3.1: type: ["string", "null"]
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Example              any                `json:"example,omitempty"`
+}
+
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema // Sin MarshalJSON, para no entrar en recursión
+	out := struct {
+		Type any `json:"type,omitempty"`
+		plain
+	}{plain: plain(s)}
+	switch {
+	case s.Type != "" && s.Nullable:
+		out.Type = []string{s.Type, "null"}
+	case s.Type != "":
+		out.Type = s.Type
+	}
+	return json.Marshal(out)
+}
+
+// Atajos para los parámetros y los esquemas que se definen a mano
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+// Enum es un string con valores fijos
+func Enum(values ...string) *Schema {
+	s := &Schema{Type: "string"}
+	for _, v := range values {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	rawJSONType   = reflect.TypeOf(json.RawMessage(nil))
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemas genera esquemas a partir de tipos Go con las mismas reglas que
+// encoding/json. Los structs con nombre van a components/schemas y se
+// referencian con $ref.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	overrides  map[reflect.Type]*Schema
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+		overrides:  make(map[reflect.Type]*Schema),
+	}
+}
+
+// of devuelve el esquema de v (un valor de ejemplo del tipo, p. ej. Foo{})
+func (g *schemas) of(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	if s, ok := v.(*Schema); ok {
+		return s
+	}
+	return g.forType(reflect.TypeOf(v))
+}
+
+func (g *schemas) forType(t reflect.Type) *Schema {
+	if s, ok := g.overrides[t]; ok {
+		return g.named(t, func() *Schema { return s })
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case t == rawJSONType:
+		return &Schema{}
+	case t.Kind() != reflect.Pointer && t.Implements(marshalerType),
+		t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(marshalerType):
+		// JSON propio sin esquema declarado (ver Spec.DefineSchema): cualquier valor
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := *g.forType(t.Elem())
+		if s.Ref != "" {
+			// $ref no admite hermanos en todas las herramientas: se deja tal cual
+			return &s
+		}
+		s.Nullable = true
+		return &s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.forType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.named(t, func() *Schema { return g.structSchema(t) })
+	default:
+		// interface{} y compañía: cualquier valor
+		return &Schema{}
+	}
+}
+
+// named registra el esquema del tipo en components y devuelve el $ref
+func (g *schemas) named(t reflect.Type, build func() *Schema) *Schema {
+	if t.Name() == "" {
+		return build()
+	}
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		g.components[name] = &Schema{} // Reserva el nombre (tipos recursivos)
+		*g.components[name] = *build()
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName usa el nombre del tipo; si dos paquetes tienen uno igual, el
+// segundo lleva el paquete como prefijo (domain.Product -> DomainProduct).
+// Los genéricos pegan sus argumentos: page[pkg.User] -> PageUser.
+func (g *schemas) componentName(t reflect.Type) string {
+	name := typeName(t.Name())
+	if _, taken := g.components[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	base := exportedName(pkg) + name
+	name = base
+	for i := 2; ; i++ {
+		if _, taken := g.components[name]; !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// structSchema recorre los campos como encoding/json: tag json, omitempty,
+// "-" y structs embebidos (sus campos suben al nivel de afuera)
+func (g *schemas) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := g.structSchema(ft)
+				for k, v := range embedded.Properties {
+					s.Properties[k] = v
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		var prop *Schema
+		if strings.Contains(opts, "string") {
+			prop = &Schema{Type: "string"}
+		} else {
+			prop = g.forType(f.Type)
+		}
+		// openapi:"readonly": lo asigna el servidor (id, created_at); no es
+		// requerido en los requests
+		readOnly := f.Tag.Get("openapi") == "readonly"
+		if readOnly && prop.Ref == "" {
+			prop.ReadOnly = true
+		}
+		s.Properties[name] = prop
+		// Requerido = siempre presente en el JSON: sin omitempty y sin puntero
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+func typeName(name string) string {
+	base, args, generic := strings.Cut(name, "[")
+	if !generic {
+		return sanitize(exportedName(name))
+	}
+	var b strings.Builder
+	b.WriteString(exportedName(base))
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		// "github.com/x/pkg.User" -> "User"
+		if i := strings.LastIndexAny(arg, "./"); i >= 0 {
+			arg = arg[i+1:]
+		}
+		b.WriteString(exportedName(arg))
+	}
+	return sanitize(b.String())
+}
+
+func exportedName(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// sanitize deja solo caracteres válidos en un nombre de components (los
+// genéricos tienen corchetes y rutas de paquetes)
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,388 @@
+// Package openapi genera un documento OpenAPI 3.1 a partir de las rutas
+// registradas en un router chi y de los tipos Go de requests y respuestas.
+// También trae un middleware opcional que valida los requests contra el
+// documento.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ============================================================================
+// SPEC: RUTAS + TIPOS -> OPENAPI 3.1
+// ============================================================================
+// Las rutas salen del router (chi.Walk), así que el documento no se
+// desincroniza cuando se agrega un endpoint: aparece aunque nadie lo
+// describa. Lo que el router no sabe (tipos del body, respuestas,
+// parámetros de query) se agrega con Describe:
+//
+//	spec.Describe("POST", "/users", openapi.Operation{
+//		Summary:   "Crear usuario",
+//		Request:   handler.CreateUserRequest{},
+//		Responses: openapi.Responses{201: {Body: handler.UserResponse{}}},
+//	})
+//
+// Los esquemas se generan por reflection con las reglas de encoding/json
+// (tags json, omitempty, punteros). Similar a springdoc-openapi en Java,
+// pero sin anotaciones: los tipos se pasan como valores de ejemplo.
+// ============================================================================
+
+const (
+	Version = "3.1.0"
+
+	contentJSON = "application/json"
+)
+
+// Operation describe un endpoint. Request y Body aceptan un valor del tipo
+// (Foo{}, []Foo{}) o un *Schema armado a mano.
+type Operation struct {
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	Params      []Param
+	Request     any
+	// RequestContentType es el Content-Type del body (application/json). Con
+	// otro tipo el body se documenta como texto y no se valida.
+	RequestContentType string
+	Responses          Responses
+}
+
+// Responses por código de estado
+type Responses map[int]Response
+
+type Response struct {
+	Description string
+	Body        any
+	ContentType string // application/json
+}
+
+// Param es un parámetro de path, query o header
+type Param struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+func PathParam(name string, schema *Schema) Param {
+	return Param{Name: name, In: "path", Required: true, Schema: schema}
+}
+
+func QueryParam(name string, schema *Schema, required bool, description string) Param {
+	return Param{Name: name, In: "query", Required: required, Description: description, Schema: schema}
+}
+
+func HeaderParam(name string, schema *Schema, description string) Param {
+	return Param{Name: name, In: "header", Description: description, Schema: schema}
+}
+
+// Spec junta las descripciones. El documento se arma con Build a partir
+// del router.
+type Spec struct {
+	title       string
+	version     string
+	description string
+
+	mu         sync.Mutex
+	operations map[string]Operation // "METHOD /pattern"
+	overrides  map[reflect.Type]*Schema
+	excluded   map[string]bool
+	common     []Param
+}
+
+type Option func(*Spec)
+
+func WithDescription(d string) Option {
+	return func(s *Spec) {
+		s.description = d
+	}
+}
+
+// WithCommonParams agrega parámetros a todas las operaciones (p. ej. un
+// header que acepta toda la API)
+func WithCommonParams(params ...Param) Option {
+	return func(s *Spec) {
+		s.common = append(s.common, params...)
+	}
+}
+
+func New(title, version string, opts ...Option) *Spec {
+	s := &Spec{
+		title:      title,
+		version:    version,
+		operations: make(map[string]Operation),
+		overrides:  make(map[reflect.Type]*Schema),
+		excluded:   make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Describe agrega tipos y respuestas a la ruta method + pattern (el mismo
+// pattern que se le pasó a chi)
+func (s *Spec) Describe(method, pattern string, op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operations[strings.ToUpper(method)+" "+pattern] = op
+}
+
+// Exclude deja afuera del documento las rutas con estos patterns (debug,
+// métricas), en todos sus métodos
+func (s *Spec) Exclude(patterns ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range patterns {
+		s.excluded[p] = true
+	}
+}
+
+// DefineSchema fija el esquema de un tipo con JSON propio (MarshalJSON),
+// que reflection no puede deducir. Los tipos con nombre van a components.
+func (s *Spec) DefineSchema(example any, schema Schema) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides[reflect.TypeOf(example)] = &schema
+}
+
+// ============================================================================
+// DOCUMENTO
+// ============================================================================
+
+type Document struct {
+	OpenAPI      string                `json:"openapi"`
+	Info         Info                  `json:"info"`
+	Paths        map[string]PathItem   `json:"paths"`
+	Components   Components            `json:"components"`
+	operations   map[string]*operation // Para el validador, por "METHOD /path"
+	undocumented []string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// PathItem por método en minúsculas ("get", "post", ...)
+type PathItem map[string]*operation
+
+type operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Param             `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+
+	// Para validar requests
+	method   string
+	segments []string
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+var (
+	// paramPattern son los {param} de chi, con regexp opcional ({id:[0-9]+})
+	paramPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+	wordPattern  = regexp.MustCompile(`[A-Za-z0-9]+`)
+)
+
+// Build recorre las rutas del router y arma el documento. Las rutas sin
+// Describe aparecen con sus parámetros de path y una respuesta "default";
+// un Describe sin ruta (typo, endpoint borrado) es un error.
+func (s *Spec) Build(routes chi.Routes) (*Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gen := newSchemas()
+	for t, schema := range s.overrides {
+		gen.overrides[t] = schema
+	}
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: s.title, Version: s.version, Description: s.description},
+		Paths:      make(map[string]PathItem),
+		Components: Components{Schemas: gen.components},
+		operations: make(map[string]*operation),
+	}
+
+	ids := make(map[string]string)
+	seen := make(map[string]bool)
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/*")
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		if s.excluded[route] {
+			return nil
+		}
+		desc, ok := s.operations[method+" "+route]
+		seen[method+" "+route] = ok
+		path := paramPattern.ReplaceAllString(route, "{$1}")
+
+		op := s.operation(gen, method, path, desc)
+		if other, dup := ids[op.OperationID]; dup {
+			return fmt.Errorf("openapi: operationId %q used by %s and %s %s", op.OperationID, other, method, path)
+		}
+		ids[op.OperationID] = method + " " + path
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		doc.Paths[path][strings.ToLower(method)] = op
+		doc.operations[method+" "+path] = op
+		if !ok {
+			doc.undocumented = append(doc.undocumented, method+" "+route)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key := range s.operations {
+		if _, ok := seen[key]; !ok {
+			return nil, fmt.Errorf("openapi: %s is described but not registered in the router", key)
+		}
+	}
+	sort.Strings(doc.undocumented)
+	return doc, nil
+}
+
+func (s *Spec) operation(gen *schemas, method, path string, desc Operation) *operation {
+	op := &operation{
+		OperationID: desc.ID,
+		Summary:     desc.Summary,
+		Description: desc.Description,
+		Tags:        desc.Tags,
+		Responses:   make(map[string]response),
+		method:      method,
+		segments:    strings.Split(strings.Trim(path, "/"), "/"),
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(method, path)
+	}
+
+	// Parámetros de path: los del pattern, con el esquema de Params si está
+	declared := make(map[string]Param)
+	for _, p := range desc.Params {
+		declared[p.In+" "+p.Name] = p
+	}
+	for _, m := range paramPattern.FindAllStringSubmatch(path, -1) {
+		p, ok := declared["path "+m[1]]
+		if !ok {
+			p = PathParam(m[1], String())
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+	for _, p := range append(desc.Params, s.common...) {
+		if p.In != "path" {
+			op.Parameters = append(op.Parameters, p)
+		}
+	}
+
+	if desc.Request != nil {
+		ct := desc.RequestContentType
+		if ct == "" {
+			ct = contentJSON
+		}
+		schema := &Schema{Type: "string"}
+		if ct == contentJSON {
+			schema = gen.of(desc.Request)
+		}
+		op.RequestBody = &requestBody{Required: true, Content: map[string]mediaType{ct: {Schema: schema}}}
+	}
+
+	if len(desc.Responses) == 0 {
+		op.Responses["default"] = response{Description: "Respuesta sin documentar"}
+	}
+	for status, r := range desc.Responses {
+		resp := response{Description: r.Description}
+		if resp.Description == "" {
+			resp.Description = http.StatusText(status)
+		}
+		if r.Body != nil {
+			ct := r.ContentType
+			if ct == "" {
+				ct = contentJSON
+			}
+			schema := &Schema{Type: "string"}
+			if ct == contentJSON {
+				schema = gen.of(r.Body)
+			}
+			resp.Content = map[string]mediaType{ct: {Schema: schema}}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+	}
+	return op
+}
+
+// operationID arma un id estable: GET /users/{id}/orders -> getUsersIdOrders
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, word := range wordPattern.FindAllString(path, -1) {
+		b.WriteString(exportedName(word))
+	}
+	return b.String()
+}
+
+// Handler sirve el documento como JSON. Se arma en el primer request, así
+// incluye todas las rutas aunque el handler se registre antes que ellas.
+func (s *Spec) Handler(routes chi.Routes) http.HandlerFunc {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var doc *Document
+			if doc, err = s.Build(routes); err == nil {
+				body, err = json.MarshalIndent(doc, "", "  ")
+			}
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentJSON)
+		w.Write(body)
+	}
+}
+
+// Undocumented lista las rutas registradas sin Describe ("METHOD /pattern"),
+// para que un test falle cuando se agrega un endpoint sin documentarlo
+func (d *Document) Undocumented() []string {
+	return d.undocumented
+}
@@ -0,0 +1,330 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ============================================================================
+// VALIDACIÓN DE REQUESTS
+// ============================================================================
+// El middleware busca la operación del documento que corresponde al
+// request y revisa, antes de llegar al handler:
+//
+//	parámetros de path y query -> requeridos, tipo (integer, boolean), enum
+//	body JSON                  -> Content-Type, JSON válido, esquema
+//	                              (required, tipos, enum, date-time)
+//
+// Un request que no cumple recibe 400 (415 si el Content-Type no es JSON)
+// con todos los errores juntos. Rutas que no están en el documento pasan
+// sin cambios (el router responde 404/405). Las propiedades que no están
+// en el esquema se aceptan, igual que en encoding/json.
+// ============================================================================
+
+type validatorConfig struct {
+	maxBodyBytes int64
+}
+
+type ValidatorOption func(*validatorConfig)
+
+// WithMaxBodyBytes limita el body que se lee para validar (1 MiB)
+func WithMaxBodyBytes(n int64) ValidatorOption {
+	return func(c *validatorConfig) {
+		c.maxBodyBytes = n
+	}
+}
+
+// Validator devuelve el middleware. El documento se arma en el primer
+// request, así que se puede registrar con r.Use antes que las rutas.
+func (s *Spec) Validator(routes chi.Routes, opts ...ValidatorOption) func(http.Handler) http.Handler {
+	cfg := validatorConfig{maxBodyBytes: 1 << 20}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var (
+		once sync.Once
+		doc  *Document
+		err  error
+	)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			once.Do(func() { doc, err = s.Build(routes) })
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			doc.validator(cfg)(next).ServeHTTP(w, r)
+		})
+	}
+}
+
+func (d *Document) validator(cfg validatorConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, pathParams := d.match(r.Method, r.URL.Path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var errs []string
+			for _, p := range op.Parameters {
+				var value string
+				var present bool
+				switch p.In {
+				case "path":
+					value, present = pathParams[p.Name]
+				case "query":
+					values, ok := r.URL.Query()[p.Name]
+					if ok {
+						value, present = values[0], true
+					}
+				case "header":
+					value = r.Header.Get(p.Name)
+					present = value != ""
+				}
+				if !present {
+					if p.Required {
+						errs = append(errs, fmt.Sprintf("%s parameter %q is required", p.In, p.Name))
+					}
+					continue
+				}
+				errs = d.validateParam(value, p.Schema, p.In+" parameter "+strconv.Quote(p.Name), errs)
+			}
+
+			if media, ok := op.RequestBody.jsonSchema(); ok {
+				status, bodyErrs := d.validateBody(w, r, media, cfg.maxBodyBytes)
+				if status != http.StatusBadRequest {
+					http.Error(w, strings.Join(bodyErrs, "; "), status)
+					return
+				}
+				errs = append(errs, bodyErrs...)
+			}
+
+			if len(errs) > 0 {
+				http.Error(w, "request does not match the API spec: "+strings.Join(errs, "; "), http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// match busca la operación por método y path. Si varias coinciden gana la
+// de más segmentos fijos (/users/export antes que /users/{id}).
+func (d *Document) match(method, path string) (*operation, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var (
+		best       *operation
+		bestStatic = -1
+	)
+	for _, op := range d.operations {
+		if op.method != method || len(op.segments) != len(segments) {
+			continue
+		}
+		static := 0
+		matched := true
+		for i, tmpl := range op.segments {
+			if isParam(tmpl) {
+				continue
+			}
+			if tmpl != segments[i] {
+				matched = false
+				break
+			}
+			static++
+		}
+		if matched && static > bestStatic {
+			best, bestStatic = op, static
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	params := make(map[string]string)
+	for i, tmpl := range best.segments {
+		if isParam(tmpl) {
+			params[strings.Trim(tmpl, "{}")] = segments[i]
+		}
+	}
+	return best, params
+}
+
+// isParam reconoce un segmento {param}. Un segmento con parámetro y texto
+// fijo (chi permite /users:{action}) no se valida.
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && strings.Count(segment, "{") == 1
+}
+
+func (b *requestBody) jsonSchema() (*Schema, bool) {
+	if b == nil {
+		return nil, false
+	}
+	media, ok := b.Content[contentJSON]
+	return media.Schema, ok
+}
+
+// validateBody lee el body (y lo deja para el handler) y lo valida. El
+// status es 400 salvo errores que no son del contenido (413, 415).
+func (d *Document) validateBody(w http.ResponseWriter, r *http.Request, schema *Schema, limit int64) (int, []string) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != contentJSON && !strings.HasSuffix(mediaType, "+json")) {
+			return http.StatusUnsupportedMediaType, []string{fmt.Sprintf("Content-Type must be %s", contentJSON)}
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return http.StatusRequestEntityTooLarge, []string{"request body too large"}
+		}
+		return http.StatusBadRequest, []string{"reading request body"}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return http.StatusBadRequest, []string{"request body is required"}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return http.StatusBadRequest, []string{"request body is not valid JSON: " + err.Error()}
+	}
+	if dec.More() {
+		return http.StatusBadRequest, []string{"request body has more than one JSON value"}
+	}
+	return http.StatusBadRequest, d.validateValue(value, schema, "body", nil)
+}
+
+// validateParam valida un parámetro (siempre string en la URL) según el tipo
+// que declara su esquema
+func (d *Document) validateParam(raw string, schema *Schema, where string, errs []string) []string {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return errs
+	}
+	var value any = raw
+	switch schema.Type {
+	case "integer", "number":
+		value = json.Number(raw)
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return append(errs, fmt.Sprintf("%s must be a boolean", where))
+		}
+		value = b
+	}
+	return d.validateValue(value, schema, where, errs)
+}
+
+// validateValue valida un valor decodificado con UseNumber contra el esquema
+func (d *Document) validateValue(value any, schema *Schema, where string, errs []string) []string {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return errs
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return errs
+		}
+		return append(errs, fmt.Sprintf("%s must not be null", where))
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s must be an object", where))
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok && !d.resolveProp(schema, name).ReadOnly {
+				errs = append(errs, fmt.Sprintf("%s.%s is required", where, name))
+			}
+		}
+		// Orden estable para que el mensaje no cambie entre requests
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := schema.Properties[k]
+			if !ok {
+				prop = schema.AdditionalProperties
+			}
+			errs = d.validateValue(obj[k], prop, where+"."+k, errs)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s must be an array", where))
+		}
+		for i, item := range items {
+			errs = d.validateValue(item, schema.Items, fmt.Sprintf("%s[%d]", where, i), errs)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s must be a string", where))
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be an RFC 3339 date-time", where))
+			}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if _, err := n.Int64(); !ok || err != nil {
+			return append(errs, fmt.Sprintf("%s must be an integer", where))
+		}
+	case "number":
+		n, ok := value.(json.Number)
+		if _, err := n.Float64(); !ok || err != nil {
+			return append(errs, fmt.Sprintf("%s must be a number", where))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(errs, fmt.Sprintf("%s must be a boolean", where))
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				return errs
+			}
+		}
+		errs = append(errs, fmt.Sprintf("%s must be one of %v", where, schema.Enum))
+	}
+	return errs
+}
+
+// resolve sigue los $ref a components
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+func (d *Document) resolveProp(schema *Schema, name string) *Schema {
+	if prop := d.resolve(schema.Properties[name]); prop != nil {
+		return prop
+	}
+	return &Schema{}
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"time"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/josediaz/go-mastery-lab/http/openapi"
 )
 
 // ============================================================================
@@ -18,7 +20,7 @@ import (
 // ============================================================================
 
 type User struct {
-	ID    int    `json:"id"`
+	ID    int    `json:"id" openapi:"readonly"` // Lo asigna el servidor
 	Name  string `json:"name"`
 	Email string `json:"email"`
 }
@@ -67,6 +69,40 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// newAPISpec describe las rutas para /openapi.json (las rutas en sí salen
+// del router)
+func newAPISpec() *openapi.Spec {
+	spec := openapi.New("REST API", "1.0.0")
+	spec.Describe(http.MethodGet, "/users", openapi.Operation{
+		ID:        "listUsers",
+		Summary:   "Listar usuarios",
+		Responses: openapi.Responses{200: {Body: []User{}}},
+	})
+	spec.Describe(http.MethodGet, "/users/{id}", openapi.Operation{
+		ID:        "getUser",
+		Summary:   "Obtener usuario",
+		Params:    []openapi.Param{openapi.PathParam("id", openapi.Integer())},
+		Responses: openapi.Responses{200: {Body: User{}}, 400: {Description: "ID inválido"}, 404: {Description: "No existe"}},
+	})
+	spec.Describe(http.MethodPost, "/users", openapi.Operation{
+		ID:        "createUser",
+		Summary:   "Crear usuario",
+		Request:   User{},
+		Responses: openapi.Responses{201: {Body: User{}}, 400: {Description: "JSON inválido"}},
+	})
+	spec.Describe(http.MethodGet, "/health", openapi.Operation{
+		ID:        "health",
+		Summary:   "Health check",
+		Responses: openapi.Responses{200: {Body: openapi.String(), ContentType: "text/plain"}},
+	})
+	spec.Describe(http.MethodGet, "/openapi.json", openapi.Operation{
+		ID:        "getOpenAPI",
+		Summary:   "Este documento",
+		Responses: openapi.Responses{200: {Body: &openapi.Schema{Type: "object"}}},
+	})
+	return spec
+}
+
 func main() {
 	r := chi.NewRouter()
 
@@ -75,6 +111,13 @@ func main() {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 
+	// OpenAPI: OPENAPI_VALIDATE=true rechaza los requests que no cumplen el contrato
+	spec := newAPISpec()
+	if os.Getenv("OPENAPI_VALIDATE") == "true" {
+		r.Use(spec.Validator(r))
+	}
+	r.Get("/openapi.json", spec.Handler(r))
+
 	// Rutas
 	r.Get("/users", getUsers)
 	r.Get("/users/{id}", getUserByID)
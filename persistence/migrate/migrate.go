@@ -0,0 +1,532 @@
+// Package migrate aplica migraciones de esquema versionadas (up/down)
+// sobre database/sql, leyéndolas de un fs.FS (normalmente un embed.FS).
+//
+// Similar a Flyway o Liquibase en Java:
+//   - Cada migración es un par de archivos NNNN_nombre.up.sql / NNNN_nombre.down.sql
+//   - Las versiones aplicadas se registran en la tabla schema_migrations
+//   - Cada versión guarda un checksum SHA-256 del .up.sql: si alguien edita
+//     una migración ya aplicada, Up y Status lo detectan (ErrChecksumMismatch)
+//   - Un lock en la tabla schema_migrations_lock evita que dos procesos
+//     (por ejemplo dos réplicas arrancando a la vez) migren al mismo tiempo.
+//     Quien lo tiene renueva locked_at; un lock sin renovar por más de
+//     WithStaleLockAfter (el proceso murió) se libera solo
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josediaz/go-mastery-lab/persistence/sqltime"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("migrate: applied migration was modified")
+	ErrLocked           = errors.New("migrate: another migration is in progress")
+	ErrUnknownVersion   = errors.New("migrate: database has a version without migration file")
+)
+
+// Migration es una versión del esquema
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 de Up
+}
+
+// Status describe una migración y si está aplicada
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // El checksum guardado no coincide con el archivo
+}
+
+// Dialect define las diferencias de SQL entre drivers
+type Dialect int
+
+const (
+	SQLite   Dialect = iota // Placeholders ?
+	Postgres                // Placeholders $1, $2...
+)
+
+func (d Dialect) placeholder(n int) string {
+	if d == Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Migrator ejecuta migraciones contra una base de datos
+type Migrator struct {
+	db          *sql.DB
+	migrations  []Migration
+	dialect     Dialect
+	table       string
+	lockTable   string
+	lockTimeout time.Duration
+	staleAfter  time.Duration
+	owner       string
+}
+
+type Option func(*Migrator)
+
+func WithDialect(d Dialect) Option {
+	return func(m *Migrator) {
+		m.dialect = d
+	}
+}
+
+// WithTable cambia el nombre de la tabla de versiones (y de su tabla de lock)
+func WithTable(name string) Option {
+	return func(m *Migrator) {
+		m.table = name
+		m.lockTable = name + "_lock"
+	}
+}
+
+// WithLockTimeout define cuánto esperar a que otro proceso libere el lock
+func WithLockTimeout(d time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = d
+	}
+}
+
+// WithStaleLockAfter define después de cuánto tiempo sin renovar se
+// considera abandonado un lock (0 = nunca: hay que borrar la fila a mano)
+func WithStaleLockAfter(d time.Duration) Option {
+	return func(m *Migrator) {
+		m.staleAfter = d
+	}
+}
+
+// New carga las migraciones de dir dentro de fsys
+func New(db *sql.DB, fsys fs.FS, dir string, opts ...Option) (*Migrator, error) {
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := newOwner()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		db:          db,
+		migrations:  migrations,
+		dialect:     SQLite,
+		table:       "schema_migrations",
+		lockTable:   "schema_migrations_lock",
+		lockTimeout: 30 * time.Second,
+		staleAfter:  5 * time.Minute,
+		owner:       owner,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// newOwner identifica a este Migrator en la tabla de lock: host:pid más un
+// sufijo aleatorio, porque dos Migrators del mismo proceso no deben poder
+// renovar ni liberar el lock del otro
+func newOwner() (string, error) {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("migrate: generating lock owner: %w", err)
+	}
+	return fmt.Sprintf("%s:%d:%x", host, os.Getpid(), suffix), nil
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-zA-Z0-9_]+)\.(up|down)\.sql$`)
+
+// Load lee y valida los archivos de migración, ordenados por versión
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: reading %s: %w", dir, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrate: invalid migration file name %q (want NNNN_name.up.sql)", e.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d has two names: %q and %q", version, mig.Name, match[2])
+		}
+		if match[3] == "up" {
+			mig.Up = string(content)
+			sum := sha256.Sum256(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no .up.sql", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up aplica todas las migraciones pendientes en orden y devuelve las aplicadas
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func() error {
+		status, err := m.status(ctx)
+		if err != nil {
+			return err
+		}
+		if err := checkIntegrity(status); err != nil {
+			return err
+		}
+
+		for _, s := range status {
+			if s.Applied {
+				continue
+			}
+			if err := m.apply(ctx, s.Migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, s.Migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down revierte las últimas steps migraciones aplicadas
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func() error {
+		status, err := m.status(ctx)
+		if err != nil {
+			return err
+		}
+		if err := checkIntegrity(status); err != nil {
+			return err
+		}
+
+		for i := len(status) - 1; i >= 0 && len(reverted) < steps; i-- {
+			s := status[i]
+			if !s.Applied {
+				continue
+			}
+			if s.Down == "" {
+				return fmt.Errorf("migrate: version %d (%s) has no .down.sql", s.Version, s.Name)
+			}
+			if err := m.apply(ctx, s.Migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, s.Migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lista todas las migraciones conocidas y su estado
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTables(ctx); err != nil {
+		return nil, err
+	}
+	return m.status(ctx)
+}
+
+// Version devuelve la versión más alta aplicada (0 si ninguna)
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for _, s := range status {
+		if s.Applied {
+			version = s.Version
+		}
+	}
+	return version, nil
+}
+
+// apply ejecuta una migración y registra (o borra) su versión en la misma
+// transacción: si el SQL falla, la tabla de versiones no cambia
+func (m *Migrator) apply(ctx context.Context, mig Migration, up bool) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return fmt.Errorf("migrate: applying %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		query := fmt.Sprintf(`INSERT INTO %s (version, name, checksum, applied_at) VALUES (%s, %s, %s, %s)`,
+			m.table, m.dialect.placeholder(1), m.dialect.placeholder(2), m.dialect.placeholder(3), m.dialect.placeholder(4))
+		if _, err := tx.ExecContext(ctx, query, mig.Version, mig.Name, mig.Checksum, sqltime.Value(time.Now())); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return fmt.Errorf("migrate: reverting %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		query := fmt.Sprintf(`DELETE FROM %s WHERE version = %s`, m.table, m.dialect.placeholder(1))
+		if _, err := tx.ExecContext(ctx, query, mig.Version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) status(ctx context.Context) ([]Status, error) {
+	query := fmt.Sprintf(`SELECT version, checksum, applied_at FROM %s ORDER BY version`, m.table)
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type record struct {
+		checksum  string
+		appliedAt time.Time
+	}
+	applied := make(map[int64]record)
+	for rows.Next() {
+		var version int64
+		var r record
+		if err := rows.Scan(&version, &r.checksum, sqltime.Scan(&r.appliedAt)); err != nil {
+			return nil, err
+		}
+		applied[version] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Migration: mig}
+		if r, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = r.appliedAt
+			s.Modified = r.checksum != mig.Checksum
+			delete(applied, mig.Version)
+		}
+		status = append(status, s)
+	}
+
+	// Versiones en la base que ya no tienen archivo (¿binario viejo?)
+	if len(applied) > 0 {
+		var unknown []string
+		for version := range applied {
+			unknown = append(unknown, strconv.FormatInt(version, 10))
+		}
+		sort.Strings(unknown)
+		return status, fmt.Errorf("%w: %s", ErrUnknownVersion, strings.Join(unknown, ", "))
+	}
+	return status, nil
+}
+
+func checkIntegrity(status []Status) error {
+	var modified []string
+	for _, s := range status {
+		if s.Modified {
+			modified = append(modified, fmt.Sprintf("%04d_%s", s.Version, s.Name))
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("%w: %s (create a new migration instead of editing an applied one)",
+			ErrChecksumMismatch, strings.Join(modified, ", "))
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) error {
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`, m.table),
+		// CHECK (id = 1) garantiza una sola fila: quien la inserta tiene el lock
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			owner TEXT NOT NULL,
+			locked_at TIMESTAMP NOT NULL
+		)`, m.lockTable),
+	}
+	for _, q := range queries {
+		if _, err := m.db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withLock toma el lock de migraciones, ejecuta fn y lo libera.
+// Se usa una fila en vez de locks del motor (pg_advisory_lock, GET_LOCK)
+// para que funcione igual en SQLite y Postgres.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if err := m.ensureTables(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, owner, locked_at) VALUES (1, %s, %s)`,
+		m.lockTable, m.dialect.placeholder(1), m.dialect.placeholder(2))
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		_, err := m.db.ExecContext(ctx, query, m.owner, sqltime.Value(time.Now()))
+		if err == nil {
+			break
+		}
+		// Solo una violación de la PK significa que otro proceso tiene el
+		// lock; cualquier otro error (tabla rota, conexión) no se reintenta
+		if !isUniqueViolation(err) {
+			return fmt.Errorf("migrate: acquiring lock: %w", err)
+		}
+		released, err := m.releaseStaleLock(ctx)
+		if err != nil {
+			return err
+		}
+		if released {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w (held by %s; if that process died, delete the row from %s)",
+				ErrLocked, m.lockOwner(ctx), m.lockTable)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	stop := m.keepAlive()
+	defer func() {
+		stop()
+		unlock := fmt.Sprintf(`DELETE FROM %s WHERE id = 1 AND owner = %s`, m.lockTable, m.dialect.placeholder(1))
+		// Liberar aunque ctx esté cancelado
+		m.db.ExecContext(context.Background(), unlock, m.owner)
+	}()
+	return fn()
+}
+
+// keepAlive renueva locked_at mientras fn corre, así una migración larga no
+// parece abandonada. Devuelve la función que la detiene.
+func (m *Migrator) keepAlive() (stop func()) {
+	if m.staleAfter <= 0 {
+		return func() {}
+	}
+	query := fmt.Sprintf(`UPDATE %s SET locked_at = %s WHERE id = 1 AND owner = %s`,
+		m.lockTable, m.dialect.placeholder(1), m.dialect.placeholder(2))
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(m.staleAfter / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.db.ExecContext(context.Background(), query, sqltime.Value(time.Now()), m.owner)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// releaseStaleLock borra el lock si locked_at tiene más de staleAfter.
+// El DELETE repite owner y locked_at: si el dueño lo renovó o lo liberó
+// entre la lectura y el DELETE, no se borra nada.
+func (m *Migrator) releaseStaleLock(ctx context.Context) (bool, error) {
+	if m.staleAfter <= 0 {
+		return false, nil
+	}
+	var owner string
+	var lockedAt any
+	query := fmt.Sprintf(`SELECT owner, locked_at FROM %s WHERE id = 1`, m.lockTable)
+	if err := m.db.QueryRowContext(ctx, query).Scan(&owner, &lockedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return true, nil // Se liberó mientras tanto: reintentar ya
+		}
+		return false, fmt.Errorf("migrate: reading lock: %w", err)
+	}
+	var at time.Time
+	if err := sqltime.Scan(&at).Scan(lockedAt); err != nil {
+		return false, fmt.Errorf("migrate: reading lock: %w", err)
+	}
+	if time.Since(at) < m.staleAfter {
+		return false, nil
+	}
+
+	del := fmt.Sprintf(`DELETE FROM %s WHERE id = 1 AND owner = %s AND locked_at = %s`,
+		m.lockTable, m.dialect.placeholder(1), m.dialect.placeholder(2))
+	res, err := m.db.ExecContext(ctx, del, owner, lockedAt)
+	if err != nil {
+		return false, fmt.Errorf("migrate: releasing stale lock: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// isUniqueViolation: Postgres 23505 o el mensaje de SQLite.
+// Se evita importar los drivers: pgx y lib/pq exponen SQLState().
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return state.SQLState() == "23505"
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func (m *Migrator) lockOwner(ctx context.Context) string {
+	var owner string
+	query := fmt.Sprintf(`SELECT owner FROM %s WHERE id = 1`, m.lockTable)
+	if err := m.db.QueryRowContext(ctx, query).Scan(&owner); err != nil {
+		return "unknown"
+	}
+	return owner
+}
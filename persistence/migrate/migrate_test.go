@@ -0,0 +1,218 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var testMigrations = fstest.MapFS{
+	"migrations/0001_users.up.sql":   {Data: []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL)`)},
+	"migrations/0001_users.down.sql": {Data: []byte(`DROP TABLE users`)},
+	"migrations/0002_names.up.sql":   {Data: []byte(`ALTER TABLE users ADD COLUMN name TEXT`)},
+}
+
+// Un archivo y no :memory:, porque cada conexión del pool vería otra base
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB, owner string, opts ...Option) *Migrator {
+	t.Helper()
+	m, err := New(db, testMigrations, "migrations", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.owner = owner
+	return m
+}
+
+func holdLock(t *testing.T, db *sql.DB, owner string, at time.Time) {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, ?, ?)`, owner, at.UTC()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lockRows(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations_lock`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestUpAppliesAndReleasesLock(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := newTestMigrator(t, db, "a")
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("applied %d migrations, want 2", len(applied))
+	}
+	if lockRows(t, db) != 0 {
+		t.Error("lock row left behind after Up")
+	}
+	if applied, _ := m.Up(ctx); len(applied) != 0 {
+		t.Errorf("second Up applied %d migrations", len(applied))
+	}
+}
+
+func TestLockHeldByAnotherProcess(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := newTestMigrator(t, db, "a", WithLockTimeout(300*time.Millisecond))
+	if err := m.ensureTables(ctx); err != nil {
+		t.Fatal(err)
+	}
+	holdLock(t, db, "other-host:42", time.Now())
+
+	_, err := m.Up(ctx)
+	if !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), "other-host:42") {
+		t.Fatalf("Up err = %v, want ErrLocked naming the owner", err)
+	}
+	if lockRows(t, db) != 1 {
+		t.Error("Up removed a lock it did not own")
+	}
+}
+
+func TestMigratorsInSameProcessHaveDistinctOwners(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	first, err := New(db, testMigrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(db, testMigrations, "migrations", WithLockTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.owner == second.owner {
+		t.Fatalf("both migrators use owner %q", first.owner)
+	}
+
+	err = first.withLock(ctx, func() error {
+		// El segundo no debe tomar como propio el lock del primero
+		if _, err := second.Up(ctx); !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), first.owner) {
+			t.Errorf("second Up err = %v, want ErrLocked naming the first owner", err)
+		}
+		if lockRows(t, db) != 1 {
+			t.Error("second migrator released the first one's lock")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusReadsTextTimestamps(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	// applied_at declarado como TEXT: go-sqlite3 lo devuelve como string
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestMigrator(t, db, "a")
+	before := time.Now().Add(-time.Second)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if !s.Applied || s.AppliedAt.Before(before) || s.AppliedAt.Location() != time.UTC {
+			t.Errorf("%d applied = %v at %v, want a recent UTC time", s.Version, s.Applied, s.AppliedAt)
+		}
+	}
+}
+
+func TestStaleLockIsReleased(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := newTestMigrator(t, db, "a", WithLockTimeout(time.Second), WithStaleLockAfter(time.Minute))
+	if err := m.ensureTables(ctx); err != nil {
+		t.Fatal(err)
+	}
+	holdLock(t, db, "dead-host:1", time.Now().Add(-time.Hour))
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up with a stale lock: %v", err)
+	}
+	if lockRows(t, db) != 0 {
+		t.Error("lock row left behind")
+	}
+
+	// Con la detección desactivada el lock viejo se respeta
+	holdLock(t, db, "dead-host:1", time.Now().Add(-time.Hour))
+	m = newTestMigrator(t, db, "a", WithLockTimeout(100*time.Millisecond), WithStaleLockAfter(0))
+	if _, err := m.Up(ctx); !errors.Is(err, ErrLocked) {
+		t.Errorf("Up err = %v, want ErrLocked", err)
+	}
+}
+
+func TestLongMigrationKeepsLockAlive(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	staleAfter := 150 * time.Millisecond
+	holder := newTestMigrator(t, db, "holder", WithStaleLockAfter(staleAfter))
+	other := newTestMigrator(t, db, "other", WithStaleLockAfter(staleAfter), WithLockTimeout(50*time.Millisecond))
+
+	err := holder.withLock(ctx, func() error {
+		// Más que staleAfter: sin renovar locked_at, other robaría el lock
+		time.Sleep(3 * staleAfter)
+		return other.withLock(ctx, func() error {
+			t.Error("other acquired a lock that is still alive")
+			return nil
+		})
+	})
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("other.withLock err = %v, want ErrLocked", err)
+	}
+}
+
+func TestLockErrorsOtherThanContentionAreNotRetried(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	// Tabla de lock con otro esquema: el INSERT falla por la columna, no
+	// porque el lock esté tomado
+	if _, err := db.Exec(`CREATE TABLE schema_migrations_lock (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestMigrator(t, db, "a", WithLockTimeout(5*time.Second))
+
+	start := time.Now()
+	_, err := m.Up(ctx)
+	if err == nil || errors.Is(err, ErrLocked) {
+		t.Fatalf("Up err = %v, want the INSERT error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Up retried for %v on a non-contention error", elapsed)
+	}
+}
@@ -3,10 +3,16 @@ package main
 import (
 	"context"
 	"database/sql"
+	"embed"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
 	"time"
 	_ "github.com/mattn/go-sqlite3" // Driver SQLite (necesitas instalarlo)
+	"github.com/josediaz/go-mastery-lab/persistence/migrate"
+	"github.com/josediaz/go-mastery-lab/persistence/sqltime"
 )
 
 // ============================================================================
@@ -23,21 +29,52 @@ type User struct {
 	CreatedAt time.Time
 }
 
+// ============================================================================
+// MIGRACIONES
+// ============================================================================
+// El esquema vive en migrations/ como archivos versionados, embebidos en el
+// binario con embed.FS (no hay que copiar .sql junto al ejecutable).
+// Ver persistence/migrate para el versionado, checksums y locking.
+//
+//	go run . -db users.db migrate status
+//	go run . -db users.db migrate up
+//	go run . -db users.db migrate down 1
+// ============================================================================
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
 func main() {
+	// Con :memory: cada conexión del pool tendría su propia base vacía;
+	// cache=shared hace que todas vean la misma
+	dsn := flag.String("db", "file:demo?mode=memory&cache=shared", "SQLite DSN (ej: users.db)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-db DSN] [migrate up|down [N]|status]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
 	// Abrir conexión (pool de conexiones automático)
-	db, err := sql.Open("sqlite3", ":memory:")
+	db, err := sql.Open("sqlite3", *dsn)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(db, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Configurar pool de conexiones
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	// Crear tabla
-	if err := createTable(db); err != nil {
+	// Crear/actualizar esquema
+	if err := migrateUp(db); err != nil {
 		log.Fatal(err)
 	}
 
@@ -66,18 +103,70 @@ func main() {
 	fmt.Printf("Total users: %d\n", len(users))
 }
 
-func createTable(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		email TEXT NOT NULL UNIQUE,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	)`
-	_, err := db.Exec(query)
+func newMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	return migrate.New(db, migrationsFS, "migrations")
+}
+
+func migrateUp(db *sql.DB) error {
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	applied, err := m.Up(context.Background())
+	for _, mig := range applied {
+		fmt.Printf("Applied migration %04d_%s\n", mig.Version, mig.Name)
+	}
 	return err
 }
 
+// runMigrate implementa el subcomando: migrate up | down [N] | status
+func runMigrate(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [N]|status")
+	}
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return migrateUp(db)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mig := range reverted {
+			fmt.Printf("Reverted migration %04d_%s\n", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Modified {
+				state += " (MODIFIED: checksum mismatch)"
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up, down or status)", args[0])
+	}
+}
+
 func insertUser(ctx context.Context, db *sql.DB, name, email string) (int64, error) {
 	query := `INSERT INTO users (name, email) VALUES (?, ?)`
 	result, err := db.ExecContext(ctx, query, name, email)
@@ -91,14 +180,13 @@ func getUser(ctx context.Context, db *sql.DB, id int64) (*User, error) {
 	query := `SELECT id, name, email, created_at FROM users WHERE id = ?`
 	row := db.QueryRowContext(ctx, query, id)
 
+	// sqltime.Scan acepta time.Time, string o []byte según el driver y
+	// devuelve error si no puede interpretarlo (nunca una fecha en cero)
 	var user User
-	var createdAt string
-	err := row.Scan(&user.ID, &user.Name, &user.Email, &createdAt)
+	err := row.Scan(&user.ID, &user.Name, &user.Email, sqltime.Scan(&user.CreatedAt))
 	if err != nil {
 		return nil, err
 	}
-
-	user.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
 	return &user, nil
 }
 
@@ -113,11 +201,9 @@ func getUsers(ctx context.Context, db *sql.DB) ([]User, error) {
 	var users []User
 	for rows.Next() {
 		var user User
-		var createdAt string
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &createdAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, sqltime.Scan(&user.CreatedAt)); err != nil {
 			return nil, err
 		}
-		user.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
 		users = append(users, user)
 	}
 	return users, rows.Err()
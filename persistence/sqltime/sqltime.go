@@ -0,0 +1,113 @@
+// Package sqltime escanea y escribe timestamps de forma portable entre
+// drivers de database/sql, siempre en UTC.
+//
+// Cada driver devuelve las columnas de fecha de forma distinta:
+//   - go-sqlite3: time.Time si la columna se declaró DATETIME/TIMESTAMP,
+//     string si no (por ejemplo en expresiones o vistas)
+//   - lib/pq y pgx: time.Time para timestamp/timestamptz
+//   - MySQL sin parseTime=true: []byte
+//
+// Escanear a un string y llamar a time.Parse con un solo layout (e ignorar
+// el error) produce fechas en cero silenciosamente. Scan cubre todos los casos.
+package sqltime
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// layouts aceptados al recibir texto, del más al menos específico
+// (los mismos que go-sqlite3 usa al escribir y leer)
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Parse interpreta un timestamp textual; sin zona horaria se asume UTC
+func Parse(s string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("sqltime: cannot parse %q as timestamp", s)
+}
+
+type scanner struct {
+	dst  *time.Time
+	null **time.Time
+}
+
+// Scan devuelve un sql.Scanner que guarda la columna en dst (en UTC).
+// NULL produce un error: usar ScanNull para columnas opcionales.
+//
+//	row.Scan(&u.ID, sqltime.Scan(&u.CreatedAt))
+func Scan(dst *time.Time) sql.Scanner {
+	return &scanner{dst: dst}
+}
+
+// ScanNull es como Scan pero NULL deja dst en nil
+func ScanNull(dst **time.Time) sql.Scanner {
+	return &scanner{null: dst}
+}
+
+func (s *scanner) Scan(src any) error {
+	var t time.Time
+	switch v := src.(type) {
+	case nil:
+		if s.null != nil {
+			*s.null = nil
+			return nil
+		}
+		return fmt.Errorf("sqltime: NULL in non-nullable timestamp column")
+	case time.Time:
+		t = v.UTC()
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		t = parsed
+	case []byte:
+		parsed, err := Parse(string(v))
+		if err != nil {
+			return err
+		}
+		t = parsed
+	case int64:
+		t = time.Unix(v, 0).UTC() // Epoch en segundos (INTEGER en SQLite)
+	default:
+		return fmt.Errorf("sqltime: unsupported type %T for timestamp", src)
+	}
+
+	if s.null != nil {
+		*s.null = &t
+	} else {
+		*s.dst = t
+	}
+	return nil
+}
+
+// Value normaliza a UTC antes de escribir: así la base nunca guarda la
+// zona horaria local del servidor que hizo el INSERT
+func Value(t time.Time) driver.Value {
+	return t.UTC()
+}
+
+// NullValue escribe NULL si t es nil
+func NullValue(t *time.Time) driver.Value {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
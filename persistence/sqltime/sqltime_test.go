@@ -0,0 +1,129 @@
+package sqltime
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestParseLayouts(t *testing.T) {
+	want := time.Date(2024, 3, 9, 14, 5, 7, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-03-09T14:05:07Z", want},
+		{"2024-03-09T11:05:07-03:00", want},
+		{"2024-03-09 11:05:07-03:00", want},
+		{"2024-03-09 14:05:07.000000001+00:00", want.Add(time.Nanosecond)},
+		{"2024-03-09 14:05:07", want}, // Sin zona: UTC
+		{"2024-03-09T14:05:07", want},
+		{"2024-03-09 14:05", want.Add(-7 * time.Second)},
+		{"2024-03-09", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("Parse(%q) = %v, want %v in UTC", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "yesterday", "09/03/2024"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestScanSourceTypes(t *testing.T) {
+	lima := time.FixedZone("Lima", -5*3600)
+	want := time.Date(2024, 3, 9, 14, 5, 7, 0, time.UTC)
+	tests := []struct {
+		name string
+		src  any
+	}{
+		{"time.Time in another zone", want.In(lima)},
+		{"string", "2024-03-09 14:05:07"},
+		{"[]byte", []byte("2024-03-09T14:05:07Z")},
+		{"epoch seconds", want.Unix()},
+	}
+	for _, tt := range tests {
+		var got time.Time
+		if err := Scan(&got).Scan(tt.src); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("%s: got %v, want %v in UTC", tt.name, got, want)
+		}
+	}
+
+	var got time.Time
+	for _, src := range []any{nil, "not a date", 3.14} {
+		if err := Scan(&got).Scan(src); err == nil {
+			t.Errorf("Scan(%#v) succeeded", src)
+		}
+	}
+}
+
+func TestScanNull(t *testing.T) {
+	previous := time.Now()
+	got := &previous
+	if err := ScanNull(&got).Scan(nil); err != nil || got != nil {
+		t.Errorf("ScanNull(nil) = %v, %v; want nil", got, err)
+	}
+	if err := ScanNull(&got).Scan("2024-03-09"); err != nil || got == nil || got.Day() != 9 {
+		t.Errorf("ScanNull(date) = %v, %v", got, err)
+	}
+}
+
+func TestValueIsUTC(t *testing.T) {
+	local := time.Date(2024, 3, 9, 9, 0, 0, 0, time.FixedZone("Lima", -5*3600))
+	if v := Value(local).(time.Time); v.Location() != time.UTC || !v.Equal(local) {
+		t.Errorf("Value = %v, want the same instant in UTC", v)
+	}
+	if NullValue(nil) != nil {
+		t.Error("NullValue(nil) is not NULL")
+	}
+	if v := NullValue(&local).(time.Time); v.Location() != time.UTC {
+		t.Errorf("NullValue = %v, want UTC", v)
+	}
+}
+
+// Con go-sqlite3 la misma columna llega como time.Time o como string según
+// cómo se lea: Scan debe dar el mismo instante en ambos casos
+func TestScanSQLiteRoundTrip(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE events (at TIMESTAMP NOT NULL, deleted_at TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2024, 3, 9, 9, 0, 0, 123456789, time.FixedZone("Lima", -5*3600))
+	if _, err := db.Exec(`INSERT INTO events (at, deleted_at) VALUES (?, ?)`, Value(at), NullValue(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	var column, expression time.Time
+	var deletedAt *time.Time
+	row := db.QueryRow(`SELECT at, at || '', deleted_at FROM events`)
+	if err := row.Scan(Scan(&column), Scan(&expression), ScanNull(&deletedAt)); err != nil {
+		t.Fatal(err)
+	}
+	if !column.Equal(at) || !expression.Equal(at) {
+		t.Errorf("column = %v, expression = %v; want %v", column, expression, at)
+	}
+	if deletedAt != nil {
+		t.Errorf("deleted_at = %v, want nil", deletedAt)
+	}
+}
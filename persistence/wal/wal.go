@@ -0,0 +1,596 @@
+// Package wal implementa un write-ahead log segmentado con snapshots, para
+// dar durabilidad a estructuras en memoria sin una base de datos.
+//
+// Similar al transaction log de un motor SQL, o al AOF + RDB de Redis:
+//   - Append agrega un registro [longitud][CRC-32C][payload] al segmento actual
+//   - SyncPolicy decide cuándo hacer fsync: en cada Append, periódicamente o
+//     nunca (el sistema operativo decide cuándo llega al disco)
+//   - Compact rota el segmento y combina el snapshot anterior con los segmentos
+//     cerrados en un snapshot nuevo; después borra lo que ya no hace falta
+//   - Recover carga el último snapshot y reproduce los segmentos posteriores
+//
+// Un registro incompleto al final del último segmento es un crash a mitad de
+// escritura: se descarta y el archivo se trunca. Cualquier otro registro con
+// checksum inválido, o un segmento faltante, es ErrCorrupt: mejor no arrancar
+// que arrancar con datos perdidos en silencio.
+//
+// Archivos en el directorio:
+//
+//	0000000000000003.wal   segmento 3
+//	0000000000000002.snap  snapshot que incluye todo hasta el segmento 2
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCorrupt      = errors.New("wal: corrupt data")
+	ErrClosed       = errors.New("wal: log is closed")
+	ErrNotRecovered = errors.New("wal: Recover must be called before writing")
+)
+
+const (
+	segmentExt  = ".wal"
+	snapshotExt = ".snap"
+	headerSize  = 8
+	// maxRecordSize acota la longitud leída del header: un valor absurdo es
+	// basura, no un registro real
+	maxRecordSize = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SyncPolicy define cuándo se hace fsync de los registros escritos
+type SyncPolicy int
+
+const (
+	SyncAlways   SyncPolicy = iota // fsync en cada Append: no se pierde nada confirmado
+	SyncInterval                   // fsync periódico: se puede perder el último intervalo
+	SyncNever                      // fsync solo al rotar o cerrar
+)
+
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncAlways:
+		return "always"
+	case SyncInterval:
+		return "interval"
+	case SyncNever:
+		return "never"
+	default:
+		return "SyncPolicy(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// ParseSyncPolicy convierte "always", "interval" o "never" en SyncPolicy
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch s {
+	case "always":
+		return SyncAlways, nil
+	case "interval":
+		return SyncInterval, nil
+	case "never":
+		return SyncNever, nil
+	default:
+		return 0, fmt.Errorf("wal: invalid sync policy %q (want always, interval or never)", s)
+	}
+}
+
+// Log es un write-ahead log en un directorio. Es seguro para uso concurrente.
+type Log struct {
+	dir          string
+	syncPolicy   SyncPolicy
+	syncInterval time.Duration
+
+	mu        sync.Mutex
+	file      *os.File
+	segment   uint64 // segmento donde escribe Append
+	snapshot  uint64 // último segmento incluido en el snapshot (0 = sin snapshot)
+	pending   int    // registros que todavía no están en un snapshot
+	dirty     bool   // hay escrituras sin fsync
+	recovered bool
+	closed    bool
+
+	compactMu sync.Mutex // una compactación a la vez
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+type Option func(*Log)
+
+func WithSyncPolicy(p SyncPolicy) Option {
+	return func(l *Log) {
+		l.syncPolicy = p
+	}
+}
+
+// WithSyncInterval define cada cuánto se hace fsync con SyncInterval
+func WithSyncInterval(d time.Duration) Option {
+	return func(l *Log) {
+		l.syncInterval = d
+	}
+}
+
+// Open prepara el log en dir (creándolo si no existe). No lee nada:
+// hay que llamar a Recover antes de escribir.
+func Open(dir string, opts ...Option) (*Log, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	l := &Log{
+		dir:          dir,
+		syncPolicy:   SyncAlways,
+		syncInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+// Recover entrega el último snapshot a restore (si existe) y luego cada
+// registro posterior a apply, en orden. Después abre un segmento nuevo
+// para las escrituras, salvo que el último esté vacío: ese se reutiliza,
+// si no cada arranque sin escrituras dejaría un segmento vacío más.
+func (l *Log) Recover(restore func(snapshot []byte) error, apply func(record []byte) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.recovered {
+		return errors.New("wal: Recover called twice")
+	}
+
+	segments, snapshots, err := l.list()
+	if err != nil {
+		return err
+	}
+
+	// Snapshot más reciente; los anteriores quedan de una compactación interrumpida
+	if len(snapshots) > 0 {
+		l.snapshot = snapshots[len(snapshots)-1]
+		data, err := readSnapshot(l.path(l.snapshot, snapshotExt))
+		if err != nil {
+			return err
+		}
+		if err := restore(data); err != nil {
+			return err
+		}
+	}
+	l.removeCompacted(l.snapshot, snapshots, segments)
+
+	last := l.snapshot
+	for i, seq := range segments {
+		if seq <= l.snapshot {
+			continue
+		}
+		if seq != last+1 {
+			return fmt.Errorf("%w: missing segment %d", ErrCorrupt, last+1)
+		}
+		n, err := readSegment(l.path(seq, segmentExt), i == len(segments)-1, apply)
+		if err != nil {
+			return err
+		}
+		l.pending += n
+		last = seq
+	}
+
+	reuse := false
+	if last > l.snapshot {
+		info, err := os.Stat(l.path(last, segmentExt))
+		if err != nil {
+			return err
+		}
+		reuse = info.Size() == 0
+	}
+	if reuse {
+		err = l.openSegment(last, true)
+	} else {
+		err = l.openSegment(last+1, false)
+	}
+	if err != nil {
+		return err
+	}
+	l.recovered = true
+
+	if l.syncPolicy == SyncInterval {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.syncLoop()
+	}
+	return nil
+}
+
+// Append escribe un registro. El registro es la unidad atómica: al
+// recuperar se aplica completo o no se aplica.
+func (l *Log) Append(record []byte) error {
+	if len(record) > maxRecordSize {
+		return fmt.Errorf("wal: record of %d bytes exceeds limit", len(record))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	if !l.recovered {
+		return ErrNotRecovered
+	}
+
+	// Header y payload en un solo Write
+	if _, err := l.file.Write(encodeRecord(record)); err != nil {
+		return err
+	}
+	l.pending++
+
+	if l.syncPolicy == SyncAlways {
+		return l.file.Sync()
+	}
+	l.dirty = true
+	return nil
+}
+
+// Sync fuerza un fsync de lo escrito hasta ahora
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.syncLocked()
+}
+
+// Pending devuelve cuántos registros se reproducirían al recuperar
+// (los que todavía no están en un snapshot)
+func (l *Log) Pending() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.pending
+}
+
+// Compact genera un snapshot nuevo. fold recibe el snapshot anterior (nil si
+// no hay) y los registros posteriores, y devuelve el estado combinado.
+// Los Append pueden continuar mientras tanto: van al segmento nuevo.
+func (l *Log) Compact(fold func(snapshot []byte, records [][]byte) ([]byte, error)) error {
+	l.compactMu.Lock()
+	defer l.compactMu.Unlock()
+
+	// 1. Rotar: el segmento actual queda cerrado e inmutable
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	if !l.recovered {
+		l.mu.Unlock()
+		return ErrNotRecovered
+	}
+	if l.pending == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	cut, previous := l.segment, l.snapshot
+	if err := l.syncLocked(); err != nil {
+		l.mu.Unlock()
+		return err
+	}
+	if err := l.file.Close(); err != nil {
+		l.mu.Unlock()
+		return err
+	}
+	if err := l.openSegment(cut+1, false); err != nil {
+		l.mu.Unlock()
+		return err
+	}
+	l.mu.Unlock()
+
+	// 2. Leer snapshot anterior + segmentos cerrados, sin bloquear Append
+	var base []byte
+	if previous > 0 {
+		data, err := readSnapshot(l.path(previous, snapshotExt))
+		if err != nil {
+			return err
+		}
+		base = data
+	}
+	var records [][]byte
+	for seq := previous + 1; seq <= cut; seq++ {
+		_, err := readSegment(l.path(seq, segmentExt), false, func(record []byte) error {
+			records = append(records, record)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	state, err := fold(base, records)
+	if err != nil {
+		return err
+	}
+
+	// 3. Escribir el snapshot de forma atómica: tmp + fsync + rename
+	if err := writeSnapshot(l.path(cut, snapshotExt), state); err != nil {
+		return err
+	}
+	if err := syncDir(l.dir); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.snapshot = cut
+	l.pending -= len(records)
+	l.mu.Unlock()
+
+	// 4. Borrar lo que el snapshot nuevo ya incluye
+	segments, snapshots, err := l.list()
+	if err != nil {
+		return err
+	}
+	l.removeCompacted(cut, snapshots, segments)
+	return nil
+}
+
+// Close hace fsync y cierra el segmento actual
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	stop := l.stop
+	l.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-l.done
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Sync()
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (l *Log) syncLoop() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.Sync()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+func (l *Log) syncLocked() error {
+	if !l.dirty || l.file == nil {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// openSegment crea el segmento seq (o abre el existente si reuse) y lo deja
+// como destino de Append. Requiere l.mu tomado.
+func (l *Log) openSegment(seq uint64, reuse bool) error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !reuse {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(l.path(seq, segmentExt), flags, 0o644)
+	if err != nil {
+		return err
+	}
+	// El archivo nuevo debe sobrevivir a un crash aunque todavía esté vacío
+	if err := syncDir(l.dir); err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.segment, l.dirty = f, seq, false
+	return nil
+}
+
+// removeCompacted borra snapshots viejos, segmentos ya incluidos en el
+// snapshot actual y temporales de compactaciones interrumpidas.
+// Los errores se ignoran: un archivo sobrante se vuelve a borrar la próxima vez.
+func (l *Log) removeCompacted(current uint64, snapshots, segments []uint64) {
+	for _, seq := range snapshots {
+		if seq < current {
+			os.Remove(l.path(seq, snapshotExt))
+		}
+	}
+	for _, seq := range segments {
+		if seq <= current {
+			os.Remove(l.path(seq, segmentExt))
+		}
+	}
+	tmps, _ := filepath.Glob(filepath.Join(l.dir, "*.tmp"))
+	for _, tmp := range tmps {
+		os.Remove(tmp)
+	}
+}
+
+func (l *Log) path(seq uint64, ext string) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%016d%s", seq, ext))
+}
+
+// list devuelve los números de segmento y de snapshot ordenados
+func (l *Log) list() (segments, snapshots []uint64, err error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		ext := filepath.Ext(name)
+		if ext != segmentExt && ext != snapshotExt {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, ext), 10, 64)
+		if err != nil {
+			continue
+		}
+		if ext == segmentExt {
+			segments = append(segments, seq)
+		} else {
+			snapshots = append(snapshots, seq)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i] < snapshots[j] })
+	return segments, snapshots, nil
+}
+
+// ============================================================================
+// FORMATO DE REGISTROS
+// ============================================================================
+
+var (
+	errTorn     = errors.New("torn record")
+	errChecksum = errors.New("checksum mismatch")
+)
+
+func encodeRecord(data []byte) []byte {
+	buf := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(data, crcTable))
+	copy(buf[headerSize:], data)
+	return buf
+}
+
+// readRecord lee un registro. Devuelve io.EOF si no hay más datos, errTorn si
+// el archivo termina a mitad del registro y errChecksum si el CRC no coincide
+// (o la longitud supera maxSize, que solo puede ser basura).
+func readRecord(r *bufio.Reader, maxSize uint32) ([]byte, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errTorn
+		}
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxSize {
+		return nil, errChecksum
+	}
+	record := make([]byte, size)
+	if _, err := io.ReadFull(r, record); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errTorn
+		}
+		return nil, err
+	}
+	if crc32.Checksum(record, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errChecksum
+	}
+	return record, nil
+}
+
+// readSegment entrega cada registro a apply. Si tail es true (último segmento)
+// un registro dañado al final del archivo se considera escritura interrumpida
+// y se trunca; en cualquier otro caso es ErrCorrupt.
+func readSegment(path string, tail bool, apply func([]byte) error) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	count := 0
+	for {
+		record, err := readRecord(r, maxRecordSize)
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if errors.Is(err, errTorn) || errors.Is(err, errChecksum) {
+			// Solo es escritura interrumpida si no queda nada después
+			_, peekErr := r.Peek(1)
+			atEnd := errors.Is(err, errTorn) || errors.Is(peekErr, io.EOF)
+			if tail && atEnd {
+				return count, os.Truncate(path, offset)
+			}
+			return count, fmt.Errorf("%w: %s at offset %d: %v", ErrCorrupt, filepath.Base(path), offset, err)
+		}
+		if err != nil {
+			return count, err
+		}
+		if err := apply(record); err != nil {
+			return count, err
+		}
+		offset += int64(headerSize + len(record))
+		count++
+	}
+}
+
+func readSnapshot(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Un snapshot contiene todo el estado: no tiene el límite de un registro
+	data, err := readRecord(bufio.NewReader(f), math.MaxUint32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: snapshot %s: %v", ErrCorrupt, filepath.Base(path), err)
+	}
+	return data, nil
+}
+
+func writeSnapshot(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(encodeRecord(data)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// syncDir hace fsync del directorio para que creaciones y renames sean durables
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,343 @@
+package wal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recovered es lo que Recover entregó: el snapshot y los registros posteriores
+type recovered struct {
+	snapshot string
+	records  []string
+}
+
+func openAndRecover(t *testing.T, dir string) (*Log, recovered, error) {
+	t.Helper()
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got recovered
+	err = l.Recover(func(snapshot []byte) error {
+		got.snapshot = string(snapshot)
+		return nil
+	}, func(record []byte) error {
+		got.records = append(got.records, string(record))
+		return nil
+	})
+	return l, got, err
+}
+
+func mustRecover(t *testing.T, dir string) (*Log, recovered) {
+	t.Helper()
+	l, got, err := openAndRecover(t, dir)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, got
+}
+
+func appendAll(t *testing.T, l *Log, records ...string) {
+	t.Helper()
+	for _, r := range records {
+		if err := l.Append([]byte(r)); err != nil {
+			t.Fatalf("Append(%q): %v", r, err)
+		}
+	}
+}
+
+func files(t *testing.T, dir, ext string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range matches {
+		matches[i] = filepath.Base(m)
+	}
+	return matches
+}
+
+// concatFold combina snapshot y registros en un solo string separado por comas
+func concatFold(snapshot []byte, records [][]byte) ([]byte, error) {
+	parts := []string{}
+	if len(snapshot) > 0 {
+		parts = append(parts, string(snapshot))
+	}
+	for _, r := range records {
+		parts = append(parts, string(r))
+	}
+	return []byte(strings.Join(parts, ",")), nil
+}
+
+func TestAppendAndRecover(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := mustRecover(t, dir)
+	appendAll(t, l, "a", "b", "")
+	if l.Pending() != 3 {
+		t.Errorf("Pending = %d, want 3", l.Pending())
+	}
+	l.Close()
+	if err := l.Append([]byte("x")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Append after Close err = %v, want ErrClosed", err)
+	}
+
+	l, got := mustRecover(t, dir)
+	if fmt.Sprint(got.records) != "[a b ]" || got.snapshot != "" {
+		t.Errorf("recovered %+v", got)
+	}
+	if l.Pending() != 3 {
+		t.Errorf("Pending after Recover = %d, want 3", l.Pending())
+	}
+}
+
+func TestAppendBeforeRecover(t *testing.T) {
+	l, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append([]byte("a")); !errors.Is(err, ErrNotRecovered) {
+		t.Errorf("Append err = %v, want ErrNotRecovered", err)
+	}
+}
+
+func TestReopenWithoutWritesReusesEmptySegment(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := mustRecover(t, dir)
+	appendAll(t, l, "a")
+	l.Close()
+
+	for i := 0; i < 3; i++ {
+		l, _ := mustRecover(t, dir)
+		l.Close()
+	}
+	if segs := files(t, dir, segmentExt); len(segs) != 2 {
+		t.Errorf("segments = %v, want the written one and a single empty one", segs)
+	}
+
+	l, _ = mustRecover(t, dir)
+	appendAll(t, l, "b")
+	l.Close()
+	_, got := mustRecover(t, dir)
+	if fmt.Sprint(got.records) != "[a b]" {
+		t.Errorf("records = %v", got.records)
+	}
+}
+
+func TestTornTailIsTruncated(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := mustRecover(t, dir)
+	appendAll(t, l, "first", "second", "third")
+	l.Close()
+
+	// Crash a mitad del último registro: sobrevive solo parte de sus bytes
+	path := filepath.Join(dir, files(t, dir, segmentExt)[0])
+	info, _ := os.Stat(path)
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	l, got := mustRecover(t, dir)
+	if fmt.Sprint(got.records) != "[first second]" {
+		t.Errorf("records = %v, want the two complete ones", got.records)
+	}
+	// El archivo quedó truncado en el último registro completo: lo que se
+	// escribe después se recupera bien
+	appendAll(t, l, "fourth")
+	l.Close()
+	_, got = mustRecover(t, dir)
+	if fmt.Sprint(got.records) != "[first second fourth]" {
+		t.Errorf("records after truncation = %v", got.records)
+	}
+}
+
+// corruptRecord invierte un byte del payload del registro index
+func corruptRecord(t *testing.T, path string, index int) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := 0
+	for i := 0; i < index; i++ {
+		size := int(data[offset])<<24 | int(data[offset+1])<<16 | int(data[offset+2])<<8 | int(data[offset+3])
+		offset += headerSize + size
+	}
+	data[offset+headerSize] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		segment int // 0 = primer segmento, 1 = último
+		record  int
+		wantErr bool
+	}{
+		// En el último registro del último segmento es una escritura interrumpida
+		{"last record of tail segment", 1, 1, false},
+		// Con registros válidos después ya no puede ser un crash
+		{"middle of tail segment", 1, 0, true},
+		{"closed segment", 0, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			l, _ := mustRecover(t, dir)
+			appendAll(t, l, "a", "b", "c")
+			l.Close()
+			l, _ = mustRecover(t, dir)
+			appendAll(t, l, "d", "e")
+			l.Close()
+
+			segs := files(t, dir, segmentExt)
+			corruptRecord(t, filepath.Join(dir, segs[tt.segment]), tt.record)
+
+			l, got, err := openAndRecover(t, dir)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCorrupt) {
+					t.Errorf("Recover err = %v, want ErrCorrupt", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Recover: %v", err)
+			}
+			defer l.Close()
+			if got := fmt.Sprint(got.records); got != "[a b c d]" {
+				t.Errorf("records = %v", got)
+			}
+		})
+	}
+}
+
+func TestMissingSegment(t *testing.T) {
+	dir := t.TempDir()
+	for _, r := range []string{"a", "b", "c"} {
+		l, _ := mustRecover(t, dir)
+		appendAll(t, l, r)
+		l.Close()
+	}
+	segs := files(t, dir, segmentExt)
+	os.Remove(filepath.Join(dir, segs[1]))
+
+	if _, _, err := openAndRecover(t, dir); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Recover err = %v, want ErrCorrupt", err)
+	}
+}
+
+func TestCompactAndRecover(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := mustRecover(t, dir)
+	appendAll(t, l, "a", "b")
+	if err := l.Compact(concatFold); err != nil {
+		t.Fatal(err)
+	}
+	if l.Pending() != 0 {
+		t.Errorf("Pending after Compact = %d, want 0", l.Pending())
+	}
+	appendAll(t, l, "c")
+	if err := l.Compact(concatFold); err != nil {
+		t.Fatal(err)
+	}
+	appendAll(t, l, "d")
+	l.Close()
+
+	// Solo queda el último snapshot y el segmento posterior
+	if snaps := files(t, dir, snapshotExt); len(snaps) != 1 {
+		t.Errorf("snapshots = %v, want 1", snaps)
+	}
+	if segs := files(t, dir, segmentExt); len(segs) != 1 {
+		t.Errorf("segments = %v, want 1", segs)
+	}
+
+	l, got := mustRecover(t, dir)
+	if got.snapshot != "a,b,c" || fmt.Sprint(got.records) != "[d]" {
+		t.Errorf("recovered %+v, want snapshot a,b,c and record d", got)
+	}
+	if l.Pending() != 1 {
+		t.Errorf("Pending = %d, want 1", l.Pending())
+	}
+
+	// Sin registros nuevos Compact no hace nada
+	l.Close()
+	l, _ = mustRecover(t, dir)
+	appendAll(t, l, "e")
+	if err := l.Compact(concatFold); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Compact(func([]byte, [][]byte) ([]byte, error) {
+		t.Error("fold called without pending records")
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompactFoldErrorKeepsData(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := mustRecover(t, dir)
+	appendAll(t, l, "a")
+	boom := errors.New("boom")
+	if err := l.Compact(func([]byte, [][]byte) ([]byte, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Fatalf("Compact err = %v", err)
+	}
+	appendAll(t, l, "b")
+	l.Close()
+
+	_, got := mustRecover(t, dir)
+	if got.snapshot != "" || fmt.Sprint(got.records) != "[a b]" {
+		t.Errorf("recovered %+v after a failed compaction", got)
+	}
+}
+
+func TestRecoverAfterInterruptedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := mustRecover(t, dir)
+	appendAll(t, l, "a")
+	l.Compact(concatFold)
+	appendAll(t, l, "b")
+	l.Close()
+
+	// Restos de una compactación que murió: un .tmp a medio escribir
+	snap := files(t, dir, snapshotExt)[0]
+	tmp := filepath.Join(dir, "0000000000000009.snap.tmp")
+	if err := os.WriteFile(tmp, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, got := mustRecover(t, dir)
+	if got.snapshot != "a" || fmt.Sprint(got.records) != "[b]" {
+		t.Errorf("recovered %+v", got)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Error("leftover .tmp was not removed")
+	}
+	if files(t, dir, snapshotExt)[0] != snap {
+		t.Error("snapshot changed on Recover")
+	}
+}
+
+func TestCorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := mustRecover(t, dir)
+	appendAll(t, l, "a")
+	l.Compact(concatFold)
+	l.Close()
+
+	path := filepath.Join(dir, files(t, dir, snapshotExt)[0])
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(data, []byte("a"), []byte("z"), 1), 0o644)
+
+	if _, _, err := openAndRecover(t, dir); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Recover err = %v, want ErrCorrupt", err)
+	}
+}
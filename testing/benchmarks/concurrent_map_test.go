@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/josediaz/go-mastery-lab/concurrency/shardmap"
+)
+
+// ============================================================================
+// BENCHMARK: MAPS CONCURRENTES
+// ============================================================================
+// Compara un map con un solo RWMutex (como SafeMap en concurrency/sync),
+// sync.Map y shardmap.Map con distintas cantidades de shards.
+// Ejecutar con: go test -bench=ConcurrentMap -cpu=1,4,8
+// La diferencia aparece al subir -cpu: con un solo lock todas las
+// goroutines compiten por él; con shards la contención se reparte.
+// ============================================================================
+
+type concurrentMap interface {
+	Get(key string) (int, bool)
+	Set(key string, value int)
+}
+
+// singleLockMap replica SafeMap de concurrency/sync
+type singleLockMap struct {
+	mu   sync.RWMutex
+	data map[string]int
+}
+
+func (m *singleLockMap) Get(key string) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[key]
+	return v, ok
+}
+
+func (m *singleLockMap) Set(key string, value int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
+}
+
+// syncMap adapta sync.Map a la misma interfaz
+type syncMap struct {
+	m sync.Map
+}
+
+func (m *syncMap) Get(key string) (int, bool) {
+	v, ok := m.m.Load(key)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+func (m *syncMap) Set(key string, value int) {
+	m.m.Store(key, value)
+}
+
+var benchKeys = func() []string {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	return keys
+}()
+
+func concurrentMapImplementations() []struct {
+	name string
+	new  func() concurrentMap
+} {
+	return []struct {
+		name string
+		new  func() concurrentMap
+	}{
+		{"single-lock", func() concurrentMap { return &singleLockMap{data: make(map[string]int)} }},
+		{"sync.Map", func() concurrentMap { return &syncMap{} }},
+		{"shardmap-8", func() concurrentMap { return shardmap.New[string, int](shardmap.WithShards(8)) }},
+		{"shardmap-32", func() concurrentMap { return shardmap.New[string, int](shardmap.WithShards(32)) }},
+		{"shardmap-128", func() concurrentMap { return shardmap.New[string, int](shardmap.WithShards(128)) }},
+	}
+}
+
+// startOffset da a cada goroutine de RunParallel un punto de partida
+// distinto en benchKeys: si todas arrancan en 0 recorren las mismas claves
+// a la vez y se mide la contención sobre esas pocas claves
+func startOffset() int {
+	return rand.Intn(len(benchKeys))
+}
+
+// benchmarkMixed ejecuta una carga con writePercent% de escrituras
+func benchmarkMixed(b *testing.B, writePercent int) {
+	for _, impl := range concurrentMapImplementations() {
+		b.Run(impl.name, func(b *testing.B) {
+			m := impl.new()
+			for i, k := range benchKeys {
+				m.Set(k, i)
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := startOffset()
+				for pb.Next() {
+					key := benchKeys[i%len(benchKeys)]
+					if i%100 < writePercent {
+						m.Set(key, i)
+					} else {
+						m.Get(key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkConcurrentMapWriteHeavy(b *testing.B) {
+	benchmarkMixed(b, 90)
+}
+
+func BenchmarkConcurrentMapReadHeavy(b *testing.B) {
+	benchmarkMixed(b, 10)
+}
+
+// BenchmarkConcurrentMapCounter compara un incremento atómico:
+// con un solo lock se necesita Lock+leer+escribir; shardmap usa Upsert
+func BenchmarkConcurrentMapCounter(b *testing.B) {
+	b.Run("single-lock", func(b *testing.B) {
+		m := &singleLockMap{data: make(map[string]int)}
+		b.RunParallel(func(pb *testing.PB) {
+			i := startOffset()
+			for pb.Next() {
+				key := benchKeys[i%len(benchKeys)]
+				m.mu.Lock()
+				m.data[key]++
+				m.mu.Unlock()
+				i++
+			}
+		})
+	})
+
+	b.Run("shardmap-32", func(b *testing.B) {
+		m := shardmap.New[string, int]()
+		add := func(a, b int) int { return a + b }
+		b.RunParallel(func(pb *testing.PB) {
+			i := startOffset()
+			for pb.Next() {
+				m.Upsert(benchKeys[i%len(benchKeys)], 1, add)
+				i++
+			}
+		})
+	})
+}